@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,29 +18,59 @@ import (
 	"suitemedia/internal/middleware"
 	"suitemedia/internal/repository"
 	"suitemedia/internal/service"
+	"suitemedia/pkg/cache"
+	"suitemedia/pkg/health"
 	"suitemedia/pkg/logger"
+	"suitemedia/pkg/pagination"
+	"suitemedia/pkg/payment"
+	"suitemedia/pkg/ratelimit"
 	"suitemedia/pkg/redis"
+	"suitemedia/pkg/suggest"
+	"suitemedia/pkg/tax"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load .env file
+	// Load .env file first so that it can set CONFIG_FILE
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	configFiles := flag.String("config", os.Getenv("CONFIG_FILE"), "comma-separated YAML or TOML config files, applied in order")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	files := strings.Split(*configFiles, ",")
+	cfg, err := config.Load(files...)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	if *printConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
 	// Initialize logger
 	logger := logger.NewLogger(cfg.App.LogLevel)
 	logger.Info("Starting SuiteMedia API Server")
 
+	// Reload dynamic settings on SIGHUP or config file change
+	configStore := config.NewStore(cfg)
+	configStore.Subscribe(func(c *config.Config) {
+		logger.SetLevel(c.App.LogLevel)
+	})
+
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	reloader := config.NewReloader(configStore, files, logger, 10*time.Second)
+	go reloader.Watch(reloadCtx)
+
 	// Initialize database connection
 	db, err := database.NewConnection(cfg.Database)
 	if err != nil {
@@ -46,32 +78,148 @@ func main() {
 	}
 	defer db.Close()
 
-	// Run database migrations
-	if err := database.RunMigrations(db); err != nil {
-		logger.Fatal("Failed to run migrations", "error", err)
-	}
+	// Register dependency health checks
+	checkTimeout := time.Duration(cfg.Health.CheckTimeoutMs) * time.Millisecond
+	healthRegistry := health.NewRegistry(time.Duration(cfg.Health.CacheTTLMs) * time.Millisecond)
+	healthRegistry.Register("database", health.CheckerFunc(db.PingContext), health.Options{Timeout: checkTimeout, Critical: true})
 
-	// Initialize Redis client
-	redisClient, err := redis.NewClient(cfg.Redis)
-	if err != nil {
-		logger.Fatal("Failed to connect to Redis", "error", err)
+	// Initialize cache: Redis, in-memory, or Redis with an in-memory fallback.
+	// The rate limiter and suggestion index follow the same choice.
+	var appCache cache.Cache
+	memoryLimiter := ratelimit.NewMemory()
+	var limiter ratelimit.Limiter = memoryLimiter
+	memorySuggestions := suggest.NewMemory()
+	var suggestions suggest.Index = memorySuggestions
+	switch cfg.Cache.Driver {
+	case "memory":
+		appCache = cache.NewMemory(cfg.Cache.MaxEntries)
+		logger.Info("Using in-memory cache")
+	case "redis":
+		redisClient, err := redis.NewClient(cfg.Redis)
+		if err != nil {
+			logger.Fatal("Failed to connect to Redis", "error", err)
+		}
+		appCache = redisClient
+		limiter = ratelimit.NewFallback(func() (ratelimit.Scripter, bool) { return redisClient, true }, memoryLimiter)
+		suggestions = suggest.NewFallback(func() (suggest.Scripter, bool) { return redisClient, true }, memorySuggestions)
+		healthRegistry.Register("redis", health.CheckerFunc(redisClient.Ping), health.Options{Timeout: checkTimeout, Critical: true})
+	default:
+		fallback := cache.NewFallback(func(ctx context.Context) (cache.Cache, error) {
+			client, err := redis.NewClient(cfg.Redis)
+			if err != nil {
+				return nil, err
+			}
+			return client, nil
+		}, cache.NewMemory(cfg.Cache.MaxEntries), time.Duration(cfg.Cache.ReconnectIntervalSeconds)*time.Second, logger)
+		fallback.Start(context.Background())
+		appCache = fallback
+		limiter = ratelimit.NewFallback(func() (ratelimit.Scripter, bool) {
+			primary, ok := fallback.Primary()
+			if !ok {
+				return nil, false
+			}
+			scripter, ok := primary.(ratelimit.Scripter)
+			return scripter, ok
+		}, memoryLimiter)
+		suggestions = suggest.NewFallback(func() (suggest.Scripter, bool) {
+			primary, ok := fallback.Primary()
+			if !ok {
+				return nil, false
+			}
+			scripter, ok := primary.(suggest.Scripter)
+			return scripter, ok
+		}, memorySuggestions)
+		healthRegistry.Register("redis", health.CheckerFunc(fallback.Ping), health.Options{Timeout: checkTimeout})
 	}
-	defer redisClient.Close()
+	defer appCache.Close()
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	productRepo := repository.NewProductRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	rateRepo := repository.NewRateRepository(db)
+	variantRepo := repository.NewVariantRepository(db)
+	inventoryRepo := repository.NewInventoryRepository(db)
+	warehouseRepo := repository.NewWarehouseRepository(db)
+	cartRepo := repository.NewCartRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	paymentRepo := repository.NewPaymentRepository(db)
+	promotionRepo := repository.NewPromotionRepository(db)
+	transactor := repository.NewTransactor(db)
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, redisClient)
-	productService := service.NewProductService(productRepo, redisClient)
-	authService := service.NewAuthService(userRepo, cfg.JWT)
+	userService := service.NewUserService(userRepo, appCache, cfg.Cache)
+	// Products are categorized through the category service and renamed
+	// categories evict their products through the product service.
+	var productService service.ProductService
+	categoryService := service.NewCategoryService(categoryRepo, appCache, cfg.Cache, suggestions, func(ctx context.Context, id string) {
+		productService.Invalidate(ctx, id)
+	})
+	currencyService := service.NewCurrencyService(rateRepo, appCache, cfg.Cache, cfg.Currency.Base)
+	productService = service.NewProductService(productRepo, inventoryRepo, transactor, categoryService, currencyService, appCache, cfg.Cache, suggestions)
+	variantService := service.NewVariantService(variantRepo, inventoryRepo, transactor, productService)
+	inventoryService := service.NewInventoryService(inventoryRepo, warehouseRepo, transactor, productService, cfg.Inventory)
+	warehouseService := service.NewWarehouseService(warehouseRepo)
+	promotionService := service.NewPromotionService(promotionRepo, currencyService)
+
+	// The rates were checked when the configuration was loaded.
+	taxRules, err := tax.ParseRules(cfg.Tax.Rates)
+	if err != nil {
+		logger.Fatal("Failed to parse tax rates", "error", err)
+	}
+	taxCalculator, err := tax.Open(cfg.Tax.Provider, tax.Settings{
+		Rules:            taxRules,
+		PricesIncludeTax: cfg.Tax.PricesIncludeTax,
+		Rounding:         cfg.Tax.Rounding,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create tax calculator", "error", err)
+	}
+	pricingService := service.NewPricingService(promotionRepo, orderRepo, categoryService, taxCalculator, cfg.Tax, cfg.Orders)
+	cartService := service.NewCartService(cartRepo, productService, variantService, currencyService, pricingService, appCache, cfg.Cache, cfg.Cart)
+	orderService := service.NewOrderService(orderRepo, promotionRepo, transactor, cartService, pricingService, inventoryService, cfg.Orders)
+	authService := service.NewAuthService(userRepo, cfg.JWT, cartService, logger)
+
+	// The fake payment provider falls back to a webhook secret derived
+	// from the JWT secret, like list cursors.
+	webhookSecret := cfg.Payments.WebhookSecret
+	if webhookSecret == "" {
+		webhookSecret = "payments:" + cfg.JWT.Secret
+	}
+	paymentProvider, err := payment.Open(cfg.Payments.Provider, payment.Settings{
+		WebhookSecret:    webhookSecret,
+		WebhookTolerance: time.Duration(cfg.Payments.WebhookToleranceSeconds) * time.Second,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create payment provider", "error", err)
+	}
+	// The fake provider is rejected in production by configuration
+	// validation; elsewhere admins drive its simulator.
+	fakePayments, _ := paymentProvider.(*payment.FakeProvider)
+	paymentService := service.NewPaymentService(paymentRepo, transactor, orderService, paymentProvider)
+
+	// Cursors fall back to a key derived from the JWT secret so that
+	// existing deployments need no new configuration.
+	cursorSecret := cfg.Pagination.CursorSecret
+	if cursorSecret == "" {
+		cursorSecret = "pagination:" + cfg.JWT.Secret
+	}
+	cursors := pagination.NewSigner(cursorSecret)
 
 	// Initialize handlers
-	healthHandler := handlers.NewHealthHandler(db, redisClient)
+	healthHandler := handlers.NewHealthHandler(healthRegistry)
 	authHandler := handlers.NewAuthHandler(authService)
-	userHandler := handlers.NewUserHandler(userService)
-	productHandler := handlers.NewProductHandler(productService)
+	userHandler := handlers.NewUserHandler(userService, cursors)
+	productHandler := handlers.NewProductHandler(productService, cursors)
+	categoryHandler := handlers.NewCategoryHandler(categoryService, productService, cursors)
+	currencyHandler := handlers.NewCurrencyHandler(currencyService)
+	variantHandler := handlers.NewVariantHandler(variantService)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService, cursors)
+	warehouseHandler := handlers.NewWarehouseHandler(warehouseService)
+	cartHandler := handlers.NewCartHandler(cartService, cfg.Cart.AnonymousTTLHours)
+	orderHandler := handlers.NewOrderHandler(orderService, cursors)
+	paymentHandler := handlers.NewPaymentHandler(paymentService, fakePayments)
+	promotionHandler := handlers.NewPromotionHandler(promotionService, cursors)
 
 	// Setup Gin router
 	if cfg.App.Environment == "production" {
@@ -79,54 +227,164 @@ func main() {
 	}
 
 	router := gin.New()
+	// The client IP used by rate limits and idempotency keys only comes
+	// from X-Forwarded-For when the request came through a trusted proxy.
+	if err := router.SetTrustedProxies(cfg.App.TrustedProxies); err != nil {
+		logger.Fatal("Failed to set trusted proxies", "error", err)
+	}
 
 	// Global middleware
 	router.Use(middleware.Logger(logger))
 	router.Use(middleware.Recovery(logger))
-	router.Use(middleware.CORS(cfg.CORS))
+	router.Use(middleware.DynamicCORS(configStore))
 	router.Use(middleware.RequestID())
 	router.Use(middleware.Metrics())
 
 	// Health check endpoints
 	router.GET("/health", healthHandler.Health)
 	router.GET("/ready", healthHandler.Ready)
+	router.GET("/startup", healthHandler.Startup)
 	router.GET("/metrics", handlers.PrometheusHandler())
 
+	idempotent := middleware.Idempotency(appCache, cfg.Idempotency, logger)
+	// Only writes to versioned resources take If-Match.
+	ifMatch := middleware.IfMatch(configStore)
+
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
 		// Public routes
 		auth := v1.Group("/auth")
+		auth.Use(middleware.RateLimit(limiter, configStore, "auth", logger))
 		{
-			auth.POST("/register", authHandler.Register)
+			auth.POST("/register", idempotent, authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/refresh", authHandler.RefreshToken)
 		}
 
+		// Cart routes serve logged-in users and anonymous shoppers alike
+		cart := v1.Group("/cart")
+		cart.Use(middleware.OptionalAuth(cfg.JWT))
+		cart.Use(middleware.RateLimit(limiter, configStore, "api", logger))
+		{
+			cart.GET("", cartHandler.Get)
+			cart.DELETE("", cartHandler.Clear)
+			cart.POST("/items", cartHandler.AddItem)
+			cart.PUT("/items/:itemId", cartHandler.UpdateItem)
+			cart.DELETE("/items/:itemId", cartHandler.RemoveItem)
+			cart.POST("/promotions", cartHandler.ApplyPromotion)
+			cart.DELETE("/promotions/:code", cartHandler.RemovePromotion)
+		}
+
+		// Payment providers authenticate their webhooks with signatures
+		v1.POST("/payments/webhooks/:provider", paymentHandler.Webhook)
+
 		// Protected routes
 		protected := v1.Group("")
 		protected.Use(middleware.AuthRequired(cfg.JWT))
+		protected.Use(middleware.RateLimit(limiter, configStore, "api", logger))
 		{
 			// User routes
 			users := protected.Group("/users")
 			{
 				users.GET("", userHandler.List)
 				users.GET("/:id", userHandler.GetByID)
-				users.POST("", userHandler.Create)
-				users.PUT("/:id", userHandler.Update)
-				users.DELETE("/:id", userHandler.Delete)
+				users.POST("", middleware.RoleRequired("admin"), idempotent, userHandler.Create)
+				users.PUT("/:id", middleware.RoleRequired("admin"), ifMatch, userHandler.Update)
+				users.PATCH("/:id", middleware.RoleRequired("admin"), ifMatch, userHandler.Patch)
+				users.DELETE("/:id", middleware.RoleRequired("admin"), ifMatch, userHandler.Delete)
 				users.GET("/me", userHandler.GetProfile)
-				users.PUT("/me", userHandler.UpdateProfile)
+				users.PUT("/me", ifMatch, userHandler.UpdateProfile)
+				users.GET("/me/orders", orderHandler.ListMine)
+				users.GET("/me/orders/:orderId", orderHandler.GetMine)
+				users.POST("/me/orders/:orderId/cancel", orderHandler.CancelMine)
+				users.POST("/me/orders/:orderId/payments", idempotent, paymentHandler.Pay)
 			}
 
 			// Product routes
 			products := protected.Group("/products")
 			{
 				products.GET("", productHandler.List)
+				products.GET("/facets", productHandler.Facets)
+				products.GET("/suggest", middleware.RateLimit(limiter, configStore, "suggest", logger), productHandler.Suggest)
 				products.GET("/:id", productHandler.GetByID)
-				products.POST("", middleware.RoleRequired("admin"), productHandler.Create)
-				products.PUT("/:id", middleware.RoleRequired("admin"), productHandler.Update)
-				products.DELETE("/:id", middleware.RoleRequired("admin"), productHandler.Delete)
+				products.POST("", middleware.RoleRequired("admin"), idempotent, productHandler.Create)
+				products.PUT("/:id", middleware.RoleRequired("admin"), ifMatch, productHandler.Update)
+				products.PATCH("/:id", middleware.RoleRequired("admin"), ifMatch, productHandler.Patch)
+				products.DELETE("/:id", middleware.RoleRequired("admin"), ifMatch, productHandler.Delete)
+
+				products.GET("/:id/variants", variantHandler.List)
+				products.GET("/:id/variants/:variantId", variantHandler.GetByID)
+				products.POST("/:id/variants", middleware.RoleRequired("admin"), idempotent, variantHandler.Create)
+				products.POST("/:id/variants/generate", middleware.RoleRequired("admin"), variantHandler.Generate)
+				products.PUT("/:id/variants/:variantId", middleware.RoleRequired("admin"), ifMatch, variantHandler.Update)
+				products.DELETE("/:id/variants/:variantId", middleware.RoleRequired("admin"), ifMatch, variantHandler.Delete)
+
+				products.GET("/:id/inventory", middleware.RoleRequired("admin"), inventoryHandler.Levels)
+				products.GET("/:id/inventory/movements", middleware.RoleRequired("admin"), inventoryHandler.Movements)
+				products.POST("/:id/inventory/movements", middleware.RoleRequired("admin"), idempotent, inventoryHandler.Record)
+				products.POST("/:id/inventory/transfers", middleware.RoleRequired("admin"), idempotent, inventoryHandler.Transfer)
+				products.POST("/:id/inventory/reservations", middleware.RoleRequired("admin"), idempotent, inventoryHandler.Reserve)
+				products.DELETE("/:id/inventory/reservations/:reservationId", middleware.RoleRequired("admin"), inventoryHandler.Release)
+				products.POST("/:id/inventory/reservations/:reservationId/commit", middleware.RoleRequired("admin"), idempotent, inventoryHandler.Commit)
+			}
+
+			// Category routes
+			categories := protected.Group("/categories")
+			{
+				categories.GET("", categoryHandler.List)
+				categories.GET("/:id", categoryHandler.GetByID)
+				categories.GET("/:id/products", categoryHandler.Products)
+				categories.POST("", middleware.RoleRequired("admin"), idempotent, categoryHandler.Create)
+				categories.PUT("/:id", middleware.RoleRequired("admin"), ifMatch, categoryHandler.Update)
+				categories.DELETE("/:id", middleware.RoleRequired("admin"), ifMatch, categoryHandler.Delete)
+			}
+
+			// Warehouse routes
+			warehouses := protected.Group("/warehouses")
+			{
+				warehouses.GET("", warehouseHandler.List)
+				warehouses.GET("/:id", warehouseHandler.GetByID)
+				warehouses.POST("", middleware.RoleRequired("admin"), idempotent, warehouseHandler.Create)
+				warehouses.PUT("/:id", middleware.RoleRequired("admin"), ifMatch, warehouseHandler.Update)
+				warehouses.DELETE("/:id", middleware.RoleRequired("admin"), ifMatch, warehouseHandler.Delete)
+			}
+
+			// Order routes
+			orders := protected.Group("/orders")
+			{
+				orders.POST("", idempotent, orderHandler.Checkout)
+				orders.GET("", middleware.RoleRequired("admin"), orderHandler.List)
+				orders.GET("/:id", middleware.RoleRequired("admin"), orderHandler.GetByID)
+				orders.PUT("/:id/status", middleware.RoleRequired("admin"), ifMatch, orderHandler.Transition)
+				orders.GET("/:id/payments", middleware.RoleRequired("admin"), paymentHandler.List)
+				orders.POST("/:id/refund", middleware.RoleRequired("admin"), idempotent, paymentHandler.Refund)
+			}
+
+			// Promotion routes
+			promotions := protected.Group("/promotions")
+			{
+				promotions.GET("", middleware.RoleRequired("admin"), promotionHandler.List)
+				promotions.GET("/:id", middleware.RoleRequired("admin"), promotionHandler.GetByID)
+				promotions.POST("", middleware.RoleRequired("admin"), idempotent, promotionHandler.Create)
+				promotions.PUT("/:id", middleware.RoleRequired("admin"), ifMatch, promotionHandler.Update)
+				promotions.DELETE("/:id", middleware.RoleRequired("admin"), ifMatch, promotionHandler.Delete)
+			}
+
+			// Fake payment provider simulator
+			if fakePayments != nil {
+				fake := protected.Group("/payments/fake", middleware.RoleRequired("admin"))
+				{
+					fake.POST("/simulate", paymentHandler.Simulate)
+					fake.POST("/failures", paymentHandler.FailNext)
+				}
+			}
+
+			// Currency routes
+			currencies := protected.Group("/currencies")
+			{
+				currencies.GET("/rates", currencyHandler.Rates)
+				currencies.PUT("/rates", middleware.RoleRequired("admin"), currencyHandler.UpdateRates)
 			}
 		}
 	}
@@ -148,11 +406,64 @@ func main() {
 		}
 	}()
 
+	// Run database migrations while the startup probe reports "starting"
+	if err := database.RunMigrations(db, cfg.Currency.Base); err != nil {
+		logger.Fatal("Failed to run migrations", "error", err)
+	}
+	if cfg.Currency.RatesFile != "" {
+		if err := currencyService.LoadRatesFile(context.Background(), cfg.Currency.RatesFile); err != nil {
+			logger.Fatal("Failed to load exchange rates", "error", err)
+		}
+	}
+	healthRegistry.MarkStarted()
+	logger.Info("Startup complete")
+
+	go func() {
+		if err := productService.RebuildSuggestions(context.Background()); err != nil {
+			logger.Warn("Failed to build suggestion index", "error", err)
+		}
+	}()
+
+	// Return the stock of expired reservations and cancel unpaid orders
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	defer stopSweep()
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.Inventory.SweepIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sweepCtx.Done():
+				return
+			case <-ticker.C:
+				// Cancel unpaid orders first so that their reservations
+				// are released with a record of why.
+				cancelled, err := orderService.ExpirePending(sweepCtx)
+				if err != nil {
+					logger.Warn("Failed to cancel unpaid orders", "error", err)
+				} else if cancelled > 0 {
+					logger.Info("Cancelled unpaid orders", "count", cancelled)
+				}
+
+				expired, err := inventoryService.ExpireReservations(sweepCtx)
+				if err != nil {
+					logger.Warn("Failed to expire stock reservations", "error", err)
+				} else if expired > 0 {
+					logger.Info("Expired stock reservations", "count", expired)
+				}
+			}
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	// Fail readiness first so load balancers stop sending new traffic
+	logger.Info("Draining server...", "delay_seconds", cfg.Health.DrainDelaySeconds)
+	healthRegistry.SetDraining(true)
+	time.Sleep(time.Duration(cfg.Health.DrainDelaySeconds) * time.Second)
+
 	logger.Info("Shutting down server...")
 
 	// Graceful shutdown with 30 second timeout
@@ -1,148 +1,359 @@
 package config
 
 import (
-	"fmt"
-	"os"
-	"strconv"
 	"strings"
 )
 
+// Config is the full application configuration. Values are layered in the
+// following order, later layers overriding earlier ones: built-in defaults,
+// configuration files (YAML or TOML), environment variables and finally
+// *_FILE secret files.
+//
+// Fields tagged reload:"dynamic" are re-applied at runtime by the Reloader;
+// every other setting requires a restart.
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	CORS     CORSConfig
-	AWS      AWSConfig
+	App         AppConfig         `yaml:"app" toml:"app"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	Redis       RedisConfig       `yaml:"redis" toml:"redis"`
+	Cache       CacheConfig       `yaml:"cache" toml:"cache"`
+	JWT         JWTConfig         `yaml:"jwt" toml:"jwt"`
+	CORS        CORSConfig        `yaml:"cors" toml:"cors" reload:"dynamic"`
+	AWS         AWSConfig         `yaml:"aws" toml:"aws"`
+	Health      HealthConfig      `yaml:"health" toml:"health"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit" reload:"dynamic"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	Pagination  PaginationConfig  `yaml:"pagination" toml:"pagination"`
+	Currency    CurrencyConfig    `yaml:"currency" toml:"currency"`
+	Inventory   InventoryConfig   `yaml:"inventory" toml:"inventory"`
+	Cart        CartConfig        `yaml:"cart" toml:"cart"`
+	Orders      OrderConfig       `yaml:"orders" toml:"orders"`
+	Payments    PaymentConfig     `yaml:"payments" toml:"payments"`
+	Tax         TaxConfig         `yaml:"tax" toml:"tax"`
 }
 
+// AppConfig holds general server settings. RequireIfMatch makes PUT, PATCH
+// and DELETE requests fail with 428 unless they carry an If-Match header.
+// TrustedProxies lists the IPs and CIDRs whose X-Forwarded-For header is
+// believed when working out the client IP; by default none are.
 type AppConfig struct {
-	Name        string
-	Environment string
-	Port        string
-	LogLevel    string
+	Name           string   `yaml:"name" toml:"name"`
+	Environment    string   `yaml:"environment" toml:"environment"`
+	Port           string   `yaml:"port" toml:"port"`
+	LogLevel       string   `yaml:"log_level" toml:"log_level" reload:"dynamic"`
+	RequireIfMatch bool     `yaml:"require_if_match" toml:"require_if_match" reload:"dynamic"`
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
 }
 
 type DatabaseConfig struct {
-	Host            string
-	Port            int
-	User            string
-	Password        string
-	Database        string
-	SSLMode         string
-	MaxOpenConns    int
-	MaxIdleConns    int
-	ConnMaxLifetime int
+	Host            string `yaml:"host" toml:"host"`
+	Port            int    `yaml:"port" toml:"port"`
+	User            string `yaml:"user" toml:"user"`
+	Password        string `yaml:"password" toml:"password" secret:"true"`
+	Database        string `yaml:"database" toml:"database"`
+	SSLMode         string `yaml:"ssl_mode" toml:"ssl_mode"`
+	MaxOpenConns    int    `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int    `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime int    `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
 }
 
+// RedisConfig describes a standalone server, a Sentinel-managed primary or a
+// Redis Cluster. Host and Port address standalone servers; Addrs lists the
+// sentinels or cluster seed nodes.
 type RedisConfig struct {
-	Host      string
-	Port      int
-	Password  string
-	DB        int
-	KeyPrefix string
+	Mode             string         `yaml:"mode" toml:"mode"`
+	Host             string         `yaml:"host" toml:"host"`
+	Port             int            `yaml:"port" toml:"port"`
+	Addrs            []string       `yaml:"addrs" toml:"addrs"`
+	MasterName       string         `yaml:"master_name" toml:"master_name"`
+	Username         string         `yaml:"username" toml:"username"`
+	Password         string         `yaml:"password" toml:"password" secret:"true"`
+	SentinelUsername string         `yaml:"sentinel_username" toml:"sentinel_username"`
+	SentinelPassword string         `yaml:"sentinel_password" toml:"sentinel_password" secret:"true"`
+	DB               int            `yaml:"db" toml:"db"`
+	KeyPrefix        string         `yaml:"key_prefix" toml:"key_prefix"`
+	TLS              RedisTLSConfig `yaml:"tls" toml:"tls"`
+	DialTimeoutMs    int            `yaml:"dial_timeout_ms" toml:"dial_timeout_ms"`
+	ReadTimeoutMs    int            `yaml:"read_timeout_ms" toml:"read_timeout_ms"`
+	WriteTimeoutMs   int            `yaml:"write_timeout_ms" toml:"write_timeout_ms"`
+	PoolSize         int            `yaml:"pool_size" toml:"pool_size"`
+	MinIdleConns     int            `yaml:"min_idle_conns" toml:"min_idle_conns"`
+}
+
+type RedisTLSConfig struct {
+	Enabled            bool   `yaml:"enabled" toml:"enabled"`
+	CAFile             string `yaml:"ca_file" toml:"ca_file"`
+	CertFile           string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile            string `yaml:"key_file" toml:"key_file"`
+	ServerName         string `yaml:"server_name" toml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" toml:"insecure_skip_verify"`
+}
+
+// CacheConfig selects the cache backend: "redis" requires Redis, "memory"
+// never uses it, and "auto" uses Redis when reachable with an in-memory
+// fallback, reconnecting in the background.
+//
+// Read-through caching of records uses TTLSeconds (ListTTLSeconds for list
+// pages, FacetTTLSeconds for search facets), randomly spread by JitterPercent, and remembers missing records
+// for NegativeTTLSeconds. Codec is "json" or "msgpack".
+type CacheConfig struct {
+	Driver                   string `yaml:"driver" toml:"driver"`
+	MaxEntries               int    `yaml:"max_entries" toml:"max_entries"`
+	ReconnectIntervalSeconds int    `yaml:"reconnect_interval_seconds" toml:"reconnect_interval_seconds"`
+	Codec                    string `yaml:"codec" toml:"codec"`
+	TTLSeconds               int    `yaml:"ttl_seconds" toml:"ttl_seconds"`
+	ListTTLSeconds           int    `yaml:"list_ttl_seconds" toml:"list_ttl_seconds"`
+	FacetTTLSeconds          int    `yaml:"facet_ttl_seconds" toml:"facet_ttl_seconds"`
+	NegativeTTLSeconds       int    `yaml:"negative_ttl_seconds" toml:"negative_ttl_seconds"`
+	JitterPercent            int    `yaml:"jitter_percent" toml:"jitter_percent"`
 }
 
 type JWTConfig struct {
-	Secret                string
-	RefreshSecret         string
-	ExpirationHours       int
-	RefreshExpirationDays int
+	Secret                string `yaml:"secret" toml:"secret" secret:"true"`
+	RefreshSecret         string `yaml:"refresh_secret" toml:"refresh_secret" secret:"true"`
+	ExpirationHours       int    `yaml:"expiration_hours" toml:"expiration_hours"`
+	RefreshExpirationDays int    `yaml:"refresh_expiration_days" toml:"refresh_expiration_days"`
 }
 
 type CORSConfig struct {
-	AllowedOrigins   []string
-	AllowedMethods   []string
-	AllowedHeaders   []string
-	AllowCredentials bool
+	AllowedOrigins   []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers" toml:"allowed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           int      `yaml:"max_age" toml:"max_age"`
 }
 
 type AWSConfig struct {
-	Region          string
-	AccessKeyID     string
-	SecretAccessKey string
-	S3Bucket        string
+	Region          string `yaml:"region" toml:"region"`
+	AccessKeyID     string `yaml:"access_key_id" toml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key" toml:"secret_access_key" secret:"true"`
+	S3Bucket        string `yaml:"s3_bucket" toml:"s3_bucket"`
+}
+
+type HealthConfig struct {
+	CheckTimeoutMs    int `yaml:"check_timeout_ms" toml:"check_timeout_ms"`
+	CacheTTLMs        int `yaml:"cache_ttl_ms" toml:"cache_ttl_ms"`
+	DrainDelaySeconds int `yaml:"drain_delay_seconds" toml:"drain_delay_seconds"`
+}
+
+// IdempotencyConfig controls how long responses to requests carrying an
+// Idempotency-Key are kept for replay, and how long a request may hold its
+// key before a retry is allowed to run it again.
+type IdempotencyConfig struct {
+	TTLHours           int `yaml:"ttl_hours" toml:"ttl_hours"`
+	LockTimeoutSeconds int `yaml:"lock_timeout_seconds" toml:"lock_timeout_seconds"`
+}
+
+// PaginationConfig holds the secret list cursors are signed with. When it
+// is empty a key derived from the JWT secret is used.
+type PaginationConfig struct {
+	CursorSecret string `yaml:"cursor_secret" toml:"cursor_secret" secret:"true"`
+}
+
+// CurrencyConfig sets the ISO 4217 currency products are priced in. When
+// RatesFile is set, the exchange rates from the base currency it contains,
+// a JSON object such as {"EUR": "0.92"}, are loaded on startup.
+type CurrencyConfig struct {
+	Base      string `yaml:"base" toml:"base"`
+	RatesFile string `yaml:"rates_file" toml:"rates_file"`
+}
+
+// InventoryConfig sets how long stock reservations last unless a request
+// asks for another duration, how often expired reservations are swept, and
+// the strategy choosing the warehouse a reservation is taken from:
+// "priority", "largest" or "nearest".
+type InventoryConfig struct {
+	ReservationTTLSeconds int    `yaml:"reservation_ttl_seconds" toml:"reservation_ttl_seconds"`
+	SweepIntervalSeconds  int    `yaml:"sweep_interval_seconds" toml:"sweep_interval_seconds"`
+	AllocationStrategy    string `yaml:"allocation_strategy" toml:"allocation_strategy"`
+}
+
+// CartConfig limits carts to MaxItems lines of at most MaxQuantity each.
+// Anonymous carts expire AnonymousTTLHours after they last changed.
+type CartConfig struct {
+	AnonymousTTLHours int `yaml:"anonymous_ttl_hours" toml:"anonymous_ttl_hours"`
+	MaxItems          int `yaml:"max_items" toml:"max_items"`
+	MaxQuantity       int `yaml:"max_quantity" toml:"max_quantity"`
+}
+
+// OrderConfig sets how long the stock of an unpaid order stays reserved.
+// Orders still unpaid after PaymentTimeoutSeconds are cancelled.
+// ShippingFee is charged per order in minor units of the base currency.
+type OrderConfig struct {
+	PaymentTimeoutSeconds int `yaml:"payment_timeout_seconds" toml:"payment_timeout_seconds"`
+	ShippingFee           int `yaml:"shipping_fee" toml:"shipping_fee"`
+}
+
+// TaxConfig selects the tax calculator. The rules calculator charges
+// Rates, in percent, keyed by "COUNTRY", "COUNTRY-REGION" or either
+// followed by "/CLASS"; the most specific key matching an address and
+// product tax class applies. Rounding is "line" or "order". Carts are
+// taxed at DefaultCountry and DefaultRegion until checkout names an
+// address; no default country means no tax before then.
+type TaxConfig struct {
+	Provider         string            `yaml:"provider" toml:"provider"`
+	PricesIncludeTax bool              `yaml:"prices_include_tax" toml:"prices_include_tax"`
+	Rounding         string            `yaml:"rounding" toml:"rounding"`
+	DefaultCountry   string            `yaml:"default_country" toml:"default_country"`
+	DefaultRegion    string            `yaml:"default_region" toml:"default_region"`
+	Rates            map[string]string `yaml:"rates" toml:"rates"`
+}
+
+// PaymentConfig selects the payment provider. Webhooks must be signed with
+// WebhookSecret no more than WebhookToleranceSeconds ago. The fake
+// provider falls back to a secret derived from the JWT secret.
+type PaymentConfig struct {
+	Provider                string `yaml:"provider" toml:"provider"`
+	WebhookSecret           string `yaml:"webhook_secret" toml:"webhook_secret" secret:"true"`
+	WebhookToleranceSeconds int    `yaml:"webhook_tolerance_seconds" toml:"webhook_tolerance_seconds"`
+}
+
+// RateLimitConfig holds named policies. Each route group uses the policy of
+// the same name unless Routes maps the route, written as "METHOD /path"
+// with the registered path pattern, to another policy.
+type RateLimitConfig struct {
+	Enabled   bool                       `yaml:"enabled" toml:"enabled"`
+	Algorithm string                     `yaml:"algorithm" toml:"algorithm"`
+	Policies  map[string]RateLimitPolicy `yaml:"policies" toml:"policies"`
+	Routes    map[string]string          `yaml:"routes" toml:"routes"`
 }
 
-func Load() (*Config, error) {
-	cfg := &Config{
+// RateLimitPolicy allows Limit requests per PeriodSeconds for each key.
+// KeyBy is "ip", "user", "api_key" or "group" (one budget shared by the
+// whole route group). Burst only applies to GCRA and defaults to Limit.
+// Algorithm overrides the global algorithm for this policy.
+type RateLimitPolicy struct {
+	Limit         int    `yaml:"limit" toml:"limit"`
+	PeriodSeconds int    `yaml:"period_seconds" toml:"period_seconds"`
+	Burst         int    `yaml:"burst" toml:"burst"`
+	KeyBy         string `yaml:"key_by" toml:"key_by"`
+	Algorithm     string `yaml:"algorithm" toml:"algorithm"`
+}
+
+// Default returns the built-in configuration used as the lowest layer.
+func Default() *Config {
+	return &Config{
 		App: AppConfig{
-			Name:        getEnv("APP_NAME", "SuiteMedia"),
-			Environment: getEnv("NODE_ENV", "development"),
-			Port:        getEnv("PORT", "3000"),
-			LogLevel:    getEnv("LOG_LEVEL", "info"),
+			Name:        "SuiteMedia",
+			Environment: "development",
+			Port:        "3000",
+			LogLevel:    "info",
 		},
 		Database: DatabaseConfig{
-			Host:            getEnv("DB_HOST", "localhost"),
-			Port:            getEnvInt("DB_PORT", 5432),
-			User:            getEnv("DB_USER", "postgres"),
-			Password:        getEnv("DB_PASSWORD", ""),
-			Database:        getEnv("DB_NAME", "suitemedia"),
-			SSLMode:         getEnv("DB_SSLMODE", "disable"),
-			MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 10),
-			MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
-			ConnMaxLifetime: getEnvInt("DB_CONN_MAX_LIFETIME", 300),
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Database:        "suitemedia",
+			SSLMode:         "disable",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 300,
 		},
 		Redis: RedisConfig{
-			Host:      getEnv("REDIS_HOST", "localhost"),
-			Port:      getEnvInt("REDIS_PORT", 6379),
-			Password:  getEnv("REDIS_PASSWORD", ""),
-			DB:        getEnvInt("REDIS_DB", 0),
-			KeyPrefix: getEnv("REDIS_KEY_PREFIX", "suitemedia:"),
+			Mode:           "standalone",
+			Host:           "localhost",
+			Port:           6379,
+			KeyPrefix:      "suitemedia:",
+			DialTimeoutMs:  5000,
+			ReadTimeoutMs:  3000,
+			WriteTimeoutMs: 3000,
+		},
+		Cache: CacheConfig{
+			Driver:                   "auto",
+			MaxEntries:               10000,
+			ReconnectIntervalSeconds: 5,
+			Codec:                    "json",
+			TTLSeconds:               300,
+			ListTTLSeconds:           60,
+			FacetTTLSeconds:          30,
+			NegativeTTLSeconds:       30,
+			JitterPercent:            10,
 		},
 		JWT: JWTConfig{
-			Secret:                getEnv("JWT_SECRET", "your-secret-key"),
-			RefreshSecret:         getEnv("JWT_REFRESH_SECRET", "your-refresh-secret-key"),
-			ExpirationHours:       getEnvInt("JWT_EXPIRATION_HOURS", 24),
-			RefreshExpirationDays: getEnvInt("JWT_REFRESH_EXPIRATION_DAYS", 30),
+			Secret:                "your-secret-key",
+			RefreshSecret:         "your-refresh-secret-key",
+			ExpirationHours:       24,
+			RefreshExpirationDays: 30,
 		},
 		CORS: CORSConfig{
-			AllowedOrigins:   strings.Split(getEnv("CORS_ALLOWED_ORIGINS", "*"), ","),
-			AllowedMethods:   strings.Split(getEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS"), ","),
-			AllowedHeaders:   strings.Split(getEnv("CORS_ALLOWED_HEADERS", "Accept,Authorization,Content-Type,X-Request-Id"), ","),
-			AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", true),
+			AllowedOrigins:   []string{"*"},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-Id", "Idempotency-Key", "X-API-Key", "If-Match", "If-None-Match", "X-Cart-Token"},
+			AllowCredentials: true,
+			MaxAge:           12 * 3600,
 		},
 		AWS: AWSConfig{
-			Region:          getEnv("AWS_REGION", "us-east-1"),
-			AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
-			SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
-			S3Bucket:        getEnv("AWS_S3_BUCKET", ""),
+			Region: "us-east-1",
+		},
+		Health: HealthConfig{
+			CheckTimeoutMs:    2000,
+			CacheTTLMs:        1000,
+			DrainDelaySeconds: 5,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:   true,
+			Algorithm: "gcra",
+			Policies: map[string]RateLimitPolicy{
+				"auth": {Limit: 10, PeriodSeconds: 60, Burst: 5, KeyBy: "ip"},
+				"api":  {Limit: 300, PeriodSeconds: 60, Burst: 50, KeyBy: "user"},
+				// Autocompletion sends a request per keystroke.
+				"suggest": {Limit: 600, PeriodSeconds: 60, Burst: 20, KeyBy: "ip"},
+			},
+			Routes: map[string]string{},
+		},
+		Idempotency: IdempotencyConfig{
+			TTLHours:           24,
+			LockTimeoutSeconds: 60,
+		},
+		Currency: CurrencyConfig{
+			Base: "USD",
+		},
+		Inventory: InventoryConfig{
+			ReservationTTLSeconds: 900,
+			SweepIntervalSeconds:  60,
+			AllocationStrategy:    "priority",
+		},
+		Cart: CartConfig{
+			AnonymousTTLHours: 168,
+			MaxItems:          50,
+			MaxQuantity:       99,
+		},
+		Orders: OrderConfig{
+			PaymentTimeoutSeconds: 1800,
+		},
+		Payments: PaymentConfig{
+			Provider:                "fake",
+			WebhookToleranceSeconds: 300,
+		},
+		Tax: TaxConfig{
+			Provider: "rules",
+			Rounding: "line",
 		},
 	}
+}
 
-	// Validate required fields
-	if cfg.Database.Password == "" {
-		return nil, fmt.Errorf("DB_PASSWORD is required")
-	}
+// Load builds the configuration from defaults, the given configuration files
+// (applied in order, empty paths are skipped) and the environment. Every
+// parse and validation problem is collected and returned together as a
+// *ValidationError.
+func Load(files ...string) (*Config, error) {
+	cfg := Default()
+	problems := &ValidationError{}
 
-	if cfg.JWT.Secret == "" || cfg.JWT.Secret == "your-secret-key" {
-		return nil, fmt.Errorf("JWT_SECRET must be set")
+	for _, file := range files {
+		if strings.TrimSpace(file) == "" {
+			continue
+		}
+		loadFile(file, cfg, problems)
 	}
 
-	return cfg, nil
-}
+	env := &envLoader{problems: problems}
+	env.apply(cfg)
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
+	cfg.validate(problems)
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
+	if err := problems.err(); err != nil {
+		return nil, err
 	}
-	return defaultValue
-}
 
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
-			return boolValue
-		}
-	}
-	return defaultValue
+	return cfg, nil
 }
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"suitemedia/pkg/payment"
 )
 
 func TestLoad(t *testing.T) {
@@ -48,3 +53,245 @@ func TestLoadMissingRequiredVar(t *testing.T) {
 		t.Error("Expected error for missing required variables, got nil")
 	}
 }
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "testsecret")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadFileLayering(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_HOST", "env-host")
+
+	yamlFile := writeFile(t, "base.yaml", "app:\n  log_level: debug\ndatabase:\n  host: file-host\n  port: 6543\n")
+	tomlFile := writeFile(t, "override.toml", "[database]\nport = 7654\n")
+
+	cfg, err := Load(yamlFile, tomlFile)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.App.LogLevel != "debug" {
+		t.Errorf("Expected log level from file, got %s", cfg.App.LogLevel)
+	}
+	if cfg.Database.Port != 7654 {
+		t.Errorf("Expected later file to win, got port %d", cfg.Database.Port)
+	}
+	if cfg.Database.Host != "env-host" {
+		t.Errorf("Expected env to override file, got %s", cfg.Database.Host)
+	}
+}
+
+func TestLoadReportsAllProblems(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_PORT", "not-a-number")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "maybe")
+
+	file := writeFile(t, "config.yaml", "app:\n  lgo_level: debug\nredis:\n  hots: localhost\n")
+
+	_, err := Load(file)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected *ValidationError, got %v", err)
+	}
+	if len(validationErr.Problems) != 4 {
+		t.Errorf("Expected 4 problems, got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+	for _, want := range []string{"app.lgo_level", "redis.hots", "DB_PORT", "CORS_ALLOW_CREDENTIALS"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestLoadReportsAllTOMLTypeErrors(t *testing.T) {
+	setRequiredEnv(t)
+
+	file := writeFile(t, "config.toml", "[database]\nport = \"five\"\nhost = \"db\"\n\n[cache]\nmax_entries = true\n\n[rate_limit.routes]\n\"GET /api/v1/products\" = \"api\"\n")
+
+	_, err := Load(file)
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected *ValidationError, got %v", err)
+	}
+	if len(validationErr.Problems) != 2 {
+		t.Errorf("Expected 2 problems, got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+	for _, want := range []string{"database.port", "cache.max_entries"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestLoadSecretFile(t *testing.T) {
+	t.Setenv("JWT_SECRET", "testsecret")
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, "db-password", "from-file\n"))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Database.Password != "from-file" {
+		t.Errorf("Expected password from file, got %q", cfg.Database.Password)
+	}
+
+	t.Setenv("DB_PASSWORD", "also-set")
+	if _, err := Load(); err == nil {
+		t.Error("Expected error when both DB_PASSWORD and DB_PASSWORD_FILE are set")
+	}
+}
+
+func TestValidateProductionCORS(t *testing.T) {
+	cfg := Default()
+	cfg.App.Environment = "production"
+	cfg.Database.Password = "testpass"
+	cfg.JWT.Secret = strings.Repeat("s", 32)
+	cfg.JWT.RefreshSecret = strings.Repeat("r", 32)
+	cfg.Payments.Provider = registerTestPayments()
+	cfg.Payments.WebhookSecret = "whsec"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "wildcard origin") {
+		t.Fatalf("Expected wildcard CORS origin to be rejected in production, got %v", err)
+	}
+
+	cfg.CORS.AllowedOrigins = []string{"https://suitemedia.com"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid production config, got %v", err)
+	}
+}
+
+func TestValidateProductionPayments(t *testing.T) {
+	cfg := Default()
+	cfg.App.Environment = "production"
+	cfg.Database.Password = "testpass"
+	cfg.JWT.Secret = strings.Repeat("s", 32)
+	cfg.JWT.RefreshSecret = strings.Repeat("r", 32)
+	cfg.CORS.AllowedOrigins = []string{"https://suitemedia.com"}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "payments.provider") {
+		t.Fatalf("Expected the fake payment provider to be rejected in production, got %v", err)
+	}
+
+	cfg.App.Environment = "staging"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the fake payment provider outside production, got %v", err)
+	}
+}
+
+// registerTestPayments registers a stand-in for a real payment provider
+// and returns its name.
+func registerTestPayments() string {
+	payment.Register("test", func(settings payment.Settings) (payment.Provider, error) {
+		return payment.NewFake(settings), nil
+	})
+	return "test"
+}
+
+func TestValidateRateLimit(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "testpass"
+	cfg.JWT.Secret = "testsecret"
+	cfg.RateLimit.Routes = map[string]string{"POST /api/v1/products": "missing"}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "unknown policy") {
+		t.Errorf("Expected unknown rate limit policy to be rejected, got %v", err)
+	}
+}
+
+func TestValidateCurrency(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "testpass"
+	cfg.JWT.Secret = "testsecret"
+	cfg.Currency.Base = "usd"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "currency.base") {
+		t.Errorf("Expected lowercase currency code to be rejected, got %v", err)
+	}
+}
+
+func TestValidatePayments(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "testpass"
+	cfg.JWT.Secret = "testsecret"
+	cfg.Payments.Provider = "unknown"
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "payments.provider") {
+		t.Errorf("Expected unknown payment provider to be rejected, got %v", err)
+	}
+}
+
+func TestValidateShippingFee(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "testpass"
+	cfg.JWT.Secret = "testsecret"
+	cfg.Orders.ShippingFee = -1
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "orders.shipping_fee") {
+		t.Errorf("Expected negative shipping fee to be rejected, got %v", err)
+	}
+}
+
+func TestValidateTax(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "testpass"
+	cfg.JWT.Secret = "testsecret"
+	cfg.Tax.Rounding = "invoice"
+	cfg.Tax.DefaultCountry = "USA"
+	cfg.Tax.Rates = map[string]string{"US-CA": "7.25", "US/food": "150"}
+
+	err := cfg.Validate()
+	for _, key := range []string{"tax.rounding", "tax.default_country", "tax.rates"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected %s to be rejected, got %v", key, err)
+		}
+	}
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "testpass"
+	cfg.JWT.Secret = "testsecret"
+	cfg.App.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.1"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected IPs and CIDRs to be accepted, got %v", err)
+	}
+
+	cfg.App.TrustedProxies = []string{"proxy.internal"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "app.trusted_proxies") {
+		t.Errorf("Expected a hostname to be rejected, got %v", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "testpass"
+
+	redacted := cfg.Redacted()
+	if redacted.Database.Password != redactedValue {
+		t.Errorf("Expected password to be redacted, got %s", redacted.Database.Password)
+	}
+	if redacted.AWS.SecretAccessKey != "" {
+		t.Errorf("Expected empty secret to stay empty, got %s", redacted.AWS.SecretAccessKey)
+	}
+	if cfg.Database.Password != "testpass" {
+		t.Error("Expected original config to be left untouched")
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envLoader overrides configuration values from environment variables.
+// Every variable can alternatively be provided as KEY_FILE pointing to a
+// file holding the value, which is how Kubernetes mounts secrets.
+type envLoader struct {
+	problems *ValidationError
+}
+
+func (l *envLoader) apply(cfg *Config) {
+	l.str(&cfg.App.Name, "APP_NAME")
+	l.str(&cfg.App.Environment, "NODE_ENV")
+	l.str(&cfg.App.Port, "PORT")
+	l.str(&cfg.App.LogLevel, "LOG_LEVEL")
+	l.bool(&cfg.App.RequireIfMatch, "REQUIRE_IF_MATCH")
+	l.list(&cfg.App.TrustedProxies, "TRUSTED_PROXIES")
+
+	l.str(&cfg.Database.Host, "DB_HOST")
+	l.int(&cfg.Database.Port, "DB_PORT")
+	l.str(&cfg.Database.User, "DB_USER")
+	l.str(&cfg.Database.Password, "DB_PASSWORD")
+	l.str(&cfg.Database.Database, "DB_NAME")
+	l.str(&cfg.Database.SSLMode, "DB_SSLMODE")
+	l.int(&cfg.Database.MaxOpenConns, "DB_MAX_OPEN_CONNS")
+	l.int(&cfg.Database.MaxIdleConns, "DB_MAX_IDLE_CONNS")
+	l.int(&cfg.Database.ConnMaxLifetime, "DB_CONN_MAX_LIFETIME")
+
+	l.str(&cfg.Redis.Mode, "REDIS_MODE")
+	l.str(&cfg.Redis.Host, "REDIS_HOST")
+	l.int(&cfg.Redis.Port, "REDIS_PORT")
+	l.list(&cfg.Redis.Addrs, "REDIS_ADDRS")
+	l.str(&cfg.Redis.MasterName, "REDIS_MASTER_NAME")
+	l.str(&cfg.Redis.Username, "REDIS_USERNAME")
+	l.str(&cfg.Redis.Password, "REDIS_PASSWORD")
+	l.str(&cfg.Redis.SentinelUsername, "REDIS_SENTINEL_USERNAME")
+	l.str(&cfg.Redis.SentinelPassword, "REDIS_SENTINEL_PASSWORD")
+	l.int(&cfg.Redis.DB, "REDIS_DB")
+	l.str(&cfg.Redis.KeyPrefix, "REDIS_KEY_PREFIX")
+	l.bool(&cfg.Redis.TLS.Enabled, "REDIS_TLS_ENABLED")
+	l.str(&cfg.Redis.TLS.CAFile, "REDIS_TLS_CA_FILE")
+	l.str(&cfg.Redis.TLS.CertFile, "REDIS_TLS_CERT_FILE")
+	l.str(&cfg.Redis.TLS.KeyFile, "REDIS_TLS_KEY_FILE")
+	l.str(&cfg.Redis.TLS.ServerName, "REDIS_TLS_SERVER_NAME")
+	l.bool(&cfg.Redis.TLS.InsecureSkipVerify, "REDIS_TLS_INSECURE_SKIP_VERIFY")
+	l.int(&cfg.Redis.DialTimeoutMs, "REDIS_DIAL_TIMEOUT_MS")
+	l.int(&cfg.Redis.ReadTimeoutMs, "REDIS_READ_TIMEOUT_MS")
+	l.int(&cfg.Redis.WriteTimeoutMs, "REDIS_WRITE_TIMEOUT_MS")
+	l.int(&cfg.Redis.PoolSize, "REDIS_POOL_SIZE")
+	l.int(&cfg.Redis.MinIdleConns, "REDIS_MIN_IDLE_CONNS")
+
+	l.str(&cfg.Cache.Driver, "CACHE_DRIVER")
+	l.int(&cfg.Cache.MaxEntries, "CACHE_MAX_ENTRIES")
+	l.int(&cfg.Cache.ReconnectIntervalSeconds, "CACHE_RECONNECT_INTERVAL_SECONDS")
+	l.str(&cfg.Cache.Codec, "CACHE_CODEC")
+	l.int(&cfg.Cache.TTLSeconds, "CACHE_TTL_SECONDS")
+	l.int(&cfg.Cache.ListTTLSeconds, "CACHE_LIST_TTL_SECONDS")
+	l.int(&cfg.Cache.FacetTTLSeconds, "CACHE_FACET_TTL_SECONDS")
+	l.int(&cfg.Cache.NegativeTTLSeconds, "CACHE_NEGATIVE_TTL_SECONDS")
+	l.int(&cfg.Cache.JitterPercent, "CACHE_JITTER_PERCENT")
+
+	l.str(&cfg.JWT.Secret, "JWT_SECRET")
+	l.str(&cfg.JWT.RefreshSecret, "JWT_REFRESH_SECRET")
+	l.int(&cfg.JWT.ExpirationHours, "JWT_EXPIRATION_HOURS")
+	l.int(&cfg.JWT.RefreshExpirationDays, "JWT_REFRESH_EXPIRATION_DAYS")
+
+	l.list(&cfg.CORS.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	l.list(&cfg.CORS.AllowedMethods, "CORS_ALLOWED_METHODS")
+	l.list(&cfg.CORS.AllowedHeaders, "CORS_ALLOWED_HEADERS")
+	l.bool(&cfg.CORS.AllowCredentials, "CORS_ALLOW_CREDENTIALS")
+	l.int(&cfg.CORS.MaxAge, "CORS_MAX_AGE")
+
+	l.str(&cfg.AWS.Region, "AWS_REGION")
+	l.str(&cfg.AWS.AccessKeyID, "AWS_ACCESS_KEY_ID")
+	l.str(&cfg.AWS.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+	l.str(&cfg.AWS.S3Bucket, "AWS_S3_BUCKET")
+
+	l.int(&cfg.Health.CheckTimeoutMs, "HEALTH_CHECK_TIMEOUT_MS")
+	l.int(&cfg.Health.CacheTTLMs, "HEALTH_CACHE_TTL_MS")
+	l.int(&cfg.Health.DrainDelaySeconds, "SHUTDOWN_DRAIN_SECONDS")
+
+	l.bool(&cfg.RateLimit.Enabled, "RATE_LIMIT_ENABLED")
+	l.str(&cfg.RateLimit.Algorithm, "RATE_LIMIT_ALGORITHM")
+
+	l.int(&cfg.Idempotency.TTLHours, "IDEMPOTENCY_TTL_HOURS")
+	l.int(&cfg.Idempotency.LockTimeoutSeconds, "IDEMPOTENCY_LOCK_TIMEOUT_SECONDS")
+
+	l.str(&cfg.Pagination.CursorSecret, "PAGINATION_CURSOR_SECRET")
+
+	l.str(&cfg.Currency.Base, "CURRENCY_BASE")
+	l.str(&cfg.Currency.RatesFile, "CURRENCY_RATES_FILE")
+
+	l.int(&cfg.Inventory.ReservationTTLSeconds, "INVENTORY_RESERVATION_TTL_SECONDS")
+	l.int(&cfg.Inventory.SweepIntervalSeconds, "INVENTORY_SWEEP_INTERVAL_SECONDS")
+	l.str(&cfg.Inventory.AllocationStrategy, "INVENTORY_ALLOCATION_STRATEGY")
+
+	l.int(&cfg.Cart.AnonymousTTLHours, "CART_ANONYMOUS_TTL_HOURS")
+	l.int(&cfg.Cart.MaxItems, "CART_MAX_ITEMS")
+	l.int(&cfg.Cart.MaxQuantity, "CART_MAX_QUANTITY")
+
+	l.int(&cfg.Orders.PaymentTimeoutSeconds, "ORDER_PAYMENT_TIMEOUT_SECONDS")
+	l.int(&cfg.Orders.ShippingFee, "ORDER_SHIPPING_FEE")
+
+	l.str(&cfg.Payments.Provider, "PAYMENT_PROVIDER")
+	l.str(&cfg.Payments.WebhookSecret, "PAYMENT_WEBHOOK_SECRET")
+	l.int(&cfg.Payments.WebhookToleranceSeconds, "PAYMENT_WEBHOOK_TOLERANCE_SECONDS")
+
+	l.str(&cfg.Tax.Provider, "TAX_PROVIDER")
+	l.bool(&cfg.Tax.PricesIncludeTax, "TAX_PRICES_INCLUDE_TAX")
+	l.str(&cfg.Tax.Rounding, "TAX_ROUNDING")
+	l.str(&cfg.Tax.DefaultCountry, "TAX_DEFAULT_COUNTRY")
+	l.str(&cfg.Tax.DefaultRegion, "TAX_DEFAULT_REGION")
+	l.pairs(&cfg.Tax.Rates, "TAX_RATES")
+}
+
+// lookup returns the value of key, reading it from the file named by
+// KEY_FILE when the plain variable is not set. Setting both is an error.
+func (l *envLoader) lookup(key string) (string, bool) {
+	value := os.Getenv(key)
+	path := os.Getenv(key + "_FILE")
+
+	if path == "" {
+		return value, value != ""
+	}
+	if value != "" {
+		l.problems.addf("%s and %s_FILE are both set", key, key)
+		return value, true
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		l.problems.addf("%s_FILE: %v", key, err)
+		return "", false
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), true
+}
+
+func (l *envLoader) str(dst *string, key string) {
+	if value, ok := l.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (l *envLoader) int(dst *int, key string) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+
+	intValue, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		l.problems.addf("%s: %q is not a valid integer", key, value)
+		return
+	}
+	*dst = intValue
+}
+
+func (l *envLoader) bool(dst *bool, key string) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+
+	boolValue, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		l.problems.addf("%s: %q is not a valid boolean", key, value)
+		return
+	}
+	*dst = boolValue
+}
+
+func (l *envLoader) list(dst *[]string, key string) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+// pairs reads a comma-separated list of key=value pairs.
+func (l *envLoader) pairs(dst *map[string]string, key string) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+
+	pairs := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		name, pairValue, found := strings.Cut(item, "=")
+		if !found {
+			l.problems.addf("%s: %q is not a key=value pair", key, item)
+			return
+		}
+		pairs[strings.TrimSpace(name)] = strings.TrimSpace(pairValue)
+	}
+	*dst = pairs
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile decodes a YAML or TOML file on top of cfg. Unknown keys and type
+// mismatches are all recorded in problems rather than stopping at the first.
+func loadFile(path string, cfg *Config, problems *ValidationError) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		problems.addf("config file: %v", err)
+		return
+	}
+
+	var raw map[string]interface{}
+	var decode func(v interface{}) error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			problems.addf("%s: %v", path, err)
+			return
+		}
+		decode = func(v interface{}) error {
+			return yaml.NewDecoder(bytes.NewReader(data)).Decode(v)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			problems.addf("%s: %v", path, err)
+			return
+		}
+		// The TOML decoder stops at the first type mismatch, so values are
+		// decoded one at a time to report all of them.
+		decode = func(v interface{}) error {
+			return decodeTOML(raw, reflect.ValueOf(v).Elem(), "")
+		}
+	default:
+		problems.addf("%s: unsupported config file extension (use .yaml, .yml or .toml)", path)
+		return
+	}
+
+	for _, key := range unknownKeys(raw, reflect.TypeOf(Config{}), "") {
+		problems.addf("%s: unknown key %q", path, key)
+	}
+
+	if err := decode(cfg); err != nil {
+		var typeErr *yaml.TypeError
+		var valueErrs tomlErrors
+		if errors.As(err, &valueErrs) {
+			for _, msg := range valueErrs {
+				problems.addf("%s: %s", path, msg)
+			}
+			return
+		}
+		if errors.As(err, &typeErr) {
+			for _, msg := range typeErr.Errors {
+				problems.addf("%s: %s", path, msg)
+			}
+			return
+		}
+		problems.addf("%s: %v", path, err)
+	}
+}
+
+// tomlErrors lists the values of a TOML document that could not be decoded.
+type tomlErrors []string
+
+func (e tomlErrors) Error() string {
+	return strings.Join(e, "; ")
+}
+
+// decodeTOML decodes every value of a TOML document onto the fields of the
+// struct v, descending into tables, and returns a tomlErrors for those of
+// the wrong type. Unknown keys are skipped.
+func decodeTOML(raw map[string]interface{}, v reflect.Value, prefix string) error {
+	fields := make(map[string]int)
+	for i := 0; i < v.NumField(); i++ {
+		if name := fieldName(v.Type().Field(i)); name != "" {
+			fields[name] = i
+		}
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs tomlErrors
+	for _, key := range keys {
+		i, ok := fields[key]
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		if table, ok := raw[key].(map[string]interface{}); ok && field.Kind() == reflect.Struct {
+			var nested tomlErrors
+			if errors.As(decodeTOML(table, field, prefix+key+"."), &nested) {
+				errs = append(errs, nested...)
+			}
+			continue
+		}
+
+		// Decode the single value into a struct holding the current value
+		// of the field, so that maps are merged into as before.
+		holder := reflect.New(reflect.StructOf([]reflect.StructField{
+			{Name: "Value", Type: field.Type(), Tag: `toml:"value"`},
+		}))
+		holder.Elem().Field(0).Set(field)
+		data, err := toml.Marshal(map[string]interface{}{"value": raw[key]})
+		if err == nil {
+			err = toml.Unmarshal(data, holder.Interface())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s%s: cannot decode %s into %s", prefix, key, tomlType(raw[key]), field.Type()))
+			continue
+		}
+		field.Set(holder.Elem().Field(0))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// tomlType names the TOML type of a decoded value.
+func tomlType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "table"
+	}
+	return "datetime"
+}
+
+// unknownKeys walks a decoded document and returns the dotted paths of keys
+// that do not correspond to any field of t.
+func unknownKeys(raw map[string]interface{}, t reflect.Type, prefix string) []string {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name := fieldName(field); name != "" {
+			fields[name] = field.Type
+		}
+	}
+
+	var unknown []string
+	for key, value := range raw {
+		path := prefix + key
+		fieldType, ok := fields[key]
+		if !ok {
+			unknown = append(unknown, path)
+			continue
+		}
+		unknown = append(unknown, unknownNested(value, fieldType, path)...)
+	}
+
+	sort.Strings(unknown)
+	return unknown
+}
+
+func unknownNested(value interface{}, t reflect.Type, path string) []string {
+	nested, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return unknownKeys(nested, t, path+".")
+	case reflect.Map:
+		var unknown []string
+		for key, item := range nested {
+			unknown = append(unknown, unknownNested(item, t.Elem(), path+"."+key)...)
+		}
+		return unknown
+	}
+
+	return nil
+}
+
+// fieldName returns the configuration key of a struct field.
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"suitemedia/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	reloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "config_reloads_total",
+		Help: "Configuration reload attempts by result.",
+	}, []string{"result"})
+
+	lastReloadSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "config_last_reload_success_timestamp_seconds",
+		Help: "Unix time of the last successful configuration reload.",
+	})
+)
+
+// Store holds the active configuration. Readers get the current value with a
+// single atomic load; reloads swap in a new value and notify subscribers.
+type Store struct {
+	current     atomic.Pointer[Config]
+	mu          sync.Mutex
+	subscribers []func(*Config)
+}
+
+func NewStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+// Current returns the active configuration. It must be treated as read-only.
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// Subscribe registers fn to be called with the new configuration after
+// every successful reload.
+func (s *Store) Subscribe(fn func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Update atomically replaces the active configuration and notifies
+// subscribers. The Reloader validates configurations before calling it.
+func (s *Store) Update(cfg *Config) {
+	s.current.Store(cfg)
+
+	s.mu.Lock()
+	subscribers := append([]func(*Config){}, s.subscribers...)
+	s.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(cfg)
+	}
+}
+
+// Reloader re-reads the configuration on SIGHUP or when one of the config
+// files changes. Only fields tagged reload:"dynamic" are applied; changes
+// to any other field are logged as requiring a restart.
+type Reloader struct {
+	store    *Store
+	files    []string
+	logger   *logger.Logger
+	interval time.Duration
+	mu       sync.Mutex
+}
+
+func NewReloader(store *Store, files []string, logger *logger.Logger, interval time.Duration) *Reloader {
+	return &Reloader{
+		store:    store,
+		files:    files,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+// Reload loads and validates the configuration and, if valid, atomically
+// applies its dynamic settings.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := Load(r.files...)
+	if err != nil {
+		reloadsTotal.WithLabelValues("failure").Inc()
+		r.logger.Error("Configuration reload failed, keeping current configuration", "error", err)
+		return err
+	}
+
+	merged, applied, restart := mergeDynamic(r.store.Current(), next)
+	if err := merged.Validate(); err != nil {
+		reloadsTotal.WithLabelValues("failure").Inc()
+		r.logger.Error("Configuration reload failed, keeping current configuration", "error", err)
+		return err
+	}
+	if len(restart) > 0 {
+		r.logger.Warn("Configuration changes require a restart to take effect", "keys", restart)
+	}
+
+	r.store.Update(merged)
+	reloadsTotal.WithLabelValues("success").Inc()
+	lastReloadSuccess.SetToCurrentTime()
+	r.logger.Info("Configuration reloaded", "changed", applied)
+
+	return nil
+}
+
+// Watch triggers a reload on SIGHUP and whenever a config file's size or
+// modification time changes. It blocks until ctx is cancelled.
+func (r *Reloader) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	versions := r.fileVersions()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.logger.Info("Received SIGHUP, reloading configuration")
+			r.Reload()
+			versions = r.fileVersions()
+		case <-ticker.C:
+			current := r.fileVersions()
+			if !reflect.DeepEqual(current, versions) {
+				versions = current
+				r.logger.Info("Configuration file changed, reloading configuration")
+				r.Reload()
+			}
+		}
+	}
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func (r *Reloader) fileVersions() map[string]fileVersion {
+	versions := make(map[string]fileVersion)
+	for _, file := range r.files {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			versions[file] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return versions
+}
+
+// mergeDynamic returns a copy of current with the dynamic fields taken from
+// next, the keys of dynamic settings that changed, and the keys of static
+// settings that changed but were not applied.
+func mergeDynamic(current, next *Config) (*Config, []string, []string) {
+	merged := *current
+	m := &merger{}
+	m.merge(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(next).Elem(), "", false)
+	return &merged, m.applied, m.restart
+}
+
+type merger struct {
+	applied []string
+	restart []string
+}
+
+func (m *merger) merge(dst, src reflect.Value, prefix string, dynamic bool) {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := prefix + fieldName(field)
+		fieldDynamic := dynamic || field.Tag.Get("reload") == "dynamic"
+
+		if field.Type.Kind() == reflect.Struct {
+			m.merge(dst.Field(i), src.Field(i), path+".", fieldDynamic)
+			continue
+		}
+
+		if reflect.DeepEqual(dst.Field(i).Interface(), src.Field(i).Interface()) {
+			continue
+		}
+
+		if fieldDynamic {
+			dst.Field(i).Set(src.Field(i))
+			m.applied = append(m.applied, path)
+		} else {
+			m.restart = append(m.restart, path)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"suitemedia/pkg/logger"
+)
+
+func TestMergeDynamic(t *testing.T) {
+	current := Default()
+	next := Default()
+	next.App.LogLevel = "debug"
+	next.CORS.AllowedOrigins = []string{"https://suitemedia.com"}
+	next.Database.Host = "db.internal"
+	next.App.Port = "8080"
+
+	merged, applied, restart := mergeDynamic(current, next)
+
+	if merged.App.LogLevel != "debug" {
+		t.Errorf("Expected dynamic log level to be applied, got %s", merged.App.LogLevel)
+	}
+	if merged.CORS.AllowedOrigins[0] != "https://suitemedia.com" {
+		t.Errorf("Expected dynamic CORS origins to be applied, got %v", merged.CORS.AllowedOrigins)
+	}
+	if merged.Database.Host != "localhost" || merged.App.Port != "3000" {
+		t.Error("Expected static settings to keep their current values")
+	}
+	if len(applied) != 2 {
+		t.Errorf("Expected 2 applied keys, got %v", applied)
+	}
+	if len(restart) != 2 {
+		t.Errorf("Expected 2 restart keys, got %v", restart)
+	}
+	if current.App.LogLevel != "info" {
+		t.Error("Expected current config to be left untouched")
+	}
+}
+
+func TestReloaderReload(t *testing.T) {
+	setRequiredEnv(t)
+	file := writeFile(t, "config.yaml", "app:\n  log_level: info\n")
+
+	cfg, err := Load(file)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	store := NewStore(cfg)
+	var notified *Config
+	store.Subscribe(func(c *Config) { notified = c })
+
+	reloader := NewReloader(store, []string{file}, logger.NewLogger("error"), time.Second)
+
+	if err := os.WriteFile(file, []byte("app:\n  log_level: debug\n"), 0o600); err != nil {
+		t.Fatalf("Failed to update config file: %v", err)
+	}
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Expected reload to succeed, got %v", err)
+	}
+	if store.Current().App.LogLevel != "debug" || notified != store.Current() {
+		t.Errorf("Expected new log level to be applied and subscribers notified")
+	}
+
+	if err := os.WriteFile(file, []byte("app:\n  log_level: loud\n"), 0o600); err != nil {
+		t.Fatalf("Failed to update config file: %v", err)
+	}
+	if err := reloader.Reload(); err == nil {
+		t.Error("Expected invalid config to be rejected")
+	}
+	if store.Current().App.LogLevel != "debug" {
+		t.Errorf("Expected previous config to be kept, got %s", store.Current().App.LogLevel)
+	}
+}
@@ -0,0 +1,334 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"suitemedia/pkg/allocation"
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/payment"
+	"suitemedia/pkg/tax"
+)
+
+// ValidationError reports every problem found while loading and validating
+// the configuration, so operators can fix them in one pass.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func (e *ValidationError) addf(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+func (e *ValidationError) err() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
+
+var (
+	validEnvironments = []string{"development", "test", "staging", "production"}
+	validLogLevels    = []string{"debug", "info", "warn", "error"}
+	validSSLModes     = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	validCacheDrivers = []string{"auto", "redis", "memory"}
+	validRedisModes   = []string{"standalone", "sentinel", "cluster"}
+	validCacheCodecs  = []string{"json", "msgpack"}
+	validAlgorithms   = []string{"gcra", "sliding_window"}
+	validRateKeys     = []string{"ip", "user", "api_key", "group"}
+)
+
+// Validate checks the configuration, including rules that only apply to
+// specific environments, and returns a *ValidationError listing every
+// problem found.
+func (c *Config) Validate() error {
+	problems := &ValidationError{}
+	c.validate(problems)
+	return problems.err()
+}
+
+func (c *Config) validate(p *ValidationError) {
+	production := c.App.Environment == "production"
+
+	if !contains(validEnvironments, c.App.Environment) {
+		p.addf("app.environment: %q must be one of %s", c.App.Environment, strings.Join(validEnvironments, ", "))
+	}
+	if !contains(validLogLevels, c.App.LogLevel) {
+		p.addf("app.log_level: %q must be one of %s", c.App.LogLevel, strings.Join(validLogLevels, ", "))
+	}
+	if port, err := strconv.Atoi(c.App.Port); err != nil || port < 1 || port > 65535 {
+		p.addf("app.port: %q is not a valid port", c.App.Port)
+	}
+	for _, proxy := range c.App.TrustedProxies {
+		if !isIPOrCIDR(proxy) {
+			p.addf("app.trusted_proxies: %q is not an IP address or CIDR", proxy)
+		}
+	}
+
+	if c.Database.Password == "" {
+		p.addf("DB_PASSWORD is required")
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		p.addf("database.port: %d is not a valid port", c.Database.Port)
+	}
+	if !contains(validSSLModes, c.Database.SSLMode) {
+		p.addf("database.ssl_mode: %q must be one of %s", c.Database.SSLMode, strings.Join(validSSLModes, ", "))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnMaxLifetime < 0 {
+		p.addf("database: pool settings must not be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		p.addf("database.max_idle_conns: %d exceeds max_open_conns %d", c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+
+	c.Redis.validate(p, production)
+
+	if !contains(validCacheDrivers, c.Cache.Driver) {
+		p.addf("cache.driver: %q must be one of %s", c.Cache.Driver, strings.Join(validCacheDrivers, ", "))
+	}
+	if c.Cache.MaxEntries <= 0 || c.Cache.ReconnectIntervalSeconds <= 0 {
+		p.addf("cache: max_entries and reconnect_interval_seconds must be positive")
+	}
+	if !contains(validCacheCodecs, c.Cache.Codec) {
+		p.addf("cache.codec: %q must be one of %s", c.Cache.Codec, strings.Join(validCacheCodecs, ", "))
+	}
+	if c.Cache.TTLSeconds < 0 || c.Cache.ListTTLSeconds < 0 || c.Cache.FacetTTLSeconds < 0 || c.Cache.NegativeTTLSeconds < 0 {
+		p.addf("cache: TTLs must not be negative")
+	}
+	if c.Cache.JitterPercent < 0 || c.Cache.JitterPercent > 100 {
+		p.addf("cache.jitter_percent: must be between 0 and 100")
+	}
+
+	if c.JWT.Secret == "" || c.JWT.Secret == "your-secret-key" {
+		p.addf("JWT_SECRET must be set")
+	}
+	if c.JWT.ExpirationHours <= 0 || c.JWT.RefreshExpirationDays <= 0 {
+		p.addf("jwt: expiration settings must be positive")
+	}
+	if production {
+		if len(c.JWT.Secret) < 32 {
+			p.addf("jwt.secret: must be at least 32 characters in production")
+		}
+		if c.JWT.RefreshSecret == "" || c.JWT.RefreshSecret == "your-refresh-secret-key" || c.JWT.RefreshSecret == c.JWT.Secret {
+			p.addf("jwt.refresh_secret: must be set and differ from jwt.secret in production")
+		}
+	}
+
+	c.CORS.validate(p, production)
+
+	if c.Health.CheckTimeoutMs <= 0 {
+		p.addf("health.check_timeout_ms: must be positive")
+	}
+	if c.Health.CacheTTLMs < 0 || c.Health.DrainDelaySeconds < 0 {
+		p.addf("health: cache_ttl_ms and drain_delay_seconds must not be negative")
+	}
+
+	c.RateLimit.validate(p)
+
+	if c.Idempotency.TTLHours <= 0 || c.Idempotency.LockTimeoutSeconds <= 0 {
+		p.addf("idempotency: ttl_hours and lock_timeout_seconds must be positive")
+	}
+
+	if !money.IsCurrency(c.Currency.Base) {
+		p.addf("currency.base: %q is not an ISO 4217 currency code", c.Currency.Base)
+	}
+
+	if c.Inventory.ReservationTTLSeconds <= 0 || c.Inventory.ReservationTTLSeconds > 86400 {
+		p.addf("inventory.reservation_ttl_seconds: must be between 1 and 86400")
+	}
+	if c.Inventory.SweepIntervalSeconds <= 0 {
+		p.addf("inventory.sweep_interval_seconds: must be positive")
+	}
+	if _, ok := allocation.Lookup(c.Inventory.AllocationStrategy); !ok {
+		p.addf("inventory.allocation_strategy: must be one of %s", strings.Join(allocation.Names(), ", "))
+	}
+
+	if c.Cart.AnonymousTTLHours <= 0 {
+		p.addf("cart.anonymous_ttl_hours: must be positive")
+	}
+	if c.Cart.MaxItems <= 0 {
+		p.addf("cart.max_items: must be positive")
+	}
+	if c.Cart.MaxQuantity <= 0 {
+		p.addf("cart.max_quantity: must be positive")
+	}
+
+	// Unpaid orders hold their stock with reservations, which last at most
+	// a day.
+	if c.Orders.PaymentTimeoutSeconds <= 0 || c.Orders.PaymentTimeoutSeconds > 86400 {
+		p.addf("orders.payment_timeout_seconds: must be between 1 and 86400")
+	}
+	if c.Orders.ShippingFee < 0 {
+		p.addf("orders.shipping_fee: must not be negative")
+	}
+
+	if !payment.Registered(c.Payments.Provider) {
+		p.addf("payments.provider: must be one of %s", strings.Join(payment.Names(), ", "))
+	} else if strings.EqualFold(c.Payments.Provider, payment.Fake) {
+		if production {
+			p.addf("payments.provider: %s is not permitted in production", payment.Fake)
+		}
+	} else if c.Payments.WebhookSecret == "" {
+		p.addf("payments.webhook_secret: must be set for provider %s", c.Payments.Provider)
+	}
+	if c.Payments.WebhookToleranceSeconds <= 0 {
+		p.addf("payments.webhook_tolerance_seconds: must be positive")
+	}
+
+	if !tax.Registered(c.Tax.Provider) {
+		p.addf("tax.provider: must be one of %s", strings.Join(tax.Names(), ", "))
+	}
+	if c.Tax.Rounding != tax.RoundPerLine && c.Tax.Rounding != tax.RoundPerOrder {
+		p.addf("tax.rounding: must be %s or %s", tax.RoundPerLine, tax.RoundPerOrder)
+	}
+	if c.Tax.DefaultCountry != "" && !isCountryCode(c.Tax.DefaultCountry) {
+		p.addf("tax.default_country: %q is not an ISO 3166-1 alpha-2 code", c.Tax.DefaultCountry)
+	}
+	if c.Tax.DefaultRegion != "" && c.Tax.DefaultCountry == "" {
+		p.addf("tax.default_region: requires tax.default_country")
+	}
+	if _, err := tax.ParseRules(c.Tax.Rates); err != nil {
+		p.addf("tax.rates: %v", err)
+	}
+}
+
+// isIPOrCIDR reports whether value is an IP address or a CIDR range.
+func isIPOrCIDR(value string) bool {
+	if net.ParseIP(value) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(value)
+	return err == nil
+}
+
+// isCountryCode reports whether code has the form of an ISO 3166-1 alpha-2
+// code.
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range strings.ToUpper(code) {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *RedisConfig) validate(p *ValidationError, production bool) {
+	switch c.Mode {
+	case "standalone":
+		if c.Port < 1 || c.Port > 65535 {
+			p.addf("redis.port: %d is not a valid port", c.Port)
+		}
+	case "sentinel":
+		if c.MasterName == "" || len(c.Addrs) == 0 {
+			p.addf("redis: sentinel mode requires master_name and addrs")
+		}
+	case "cluster":
+		if len(c.Addrs) == 0 {
+			p.addf("redis: cluster mode requires addrs")
+		}
+		if c.DB != 0 {
+			p.addf("redis.db: cluster mode only supports database 0")
+		}
+		if strings.ContainsAny(c.KeyPrefix, "{}") {
+			p.addf("redis.key_prefix: must not contain a hash tag in cluster mode")
+		}
+	default:
+		p.addf("redis.mode: %q must be one of %s", c.Mode, strings.Join(validRedisModes, ", "))
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		p.addf("redis.tls: cert_file and key_file must be set together")
+	}
+	if production && c.TLS.InsecureSkipVerify {
+		p.addf("redis.tls.insecure_skip_verify: not permitted in production")
+	}
+	if c.DialTimeoutMs < 0 || c.ReadTimeoutMs < 0 || c.WriteTimeoutMs < 0 || c.PoolSize < 0 || c.MinIdleConns < 0 {
+		p.addf("redis: timeouts and pool settings must not be negative")
+	}
+}
+
+func (c *CORSConfig) validate(p *ValidationError, production bool) {
+	if len(c.AllowedOrigins) == 0 {
+		p.addf("cors.allowed_origins: at least one origin is required")
+	}
+
+	wildcard := false
+	for _, origin := range c.AllowedOrigins {
+		switch {
+		case origin == "*":
+			wildcard = true
+		case strings.HasPrefix(origin, "http://"), strings.HasPrefix(origin, "https://"):
+		default:
+			p.addf("cors.allowed_origins: %q must be \"*\" or start with http:// or https://", origin)
+		}
+	}
+
+	if wildcard && len(c.AllowedOrigins) > 1 {
+		p.addf("cors.allowed_origins: \"*\" cannot be combined with other origins")
+	}
+	if production && wildcard && c.AllowCredentials {
+		p.addf("cors: wildcard origin \"*\" with allow_credentials is not permitted in production")
+	}
+	if c.MaxAge < 0 {
+		p.addf("cors.max_age: must not be negative")
+	}
+}
+
+func (c *RateLimitConfig) validate(p *ValidationError) {
+	if !contains(validAlgorithms, c.Algorithm) {
+		p.addf("rate_limit.algorithm: %q must be one of %s", c.Algorithm, strings.Join(validAlgorithms, ", "))
+	}
+
+	for _, name := range sortedKeys(c.Policies) {
+		policy := c.Policies[name]
+		if policy.Limit <= 0 || policy.PeriodSeconds <= 0 {
+			p.addf("rate_limit.policies.%s: limit and period_seconds must be positive", name)
+		}
+		if policy.Burst < 0 {
+			p.addf("rate_limit.policies.%s.burst: must not be negative", name)
+		}
+		if !contains(validRateKeys, policy.KeyBy) {
+			p.addf("rate_limit.policies.%s.key_by: %q must be one of %s", name, policy.KeyBy, strings.Join(validRateKeys, ", "))
+		}
+		if policy.Algorithm != "" && !contains(validAlgorithms, policy.Algorithm) {
+			p.addf("rate_limit.policies.%s.algorithm: %q must be one of %s", name, policy.Algorithm, strings.Join(validAlgorithms, ", "))
+		}
+	}
+
+	for _, route := range sortedKeys(c.Routes) {
+		if parts := strings.SplitN(route, " ", 2); len(parts) != 2 || !strings.HasPrefix(parts[1], "/") {
+			p.addf("rate_limit.routes: %q must be written as \"METHOD /path\"", route)
+		}
+		if _, ok := c.Policies[c.Routes[route]]; !ok {
+			p.addf("rate_limit.routes: %q refers to unknown policy %q", route, c.Routes[route])
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"suitemedia/config"
+	"suitemedia/pkg/money"
 
 	_ "github.com/lib/pq"
 )
@@ -34,7 +35,9 @@ func NewConnection(cfg config.DatabaseConfig) (*sql.DB, error) {
 	return db, nil
 }
 
-func RunMigrations(db *sql.DB) error {
+// RunMigrations creates and updates the schema. Prices stored before
+// currencies were introduced are migrated to baseCurrency.
+func RunMigrations(db *sql.DB, baseCurrency string) error {
 	// Create users table
 	_, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS users (
@@ -81,5 +84,535 @@ func RunMigrations(db *sql.DB) error {
 		return fmt.Errorf("failed to create products table: %w", err)
 	}
 
+	// Add version columns for optimistic concurrency control
+	_, err = db.Exec(`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+		ALTER TABLE products ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add version columns: %w", err)
+	}
+
+	// Add full-text search over products, with trigram matching on the
+	// name for typos
+	_, err = db.Exec(`
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+		ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', COALESCE(name, '')), 'A') ||
+			setweight(to_tsvector('english', COALESCE(category, '')), 'B') ||
+			setweight(to_tsvector('english', COALESCE(description, '')), 'C')
+		) STORED;
+
+		CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+		CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add product search: %w", err)
+	}
+
+	// Create categories table. Path is the materialized path of IDs from
+	// the root, which makes subtree queries a prefix match
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS categories (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			parent_id UUID REFERENCES categories(id),
+			name VARCHAR(100) NOT NULL,
+			slug VARCHAR(120) NOT NULL,
+			path TEXT NOT NULL,
+			position INTEGER NOT NULL DEFAULT 0,
+			version INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_slug ON categories(slug) WHERE deleted_at IS NULL;
+		CREATE INDEX IF NOT EXISTS idx_categories_path ON categories(path text_pattern_ops);
+		CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id);
+
+		ALTER TABLE products ADD COLUMN IF NOT EXISTS category_id UUID REFERENCES categories(id);
+		CREATE INDEX IF NOT EXISTS idx_products_category_id ON products(category_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create categories table: %w", err)
+	}
+
+	// Turn free-text product categories into top-level categories. Names
+	// that only differ in case, spacing or punctuation share a slug and
+	// become one category; product category names are normalized to it
+	_, err = db.Exec(`
+		WITH names AS (
+			SELECT DISTINCT ON (slug) slug, name
+			FROM (
+				SELECT btrim(regexp_replace(lower(category), '[^a-z0-9]+', '-', 'g'), '-') AS slug,
+					btrim(regexp_replace(category, '\s+', ' ', 'g')) AS name
+				FROM products
+				WHERE category_id IS NULL AND category IS NOT NULL
+			) candidates
+			WHERE slug <> ''
+			ORDER BY slug, name
+		), created AS (
+			SELECT gen_random_uuid() AS id, slug, name FROM names
+		)
+		INSERT INTO categories (id, name, slug, path)
+		SELECT id, name, slug, '/' || id || '/' FROM created
+		WHERE NOT EXISTS (SELECT 1 FROM categories c WHERE c.slug = created.slug AND c.deleted_at IS NULL);
+
+		UPDATE products p
+		SET category_id = c.id, category = c.name
+		FROM categories c
+		WHERE p.category_id IS NULL AND c.deleted_at IS NULL
+			AND c.slug = btrim(regexp_replace(lower(p.category), '[^a-z0-9]+', '-', 'g'), '-');
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to migrate product categories: %w", err)
+	}
+
+	// Store prices as integer minor units. Existing DECIMAL prices carry no
+	// currency and are taken to be in the base currency.
+	_, err = db.Exec(fmt.Sprintf(`
+		DO $$
+		BEGIN
+			IF EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_name = 'products' AND column_name = 'price' AND data_type = 'numeric'
+			) THEN
+				ALTER TABLE products ALTER COLUMN price TYPE BIGINT USING round(price * power(10::numeric, %d));
+			END IF;
+		END $$;
+
+		ALTER TABLE products ADD COLUMN IF NOT EXISTS currency CHAR(3);
+	`, money.Digits(baseCurrency)))
+	if err != nil {
+		return fmt.Errorf("failed to migrate product prices: %w", err)
+	}
+	_, err = db.Exec(`UPDATE products SET currency = $1 WHERE currency IS NULL`, baseCurrency)
+	if err == nil {
+		_, err = db.Exec(`ALTER TABLE products ALTER COLUMN currency SET NOT NULL`)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate product currencies: %w", err)
+	}
+
+	// Explicit product prices in other currencies, and exchange rates from
+	// the base currency for converting the rest
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS product_prices (
+			product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+			currency CHAR(3) NOT NULL,
+			amount BIGINT NOT NULL CHECK (amount >= 0),
+			PRIMARY KEY (product_id, currency)
+		);
+
+		CREATE TABLE IF NOT EXISTS exchange_rates (
+			currency CHAR(3) PRIMARY KEY,
+			rate NUMERIC(24,12) NOT NULL CHECK (rate > 0),
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create price tables: %w", err)
+	}
+
+	// Create product options and variants. SKUs are unique among live
+	// variants, as are option combinations within a product
+	_, err = db.Exec(`
+		ALTER TABLE products ADD COLUMN IF NOT EXISTS options JSONB NOT NULL DEFAULT '[]';
+
+		CREATE TABLE IF NOT EXISTS product_variants (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+			sku VARCHAR(64) NOT NULL,
+			options JSONB NOT NULL DEFAULT '{}',
+			price BIGINT CHECK (price >= 0),
+			stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+			barcode VARCHAR(64),
+			image_url VARCHAR(500),
+			position INTEGER NOT NULL DEFAULT 0,
+			version INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_sku ON product_variants(sku) WHERE deleted_at IS NULL;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variants_options ON product_variants(product_id, options) WHERE deleted_at IS NULL;
+		CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants(product_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create product variants table: %w", err)
+	}
+
+	// Create the inventory ledger. Movements are append-only; the stock
+	// columns of products and variants hold the running balance
+	_, err = db.Exec(`
+		UPDATE products SET stock = 0 WHERE stock IS NULL;
+		ALTER TABLE products ALTER COLUMN stock SET NOT NULL;
+
+		CREATE TABLE IF NOT EXISTS inventory_movements (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			product_id UUID NOT NULL REFERENCES products(id),
+			variant_id UUID REFERENCES product_variants(id),
+			kind VARCHAR(20) NOT NULL CHECK (kind IN ('receipt', 'sale', 'adjustment', 'return')),
+			quantity INTEGER NOT NULL CHECK (quantity <> 0),
+			stock_after INTEGER NOT NULL CHECK (stock_after >= 0),
+			reference VARCHAR(100),
+			note TEXT,
+			created_by UUID REFERENCES users(id),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_inventory_movements_product ON inventory_movements(product_id, created_at DESC);
+
+		CREATE OR REPLACE FUNCTION inventory_movements_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'inventory movements are append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS inventory_movements_append_only ON inventory_movements;
+		CREATE TRIGGER inventory_movements_append_only BEFORE UPDATE OR DELETE ON inventory_movements
+			FOR EACH ROW EXECUTE FUNCTION inventory_movements_append_only();
+
+		CREATE TABLE IF NOT EXISTS stock_reservations (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			product_id UUID NOT NULL REFERENCES products(id),
+			variant_id UUID REFERENCES product_variants(id),
+			quantity INTEGER NOT NULL CHECK (quantity > 0),
+			reference VARCHAR(100),
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_stock_reservations_item ON stock_reservations(product_id, variant_id, expires_at);
+		CREATE INDEX IF NOT EXISTS idx_stock_reservations_expires_at ON stock_reservations(expires_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create inventory tables: %w", err)
+	}
+
+	// Record the stock that predates the ledger as opening balances
+	_, err = db.Exec(`
+		INSERT INTO inventory_movements (product_id, kind, quantity, stock_after, note)
+		SELECT p.id, 'adjustment', p.stock, p.stock, 'opening balance'
+		FROM products p
+		WHERE p.stock > 0 AND NOT EXISTS (
+			SELECT 1 FROM inventory_movements m WHERE m.product_id = p.id AND m.variant_id IS NULL
+		);
+
+		INSERT INTO inventory_movements (product_id, variant_id, kind, quantity, stock_after, note)
+		SELECT v.product_id, v.id, 'adjustment', v.stock, v.stock, 'opening balance'
+		FROM product_variants v
+		WHERE v.stock > 0 AND NOT EXISTS (
+			SELECT 1 FROM inventory_movements m WHERE m.variant_id = v.id
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to record opening stock: %w", err)
+	}
+
+	// Create warehouses, starting with a default one holding existing stock
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS warehouses (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			code VARCHAR(50) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			address TEXT,
+			country CHAR(2),
+			latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+			longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+			priority INTEGER NOT NULL DEFAULT 0,
+			is_active BOOLEAN NOT NULL DEFAULT true,
+			is_default BOOLEAN NOT NULL DEFAULT false,
+			version INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_code ON warehouses(code) WHERE deleted_at IS NULL;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_default ON warehouses(is_default) WHERE is_default AND deleted_at IS NULL;
+
+		INSERT INTO warehouses (code, name, is_default)
+		SELECT 'MAIN', 'Main warehouse', true
+		WHERE NOT EXISTS (SELECT 1 FROM warehouses WHERE is_default AND deleted_at IS NULL);
+
+		CREATE TABLE IF NOT EXISTS warehouse_stock (
+			warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+			product_id UUID NOT NULL REFERENCES products(id),
+			variant_id UUID REFERENCES product_variants(id),
+			stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouse_stock_item
+			ON warehouse_stock(warehouse_id, product_id, COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'));
+		CREATE INDEX IF NOT EXISTS idx_warehouse_stock_product ON warehouse_stock(product_id, variant_id);
+
+		ALTER TABLE inventory_movements ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses(id);
+		ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS warehouse_id UUID REFERENCES warehouses(id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create warehouse tables: %w", err)
+	}
+
+	// Move stock, movements and reservations from before warehouses to the
+	// default warehouse
+	_, err = db.Exec(`
+		INSERT INTO warehouse_stock (warehouse_id, product_id, stock)
+		SELECT w.id, p.id, p.stock
+		FROM products p, warehouses w
+		WHERE w.is_default AND w.deleted_at IS NULL AND p.stock > 0 AND NOT EXISTS (
+			SELECT 1 FROM warehouse_stock ws WHERE ws.product_id = p.id AND ws.variant_id IS NULL
+		);
+
+		INSERT INTO warehouse_stock (warehouse_id, product_id, variant_id, stock)
+		SELECT w.id, v.product_id, v.id, v.stock
+		FROM product_variants v, warehouses w
+		WHERE w.is_default AND w.deleted_at IS NULL AND v.stock > 0 AND NOT EXISTS (
+			SELECT 1 FROM warehouse_stock ws WHERE ws.variant_id = v.id
+		);
+
+		UPDATE stock_reservations
+		SET warehouse_id = (SELECT id FROM warehouses WHERE is_default AND deleted_at IS NULL)
+		WHERE warehouse_id IS NULL;
+
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM inventory_movements WHERE warehouse_id IS NULL) THEN
+				ALTER TABLE inventory_movements DISABLE TRIGGER inventory_movements_append_only;
+				UPDATE inventory_movements
+				SET warehouse_id = (SELECT id FROM warehouses WHERE is_default AND deleted_at IS NULL)
+				WHERE warehouse_id IS NULL;
+				ALTER TABLE inventory_movements ENABLE TRIGGER inventory_movements_append_only;
+			END IF;
+		END $$;
+
+		ALTER TABLE inventory_movements ALTER COLUMN warehouse_id SET NOT NULL;
+		ALTER TABLE stock_reservations ALTER COLUMN warehouse_id SET NOT NULL;
+
+		DO $$
+		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM pg_constraint
+				WHERE conname = 'inventory_movements_kind_check' AND pg_get_constraintdef(oid) LIKE '%transfer%'
+			) THEN
+				ALTER TABLE inventory_movements DROP CONSTRAINT IF EXISTS inventory_movements_kind_check;
+				ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movements_kind_check
+					CHECK (kind IN ('receipt', 'sale', 'adjustment', 'return', 'transfer'));
+			END IF;
+		END $$;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to move stock to the default warehouse: %w", err)
+	}
+
+	// Persist the carts of logged-in users
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS carts (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL UNIQUE REFERENCES users(id),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS cart_items (
+			id UUID PRIMARY KEY,
+			cart_id UUID NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+			product_id UUID NOT NULL REFERENCES products(id),
+			variant_id UUID REFERENCES product_variants(id),
+			quantity INTEGER NOT NULL CHECK (quantity > 0),
+			unit_price BIGINT NOT NULL,
+			currency CHAR(3) NOT NULL,
+			added_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_cart_items_cart ON cart_items(cart_id, added_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create cart tables: %w", err)
+	}
+
+	// Orders with their items and status history
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS orders (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id),
+			status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'paid', 'fulfilled', 'shipped', 'delivered', 'cancelled', 'refunded')),
+			item_count INTEGER NOT NULL,
+			subtotal BIGINT NOT NULL,
+			total BIGINT NOT NULL,
+			currency CHAR(3) NOT NULL,
+			note VARCHAR(500),
+			expires_at TIMESTAMP,
+			version INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_orders_user ON orders(user_id, created_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status, created_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_orders_pending_expires_at ON orders(expires_at) WHERE status = 'pending';
+
+		CREATE TABLE IF NOT EXISTS order_items (
+			id UUID PRIMARY KEY,
+			order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+			product_id UUID NOT NULL REFERENCES products(id),
+			variant_id UUID REFERENCES product_variants(id),
+			warehouse_id UUID NOT NULL REFERENCES warehouses(id),
+			reservation_id UUID,
+			sku VARCHAR(64),
+			name VARCHAR(255) NOT NULL,
+			unit_price BIGINT NOT NULL,
+			quantity INTEGER NOT NULL CHECK (quantity > 0),
+			total BIGINT NOT NULL,
+			position INTEGER NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_order_items_order ON order_items(order_id, position);
+
+		CREATE TABLE IF NOT EXISTS order_status_history (
+			id BIGSERIAL PRIMARY KEY,
+			order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+			from_status VARCHAR(20),
+			to_status VARCHAR(20) NOT NULL,
+			note VARCHAR(500),
+			changed_by UUID REFERENCES users(id),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history(order_id, id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create order tables: %w", err)
+	}
+
+	// Payments of orders and the provider webhook events already handled
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS payments (
+			id UUID PRIMARY KEY,
+			order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+			provider VARCHAR(50) NOT NULL,
+			intent_id VARCHAR(255) NOT NULL,
+			amount BIGINT NOT NULL,
+			currency CHAR(3) NOT NULL,
+			status VARCHAR(20) NOT NULL CHECK (status IN ('requires_payment', 'authorized', 'captured', 'failed', 'refunded')),
+			client_secret VARCHAR(255),
+			failure_reason VARCHAR(255),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_intent ON payments(provider, intent_id);
+		CREATE INDEX IF NOT EXISTS idx_payments_order ON payments(order_id, created_at);
+
+		CREATE TABLE IF NOT EXISTS payment_events (
+			provider VARCHAR(50) NOT NULL,
+			event_id VARCHAR(255) NOT NULL,
+			type VARCHAR(50) NOT NULL,
+			intent_id VARCHAR(255) NOT NULL,
+			payload JSONB NOT NULL,
+			received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (provider, event_id)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create payment tables: %w", err)
+	}
+
+	// Promotions with their redemptions, the codes entered in carts and the
+	// discounts and shipping of orders. Codes are unique among live
+	// promotions regardless of case
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS promotions (
+			id UUID PRIMARY KEY,
+			code VARCHAR(50),
+			name VARCHAR(255) NOT NULL,
+			description VARCHAR(1000),
+			kind VARCHAR(20) NOT NULL CHECK (kind IN ('percentage', 'fixed', 'free_shipping')),
+			percent_off INTEGER NOT NULL DEFAULT 0 CHECK (percent_off BETWEEN 0 AND 100),
+			amount_off BIGINT,
+			min_subtotal BIGINT,
+			currency CHAR(3),
+			product_ids UUID[] NOT NULL DEFAULT '{}',
+			category_ids UUID[] NOT NULL DEFAULT '{}',
+			first_order_only BOOLEAN NOT NULL DEFAULT false,
+			starts_at TIMESTAMP,
+			ends_at TIMESTAMP,
+			stackable BOOLEAN NOT NULL DEFAULT false,
+			priority INTEGER NOT NULL DEFAULT 0,
+			usage_limit INTEGER CHECK (usage_limit > 0),
+			per_user_limit INTEGER CHECK (per_user_limit > 0),
+			times_used INTEGER NOT NULL DEFAULT 0,
+			is_active BOOLEAN NOT NULL DEFAULT true,
+			version INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			deleted_at TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_promotions_code ON promotions(UPPER(code)) WHERE code IS NOT NULL AND deleted_at IS NULL;
+		CREATE INDEX IF NOT EXISTS idx_promotions_automatic ON promotions(priority) WHERE code IS NULL AND is_active AND deleted_at IS NULL;
+
+		CREATE TABLE IF NOT EXISTS promotion_redemptions (
+			id BIGSERIAL PRIMARY KEY,
+			promotion_id UUID NOT NULL REFERENCES promotions(id),
+			user_id UUID NOT NULL REFERENCES users(id),
+			order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+			amount BIGINT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (promotion_id, order_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_user ON promotion_redemptions(user_id, promotion_id);
+		CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_order ON promotion_redemptions(order_id);
+
+		ALTER TABLE carts ADD COLUMN IF NOT EXISTS promotion_codes TEXT[] NOT NULL DEFAULT '{}';
+
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_total BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount BIGINT NOT NULL DEFAULT 0;
+
+		CREATE TABLE IF NOT EXISTS order_discounts (
+			order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			promotion_id UUID NOT NULL REFERENCES promotions(id),
+			code VARCHAR(50),
+			name VARCHAR(255) NOT NULL,
+			kind VARCHAR(20) NOT NULL,
+			amount BIGINT NOT NULL,
+			PRIMARY KEY (order_id, position)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create promotion tables: %w", err)
+	}
+
+	_, err = db.Exec(`
+		ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_class VARCHAR(50) NOT NULL DEFAULT 'standard';
+
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_total BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_country CHAR(2);
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_region VARCHAR(50);
+		ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax BIGINT NOT NULL DEFAULT 0;
+
+		CREATE TABLE IF NOT EXISTS order_taxes (
+			order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			country CHAR(2) NOT NULL,
+			region VARCHAR(50),
+			class VARCHAR(50),
+			rate VARCHAR(20) NOT NULL,
+			taxable BIGINT NOT NULL,
+			amount BIGINT NOT NULL,
+			PRIMARY KEY (order_id, position)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create tax tables: %w", err)
+	}
+
 	return nil
 }
@@ -54,7 +54,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user and return access token
+// @Description Authenticate user and return access token. The anonymous cart in cart_token, the X-Cart-Token header or the cart_token cookie is merged into the cart of the user.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -70,6 +70,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
+	if req.CartToken == "" {
+		req.CartToken = cartToken(c)
+	}
 
 	authResp, err := h.authService.Login(c.Request.Context(), req)
 	if err != nil {
@@ -80,6 +83,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		response.Error(c, http.StatusInternalServerError, "Failed to login", err)
 		return
 	}
+	// The anonymous cart now belongs to the user
+	if req.CartToken != "" {
+		setCartCookie(c, "", -1)
+	}
 
 	response.Success(c, authResp)
 }
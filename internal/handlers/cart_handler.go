@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/service"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Anonymous carts are identified by a token the client sends back in the
+// X-Cart-Token header or the cart_token cookie.
+const (
+	cartTokenHeader = "X-Cart-Token"
+	cartTokenCookie = "cart_token"
+)
+
+type CartHandler struct {
+	cartService service.CartService
+	cookieTTL   int
+}
+
+// NewCartHandler creates a handler whose cart cookies last
+// anonymousTTLHours, like the anonymous carts they identify.
+func NewCartHandler(cartService service.CartService, anonymousTTLHours int) *CartHandler {
+	return &CartHandler{
+		cartService: cartService,
+		cookieTTL:   anonymousTTLHours * 3600,
+	}
+}
+
+// cartToken returns the anonymous cart token sent with the request.
+func cartToken(c *gin.Context) string {
+	if token := c.GetHeader(cartTokenHeader); token != "" {
+		return token
+	}
+	token, _ := c.Cookie(cartTokenCookie)
+	return token
+}
+
+// setCartCookie sets the cart_token cookie, deleting it when maxAge is
+// negative.
+func setCartCookie(c *gin.Context, token string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(cartTokenCookie, token, maxAge, "/", "", c.Request.TLS != nil, true)
+}
+
+// owner identifies the cart of the request: the cart of the logged-in
+// user, or the anonymous cart of the token sent.
+func (h *CartHandler) owner(c *gin.Context) service.CartOwner {
+	if userID := c.GetString("userID"); userID != "" {
+		return service.CartOwner{UserID: userID}
+	}
+	return service.CartOwner{Token: cartToken(c)}
+}
+
+// respond writes cart, passing the token of an anonymous cart back in the
+// X-Cart-Token header and a cookie that is renewed on every change.
+func (h *CartHandler) respond(c *gin.Context, owner service.CartOwner, cart *models.Cart, changed bool) {
+	if owner.UserID == "" {
+		token := owner.Token
+		if cart.Token != "" {
+			token = cart.Token
+		}
+		if token != "" {
+			c.Header(cartTokenHeader, token)
+			if changed {
+				setCartCookie(c, token, h.cookieTTL)
+			}
+		}
+	}
+
+	response.Success(c, cart)
+}
+
+// cartError writes the response for an error returned by the cart service.
+func cartError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrProductNotFound:
+		response.Error(c, http.StatusNotFound, "Product not found", err)
+	case service.ErrVariantNotFound:
+		response.Error(c, http.StatusNotFound, "Variant not found", err)
+	case service.ErrCartItemNotFound:
+		response.Error(c, http.StatusNotFound, "Cart item not found", err)
+	case service.ErrProductUnavailable:
+		response.Error(c, http.StatusConflict, "Product is not available", err)
+	case service.ErrVariantRequired:
+		response.Error(c, http.StatusBadRequest, "Variant is required", err)
+	case service.ErrInsufficientStock:
+		response.Error(c, http.StatusConflict, "Insufficient stock", err)
+	case service.ErrCartQuantity:
+		response.Error(c, http.StatusBadRequest, "Quantity exceeds the maximum per item", err)
+	case service.ErrCartFull:
+		response.Error(c, http.StatusConflict, "Cart is full", err)
+	case service.ErrPromotionNotFound:
+		response.Error(c, http.StatusNotFound, "Promotion code not found", err)
+	case service.ErrPromotionLimitReached:
+		response.Error(c, http.StatusConflict, "Promotion has reached its usage limit", err)
+	default:
+		response.Error(c, http.StatusInternalServerError, message, err)
+	}
+}
+
+// Get godoc
+// @Summary Get cart
+// @Description Get the cart of the logged-in user, or the anonymous cart of the X-Cart-Token header or cart_token cookie. Items are checked against the current products; issues lists lines that were removed, reduced to the stock or repriced.
+// @Tags cart
+// @Produce json
+// @Param X-Cart-Token header string false "Anonymous cart token"
+// @Success 200 {object} response.Response{data=models.Cart}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/cart [get]
+func (h *CartHandler) Get(c *gin.Context) {
+	owner := h.owner(c)
+	cart, err := h.cartService.Get(c.Request.Context(), owner)
+	if err != nil {
+		cartError(c, err, "Failed to fetch cart")
+		return
+	}
+
+	h.respond(c, owner, cart, false)
+}
+
+// AddItem godoc
+// @Summary Add cart item
+// @Description Add a product or variant to the cart, creating an anonymous cart and returning its token when there is none
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Anonymous cart token"
+// @Param item body models.AddCartItemRequest true "Item"
+// @Success 200 {object} response.Response{data=models.Cart}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/cart/items [post]
+func (h *CartHandler) AddItem(c *gin.Context) {
+	var req models.AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	owner := h.owner(c)
+	cart, err := h.cartService.AddItem(c.Request.Context(), owner, req)
+	if err != nil {
+		cartError(c, err, "Failed to add cart item")
+		return
+	}
+
+	h.respond(c, owner, cart, true)
+}
+
+// UpdateItem godoc
+// @Summary Update cart item
+// @Description Set the quantity of a cart line
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Anonymous cart token"
+// @Param itemId path string true "Cart item ID"
+// @Param item body models.UpdateCartItemRequest true "Quantity"
+// @Success 200 {object} response.Response{data=models.Cart}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/cart/items/{itemId} [put]
+func (h *CartHandler) UpdateItem(c *gin.Context) {
+	var req models.UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	owner := h.owner(c)
+	cart, err := h.cartService.UpdateItem(c.Request.Context(), owner, c.Param("itemId"), req)
+	if err != nil {
+		cartError(c, err, "Failed to update cart item")
+		return
+	}
+
+	h.respond(c, owner, cart, true)
+}
+
+// RemoveItem godoc
+// @Summary Remove cart item
+// @Description Remove a line from the cart
+// @Tags cart
+// @Produce json
+// @Param X-Cart-Token header string false "Anonymous cart token"
+// @Param itemId path string true "Cart item ID"
+// @Success 200 {object} response.Response{data=models.Cart}
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/cart/items/{itemId} [delete]
+func (h *CartHandler) RemoveItem(c *gin.Context) {
+	owner := h.owner(c)
+	cart, err := h.cartService.RemoveItem(c.Request.Context(), owner, c.Param("itemId"))
+	if err != nil {
+		cartError(c, err, "Failed to remove cart item")
+		return
+	}
+
+	h.respond(c, owner, cart, true)
+}
+
+// ApplyPromotion godoc
+// @Summary Apply promotion code
+// @Description Add a promotion code to the cart. Codes whose conditions the cart does not meet yet are kept and listed in pricing.rejected_promotions with the reason.
+// @Tags cart
+// @Accept json
+// @Produce json
+// @Param X-Cart-Token header string false "Anonymous cart token"
+// @Param promotion body models.ApplyPromotionRequest true "Promotion code"
+// @Success 200 {object} response.Response{data=models.Cart}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/cart/promotions [post]
+func (h *CartHandler) ApplyPromotion(c *gin.Context) {
+	var req models.ApplyPromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	owner := h.owner(c)
+	cart, err := h.cartService.ApplyPromotion(c.Request.Context(), owner, req)
+	if err != nil {
+		cartError(c, err, "Failed to apply promotion")
+		return
+	}
+
+	h.respond(c, owner, cart, true)
+}
+
+// RemovePromotion godoc
+// @Summary Remove promotion code
+// @Description Remove a promotion code from the cart
+// @Tags cart
+// @Produce json
+// @Param X-Cart-Token header string false "Anonymous cart token"
+// @Param code path string true "Promotion code"
+// @Success 200 {object} response.Response{data=models.Cart}
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/cart/promotions/{code} [delete]
+func (h *CartHandler) RemovePromotion(c *gin.Context) {
+	owner := h.owner(c)
+	cart, err := h.cartService.RemovePromotion(c.Request.Context(), owner, c.Param("code"))
+	if err != nil {
+		cartError(c, err, "Failed to remove promotion")
+		return
+	}
+
+	h.respond(c, owner, cart, true)
+}
+
+// Clear godoc
+// @Summary Clear cart
+// @Description Delete the cart with all its items
+// @Tags cart
+// @Produce json
+// @Param X-Cart-Token header string false "Anonymous cart token"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/cart [delete]
+func (h *CartHandler) Clear(c *gin.Context) {
+	owner := h.owner(c)
+	if err := h.cartService.Clear(c.Request.Context(), owner); err != nil {
+		cartError(c, err, "Failed to clear cart")
+		return
+	}
+	if owner.UserID == "" && owner.Token != "" {
+		setCartCookie(c, "", -1)
+	}
+
+	response.Success(c, gin.H{"message": "Cart cleared successfully"})
+}
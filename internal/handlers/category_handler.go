@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/service"
+	"suitemedia/pkg/pagination"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	categoryService service.CategoryService
+	productService  service.ProductService
+	cursors         *pagination.Signer
+}
+
+func NewCategoryHandler(categoryService service.CategoryService, productService service.ProductService, cursors *pagination.Signer) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService: categoryService,
+		productService:  productService,
+		cursors:         cursors,
+	}
+}
+
+// List returns the category tree.
+func (h *CategoryHandler) List(c *gin.Context) {
+	tree, err := h.categoryService.Tree(c.Request.Context())
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch categories", err)
+		return
+	}
+
+	response.Success(c, tree)
+}
+
+// GetByID returns the category with the given ID or slug.
+func (h *CategoryHandler) GetByID(c *gin.Context) {
+	category, err := h.categoryService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if err == service.ErrCategoryNotFound {
+			response.Error(c, http.StatusNotFound, "Category not found", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch category", err)
+		return
+	}
+
+	response.Success(c, category)
+}
+
+// Products lists the products in the category with the given ID or slug
+// and in all of its subcategories, accepting the same parameters as the
+// product list.
+func (h *CategoryHandler) Products(c *gin.Context) {
+	category, err := h.categoryService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if err == service.ErrCategoryNotFound {
+			response.Error(c, http.StatusNotFound, "Category not found", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch category", err)
+		return
+	}
+
+	params, ok := bindListParams(c, h.cursors, models.ProductListFields)
+	if !ok {
+		return
+	}
+	params.CategoryPath = category.Path
+
+	result, err := h.productService.List(c.Request.Context(), params)
+	if err != nil {
+		if err == service.ErrInvalidCursor {
+			response.Error(c, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch products", err)
+		return
+	}
+
+	products, ok := localize(c, h.productService, result.Items...)
+	if !ok {
+		return
+	}
+
+	response.SuccessList(c, products, listMeta(params, result, h.cursors))
+}
+
+func (h *CategoryHandler) Create(c *gin.Context) {
+	var req models.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	category, err := h.categoryService.Create(c.Request.Context(), req)
+	if err != nil {
+		if err == service.ErrInvalidSlug || err == service.ErrCategoryParentNotFound {
+			response.Error(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if err == service.ErrCategorySlugExists {
+			response.Error(c, http.StatusConflict, "Category slug already exists", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to create category", err)
+		return
+	}
+
+	response.Success(c, category, http.StatusCreated)
+}
+
+func (h *CategoryHandler) Update(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	category, err := h.categoryService.Update(c.Request.Context(), id, c.GetInt("ifMatchVersion"), req)
+	if err != nil {
+		if err == service.ErrCategoryNotFound {
+			response.Error(c, http.StatusNotFound, "Category not found", err)
+			return
+		}
+		if err == service.ErrVersionConflict {
+			response.Error(c, http.StatusPreconditionFailed, "Category has been modified", err)
+			return
+		}
+		if err == service.ErrInvalidSlug || err == service.ErrCategoryParentNotFound || err == service.ErrCategoryCycle {
+			response.Error(c, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		if err == service.ErrCategorySlugExists {
+			response.Error(c, http.StatusConflict, "Category slug already exists", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to update category", err)
+		return
+	}
+
+	response.Success(c, category)
+}
+
+// Delete removes a category that has no subcategories or products.
+func (h *CategoryHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+
+	err := h.categoryService.Delete(c.Request.Context(), id, c.GetInt("ifMatchVersion"))
+	if err != nil {
+		if err == service.ErrCategoryNotFound {
+			response.Error(c, http.StatusNotFound, "Category not found", err)
+			return
+		}
+		if err == service.ErrVersionConflict {
+			response.Error(c, http.StatusPreconditionFailed, "Category has been modified", err)
+			return
+		}
+		if err == service.ErrCategoryInUse {
+			response.Error(c, http.StatusConflict, "Category has subcategories or products", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to delete category", err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Category deleted successfully"})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/service"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CurrencyHandler struct {
+	currencyService service.CurrencyService
+}
+
+func NewCurrencyHandler(currencyService service.CurrencyService) *CurrencyHandler {
+	return &CurrencyHandler{
+		currencyService: currencyService,
+	}
+}
+
+// Rates returns the exchange rates from the base currency.
+func (h *CurrencyHandler) Rates(c *gin.Context) {
+	rates, err := h.currencyService.Rates(c.Request.Context())
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch exchange rates", err)
+		return
+	}
+
+	response.Success(c, rates)
+}
+
+// UpdateRates adds or replaces exchange rates and returns all rates.
+func (h *CurrencyHandler) UpdateRates(c *gin.Context) {
+	var req models.UpdateRatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	rates, err := h.currencyService.SetRates(c.Request.Context(), req.Rates)
+	if err != nil {
+		if err == service.ErrInvalidRate {
+			response.Error(c, http.StatusBadRequest, "Invalid exchange rates", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to update exchange rates", err)
+		return
+	}
+
+	response.Success(c, rates)
+}
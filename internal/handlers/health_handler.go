@@ -1,30 +1,27 @@
 package handlers
 
 import (
-	"database/sql"
 	"net/http"
 
-	"suitemedia/pkg/redis"
+	"suitemedia/pkg/health"
 	"suitemedia/pkg/response"
 
 	"github.com/gin-gonic/gin"
 )
 
 type HealthHandler struct {
-	db    *sql.DB
-	redis *redis.Client
+	registry *health.Registry
 }
 
-func NewHealthHandler(db *sql.DB, redis *redis.Client) *HealthHandler {
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
 	return &HealthHandler{
-		db:    db,
-		redis: redis,
+		registry: registry,
 	}
 }
 
 // Health godoc
-// @Summary Health check
-// @Description Check if the service is healthy
+// @Summary Liveness check
+// @Description Check if the process is alive. Dependencies are not checked.
 // @Tags health
 // @Produce json
 // @Success 200 {object} response.Response
@@ -38,47 +35,44 @@ func (h *HealthHandler) Health(c *gin.Context) {
 
 // Ready godoc
 // @Summary Readiness check
-// @Description Check if the service is ready to accept requests
+// @Description Check if the service is ready to accept requests, with per-dependency details
 // @Tags health
 // @Produce json
-// @Success 200 {object} response.Response
-// @Failure 503 {object} response.Response
+// @Success 200 {object} response.Response{data=health.Report}
+// @Failure 503 {object} response.Response{data=health.Report}
 // @Router /ready [get]
 func (h *HealthHandler) Ready(c *gin.Context) {
-	checks := map[string]bool{
-		"database": h.checkDatabase(),
-		"redis":    h.checkRedis(),
+	if !h.registry.Started() {
+		response.ErrorWithData(c, http.StatusServiceUnavailable, "Service is starting", gin.H{"status": "starting"})
+		return
 	}
-
-	allHealthy := true
-	for _, healthy := range checks {
-		if !healthy {
-			allHealthy = false
-			break
-		}
+	if h.registry.Draining() {
+		response.ErrorWithData(c, http.StatusServiceUnavailable, "Service is shutting down", gin.H{"status": "draining"})
+		return
 	}
 
-	if !allHealthy {
-		response.Error(c, http.StatusServiceUnavailable, "Service not ready", nil)
+	report := h.registry.Run(c.Request.Context())
+	if report.Status == health.StatusDown {
+		response.ErrorWithData(c, http.StatusServiceUnavailable, "Service not ready", report)
 		return
 	}
 
-	response.Success(c, gin.H{
-		"status": "ready",
-		"checks": checks,
-	})
+	response.Success(c, report)
 }
 
-func (h *HealthHandler) checkDatabase() bool {
-	if h.db == nil {
-		return false
+// Startup godoc
+// @Summary Startup check
+// @Description Check if startup work such as database migrations has finished
+// @Tags health
+// @Produce json
+// @Success 200 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /startup [get]
+func (h *HealthHandler) Startup(c *gin.Context) {
+	if !h.registry.Started() {
+		response.ErrorWithData(c, http.StatusServiceUnavailable, "Service is starting", gin.H{"status": "starting"})
+		return
 	}
-	return h.db.Ping() == nil
-}
 
-func (h *HealthHandler) checkRedis() bool {
-	if h.redis == nil {
-		return false
-	}
-	return h.redis.Ping() == nil
+	response.Success(c, gin.H{"status": "started"})
 }
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"suitemedia/pkg/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHealthHandlerProbes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := health.NewRegistry(0)
+	registry.Register("database", health.CheckerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}), health.Options{Critical: true})
+
+	handler := NewHealthHandler(registry)
+	router := gin.New()
+	router.GET("/ready", handler.Ready)
+	router.GET("/startup", handler.Startup)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	if w := get("/startup"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected startup to fail before MarkStarted, got %d", w.Code)
+	}
+
+	registry.MarkStarted()
+	if w := get("/startup"); w.Code != http.StatusOK {
+		t.Errorf("Expected startup to succeed, got %d", w.Code)
+	}
+
+	w := get("/ready")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected readiness to fail, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "connection refused") {
+		t.Errorf("Expected failing check details in body, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/service"
+	"suitemedia/pkg/pagination"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InventoryHandler struct {
+	inventoryService service.InventoryService
+	cursors          *pagination.Signer
+}
+
+func NewInventoryHandler(inventoryService service.InventoryService, cursors *pagination.Signer) *InventoryHandler {
+	return &InventoryHandler{
+		inventoryService: inventoryService,
+		cursors:          cursors,
+	}
+}
+
+// inventoryError writes the response for an error returned by the
+// inventory service.
+func inventoryError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrProductNotFound:
+		response.Error(c, http.StatusNotFound, "Product not found", err)
+	case service.ErrVariantNotFound:
+		response.Error(c, http.StatusNotFound, "Variant not found", err)
+	case service.ErrReservationNotFound:
+		response.Error(c, http.StatusNotFound, "Reservation not found", err)
+	case service.ErrWarehouseNotFound:
+		response.Error(c, http.StatusNotFound, "Warehouse not found", err)
+	case service.ErrWarehouseInactive:
+		response.Error(c, http.StatusConflict, "Warehouse is not active", err)
+	case service.ErrUnknownStrategy:
+		response.Error(c, http.StatusBadRequest, "Unknown allocation strategy", err)
+	case service.ErrInsufficientStock:
+		response.Error(c, http.StatusConflict, "Insufficient stock", err)
+	case service.ErrVariantRequired:
+		response.Error(c, http.StatusBadRequest, "Variant is required", err)
+	case service.ErrInvalidQuantity:
+		response.Error(c, http.StatusBadRequest, "Invalid quantity", err)
+	case service.ErrInvalidCursor:
+		response.Error(c, http.StatusBadRequest, "Invalid cursor", err)
+	default:
+		response.Error(c, http.StatusInternalServerError, message, err)
+	}
+}
+
+// Levels returns the stock on hand, reserved and available of the product
+// or of each of its variants.
+func (h *InventoryHandler) Levels(c *gin.Context) {
+	levels, err := h.inventoryService.Levels(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		inventoryError(c, err, "Failed to fetch stock levels")
+		return
+	}
+
+	response.Success(c, levels)
+}
+
+// Movements lists the inventory ledger of the product.
+func (h *InventoryHandler) Movements(c *gin.Context) {
+	params, ok := bindListParams(c, h.cursors, models.MovementListFields)
+	if !ok {
+		return
+	}
+
+	result, err := h.inventoryService.Movements(c.Request.Context(), c.Param("id"), params)
+	if err != nil {
+		inventoryError(c, err, "Failed to fetch inventory movements")
+		return
+	}
+
+	response.SuccessList(c, result.Items, listMeta(params, result, h.cursors))
+}
+
+func (h *InventoryHandler) Record(c *gin.Context) {
+	var req models.RecordMovementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	movement, err := h.inventoryService.Record(c.Request.Context(), c.Param("id"), c.GetString("userID"), req)
+	if err != nil {
+		inventoryError(c, err, "Failed to record inventory movement")
+		return
+	}
+
+	response.Success(c, movement, http.StatusCreated)
+}
+
+// Transfer moves stock between warehouses.
+func (h *InventoryHandler) Transfer(c *gin.Context) {
+	var req models.TransferStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	movements, err := h.inventoryService.Transfer(c.Request.Context(), c.Param("id"), c.GetString("userID"), req)
+	if err != nil {
+		inventoryError(c, err, "Failed to transfer stock")
+		return
+	}
+
+	response.Success(c, movements, http.StatusCreated)
+}
+
+func (h *InventoryHandler) Reserve(c *gin.Context) {
+	var req models.ReserveStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	reservation, err := h.inventoryService.Reserve(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		inventoryError(c, err, "Failed to reserve stock")
+		return
+	}
+
+	response.Success(c, reservation, http.StatusCreated)
+}
+
+func (h *InventoryHandler) Release(c *gin.Context) {
+	err := h.inventoryService.Release(c.Request.Context(), c.Param("id"), c.Param("reservationId"))
+	if err != nil {
+		inventoryError(c, err, "Failed to release reservation")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Reservation released successfully"})
+}
+
+// Commit records the reservation as a sale. The body is optional.
+func (h *InventoryHandler) Commit(c *gin.Context) {
+	var req models.CommitReservationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+
+	movement, err := h.inventoryService.Commit(c.Request.Context(), c.Param("id"), c.Param("reservationId"), c.GetString("userID"), req)
+	if err != nil {
+		inventoryError(c, err, "Failed to commit reservation")
+		return
+	}
+
+	response.Success(c, movement, http.StatusCreated)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/pagination"
+	"suitemedia/pkg/query"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultListLimit = 10
+	maxListLimit     = 100
+)
+
+// bindListParams binds list query parameters and writes a 400 response when
+// they are invalid. Filters and sort fields are checked against fields. A
+// cursor parameter, even an empty one for the first page, selects cursor
+// pagination; otherwise pages are selected by number.
+func bindListParams(c *gin.Context, cursors *pagination.Signer, fields query.Schema) (models.ListParams, bool) {
+	var params models.ListParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return params, false
+	}
+
+	filters, sorts, err := fields.Parse(c.Request.URL.Query(), params.Sort)
+	if err != nil {
+		var queryErr *query.Error
+		if errors.As(err, &queryErr) {
+			response.ErrorWithData(c, http.StatusBadRequest, "Invalid query parameters", queryErr.Problems)
+			return params, false
+		}
+		response.Error(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return params, false
+	}
+	// order=asc|desc predates the "-field" syntax and still applies to a
+	// single sort field given without a direction.
+	if len(sorts) == 1 && params.Order != "" && !strings.HasPrefix(params.Sort, "-") {
+		sorts[0].Desc = params.Order == "desc"
+	}
+	params.Filters, params.Sorts = filters, sorts
+
+	if params.Limit < 1 || params.Limit > maxListLimit {
+		params.Limit = defaultListLimit
+	}
+
+	if _, ok := c.GetQuery("cursor"); !ok {
+		if params.Page < 1 {
+			params.Page = 1
+		}
+		return params, true
+	}
+
+	params.Page = 0
+	if params.Cursor != "" {
+		after, err := cursors.Decode(params.Cursor)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid cursor", err)
+			return params, false
+		}
+		params.After = after
+	}
+	return params, true
+}
+
+// listMeta builds the response metadata for a page of result.
+func listMeta[T any](params models.ListParams, result *models.ListResult[T], cursors *pagination.Signer) response.Meta {
+	meta := response.NewMeta(params.Page, params.Limit, result.Total)
+	meta.TotalEstimated = result.Estimated
+	meta.NextCursor = cursors.Encode(result.Next)
+	meta.PrevCursor = cursors.Encode(result.Prev)
+	return meta
+}
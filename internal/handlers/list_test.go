@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBindListParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cursors := pagination.NewSigner("secret")
+
+	bind := func(query string) (*httptest.ResponseRecorder, bool, int, *pagination.Cursor) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+		params, ok := bindListParams(c, cursors, models.ProductListFields)
+		return w, ok, params.Page, params.After
+	}
+
+	if _, ok, page, _ := bind("limit=5"); !ok || page != 1 {
+		t.Errorf("Expected page mode with page 1, got page %d", page)
+	}
+	if _, ok, page, after := bind("cursor=&page=3"); !ok || page != 0 || after != nil {
+		t.Errorf("Expected first page in cursor mode, got page %d after %v", page, after)
+	}
+
+	token := cursors.Encode(pagination.NewCursor("-created_at,-id", false, "2024-01-01T00:00:00Z", "id"))
+	if _, ok, _, after := bind("cursor=" + token); !ok || after == nil || after.Values[1] != "id" {
+		t.Errorf("Expected decoded cursor, got %+v", after)
+	}
+
+	forged := pagination.NewSigner("other").Encode(pagination.NewCursor("-created_at,-id", false, "x", "y"))
+	if w, ok, _, _ := bind("cursor=" + forged); ok || w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a forged cursor, got %d", w.Code)
+	}
+
+	if w, ok, _, _ := bind("filter[password][eq]=x&sort=-price"); ok || w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown filter field, got %d", w.Code)
+	}
+}
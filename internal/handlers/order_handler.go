@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/service"
+	"suitemedia/pkg/pagination"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrderHandler struct {
+	orderService service.OrderService
+	cursors      *pagination.Signer
+}
+
+func NewOrderHandler(orderService service.OrderService, cursors *pagination.Signer) *OrderHandler {
+	return &OrderHandler{
+		orderService: orderService,
+		cursors:      cursors,
+	}
+}
+
+// orderError writes the response for an error returned by the order
+// service.
+func orderError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrOrderNotFound:
+		response.Error(c, http.StatusNotFound, "Order not found", err)
+	case service.ErrVersionConflict:
+		response.Error(c, http.StatusPreconditionFailed, "Order has been modified", err)
+	case service.ErrInvalidTransition:
+		response.Error(c, http.StatusConflict, "Order cannot move to this status", err)
+	case service.ErrCartEmpty:
+		response.Error(c, http.StatusBadRequest, "Cart is empty", err)
+	case service.ErrCartChanged:
+		response.Error(c, http.StatusConflict, "Cart changed; review it before checking out", err)
+	case service.ErrInsufficientStock:
+		response.Error(c, http.StatusConflict, "Insufficient stock", err)
+	case service.ErrPromotionLimitReached:
+		response.Error(c, http.StatusConflict, "Promotion has reached its usage limit", err)
+	case service.ErrInvalidCursor:
+		response.Error(c, http.StatusBadRequest, "Invalid cursor", err)
+	default:
+		response.Error(c, http.StatusInternalServerError, message, err)
+	}
+}
+
+// Checkout turns the cart of the user into a pending order.
+func (h *OrderHandler) Checkout(c *gin.Context) {
+	var req models.CheckoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+
+	order, err := h.orderService.Checkout(c.Request.Context(), c.GetString("userID"), req)
+	if err != nil {
+		orderError(c, err, "Failed to check out")
+		return
+	}
+
+	response.Success(c, order, http.StatusCreated)
+}
+
+// List lists every order for admins, filtered by status, user and dates.
+func (h *OrderHandler) List(c *gin.Context) {
+	params, ok := bindListParams(c, h.cursors, models.OrderListFields)
+	if !ok {
+		return
+	}
+
+	result, err := h.orderService.List(c.Request.Context(), params)
+	if err != nil {
+		orderError(c, err, "Failed to fetch orders")
+		return
+	}
+
+	response.SuccessList(c, result.Items, listMeta(params, result, h.cursors))
+}
+
+func (h *OrderHandler) GetByID(c *gin.Context) {
+	order, err := h.orderService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		orderError(c, err, "Failed to fetch order")
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// Transition moves an order to the requested status.
+func (h *OrderHandler) Transition(c *gin.Context) {
+	var req models.OrderTransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	order, err := h.orderService.Transition(c.Request.Context(), c.Param("id"), c.GetInt("ifMatchVersion"), c.GetString("userID"), req)
+	if err != nil {
+		orderError(c, err, "Failed to update order status")
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// ListMine lists the orders of the current user.
+func (h *OrderHandler) ListMine(c *gin.Context) {
+	params, ok := bindListParams(c, h.cursors, models.OrderListFields)
+	if !ok {
+		return
+	}
+
+	result, err := h.orderService.ListForUser(c.Request.Context(), c.GetString("userID"), params)
+	if err != nil {
+		orderError(c, err, "Failed to fetch orders")
+		return
+	}
+
+	response.SuccessList(c, result.Items, listMeta(params, result, h.cursors))
+}
+
+func (h *OrderHandler) GetMine(c *gin.Context) {
+	order, err := h.orderService.GetForUser(c.Request.Context(), c.GetString("userID"), c.Param("orderId"))
+	if err != nil {
+		orderError(c, err, "Failed to fetch order")
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// CancelMine cancels a pending order of the current user.
+func (h *OrderHandler) CancelMine(c *gin.Context) {
+	order, err := h.orderService.Cancel(c.Request.Context(), c.GetString("userID"), c.Param("orderId"))
+	if err != nil {
+		orderError(c, err, "Failed to cancel order")
+		return
+	}
+
+	response.Success(c, order)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"suitemedia/pkg/jsonpatch"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+var acceptPatch = jsonpatch.MergePatchContentType + ", " + jsonpatch.JSONPatchContentType
+
+var (
+	errUnsupportedPatch = errors.New("unsupported patch media type")
+	errInvalidPatched   = errors.New("patched resource is invalid")
+)
+
+// patchResource applies the PATCH request body to current and decodes the
+// result into dst, which is then validated like a PUT request body. Fields
+// named in nullable may be removed or set to null, which clears them;
+// every other field must remain present.
+func patchResource(c *gin.Context, current, dst interface{}, nullable ...string) error {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch mediaType {
+	case jsonpatch.MergePatchContentType:
+		apply = jsonpatch.MergePatch
+	case jsonpatch.JSONPatchContentType:
+		apply = jsonpatch.Apply
+	default:
+		return errUnsupportedPatch
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	patched, err := apply(doc, patch)
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &fields); err != nil {
+		return fmt.Errorf("%w: must be an object", errInvalidPatched)
+	}
+	var original map[string]json.RawMessage
+	json.Unmarshal(doc, &original)
+
+	for name := range original {
+		value, ok := fields[name]
+		if ok && string(value) != "null" {
+			continue
+		}
+		if !contains(nullable, name) {
+			return fmt.Errorf("%w: %s cannot be removed or null", errInvalidPatched, name)
+		}
+		fields[name] = json.RawMessage(`""`)
+	}
+
+	normalized, _ := json.Marshal(fields)
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidPatched, err)
+	}
+	if err := binding.Validator.ValidateStruct(dst); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidPatched, err)
+	}
+
+	return nil
+}
+
+// patchError writes the response for an error returned by patchResource.
+func patchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errUnsupportedPatch):
+		c.Header("Accept-Patch", acceptPatch)
+		response.Error(c, http.StatusUnsupportedMediaType, "Content-Type must be "+acceptPatch, nil)
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		response.Error(c, http.StatusConflict, "Patch test operation failed", err)
+	case errors.Is(err, jsonpatch.ErrInvalidPatch), errors.Is(err, jsonpatch.ErrPathNotFound):
+		response.Error(c, http.StatusBadRequest, "Invalid patch document", err)
+	case errors.Is(err, errInvalidPatched):
+		response.Error(c, http.StatusUnprocessableEntity, "Patched resource is invalid", err)
+	default:
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
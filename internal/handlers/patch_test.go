@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/jsonpatch"
+	"suitemedia/pkg/money"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newPatchContext(contentType, body string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("PATCH", "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", contentType)
+	return c
+}
+
+func TestPatchResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	product := &models.Product{Name: "Widget", Description: "A widget", Price: money.New(950, "USD"), Stock: 3, Category: "tools", ImageURL: "https://cdn.suitemedia.test/widget.png", IsActive: true}
+
+	var req models.UpdateProductRequest
+	c := newPatchContext("application/merge-patch+json", `{"price":{"amount":1200},"image_url":null}`)
+	if err := patchResource(c, product.ToUpdateRequest(), &req, "image_url"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if *req.Price != money.New(1200, "USD") || *req.ImageURL != "" || *req.Name != "Widget" {
+		t.Errorf("Expected price 12.00 USD, cleared image and unchanged name, got %+v", req)
+	}
+
+	req = models.UpdateProductRequest{}
+	c = newPatchContext("application/json-patch+json", `[{"op":"test","path":"/stock","value":3},{"op":"replace","path":"/stock","value":2}]`)
+	if err := patchResource(c, product.ToUpdateRequest(), &req, "image_url"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if *req.Stock != 2 {
+		t.Errorf("Expected stock 2, got %d", *req.Stock)
+	}
+}
+
+func TestPatchResourceErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	product := &models.Product{Name: "Widget", Description: "A widget", Price: money.New(950, "USD"), Stock: 3, Category: "tools", IsActive: true}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        error
+	}{
+		{"plain json", "application/json", `{"price":12}`, errUnsupportedPatch},
+		{"null required field", "application/merge-patch+json", `{"name":null}`, errInvalidPatched},
+		{"unknown field", "application/merge-patch+json", `{"color":"red"}`, errInvalidPatched},
+		{"validation", "application/merge-patch+json", `{"stock":-1}`, errInvalidPatched},
+		{"unknown currency", "application/merge-patch+json", `{"price":{"currency":"ABC"}}`, errInvalidPatched},
+		{"failed test", "application/json-patch+json", `[{"op":"test","path":"/stock","value":5}]`, jsonpatch.ErrTestFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req models.UpdateProductRequest
+			err := patchResource(newPatchContext(tt.contentType, tt.body), product.ToUpdateRequest(), &req, "image_url")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/service"
+	"suitemedia/pkg/payment"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBytes limits the size of webhook requests.
+const maxWebhookBytes = 1 << 20
+
+type PaymentHandler struct {
+	paymentService service.PaymentService
+	fake           *payment.FakeProvider
+}
+
+// NewPaymentHandler creates a payment handler. fake is the fake provider
+// whose simulator it serves, or nil.
+func NewPaymentHandler(paymentService service.PaymentService, fake *payment.FakeProvider) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+		fake:           fake,
+	}
+}
+
+// paymentError writes the response for an error returned by the payment
+// service.
+func paymentError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrOrderNotPayable:
+		response.Error(c, http.StatusConflict, "Order is not awaiting payment", err)
+	case service.ErrOrderNotPaid:
+		response.Error(c, http.StatusConflict, "Order has not been paid", err)
+	case service.ErrInvalidWebhook:
+		response.Error(c, http.StatusBadRequest, "Invalid webhook", err)
+	case service.ErrUnknownProvider:
+		response.Error(c, http.StatusNotFound, "Unknown payment provider", err)
+	default:
+		orderError(c, err, message)
+	}
+}
+
+// Pay starts paying for an order of the current user.
+func (h *PaymentHandler) Pay(c *gin.Context) {
+	p, err := h.paymentService.Pay(c.Request.Context(), c.GetString("userID"), c.Param("orderId"))
+	if err != nil {
+		paymentError(c, err, "Failed to start payment")
+		return
+	}
+
+	response.Success(c, p, http.StatusCreated)
+}
+
+// List lists the payments of an order.
+func (h *PaymentHandler) List(c *gin.Context) {
+	payments, err := h.paymentService.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		paymentError(c, err, "Failed to fetch payments")
+		return
+	}
+
+	response.Success(c, payments)
+}
+
+// Refund refunds a paid order.
+func (h *PaymentHandler) Refund(c *gin.Context) {
+	var req models.RefundOrderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+
+	order, err := h.paymentService.Refund(c.Request.Context(), c.Param("id"), c.GetString("userID"), req)
+	if err != nil {
+		paymentError(c, err, "Failed to refund order")
+		return
+	}
+
+	response.Success(c, order)
+}
+
+// Webhook receives the webhooks of a payment provider. The signature is
+// checked against the raw body, so it is read before any decoding.
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBytes))
+	if err != nil {
+		response.Error(c, http.StatusRequestEntityTooLarge, "Webhook too large", err)
+		return
+	}
+
+	if err := h.paymentService.HandleWebhook(c.Request.Context(), c.Param("provider"), payload, c.Request.Header); err != nil {
+		paymentError(c, err, "Failed to handle webhook")
+		return
+	}
+
+	response.Success(c, gin.H{"received": true})
+}
+
+// Simulate makes the fake provider act out an event on an intent and
+// handles the webhook it sends, returning the event.
+func (h *PaymentHandler) Simulate(c *gin.Context) {
+	var req models.SimulatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	payload, header, err := h.fake.Simulate(req.IntentID, req.Event, req.Reason)
+	switch {
+	case errors.Is(err, payment.ErrIntentNotFound):
+		response.Error(c, http.StatusNotFound, "Payment intent not found", err)
+		return
+	case errors.Is(err, payment.ErrInvalidState):
+		response.Error(c, http.StatusConflict, "Payment intent cannot change this way", err)
+		return
+	case err != nil:
+		response.Error(c, http.StatusInternalServerError, "Failed to simulate payment", err)
+		return
+	}
+
+	if err := h.paymentService.HandleWebhook(c.Request.Context(), payment.Fake, payload, header); err != nil {
+		paymentError(c, err, "Failed to handle webhook")
+		return
+	}
+
+	response.Success(c, json.RawMessage(payload))
+}
+
+// FailNext makes the next call of an operation of the fake provider fail.
+func (h *PaymentHandler) FailNext(c *gin.Context) {
+	var req models.FailPaymentOperationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	message := req.Message
+	if message == "" {
+		message = "simulated provider failure"
+	}
+	h.fake.FailNext(req.Operation, errors.New(message))
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"suitemedia/internal/models"
 	"suitemedia/internal/service"
+	"suitemedia/pkg/pagination"
 	"suitemedia/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -12,35 +17,140 @@ import (
 
 type ProductHandler struct {
 	productService service.ProductService
+	cursors        *pagination.Signer
 }
 
-func NewProductHandler(productService service.ProductService) *ProductHandler {
+func NewProductHandler(productService service.ProductService, cursors *pagination.Signer) *ProductHandler {
 	return &ProductHandler{
 		productService: productService,
+		cursors:        cursors,
 	}
 }
 
 func (h *ProductHandler) List(c *gin.Context) {
-	var params models.ListParams
+	params, ok := bindListParams(c, h.cursors, models.ProductListFields)
+	if !ok {
+		return
+	}
+
+	result, err := h.productService.List(c.Request.Context(), params)
+	if err != nil {
+		if err == service.ErrInvalidCursor {
+			response.Error(c, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch products", err)
+		return
+	}
+	if params.Query != "" && params.Page <= 1 && params.After == nil {
+		h.productService.RecordPopularity(c.Request.Context(), params.Query)
+	}
+
+	products, ok := localize(c, h.productService, result.Items...)
+	if !ok {
+		return
+	}
+
+	response.SuccessList(c, products, listMeta(params, result, h.cursors))
+}
+
+const defaultSuggestLimit = 8
+
+// Suggest returns product names and categories matching a search as it is
+// being typed.
+func (h *ProductHandler) Suggest(c *gin.Context) {
+	var params models.SuggestParams
 	if err := c.ShouldBindQuery(&params); err != nil {
 		response.Error(c, http.StatusBadRequest, "Invalid query parameters", err)
 		return
 	}
+	if params.Limit == 0 {
+		params.Limit = defaultSuggestLimit
+	}
 
-	if params.Page < 1 {
-		params.Page = 1
+	suggestions, err := h.productService.Suggest(c.Request.Context(), params.Q, params.Limit)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch suggestions", err)
+		return
+	}
+
+	response.Success(c, suggestions)
+}
+
+// defaultPriceBuckets are the lower bounds, in minor units of the base
+// currency, of the price facet buckets used unless the request passes
+// price_buckets.
+var defaultPriceBuckets = []int64{0, 2500, 5000, 10000, 25000, 50000, 100000}
+
+const maxPriceBuckets = 20
+
+// Facets returns category, price and availability counts for the products
+// matching the same search and filters as List.
+func (h *ProductHandler) Facets(c *gin.Context) {
+	params, ok := bindListParams(c, h.cursors, models.ProductListFields)
+	if !ok {
+		return
 	}
-	if params.Limit < 1 || params.Limit > 100 {
-		params.Limit = 10
+
+	priceBuckets := defaultPriceBuckets
+	if raw := c.Query("price_buckets"); raw != "" {
+		var err error
+		if priceBuckets, err = parsePriceBuckets(raw); err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid price_buckets", err)
+			return
+		}
 	}
 
-	products, total, err := h.productService.List(c.Request.Context(), params)
+	facets, err := h.productService.Facets(c.Request.Context(), params, priceBuckets)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to fetch products", err)
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch product facets", err)
 		return
 	}
 
-	response.SuccessPaginated(c, products, params.Page, params.Limit, total)
+	response.Success(c, facets)
+}
+
+// parsePriceBuckets parses a comma-separated, ascending list of bucket
+// lower bounds in minor units.
+func parsePriceBuckets(raw string) ([]int64, error) {
+	items := strings.Split(raw, ",")
+	if len(items) > maxPriceBuckets {
+		return nil, fmt.Errorf("at most %d buckets are allowed", maxPriceBuckets)
+	}
+
+	buckets := make([]int64, len(items))
+	for i, item := range items {
+		value, err := strconv.ParseInt(strings.TrimSpace(item), 10, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("%q is not a valid price", item)
+		}
+		if i > 0 && value <= buckets[i-1] {
+			return nil, errors.New("bucket bounds must be in ascending order")
+		}
+		buckets[i] = value
+	}
+	return buckets, nil
+}
+
+// localize prices products in the currency requested with ?currency=, if
+// any. It writes an error response and returns false when the currency is
+// unknown or has no exchange rate.
+func localize(c *gin.Context, productService service.ProductService, products ...*models.Product) ([]*models.Product, bool) {
+	currency := c.Query("currency")
+	if currency == "" {
+		return products, true
+	}
+
+	localized, err := productService.Localize(c.Request.Context(), currency, products)
+	if err != nil {
+		if err == service.ErrUnknownCurrency || err == service.ErrNoExchangeRate {
+			response.Error(c, http.StatusBadRequest, "Invalid currency", err)
+			return nil, false
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to convert prices", err)
+		return nil, false
+	}
+	return localized, true
 }
 
 func (h *ProductHandler) GetByID(c *gin.Context) {
@@ -48,11 +158,22 @@ func (h *ProductHandler) GetByID(c *gin.Context) {
 
 	product, err := h.productService.GetByID(c.Request.Context(), id)
 	if err != nil {
-		response.Error(c, http.StatusNotFound, "Product not found", err)
+		if err == service.ErrProductNotFound {
+			response.Error(c, http.StatusNotFound, "Product not found", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch product", err)
 		return
 	}
 
-	response.Success(c, product)
+	h.productService.RecordPopularity(c.Request.Context(), product.Name)
+
+	products, ok := localize(c, h.productService, product)
+	if !ok {
+		return
+	}
+
+	response.Success(c, products[0])
 }
 
 func (h *ProductHandler) Create(c *gin.Context) {
@@ -62,8 +183,18 @@ func (h *ProductHandler) Create(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productService.Create(c.Request.Context(), req)
+	userID := c.GetString("userID")
+
+	product, err := h.productService.Create(c.Request.Context(), userID, req)
 	if err != nil {
+		if err == service.ErrCategoryNotFound || err == service.ErrInvalidSlug {
+			response.Error(c, http.StatusBadRequest, "Invalid category", err)
+			return
+		}
+		if err == service.ErrPriceCurrency || err == service.ErrNegativePrice || err == service.ErrDuplicatePrice {
+			response.Error(c, http.StatusBadRequest, "Invalid price", err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to create product", err)
 		return
 	}
@@ -80,8 +211,88 @@ func (h *ProductHandler) Update(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productService.Update(c.Request.Context(), id, req)
+	product, err := h.productService.Update(c.Request.Context(), id, c.GetInt("ifMatchVersion"), req)
+	if err != nil {
+		if err == service.ErrProductNotFound {
+			response.Error(c, http.StatusNotFound, "Product not found", err)
+			return
+		}
+		if err == service.ErrVersionConflict {
+			response.Error(c, http.StatusPreconditionFailed, "Product has been modified", err)
+			return
+		}
+		if err == service.ErrCategoryNotFound || err == service.ErrInvalidSlug {
+			response.Error(c, http.StatusBadRequest, "Invalid category", err)
+			return
+		}
+		if err == service.ErrPriceCurrency || err == service.ErrNegativePrice || err == service.ErrDuplicatePrice {
+			response.Error(c, http.StatusBadRequest, "Invalid price", err)
+			return
+		}
+		if err == service.ErrVariantRequired {
+			response.Error(c, http.StatusBadRequest, "Stock is kept per variant", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to update product", err)
+		return
+	}
+
+	response.Success(c, product)
+}
+
+// Patch applies a JSON Merge Patch or JSON Patch to the product. Removing
+// image_url or category_id or setting them to null clears them.
+func (h *ProductHandler) Patch(c *gin.Context) {
+	id := c.Param("id")
+
+	product, err := h.productService.GetLatest(c.Request.Context(), id)
+	if err != nil {
+		if err == service.ErrProductNotFound {
+			response.Error(c, http.StatusNotFound, "Product not found", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch product", err)
+		return
+	}
+
+	// The patch is computed against this version, so it must still be
+	// current when the update is written.
+	version := c.GetInt("ifMatchVersion")
+	if version == 0 {
+		version = product.Version
+	} else if version != product.Version {
+		response.Error(c, http.StatusPreconditionFailed, "Product has been modified", service.ErrVersionConflict)
+		return
+	}
+
+	var req models.UpdateProductRequest
+	if err := patchResource(c, product.ToUpdateRequest(), &req, "image_url", "category_id"); err != nil {
+		patchError(c, err)
+		return
+	}
+
+	product, err = h.productService.Update(c.Request.Context(), id, version, req)
 	if err != nil {
+		if err == service.ErrProductNotFound {
+			response.Error(c, http.StatusNotFound, "Product not found", err)
+			return
+		}
+		if err == service.ErrVersionConflict {
+			response.Error(c, http.StatusPreconditionFailed, "Product has been modified", err)
+			return
+		}
+		if err == service.ErrCategoryNotFound || err == service.ErrInvalidSlug {
+			response.Error(c, http.StatusBadRequest, "Invalid category", err)
+			return
+		}
+		if err == service.ErrPriceCurrency || err == service.ErrNegativePrice || err == service.ErrDuplicatePrice {
+			response.Error(c, http.StatusBadRequest, "Invalid price", err)
+			return
+		}
+		if err == service.ErrVariantRequired {
+			response.Error(c, http.StatusBadRequest, "Stock is kept per variant", err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to update product", err)
 		return
 	}
@@ -92,8 +303,16 @@ func (h *ProductHandler) Update(c *gin.Context) {
 func (h *ProductHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	err := h.productService.Delete(c.Request.Context(), id)
+	err := h.productService.Delete(c.Request.Context(), id, c.GetInt("ifMatchVersion"))
 	if err != nil {
+		if err == service.ErrProductNotFound {
+			response.Error(c, http.StatusNotFound, "Product not found", err)
+			return
+		}
+		if err == service.ErrVersionConflict {
+			response.Error(c, http.StatusPreconditionFailed, "Product has been modified", err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to delete product", err)
 		return
 	}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePriceBuckets(t *testing.T) {
+	buckets, err := parsePriceBuckets("0, 5000,10050")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(buckets, []int64{0, 5000, 10050}) {
+		t.Errorf("Expected [0 5000 10050], got %v", buckets)
+	}
+
+	for _, raw := range []string{"0,cheap", "-5,10", "50,10", "10,10", "0,10.5"} {
+		if _, err := parsePriceBuckets(raw); err == nil {
+			t.Errorf("Expected error for %q", raw)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/service"
+	"suitemedia/pkg/pagination"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionHandler struct {
+	promotionService service.PromotionService
+	cursors          *pagination.Signer
+}
+
+func NewPromotionHandler(promotionService service.PromotionService, cursors *pagination.Signer) *PromotionHandler {
+	return &PromotionHandler{
+		promotionService: promotionService,
+		cursors:          cursors,
+	}
+}
+
+// promotionError writes the response for an error returned by the
+// promotion service.
+func promotionError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrPromotionNotFound:
+		response.Error(c, http.StatusNotFound, "Promotion not found", err)
+	case service.ErrVersionConflict:
+		response.Error(c, http.StatusPreconditionFailed, "Promotion has been modified", err)
+	case service.ErrPromotionCodeExists:
+		response.Error(c, http.StatusConflict, "Promotion code already exists", err)
+	case service.ErrPromotionWindow, service.ErrPromotionAmount:
+		response.Error(c, http.StatusBadRequest, err.Error(), err)
+	case service.ErrInvalidCursor:
+		response.Error(c, http.StatusBadRequest, "Invalid cursor", err)
+	default:
+		response.Error(c, http.StatusInternalServerError, message, err)
+	}
+}
+
+func (h *PromotionHandler) List(c *gin.Context) {
+	params, ok := bindListParams(c, h.cursors, models.PromotionListFields)
+	if !ok {
+		return
+	}
+
+	result, err := h.promotionService.List(c.Request.Context(), params)
+	if err != nil {
+		promotionError(c, err, "Failed to fetch promotions")
+		return
+	}
+
+	response.SuccessList(c, result.Items, listMeta(params, result, h.cursors))
+}
+
+func (h *PromotionHandler) GetByID(c *gin.Context) {
+	p, err := h.promotionService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		promotionError(c, err, "Failed to fetch promotion")
+		return
+	}
+
+	response.Success(c, p)
+}
+
+func (h *PromotionHandler) Create(c *gin.Context) {
+	var req models.CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	p, err := h.promotionService.Create(c.Request.Context(), req)
+	if err != nil {
+		promotionError(c, err, "Failed to create promotion")
+		return
+	}
+
+	response.Success(c, p, http.StatusCreated)
+}
+
+func (h *PromotionHandler) Update(c *gin.Context) {
+	var req models.UpdatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	p, err := h.promotionService.Update(c.Request.Context(), c.Param("id"), c.GetInt("ifMatchVersion"), req)
+	if err != nil {
+		promotionError(c, err, "Failed to update promotion")
+		return
+	}
+
+	response.Success(c, p)
+}
+
+// Delete removes a promotion. Orders that redeemed it keep their
+// discounts.
+func (h *PromotionHandler) Delete(c *gin.Context) {
+	err := h.promotionService.Delete(c.Request.Context(), c.Param("id"), c.GetInt("ifMatchVersion"))
+	if err != nil {
+		promotionError(c, err, "Failed to delete promotion")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Promotion deleted successfully"})
+}
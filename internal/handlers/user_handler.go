@@ -5,6 +5,7 @@ import (
 
 	"suitemedia/internal/models"
 	"suitemedia/internal/service"
+	"suitemedia/pkg/pagination"
 	"suitemedia/pkg/response"
 
 	"github.com/gin-gonic/gin"
@@ -12,50 +13,52 @@ import (
 
 type UserHandler struct {
 	userService service.UserService
+	cursors     *pagination.Signer
 }
 
-func NewUserHandler(userService service.UserService) *UserHandler {
+func NewUserHandler(userService service.UserService, cursors *pagination.Signer) *UserHandler {
 	return &UserHandler{
 		userService: userService,
+		cursors:     cursors,
 	}
 }
 
 // List godoc
 // @Summary List users
-// @Description Get list of users with pagination
+// @Description Get list of users, paginated by page number or by cursor
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Param search query string false "Search term"
+// @Param cursor query string false "Cursor from next_cursor or prev_cursor; empty for the first page"
+// @Param count query string false "Total count mode" Enums(exact, estimate, none)
+// @Param filter[field][op] query string false "Filter, e.g. filter[role][in]=admin,user or filter[created_at][gte]=2024-01-01"
+// @Param sort query string false "Comma-separated sort fields, '-' for descending, e.g. -created_at,email"
 // @Security BearerAuth
 // @Success 200 {object} response.Response{data=response.PaginatedData}
+// @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /api/v1/users [get]
 func (h *UserHandler) List(c *gin.Context) {
-	var params models.ListParams
-	if err := c.ShouldBindQuery(&params); err != nil {
-		response.Error(c, http.StatusBadRequest, "Invalid query parameters", err)
+	params, ok := bindListParams(c, h.cursors, models.UserListFields)
+	if !ok {
 		return
 	}
 
-	// Set defaults
-	if params.Page < 1 {
-		params.Page = 1
-	}
-	if params.Limit < 1 || params.Limit > 100 {
-		params.Limit = 10
-	}
-
-	users, total, err := h.userService.List(c.Request.Context(), params)
+	result, err := h.userService.List(c.Request.Context(), params)
 	if err != nil {
+		if err == service.ErrInvalidCursor {
+			response.Error(c, http.StatusBadRequest, "Invalid cursor", err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to fetch users", err)
 		return
 	}
 
-	response.SuccessPaginated(c, users, params.Page, params.Limit, total)
+	response.SuccessList(c, result.Items, listMeta(params, result, h.cursors))
 }
 
 // GetByID godoc
@@ -65,8 +68,10 @@ func (h *UserHandler) List(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Security BearerAuth
 // @Success 200 {object} response.Response{data=models.UserResponse}
+// @Success 304
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
@@ -128,12 +133,15 @@ func (h *UserHandler) Create(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Param user body models.UpdateUserRequest true "User data"
 // @Security BearerAuth
 // @Success 200 {object} response.Response{data=models.UserResponse}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
+// @Failure 412 {object} response.Response
+// @Failure 428 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /api/v1/users/{id} [put]
 func (h *UserHandler) Update(c *gin.Context) {
@@ -145,12 +153,82 @@ func (h *UserHandler) Update(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.Update(c.Request.Context(), id, req)
+	user, err := h.userService.Update(c.Request.Context(), id, c.GetInt("ifMatchVersion"), req)
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			response.Error(c, http.StatusNotFound, "User not found", err)
+			return
+		}
+		if err == service.ErrVersionConflict {
+			response.Error(c, http.StatusPreconditionFailed, "User has been modified", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to update user", err)
+		return
+	}
+
+	response.Success(c, user)
+}
+
+// Patch godoc
+// @Summary Patch user
+// @Description Partially update a user with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// @Tags users
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param If-Match header string false "ETag of the version being updated"
+// @Param patch body object true "Patch document"
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=models.UserResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 412 {object} response.Response
+// @Failure 415 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /api/v1/users/{id} [patch]
+func (h *UserHandler) Patch(c *gin.Context) {
+	id := c.Param("id")
+
+	user, err := h.userService.GetLatest(c.Request.Context(), id)
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			response.Error(c, http.StatusNotFound, "User not found", err)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch user", err)
+		return
+	}
+
+	// The patch is computed against this version, so it must still be
+	// current when the update is written.
+	version := c.GetInt("ifMatchVersion")
+	if version == 0 {
+		version = user.Version
+	} else if version != user.Version {
+		response.Error(c, http.StatusPreconditionFailed, "User has been modified", service.ErrVersionConflict)
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := patchResource(c, user.ToUpdateRequest(), &req); err != nil {
+		patchError(c, err)
+		return
+	}
+
+	user, err = h.userService.Update(c.Request.Context(), id, version, req)
 	if err != nil {
 		if err == service.ErrUserNotFound {
 			response.Error(c, http.StatusNotFound, "User not found", err)
 			return
 		}
+		if err == service.ErrVersionConflict {
+			response.Error(c, http.StatusPreconditionFailed, "User has been modified", err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to update user", err)
 		return
 	}
@@ -165,21 +243,28 @@ func (h *UserHandler) Update(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Security BearerAuth
 // @Success 200 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
+// @Failure 412 {object} response.Response
+// @Failure 428 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /api/v1/users/{id} [delete]
 func (h *UserHandler) Delete(c *gin.Context) {
 	id := c.Param("id")
 
-	err := h.userService.Delete(c.Request.Context(), id)
+	err := h.userService.Delete(c.Request.Context(), id, c.GetInt("ifMatchVersion"))
 	if err != nil {
 		if err == service.ErrUserNotFound {
 			response.Error(c, http.StatusNotFound, "User not found", err)
 			return
 		}
+		if err == service.ErrVersionConflict {
+			response.Error(c, http.StatusPreconditionFailed, "User has been modified", err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to delete user", err)
 		return
 	}
@@ -216,24 +301,32 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param user body models.UpdateUserRequest true "User data"
+// @Param If-Match header string false "ETag of the version being updated"
+// @Param user body models.UpdateProfileRequest true "Profile data"
 // @Security BearerAuth
 // @Success 200 {object} response.Response{data=models.UserResponse}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
+// @Failure 412 {object} response.Response
+// @Failure 428 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /api/v1/users/me [put]
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID := c.GetString("userID")
 
-	var req models.UpdateUserRequest
+	var req models.UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
 		return
 	}
 
-	user, err := h.userService.Update(c.Request.Context(), userID, req)
+	update := models.UpdateUserRequest{FirstName: req.FirstName, LastName: req.LastName}
+	user, err := h.userService.Update(c.Request.Context(), userID, c.GetInt("ifMatchVersion"), update)
 	if err != nil {
+		if err == service.ErrVersionConflict {
+			response.Error(c, http.StatusPreconditionFailed, "Profile has been modified", err)
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to update profile", err)
 		return
 	}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/service"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VariantHandler struct {
+	variantService service.VariantService
+}
+
+func NewVariantHandler(variantService service.VariantService) *VariantHandler {
+	return &VariantHandler{
+		variantService: variantService,
+	}
+}
+
+// variantError writes the response for an error returned by the variant
+// service.
+func variantError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrProductNotFound:
+		response.Error(c, http.StatusNotFound, "Product not found", err)
+	case service.ErrVariantNotFound:
+		response.Error(c, http.StatusNotFound, "Variant not found", err)
+	case service.ErrVersionConflict:
+		response.Error(c, http.StatusPreconditionFailed, "Variant has been modified", err)
+	case service.ErrVariantExists:
+		response.Error(c, http.StatusConflict, "Variant already exists", err)
+	case service.ErrInvalidVariantOptions, service.ErrInvalidProductOptions, service.ErrTooManyVariants:
+		response.Error(c, http.StatusBadRequest, "Invalid options", err)
+	case service.ErrPriceCurrency, service.ErrNegativePrice:
+		response.Error(c, http.StatusBadRequest, "Invalid price", err)
+	default:
+		response.Error(c, http.StatusInternalServerError, message, err)
+	}
+}
+
+func (h *VariantHandler) List(c *gin.Context) {
+	variants, err := h.variantService.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		variantError(c, err, "Failed to fetch variants")
+		return
+	}
+
+	response.Success(c, variants)
+}
+
+func (h *VariantHandler) GetByID(c *gin.Context) {
+	variant, err := h.variantService.Get(c.Request.Context(), c.Param("id"), c.Param("variantId"))
+	if err != nil {
+		variantError(c, err, "Failed to fetch variant")
+		return
+	}
+
+	response.Success(c, variant)
+}
+
+func (h *VariantHandler) Create(c *gin.Context) {
+	var req models.CreateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	variant, err := h.variantService.Create(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		variantError(c, err, "Failed to create variant")
+		return
+	}
+
+	response.Success(c, variant, http.StatusCreated)
+}
+
+// Generate sets the options of the product and creates a variant for each
+// combination of option values.
+func (h *VariantHandler) Generate(c *gin.Context) {
+	var req models.GenerateVariantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	variants, err := h.variantService.Generate(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		variantError(c, err, "Failed to generate variants")
+		return
+	}
+
+	response.Success(c, variants)
+}
+
+func (h *VariantHandler) Update(c *gin.Context) {
+	var req models.UpdateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	variant, err := h.variantService.Update(c.Request.Context(), c.Param("id"), c.Param("variantId"), c.GetInt("ifMatchVersion"), req)
+	if err != nil {
+		variantError(c, err, "Failed to update variant")
+		return
+	}
+
+	response.Success(c, variant)
+}
+
+func (h *VariantHandler) Delete(c *gin.Context) {
+	err := h.variantService.Delete(c.Request.Context(), c.Param("id"), c.Param("variantId"), c.GetInt("ifMatchVersion"))
+	if err != nil {
+		variantError(c, err, "Failed to delete variant")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Variant deleted successfully"})
+}
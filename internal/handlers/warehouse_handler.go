@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/service"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WarehouseHandler struct {
+	warehouseService service.WarehouseService
+}
+
+func NewWarehouseHandler(warehouseService service.WarehouseService) *WarehouseHandler {
+	return &WarehouseHandler{
+		warehouseService: warehouseService,
+	}
+}
+
+// warehouseError writes the response for an error returned by the
+// warehouse service.
+func warehouseError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrWarehouseNotFound:
+		response.Error(c, http.StatusNotFound, "Warehouse not found", err)
+	case service.ErrVersionConflict:
+		response.Error(c, http.StatusPreconditionFailed, "Warehouse has been modified", err)
+	case service.ErrWarehouseCodeExists:
+		response.Error(c, http.StatusConflict, "Warehouse code already exists", err)
+	case service.ErrWarehouseInUse:
+		response.Error(c, http.StatusConflict, "Warehouse still holds or reserves stock", err)
+	case service.ErrDefaultWarehouse:
+		response.Error(c, http.StatusConflict, err.Error(), err)
+	default:
+		response.Error(c, http.StatusInternalServerError, message, err)
+	}
+}
+
+func (h *WarehouseHandler) List(c *gin.Context) {
+	warehouses, err := h.warehouseService.List(c.Request.Context())
+	if err != nil {
+		warehouseError(c, err, "Failed to fetch warehouses")
+		return
+	}
+
+	response.Success(c, warehouses)
+}
+
+func (h *WarehouseHandler) GetByID(c *gin.Context) {
+	warehouse, err := h.warehouseService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		warehouseError(c, err, "Failed to fetch warehouse")
+		return
+	}
+
+	response.Success(c, warehouse)
+}
+
+func (h *WarehouseHandler) Create(c *gin.Context) {
+	var req models.CreateWarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	warehouse, err := h.warehouseService.Create(c.Request.Context(), req)
+	if err != nil {
+		warehouseError(c, err, "Failed to create warehouse")
+		return
+	}
+
+	response.Success(c, warehouse, http.StatusCreated)
+}
+
+func (h *WarehouseHandler) Update(c *gin.Context) {
+	var req models.UpdateWarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	warehouse, err := h.warehouseService.Update(c.Request.Context(), c.Param("id"), c.GetInt("ifMatchVersion"), req)
+	if err != nil {
+		warehouseError(c, err, "Failed to update warehouse")
+		return
+	}
+
+	response.Success(c, warehouse)
+}
+
+// Delete removes a warehouse that is not the default and holds no stock.
+func (h *WarehouseHandler) Delete(c *gin.Context) {
+	err := h.warehouseService.Delete(c.Request.Context(), c.Param("id"), c.GetInt("ifMatchVersion"))
+	if err != nil {
+		warehouseError(c, err, "Failed to delete warehouse")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Warehouse deleted successfully"})
+}
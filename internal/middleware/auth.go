@@ -27,40 +27,63 @@ func AuthRequired(cfg config.JWTConfig) gin.HandlerFunc {
 			return
 		}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			response.Error(c, 401, "Invalid authorization header format", nil)
+		if !authenticate(c, cfg, authHeader) {
 			c.Abort()
 			return
 		}
 
-		tokenString := parts[1]
-		claims := &Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.Secret), nil
-		})
+		c.Next()
+	}
+}
 
-		if err != nil || !token.Valid {
-			response.Error(c, 401, "Invalid or expired token", err)
+// OptionalAuth sets the user info like AuthRequired when the request has an
+// Authorization header and lets anonymous requests through. An invalid
+// token is still rejected rather than treated as anonymous.
+func OptionalAuth(cfg config.JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" && !authenticate(c, cfg, authHeader) {
 			c.Abort()
 			return
 		}
 
-		// Check token expiration
-		if claims.ExpiresAt != nil && claims.ExpiresAt.Before(time.Now()) {
-			response.Error(c, 401, "Token has expired", nil)
-			c.Abort()
-			return
-		}
+		c.Next()
+	}
+}
 
-		// Set user info in context
-		c.Set("userID", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
+// authenticate validates the bearer token in authHeader and sets the user
+// info in the context, responding with 401 and returning false when the
+// token is not valid.
+func authenticate(c *gin.Context, cfg config.JWTConfig, authHeader string) bool {
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		response.Error(c, 401, "Invalid authorization header format", nil)
+		return false
+	}
 
-		c.Next()
+	tokenString := parts[1]
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(cfg.Secret), nil
+	})
+
+	if err != nil || !token.Valid {
+		response.Error(c, 401, "Invalid or expired token", err)
+		return false
 	}
+
+	// Check token expiration
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Before(time.Now()) {
+		response.Error(c, 401, "Token has expired", nil)
+		return false
+	}
+
+	// Set user info in context
+	c.Set("userID", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+
+	return true
 }
 
 func RoleRequired(roles ...string) gin.HandlerFunc {
@@ -55,3 +55,39 @@ func TestAuthRequired(t *testing.T) {
 		}
 	})
 }
+
+func TestOptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.JWTConfig{
+		Secret: "test-secret-key-for-testing",
+	}
+
+	t.Run("anonymous request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/test", nil)
+
+		OptionalAuth(cfg)(c)
+
+		if c.IsAborted() {
+			t.Errorf("expected anonymous request to pass, got status %d", w.Code)
+		}
+		if userID := c.GetString("userID"); userID != "" {
+			t.Errorf("expected no user, got %q", userID)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/test", nil)
+		c.Request.Header.Set("Authorization", "Bearer invalid.token.here")
+
+		OptionalAuth(cfg)(c)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+}
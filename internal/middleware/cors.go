@@ -1,18 +1,45 @@
 package middleware
 
 import (
+	"sync/atomic"
+	"time"
+
 	"suitemedia/config"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
+// exposedHeaders are response headers browsers may read cross-origin.
+var exposedHeaders = []string{
+	"ETag", "Retry-After", "X-Request-ID", "Idempotent-Replayed", "X-Cart-Token",
+	"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+}
+
 func CORS(cfg config.CORSConfig) gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     cfg.AllowedOrigins,
 		AllowMethods:     cfg.AllowedMethods,
 		AllowHeaders:     cfg.AllowedHeaders,
+		ExposeHeaders:    exposedHeaders,
 		AllowCredentials: cfg.AllowCredentials,
-		MaxAge:           12 * 3600, // 12 hours
+		MaxAge:           time.Duration(cfg.MaxAge) * time.Second,
 	})
 }
+
+// DynamicCORS behaves like CORS but rebuilds its policy whenever the store
+// reloads, so requests only pay for an atomic load.
+func DynamicCORS(store *config.Store) gin.HandlerFunc {
+	var current atomic.Pointer[gin.HandlerFunc]
+
+	apply := func(cfg *config.Config) {
+		handler := CORS(cfg.CORS)
+		current.Store(&handler)
+	}
+	apply(store.Current())
+	store.Subscribe(apply)
+
+	return func(c *gin.Context) {
+		(*current.Load())(c)
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
@@ -30,3 +31,35 @@ func TestCORS(t *testing.T) {
 	// Test passes if no panic occurs
 	t.Log("CORS middleware executed successfully")
 }
+
+func TestDynamicCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.CORS.AllowedOrigins = []string{"http://localhost:3000"}
+	store := config.NewStore(cfg)
+
+	router := gin.New()
+	router.Use(DynamicCORS(store))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(origin string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", origin)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("http://app.suitemedia.test"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected disallowed origin to be rejected, got %d", w.Code)
+	}
+
+	next := *cfg
+	next.CORS.AllowedOrigins = []string{"http://app.suitemedia.test"}
+	store.Update(&next)
+
+	if w := request("http://app.suitemedia.test"); w.Code != http.StatusOK {
+		t.Errorf("Expected reloaded origin to be allowed, got %d", w.Code)
+	}
+}
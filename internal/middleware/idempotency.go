@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"suitemedia/config"
+	"suitemedia/pkg/cache"
+	"suitemedia/pkg/logger"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyStoreTimeout bounds storing the outcome of a request once it
+// has run.
+const idempotencyStoreTimeout = 5 * time.Second
+
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Completed   bool        `json:"completed"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// captureWriter records the response body while writing it to the client.
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes requests carrying an Idempotency-Key header safe to
+// retry. The first request with a key runs normally and its response is
+// stored; retries with the same key and body receive the stored response.
+// Keys are scoped to the authenticated user, or the client IP for anonymous
+// requests. Reusing a key with a different body is rejected with 409 and a
+// retry while the first request is still running with 425. Server errors
+// are not stored so that the request can be retried.
+func Idempotency(store cache.Cache, cfg config.IdempotencyConfig, log *logger.Logger) gin.HandlerFunc {
+	ttl := time.Duration(cfg.TTLHours) * time.Hour
+	lockTimeout := time.Duration(cfg.LockTimeoutSeconds) * time.Second
+
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader("Idempotency-Key")
+		if idempotencyKey == "" {
+			c.Next()
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			response.Error(c, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters", nil)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "Failed to read request body", err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := c.GetString("userID")
+		if scope == "" {
+			scope = "ip:" + c.ClientIP()
+		}
+		key := "idempotency:" + scope + ":" + c.Request.Method + " " + c.FullPath() + ":" + idempotencyKey
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+		ctx := c.Request.Context()
+		pending, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+		acquired, err := store.SetNX(ctx, key, string(pending), lockTimeout)
+		if err != nil {
+			log.Warn("Idempotency store unavailable", "error", err)
+			c.Next()
+			return
+		}
+
+		if !acquired {
+			replayIdempotent(c, store, key, fingerprint)
+			return
+		}
+
+		writer := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// The request context is canceled if the client went away, but the
+		// work is done and must be recorded so that a retry does not run
+		// it again.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+		defer cancel()
+
+		if writer.Status() >= http.StatusInternalServerError {
+			store.Delete(ctx, key)
+			return
+		}
+
+		record, _ := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      writer.Status(),
+			Header:      writer.Header().Clone(),
+			Body:        writer.body.Bytes(),
+		})
+		if err := store.Set(ctx, key, string(record), ttl); err != nil {
+			log.Warn("Failed to store idempotent response", "error", err)
+		}
+	}
+}
+
+// replayIdempotent answers a request whose key is already taken.
+func replayIdempotent(c *gin.Context, store cache.Cache, key, fingerprint string) {
+	defer c.Abort()
+
+	raw, err := store.Get(c.Request.Context(), key)
+	var record idempotencyRecord
+	if err != nil || json.Unmarshal([]byte(raw), &record) != nil {
+		// The first request failed and released the key in the meantime.
+		c.Header("Retry-After", "1")
+		response.Error(c, http.StatusTooEarly, "A request with this Idempotency-Key is being processed", nil)
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		response.Error(c, http.StatusConflict, "Idempotency-Key was already used with a different request", nil)
+		return
+	}
+	if !record.Completed {
+		c.Header("Retry-After", "1")
+		response.Error(c, http.StatusTooEarly, "A request with this Idempotency-Key is being processed", nil)
+		return
+	}
+
+	// Headers set for this request, such as the request ID and CORS
+	// headers, take precedence over the stored ones.
+	header := c.Writer.Header()
+	for name, values := range record.Header {
+		if _, ok := header[name]; !ok {
+			header[name] = values
+		}
+	}
+	header.Set("Idempotent-Replayed", "true")
+
+	c.Status(record.Status)
+	c.Writer.Write(record.Body)
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"suitemedia/config"
+	"suitemedia/pkg/cache"
+	"suitemedia/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	router := gin.New()
+	router.POST("/products", Idempotency(cache.NewMemory(100), config.Default().Idempotency, logger.NewLogger("error")), func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/products/1")
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	request := func(key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/products", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := request("abc", `{"name":"Widget"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", first.Code)
+	}
+
+	retry := request("abc", `{"name":"Widget"}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed response, got %d %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get("Location") != "/products/1" || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected replayed headers, got %v", retry.Header())
+	}
+	if calls != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", calls)
+	}
+
+	if w := request("abc", `{"name":"Gadget"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for reused key with different body, got %d", w.Code)
+	}
+
+	request("", `{"name":"Widget"}`)
+	if calls != 2 {
+		t.Errorf("Expected requests without a key to run, ran %d times", calls)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := cache.NewMemory(100)
+	router := gin.New()
+	router.POST("/products", Idempotency(store, config.Default().Idempotency, logger.NewLogger("error")), func(c *gin.Context) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/products", strings.NewReader("{}"))
+		req.Header.Set("Idempotency-Key", "abc")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusTooEarly || w.Header().Get("Retry-After") == "" {
+			t.Errorf("Expected 425 with Retry-After while in flight, got %d", w.Code)
+		}
+		c.Status(http.StatusInternalServerError)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/products", strings.NewReader("{}"))
+	req.Header.Set("Idempotency-Key", "abc")
+	router.ServeHTTP(w, req)
+
+	if store.Len() != 0 {
+		t.Error("Expected key to be released after a server error")
+	}
+}
+
+// contextCache fails writes made with a canceled context, like a network
+// client would.
+type contextCache struct {
+	*cache.Memory
+}
+
+func (c contextCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Memory.Set(ctx, key, value, expiration)
+}
+
+func TestIdempotencyClientGone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	router := gin.New()
+	router.POST("/orders", Idempotency(contextCache{cache.NewMemory(100)}, config.Default().Idempotency, logger.NewLogger("error")), func(c *gin.Context) {
+		calls++
+		cancel()
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+
+	request := func(ctx context.Context) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/orders", strings.NewReader("{}")).WithContext(ctx)
+		req.Header.Set("Idempotency-Key", "abc")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	request(ctx)
+	if w := request(context.Background()); w.Header().Get("Idempotent-Replayed") != "true" || calls != 1 {
+		t.Errorf("Expected the response to be stored after the client went away, got %d with %d calls", w.Code, calls)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"suitemedia/config"
+	"suitemedia/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IfMatch reads the If-Match header of PUT, PATCH and DELETE requests and
+// stores the expected resource version under "ifMatchVersion" for handlers
+// to pass on to the service. A version of 0 means the request is
+// unconditional, which is the case for "If-Match: *" and, unless
+// app.require_if_match is set, for requests without the header. Tags that
+// cannot match any version fail with 412 right away. It belongs on the
+// routes of versioned resources only, since a client has no ETag to send
+// to others.
+func IfMatch(store *config.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		header := strings.TrimSpace(c.GetHeader("If-Match"))
+		if header == "" {
+			if store.Current().App.RequireIfMatch {
+				response.Error(c, http.StatusPreconditionRequired, "If-Match header is required", nil)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if header != "*" {
+			version, ok := response.ParseETag(header)
+			if !ok || strings.HasPrefix(header, "W/") {
+				// If-Match uses strong comparison, so weak or malformed
+				// tags never match.
+				response.Error(c, http.StatusPreconditionFailed, "Resource has been modified", nil)
+				c.Abort()
+				return
+			}
+			c.Set("ifMatchVersion", version)
+		}
+
+		c.Next()
+	}
+}
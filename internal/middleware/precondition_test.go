@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"suitemedia/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.App.RequireIfMatch = true
+	store := config.NewStore(cfg)
+
+	var version int
+	router := gin.New()
+	router.Use(IfMatch(store))
+	router.PUT("/", func(c *gin.Context) {
+		version = c.GetInt("ifMatchVersion")
+		c.Status(http.StatusOK)
+	})
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(method, ifMatch string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/", nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request("PUT", ""); code != http.StatusPreconditionRequired {
+		t.Errorf("Expected 428 without If-Match, got %d", code)
+	}
+	if code := request("GET", ""); code != http.StatusOK {
+		t.Errorf("Expected GET to be unaffected, got %d", code)
+	}
+	if code := request("PUT", `"4"`); code != http.StatusOK || version != 4 {
+		t.Errorf("Expected version 4 to be passed on, got %d with version %d", code, version)
+	}
+	if code := request("PUT", `W/"4"`); code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for weak tag, got %d", code)
+	}
+	if code := request("PUT", "*"); code != http.StatusOK || version != 0 {
+		t.Errorf("Expected unconditional request for *, got %d with version %d", code, version)
+	}
+}
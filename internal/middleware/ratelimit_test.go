@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"suitemedia/config"
+	"suitemedia/pkg/logger"
+	"suitemedia/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.RateLimit.Policies = map[string]config.RateLimitPolicy{
+		"auth":  {Limit: 2, PeriodSeconds: 60, KeyBy: "ip"},
+		"login": {Limit: 1, PeriodSeconds: 60, KeyBy: "ip"},
+	}
+	cfg.RateLimit.Routes = map[string]string{"POST /login": "login"}
+	store := config.NewStore(cfg)
+
+	router := gin.New()
+	router.Use(RateLimit(ratelimit.NewMemory(), store, "auth", logger.NewLogger("error")))
+	router.GET("/register", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := request("GET", "/register"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("Expected 200 with 1 remaining, got %d with %q", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+	request("GET", "/register")
+	w := request("GET", "/register")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+
+	if w := request("POST", "/login"); w.Code != http.StatusOK {
+		t.Errorf("Expected route policy to have its own budget, got %d", w.Code)
+	}
+	if w := request("POST", "/login"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected route policy limit to apply, got %d", w.Code)
+	}
+
+	next := *cfg
+	next.RateLimit.Enabled = false
+	store.Update(&next)
+	if w := request("GET", "/register"); w.Code != http.StatusOK {
+		t.Errorf("Expected disabled rate limiting to allow requests, got %d", w.Code)
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"suitemedia/pkg/money"
+
+	"github.com/google/uuid"
+)
+
+// Codes of the changes revalidating a cart can make to its lines.
+const (
+	CartIssueUnavailable     = "unavailable"
+	CartIssueOutOfStock      = "out_of_stock"
+	CartIssueQuantityReduced = "quantity_reduced"
+	CartIssuePriceChanged    = "price_changed"
+)
+
+// Cart is the shopping cart of a user, or an anonymous cart identified by
+// a token. Items are revalidated against the current products whenever
+// the cart is read, and Issues lists the changes that made. Pricing
+// applies the PromotionCodes entered, kept in uppercase, and any automatic
+// promotions. Token is only set in the response that created an anonymous
+// cart.
+type Cart struct {
+	ID             uuid.UUID       `json:"id"`
+	UserID         *uuid.UUID      `json:"user_id"`
+	Token          string          `json:"token,omitempty"`
+	Items          []*CartItem     `json:"items"`
+	ItemCount      int             `json:"item_count"`
+	Subtotal       money.Money     `json:"subtotal"`
+	Issues         []CartIssue     `json:"issues"`
+	PromotionCodes []string        `json:"promotion_codes"`
+	Pricing        *PriceBreakdown `json:"pricing,omitempty"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+}
+
+// CartItem is a line of a cart. UnitPrice is the price the shopper was
+// last shown; the other product details are filled in on every read.
+type CartItem struct {
+	ID         uuid.UUID   `json:"id"`
+	ProductID  uuid.UUID   `json:"product_id"`
+	VariantID  *uuid.UUID  `json:"variant_id"`
+	CategoryID *uuid.UUID  `json:"-"`
+	TaxClass   string      `json:"-"`
+	Quantity   int         `json:"quantity"`
+	UnitPrice  money.Money `json:"unit_price"`
+	Total      money.Money `json:"total"`
+	Name       string      `json:"name"`
+	SKU        string      `json:"sku,omitempty"`
+	ImageURL   string      `json:"image_url,omitempty"`
+	Available  int         `json:"available"`
+	AddedAt    time.Time   `json:"added_at"`
+}
+
+// CartIssue reports a change revalidation made to a cart line: the line
+// was removed because the product is unavailable or out of stock, its
+// quantity was reduced to the stock, or its price changed.
+type CartIssue struct {
+	ItemID           uuid.UUID    `json:"item_id"`
+	ProductID        uuid.UUID    `json:"product_id"`
+	VariantID        *uuid.UUID   `json:"variant_id"`
+	Code             string       `json:"code"`
+	PreviousQuantity int          `json:"previous_quantity,omitempty"`
+	PreviousPrice    *money.Money `json:"previous_price,omitempty"`
+}
+
+// AddCartItemRequest adds Quantity of a product, or of one of its
+// variants, to the cart. Adding an item already in the cart increases the
+// quantity of its line.
+type AddCartItemRequest struct {
+	ProductID string `json:"product_id" binding:"required,uuid"`
+	VariantID string `json:"variant_id" binding:"omitempty,uuid"`
+	Quantity  int    `json:"quantity" binding:"required,min=1"`
+}
+
+// UpdateCartItemRequest sets the quantity of a cart line.
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Category is a node in the product category tree. Path is the
+// materialized path of IDs from the root down to the category, such as
+// "/<root id>/<parent id>/<id>/", so a subtree is every category whose path
+// starts with the path of its root.
+type Category struct {
+	ID        uuid.UUID   `json:"id" db:"id"`
+	ParentID  *uuid.UUID  `json:"parent_id" db:"parent_id"`
+	Name      string      `json:"name" db:"name"`
+	Slug      string      `json:"slug" db:"slug"`
+	Path      string      `json:"path" db:"path"`
+	Position  int         `json:"position" db:"position"`
+	Version   int         `json:"version" db:"version"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time  `json:"deleted_at,omitempty" db:"deleted_at"`
+	Children  []*Category `json:"children,omitempty" db:"-"`
+}
+
+// CreateCategoryRequest creates a category. The slug is derived from the
+// name when empty.
+type CreateCategoryRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	Slug     string `json:"slug" binding:"omitempty,max=120"`
+	ParentID string `json:"parent_id" binding:"omitempty,uuid"`
+	Position int    `json:"position"`
+}
+
+// UpdateCategoryRequest changes a category. An empty ParentID moves the
+// category to the top level.
+type UpdateCategoryRequest struct {
+	Name     *string `json:"name" binding:"omitempty,max=100"`
+	Slug     *string `json:"slug" binding:"omitempty,max=120"`
+	ParentID *string `json:"parent_id" binding:"omitempty,uuid|len=0"`
+	Position *int    `json:"position"`
+}
+
+// ResourceVersion is used as the ETag of the category.
+func (c *Category) ResourceVersion() int {
+	return c.Version
+}
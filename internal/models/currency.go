@@ -0,0 +1,20 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ExchangeRates are the amounts of each currency that one unit of the
+// base currency buys. Rates are decimals, written as JSON numbers or
+// strings, and kept exact.
+type ExchangeRates struct {
+	Base      string                 `json:"base"`
+	Rates     map[string]json.Number `json:"rates"`
+	UpdatedAt *time.Time             `json:"updated_at,omitempty"`
+}
+
+// UpdateRatesRequest adds or replaces the rates of the currencies given.
+type UpdateRatesRequest struct {
+	Rates map[string]json.Number `json:"rates" binding:"required,min=1"`
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"time"
+
+	"suitemedia/pkg/query"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of inventory movements. Receipts and returns add stock, sales
+// remove it and adjustments correct it either way. A transfer is recorded
+// as a pair of movements, out of one warehouse and into another.
+const (
+	MovementReceipt    = "receipt"
+	MovementSale       = "sale"
+	MovementAdjustment = "adjustment"
+	MovementReturn     = "return"
+	MovementTransfer   = "transfer"
+)
+
+// StockItem identifies what stock is kept for: a product without variants,
+// or one variant of a product.
+type StockItem struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id"`
+}
+
+// InventoryMovement is an entry of the append-only inventory ledger.
+// Quantity is signed and applies to the stock at WarehouseID; StockAfter
+// is the stock of the item across warehouses once the movement was
+// applied.
+type InventoryMovement struct {
+	ID uuid.UUID `json:"id" db:"id"`
+	StockItem
+	WarehouseID uuid.UUID  `json:"warehouse_id" db:"warehouse_id"`
+	Kind        string     `json:"kind" db:"kind"`
+	Quantity    int        `json:"quantity" db:"quantity"`
+	StockAfter  int        `json:"stock_after" db:"stock_after"`
+	Reference   string     `json:"reference" db:"reference"`
+	Note        string     `json:"note" db:"note"`
+	CreatedBy   *uuid.UUID `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	// ProductVersion is the version of the product after the movement.
+	ProductVersion int `json:"-" db:"-"`
+}
+
+// MovementListFields are the fields inventory movements can be filtered
+// and sorted by.
+var MovementListFields = query.Schema{
+	{Name: "variant_id", Column: "variant_id", Type: query.UUID, Filterable: true},
+	{Name: "warehouse_id", Column: "warehouse_id", Type: query.UUID, Filterable: true},
+	{Name: "kind", Column: "kind", Type: query.String, Filterable: true},
+	{Name: "reference", Column: "COALESCE(reference, '')", Type: query.String, Filterable: true},
+	{Name: "created_at", Column: "created_at", Type: query.Time, Filterable: true, Sortable: true},
+}
+
+// StockLevel is the stock of an item across warehouses, with the stock
+// at each warehouse holding or reserving any in Locations. Available
+// excludes the quantities held by reservations that have not expired.
+type StockLevel struct {
+	StockItem
+	SKU       string           `json:"sku,omitempty"`
+	OnHand    int              `json:"on_hand"`
+	Reserved  int              `json:"reserved"`
+	Available int              `json:"available"`
+	Locations []*LocationStock `json:"locations"`
+}
+
+// LocationStock is the stock of an item at one warehouse.
+type LocationStock struct {
+	WarehouseID   uuid.UUID `json:"warehouse_id"`
+	WarehouseCode string    `json:"warehouse_code"`
+	OnHand        int       `json:"on_hand"`
+	Reserved      int       `json:"reserved"`
+	Available     int       `json:"available"`
+}
+
+// Reservation holds stock for a checkout until it is committed as a sale,
+// released or expires.
+type Reservation struct {
+	ID uuid.UUID `json:"id" db:"id"`
+	StockItem
+	WarehouseID uuid.UUID `json:"warehouse_id" db:"warehouse_id"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+	Reference   string    `json:"reference" db:"reference"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// RecordMovementRequest records a movement at WarehouseID, or at the
+// default warehouse when empty. Quantity is positive for receipts, sales
+// and returns, and signed for adjustments.
+type RecordMovementRequest struct {
+	VariantID   string `json:"variant_id" binding:"omitempty,uuid"`
+	WarehouseID string `json:"warehouse_id" binding:"omitempty,uuid"`
+	Kind        string `json:"kind" binding:"required,oneof=receipt sale adjustment return"`
+	Quantity    int    `json:"quantity" binding:"required"`
+	Reference   string `json:"reference" binding:"omitempty,max=100"`
+	Note        string `json:"note" binding:"omitempty,max=500"`
+}
+
+// ReserveStockRequest reserves stock for TTLSeconds, or the configured
+// default when zero. The stock is taken from WarehouseID when set, and
+// otherwise from the active warehouse chosen by Strategy, or the
+// configured strategy, among those with enough available. The nearest
+// strategy measures the distance to Latitude and Longitude.
+type ReserveStockRequest struct {
+	VariantID   string   `json:"variant_id" binding:"omitempty,uuid"`
+	WarehouseID string   `json:"warehouse_id" binding:"omitempty,uuid"`
+	Quantity    int      `json:"quantity" binding:"required,min=1"`
+	Reference   string   `json:"reference" binding:"omitempty,max=100"`
+	TTLSeconds  int      `json:"ttl_seconds" binding:"omitempty,min=1,max=86400"`
+	Strategy    string   `json:"strategy" binding:"omitempty,max=50"`
+	Latitude    *float64 `json:"latitude" binding:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude   *float64 `json:"longitude" binding:"required_with=Latitude,omitempty,min=-180,max=180"`
+}
+
+// TransferStockRequest moves stock of a product, or one of its variants,
+// between warehouses.
+type TransferStockRequest struct {
+	VariantID       string `json:"variant_id" binding:"omitempty,uuid"`
+	FromWarehouseID string `json:"from_warehouse_id" binding:"required,uuid"`
+	ToWarehouseID   string `json:"to_warehouse_id" binding:"required,uuid,nefield=FromWarehouseID"`
+	Quantity        int    `json:"quantity" binding:"required,min=1"`
+	Reference       string `json:"reference" binding:"omitempty,max=100"`
+	Note            string `json:"note" binding:"omitempty,max=500"`
+}
+
+// CommitReservationRequest turns a reservation into a sale.
+type CommitReservationRequest struct {
+	Reference string `json:"reference" binding:"omitempty,max=100"`
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/query"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of an order.
+const (
+	OrderPending   = "pending"
+	OrderPaid      = "paid"
+	OrderFulfilled = "fulfilled"
+	OrderShipped   = "shipped"
+	OrderDelivered = "delivered"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+)
+
+// orderTransitions lists the statuses an order in each status may move
+// to. Unpaid orders are cancelled; paid orders are refunded instead.
+var orderTransitions = map[string][]string{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderFulfilled, OrderRefunded},
+	OrderFulfilled: {OrderShipped, OrderRefunded},
+	OrderShipped:   {OrderDelivered, OrderRefunded},
+	OrderDelivered: {OrderRefunded},
+}
+
+// Order is a checked-out cart. Items keep the names and prices of the
+// products at checkout, Discounts the promotions redeemed, which Discount
+// adds up, and Taxes the tax charged at each rate for delivery to Country
+// and Region, which Tax adds up. Total is the subtotal less Discount plus
+// Shipping and, unless PricesIncludeTax, plus Tax.
+// The stock of a pending order is reserved until ExpiresAt; paying for it
+// records the sale.
+type Order struct {
+	ID               uuid.UUID            `json:"id" db:"id"`
+	UserID           uuid.UUID            `json:"user_id" db:"user_id"`
+	Status           string               `json:"status" db:"status"`
+	Items            []*OrderItem         `json:"items" db:"-"`
+	ItemCount        int                  `json:"item_count" db:"item_count"`
+	Subtotal         money.Money          `json:"subtotal" db:"subtotal"`
+	Discount         money.Money          `json:"discount" db:"discount_total"`
+	Shipping         money.Money          `json:"shipping" db:"shipping"`
+	Tax              money.Money          `json:"tax" db:"tax_total"`
+	PricesIncludeTax bool                 `json:"prices_include_tax" db:"prices_include_tax"`
+	Total            money.Money          `json:"total" db:"total"`
+	Discounts        []*AppliedDiscount   `json:"discounts,omitempty" db:"-"`
+	Taxes            []*TaxLine           `json:"taxes,omitempty" db:"-"`
+	Country          string               `json:"country,omitempty" db:"tax_country"`
+	Region           string               `json:"region,omitempty" db:"tax_region"`
+	Note             string               `json:"note,omitempty" db:"note"`
+	ExpiresAt        *time.Time           `json:"expires_at,omitempty" db:"expires_at"`
+	History          []*OrderStatusChange `json:"history,omitempty" db:"-"`
+	Version          int                  `json:"version" db:"version"`
+	CreatedAt        time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at" db:"updated_at"`
+}
+
+// ResourceVersion is used as the ETag of the order.
+func (o *Order) ResourceVersion() int {
+	return o.Version
+}
+
+// CanTransition reports whether the order may move to status.
+func (o *Order) CanTransition(status string) bool {
+	return slices.Contains(orderTransitions[o.Status], status)
+}
+
+// OrderItem is a line of an order with the product details at checkout.
+// Discount is its share of the discounts of the order and Tax the tax on
+// it after discounts. Its stock is reserved at, and sold from,
+// WarehouseID.
+type OrderItem struct {
+	ID            uuid.UUID   `json:"id"`
+	ProductID     uuid.UUID   `json:"product_id"`
+	VariantID     *uuid.UUID  `json:"variant_id"`
+	WarehouseID   uuid.UUID   `json:"warehouse_id"`
+	ReservationID *uuid.UUID  `json:"-"`
+	SKU           string      `json:"sku,omitempty"`
+	Name          string      `json:"name"`
+	UnitPrice     money.Money `json:"unit_price"`
+	Quantity      int         `json:"quantity"`
+	Discount      money.Money `json:"discount"`
+	Tax           money.Money `json:"tax"`
+	Total         money.Money `json:"total"`
+}
+
+// OrderStatusChange records a transition of an order. From is empty for
+// the creation of the order, and ChangedBy is nil for changes the system
+// made, such as cancelling unpaid orders.
+type OrderStatusChange struct {
+	From      string     `json:"from,omitempty"`
+	To        string     `json:"to"`
+	Note      string     `json:"note,omitempty"`
+	ChangedBy *uuid.UUID `json:"changed_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// OrderListFields are the fields orders can be filtered and sorted by.
+var OrderListFields = query.Schema{
+	{Name: "status", Column: "status", Type: query.String, Filterable: true},
+	{Name: "user_id", Column: "user_id", Type: query.UUID, Filterable: true},
+	{Name: "total", Column: "total", Type: query.Int64, Filterable: true, Sortable: true},
+	{Name: "created_at", Column: "created_at", Type: query.Time, Filterable: true, Sortable: true},
+	{Name: "updated_at", Column: "updated_at", Type: query.Time, Filterable: true, Sortable: true},
+}
+
+// CheckoutRequest turns the cart of the user into an order. Country and
+// Region are where it is delivered, which decides its tax; without them
+// the default tax address applies.
+type CheckoutRequest struct {
+	Note    string `json:"note" binding:"omitempty,max=500"`
+	Country string `json:"country" binding:"omitempty,iso3166_1_alpha2"`
+	Region  string `json:"region" binding:"omitempty,max=50"`
+}
+
+// OrderTransitionRequest moves an order to Status. Orders become paid and
+// refunded only through their payments, so clients cannot request those.
+type OrderTransitionRequest struct {
+	Status string `json:"status" binding:"required,oneof=fulfilled shipped delivered cancelled"`
+	Note   string `json:"note" binding:"omitempty,max=500"`
+}
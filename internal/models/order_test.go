@@ -0,0 +1,31 @@
+package models
+
+import "testing"
+
+func TestOrderCanTransition(t *testing.T) {
+	cases := []struct {
+		from, to string
+		want     bool
+	}{
+		{OrderPending, OrderPaid, true},
+		{OrderPending, OrderCancelled, true},
+		{OrderPending, OrderShipped, false},
+		{OrderPending, OrderRefunded, false},
+		{OrderPaid, OrderFulfilled, true},
+		{OrderPaid, OrderCancelled, false},
+		{OrderPaid, OrderRefunded, true},
+		{OrderFulfilled, OrderShipped, true},
+		{OrderShipped, OrderDelivered, true},
+		{OrderShipped, OrderFulfilled, false},
+		{OrderDelivered, OrderRefunded, true},
+		{OrderCancelled, OrderPending, false},
+		{OrderRefunded, OrderPaid, false},
+		{OrderPaid, OrderPaid, false},
+	}
+	for _, tc := range cases {
+		order := &Order{Status: tc.from}
+		if got := order.CanTransition(tc.to); got != tc.want {
+			t.Errorf("Expected %s -> %s allowed to be %v, got %v", tc.from, tc.to, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"suitemedia/pkg/money"
+
+	"github.com/google/uuid"
+)
+
+// Payment is an attempt to pay for an order with a payment provider. Its
+// Status follows the intent at the provider, which webhooks report.
+// ClientSecret lets the customer complete the payment.
+type Payment struct {
+	ID            uuid.UUID   `json:"id" db:"id"`
+	OrderID       uuid.UUID   `json:"order_id" db:"order_id"`
+	Provider      string      `json:"provider" db:"provider"`
+	IntentID      string      `json:"intent_id" db:"intent_id"`
+	Amount        money.Money `json:"amount" db:"amount"`
+	Status        string      `json:"status" db:"status"`
+	ClientSecret  string      `json:"client_secret,omitempty" db:"client_secret"`
+	FailureReason string      `json:"failure_reason,omitempty" db:"failure_reason"`
+	CreatedAt     time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// RefundOrderRequest refunds the payment of an order.
+type RefundOrderRequest struct {
+	Note string `json:"note" binding:"omitempty,max=500"`
+}
+
+// SimulatePaymentRequest makes the fake payment provider act out Event on
+// an intent and deliver the webhook about it.
+type SimulatePaymentRequest struct {
+	IntentID string `json:"intent_id" binding:"required"`
+	Event    string `json:"event" binding:"required,oneof=payment.authorized payment.captured payment.failed payment.refunded"`
+	Reason   string `json:"reason" binding:"omitempty,max=255"`
+}
+
+// FailPaymentOperationRequest makes the next call of Operation to the fake
+// payment provider fail with Message.
+type FailPaymentOperationRequest struct {
+	Operation string `json:"operation" binding:"required,oneof=create capture refund"`
+	Message   string `json:"message" binding:"omitempty,max=255"`
+}
@@ -3,39 +3,162 @@ package models
 import (
 	"time"
 
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/query"
+
 	"github.com/google/uuid"
 )
 
+// Product is priced in the base currency. Prices lists explicit prices in
+// other currencies, which take precedence over converting Price. Stock is
+// the stock of a product without variants, and only changes through
+// inventory movements. TaxClass selects the tax rates that apply to it.
 type Product struct {
-	ID          uuid.UUID  `json:"id" db:"id"`
-	Name        string     `json:"name" db:"name"`
-	Description string     `json:"description" db:"description"`
-	Price       float64    `json:"price" db:"price"`
-	Stock       int        `json:"stock" db:"stock"`
-	Category    string     `json:"category" db:"category"`
-	ImageURL    string     `json:"image_url" db:"image_url"`
-	IsActive    bool       `json:"is_active" db:"is_active"`
-	CreatedBy   uuid.UUID  `json:"created_by" db:"created_by"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	ID          uuid.UUID     `json:"id" db:"id"`
+	Name        string        `json:"name" db:"name"`
+	Description string        `json:"description" db:"description"`
+	Price       money.Money   `json:"price" db:"price"`
+	Prices      []money.Money `json:"prices" db:"-"`
+	Stock       int           `json:"stock" db:"stock"`
+	Category    string        `json:"category" db:"category"`
+	CategoryID  *uuid.UUID    `json:"category_id" db:"category_id"`
+	TaxClass    string        `json:"tax_class" db:"tax_class"`
+	ImageURL    string        `json:"image_url" db:"image_url"`
+	IsActive    bool          `json:"is_active" db:"is_active"`
+	CreatedBy   uuid.UUID     `json:"created_by" db:"created_by"`
+	Version     int           `json:"version" db:"version"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+	DeletedAt   *time.Time    `json:"deleted_at,omitempty" db:"deleted_at"`
+
+	// Options are the dimensions the product varies in, and Variants
+	// summarizes its variants when it has any.
+	Options  []ProductOption `json:"options" db:"options"`
+	Variants *VariantSummary `json:"variants,omitempty" db:"-"`
+
+	// Relevance and Headline are set in search results. Headline is an
+	// excerpt of the description with matches wrapped in <mark> tags.
+	Relevance float64 `json:"relevance,omitempty" db:"-"`
+	Headline  string  `json:"headline,omitempty" db:"-"`
+}
+
+// ProductStockColumn is the stock of a product, summed over its variants
+// when it has any.
+const ProductStockColumn = `COALESCE((
+	SELECT SUM(v.stock) FROM product_variants v WHERE v.product_id = products.id AND v.deleted_at IS NULL
+), stock, 0)`
+
+// ProductListFields are the fields products can be filtered and sorted by.
+// Nullable text columns are sorted as empty strings so that keyset
+// pagination can compare them. Prices are in minor units of the base
+// currency.
+var ProductListFields = query.Schema{
+	{Name: "name", Column: "name", Type: query.String, Filterable: true, Sortable: true},
+	{Name: "category", Column: "COALESCE(category, '')", Type: query.String, Filterable: true, Sortable: true},
+	{Name: "category_id", Column: "category_id", Type: query.UUID, Filterable: true},
+	{Name: "tax_class", Column: "tax_class", Type: query.String, Filterable: true},
+	{Name: "price", Column: "price", Type: query.Int64, Filterable: true, Sortable: true},
+	{Name: "stock", Column: ProductStockColumn, Type: query.Integer, Filterable: true, Sortable: true},
+	{Name: "is_active", Column: "is_active", Type: query.Bool, Filterable: true},
+	{Name: "created_by", Column: "created_by", Type: query.UUID, Filterable: true},
+	{Name: "created_at", Column: "created_at", Type: query.Time, Filterable: true, Sortable: true},
+	{Name: "updated_at", Column: "updated_at", Type: query.Time, Filterable: true, Sortable: true},
 }
 
+// ProductFacets summarize the products matching a search for filtering
+// them further. Each facet is counted with every filter applied except
+// those on its own field, so that selecting a category does not hide the
+// other categories.
+type ProductFacets struct {
+	Categories   []CategoryFacet `json:"categories"`
+	Prices       []PriceBucket   `json:"prices"`
+	Availability Availability    `json:"availability"`
+}
+
+// CategoryFacet counts the products in a category. ID is the value of the
+// category_id filter selecting them, and is nil for products without a
+// category.
+type CategoryFacet struct {
+	ID    *uuid.UUID `json:"id"`
+	Name  string     `json:"name"`
+	Slug  string     `json:"slug"`
+	Count int64      `json:"count"`
+}
+
+// PriceBucket counts the products priced from Min up to, but excluding,
+// Max, in minor units of the base currency. The last bucket has no upper
+// bound.
+type PriceBucket struct {
+	Min   int64  `json:"min"`
+	Max   *int64 `json:"max"`
+	Count int64  `json:"count"`
+}
+
+type Availability struct {
+	InStock    int64 `json:"in_stock"`
+	OutOfStock int64 `json:"out_of_stock"`
+}
+
+// SuggestParams are the query parameters of product suggestions.
+type SuggestParams struct {
+	Q     string `form:"q" binding:"required,max=100"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=20"`
+}
+
+// CreateProductRequest takes Price in the base currency and optional
+// Prices in other currencies. TaxClass defaults to the standard class.
 type CreateProductRequest struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description" binding:"required"`
-	Price       float64 `json:"price" binding:"required,gt=0"`
-	Stock       int     `json:"stock" binding:"required,gte=0"`
-	Category    string  `json:"category" binding:"required"`
-	ImageURL    string  `json:"image_url" binding:"omitempty,url"`
+	Name        string        `json:"name" binding:"required"`
+	Description string        `json:"description" binding:"required"`
+	Price       *money.Money  `json:"price" binding:"required"`
+	Prices      []money.Money `json:"prices" binding:"omitempty,max=50"`
+	Stock       int           `json:"stock" binding:"required,gte=0"`
+	Category    string        `json:"category" binding:"required_without=CategoryID"`
+	CategoryID  string        `json:"category_id" binding:"omitempty,uuid"`
+	TaxClass    string        `json:"tax_class" binding:"omitempty,max=50"`
+	ImageURL    string        `json:"image_url" binding:"omitempty,url"`
 }
 
+// UpdateProductRequest replaces the explicit prices when Prices is
+// present; an empty list removes them. A changed Stock is recorded as an
+// inventory adjustment.
 type UpdateProductRequest struct {
-	Name        *string  `json:"name" binding:"omitempty"`
-	Description *string  `json:"description" binding:"omitempty"`
-	Price       *float64 `json:"price" binding:"omitempty,gt=0"`
-	Stock       *int     `json:"stock" binding:"omitempty,gte=0"`
-	Category    *string  `json:"category" binding:"omitempty"`
-	ImageURL    *string  `json:"image_url" binding:"omitempty,url"`
-	IsActive    *bool    `json:"is_active" binding:"omitempty"`
+	Name        *string       `json:"name" binding:"omitempty"`
+	Description *string       `json:"description" binding:"omitempty"`
+	Price       *money.Money  `json:"price" binding:"omitempty"`
+	Prices      []money.Money `json:"prices" binding:"omitempty,max=50"`
+	Stock       *int          `json:"stock" binding:"omitempty,gte=0"`
+	Category    *string       `json:"category" binding:"omitempty"`
+	CategoryID  *string       `json:"category_id" binding:"omitempty,uuid|len=0"`
+	TaxClass    *string       `json:"tax_class" binding:"omitempty,max=50"`
+	ImageURL    *string       `json:"image_url" binding:"omitempty,url|len=0"`
+	IsActive    *bool         `json:"is_active" binding:"omitempty"`
+}
+
+// ToUpdateRequest returns the product as a fully populated update request,
+// the document PATCH requests are applied to.
+func (p *Product) ToUpdateRequest() UpdateProductRequest {
+	categoryID := ""
+	if p.CategoryID != nil {
+		categoryID = p.CategoryID.String()
+	}
+	prices := append(make([]money.Money, 0, len(p.Prices)), p.Prices...)
+
+	return UpdateProductRequest{
+		Name:        &p.Name,
+		Description: &p.Description,
+		Price:       &p.Price,
+		Prices:      prices,
+		Stock:       &p.Stock,
+		Category:    &p.Category,
+		CategoryID:  &categoryID,
+		TaxClass:    &p.TaxClass,
+		ImageURL:    &p.ImageURL,
+		IsActive:    &p.IsActive,
+	}
+}
+
+// ResourceVersion is used as the ETag of the product.
+func (p *Product) ResourceVersion() int {
+	return p.Version
 }
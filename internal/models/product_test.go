@@ -1,12 +1,16 @@
 package models
 
-import "testing"
+import (
+	"testing"
+
+	"suitemedia/pkg/money"
+)
 
 func TestProductModel(t *testing.T) {
 	product := &Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       99.99,
+		Price:       money.New(9999, "USD"),
 		Stock:       10,
 		Category:    "test",
 	}
@@ -14,8 +18,8 @@ func TestProductModel(t *testing.T) {
 	if product.Name != "Test Product" {
 		t.Errorf("Expected name 'Test Product', got %s", product.Name)
 	}
-	if product.Price != 99.99 {
-		t.Errorf("Expected price 99.99, got %f", product.Price)
+	if product.Price.Decimal() != "99.99" {
+		t.Errorf("Expected price 99.99, got %s", product.Price.Decimal())
 	}
 }
 
@@ -23,7 +27,7 @@ func TestCreateProductRequest(t *testing.T) {
 	req := CreateProductRequest{
 		Name:        "New Product",
 		Description: "Description",
-		Price:       199.99,
+		Price:       &money.Money{Amount: 19999, Currency: "USD"},
 		Stock:       5,
 		Category:    "electronics",
 	}
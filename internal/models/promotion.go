@@ -0,0 +1,200 @@
+package models
+
+import (
+	"time"
+
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/promotion"
+	"suitemedia/pkg/query"
+
+	"github.com/google/uuid"
+)
+
+// Promotion is a discount shoppers get by entering its Code, or
+// automatically when it has none. It takes PercentOff percent or AmountOff
+// off the items it applies to, or waives shipping, while its conditions
+// are met. UsageLimit caps redemptions overall and PerUserLimit per user;
+// nil means unlimited.
+type Promotion struct {
+	ID             uuid.UUID    `json:"id" db:"id"`
+	Code           string       `json:"code,omitempty" db:"code"`
+	Name           string       `json:"name" db:"name"`
+	Description    string       `json:"description,omitempty" db:"description"`
+	Kind           string       `json:"kind" db:"kind"`
+	PercentOff     int          `json:"percent_off,omitempty" db:"percent_off"`
+	AmountOff      *money.Money `json:"amount_off,omitempty" db:"amount_off"`
+	MinSubtotal    *money.Money `json:"min_subtotal,omitempty" db:"min_subtotal"`
+	ProductIDs     []uuid.UUID  `json:"product_ids" db:"product_ids"`
+	CategoryIDs    []uuid.UUID  `json:"category_ids" db:"category_ids"`
+	FirstOrderOnly bool         `json:"first_order_only" db:"first_order_only"`
+	StartsAt       *time.Time   `json:"starts_at" db:"starts_at"`
+	EndsAt         *time.Time   `json:"ends_at" db:"ends_at"`
+	Stackable      bool         `json:"stackable" db:"stackable"`
+	Priority       int          `json:"priority" db:"priority"`
+	UsageLimit     *int         `json:"usage_limit" db:"usage_limit"`
+	PerUserLimit   *int         `json:"per_user_limit" db:"per_user_limit"`
+	TimesUsed      int          `json:"times_used" db:"times_used"`
+	IsActive       bool         `json:"is_active" db:"is_active"`
+	Version        int          `json:"version" db:"version"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+	DeletedAt      *time.Time   `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// ResourceVersion is used as the ETag of the promotion.
+func (p *Promotion) ResourceVersion() int {
+	return p.Version
+}
+
+// Rule returns the promotion as the rule the promotion engine applies.
+func (p *Promotion) Rule() *promotion.Promotion {
+	rule := &promotion.Promotion{
+		ID:             p.ID.String(),
+		Code:           p.Code,
+		Name:           p.Name,
+		Kind:           p.Kind,
+		PercentOff:     p.PercentOff,
+		ProductIDs:     make([]string, len(p.ProductIDs)),
+		CategoryIDs:    make([]string, len(p.CategoryIDs)),
+		FirstOrderOnly: p.FirstOrderOnly,
+		StartsAt:       p.StartsAt,
+		EndsAt:         p.EndsAt,
+		Stackable:      p.Stackable,
+		Priority:       p.Priority,
+	}
+	if p.AmountOff != nil {
+		rule.AmountOff = *p.AmountOff
+	}
+	if p.MinSubtotal != nil {
+		rule.MinSubtotal = *p.MinSubtotal
+	}
+	for i, id := range p.ProductIDs {
+		rule.ProductIDs[i] = id.String()
+	}
+	for i, id := range p.CategoryIDs {
+		rule.CategoryIDs[i] = id.String()
+	}
+	return rule
+}
+
+// PromotionListFields are the fields promotions can be filtered and sorted
+// by.
+var PromotionListFields = query.Schema{
+	{Name: "code", Column: "code", Type: query.String, Filterable: true, Sortable: true},
+	{Name: "kind", Column: "kind", Type: query.String, Filterable: true},
+	{Name: "is_active", Column: "is_active", Type: query.Bool, Filterable: true},
+	{Name: "priority", Column: "priority", Type: query.Integer, Filterable: true, Sortable: true},
+	{Name: "times_used", Column: "times_used", Type: query.Integer, Filterable: true, Sortable: true},
+	{Name: "starts_at", Column: "starts_at", Type: query.Time, Filterable: true},
+	{Name: "ends_at", Column: "ends_at", Type: query.Time, Filterable: true},
+	{Name: "created_at", Column: "created_at", Type: query.Time, Filterable: true, Sortable: true},
+}
+
+// CreatePromotionRequest creates a promotion. Codes are matched regardless
+// of case; promotions without one apply automatically. Amounts are in the
+// base currency.
+type CreatePromotionRequest struct {
+	Code           string       `json:"code" binding:"omitempty,max=50,printascii,excludesall= "`
+	Name           string       `json:"name" binding:"required,max=255"`
+	Description    string       `json:"description" binding:"omitempty,max=1000"`
+	Kind           string       `json:"kind" binding:"required,oneof=percentage fixed free_shipping"`
+	PercentOff     int          `json:"percent_off" binding:"required_if=Kind percentage,omitempty,min=1,max=100"`
+	AmountOff      *money.Money `json:"amount_off" binding:"required_if=Kind fixed"`
+	MinSubtotal    *money.Money `json:"min_subtotal"`
+	ProductIDs     []string     `json:"product_ids" binding:"omitempty,max=100,dive,uuid"`
+	CategoryIDs    []string     `json:"category_ids" binding:"omitempty,max=100,dive,uuid"`
+	FirstOrderOnly bool         `json:"first_order_only"`
+	StartsAt       *time.Time   `json:"starts_at"`
+	EndsAt         *time.Time   `json:"ends_at"`
+	Stackable      bool         `json:"stackable"`
+	Priority       int          `json:"priority"`
+	UsageLimit     *int         `json:"usage_limit" binding:"omitempty,min=1"`
+	PerUserLimit   *int         `json:"per_user_limit" binding:"omitempty,min=1"`
+}
+
+// UpdatePromotionRequest changes a promotion. A zero UsageLimit,
+// PerUserLimit or MinSubtotal amount removes the limit, an empty code
+// makes the promotion automatic and ProductIDs or CategoryIDs, when
+// present, replace the lists.
+type UpdatePromotionRequest struct {
+	Code           *string      `json:"code" binding:"omitempty,max=50,printascii,excludesall= "`
+	Name           *string      `json:"name" binding:"omitempty,max=255"`
+	Description    *string      `json:"description" binding:"omitempty,max=1000"`
+	PercentOff     *int         `json:"percent_off" binding:"omitempty,min=1,max=100"`
+	AmountOff      *money.Money `json:"amount_off"`
+	MinSubtotal    *money.Money `json:"min_subtotal"`
+	ProductIDs     []string     `json:"product_ids" binding:"omitempty,max=100,dive,uuid"`
+	CategoryIDs    []string     `json:"category_ids" binding:"omitempty,max=100,dive,uuid"`
+	FirstOrderOnly *bool        `json:"first_order_only"`
+	StartsAt       *time.Time   `json:"starts_at"`
+	EndsAt         *time.Time   `json:"ends_at"`
+	Stackable      *bool        `json:"stackable"`
+	Priority       *int         `json:"priority"`
+	UsageLimit     *int         `json:"usage_limit" binding:"omitempty,min=0"`
+	PerUserLimit   *int         `json:"per_user_limit" binding:"omitempty,min=0"`
+	IsActive       *bool        `json:"is_active"`
+}
+
+// ApplyPromotionRequest adds a promotion code to the cart.
+type ApplyPromotionRequest struct {
+	Code string `json:"code" binding:"required,max=50"`
+}
+
+// PriceBreakdown itemizes the price of a cart or order: the subtotal of
+// its lines, each discount applied, shipping, the tax charged at each rate
+// for delivery to Country and Region and the total to pay. When
+// PricesIncludeTax is set the tax is already part of the prices and is not
+// added to the total. RejectedPromotions lists the codes entered that do
+// not apply and why.
+type PriceBreakdown struct {
+	Subtotal           money.Money          `json:"subtotal"`
+	Discounts          []*AppliedDiscount   `json:"discounts"`
+	DiscountTotal      money.Money          `json:"discount_total"`
+	Shipping           money.Money          `json:"shipping"`
+	Tax                money.Money          `json:"tax"`
+	Taxes              []*TaxLine           `json:"taxes"`
+	Country            string               `json:"country,omitempty"`
+	Region             string               `json:"region,omitempty"`
+	PricesIncludeTax   bool                 `json:"prices_include_tax"`
+	Total              money.Money          `json:"total"`
+	Lines              []*LinePrice         `json:"lines"`
+	RejectedPromotions []*RejectedPromotion `json:"rejected_promotions,omitempty"`
+}
+
+// AppliedDiscount is what a promotion takes off, shipping included.
+type AppliedDiscount struct {
+	PromotionID uuid.UUID   `json:"promotion_id"`
+	Code        string      `json:"code,omitempty"`
+	Name        string      `json:"name"`
+	Kind        string      `json:"kind"`
+	Amount      money.Money `json:"amount"`
+}
+
+// LinePrice is the price of a line after the discounts taken off it, and
+// the tax on that price.
+type LinePrice struct {
+	ItemID   uuid.UUID   `json:"item_id"`
+	Subtotal money.Money `json:"subtotal"`
+	Discount money.Money `json:"discount"`
+	Tax      money.Money `json:"tax"`
+	Total    money.Money `json:"total"`
+}
+
+// TaxLine is the tax charged at one rate: Rate percent of Taxable, the
+// amount of the lines taxed at it without tax. Region and Class are empty
+// for rates that apply to the whole country or to every class.
+type TaxLine struct {
+	Country string      `json:"country"`
+	Region  string      `json:"region,omitempty"`
+	Class   string      `json:"class,omitempty"`
+	Rate    string      `json:"rate"`
+	Taxable money.Money `json:"taxable"`
+	Amount  money.Money `json:"amount"`
+}
+
+// RejectedPromotion explains why a promotion code does not apply, such as
+// "min_subtotal" or "usage_limit".
+type RejectedPromotion struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
@@ -3,6 +3,9 @@ package models
 import (
 	"time"
 
+	"suitemedia/pkg/pagination"
+	"suitemedia/pkg/query"
+
 	"github.com/google/uuid"
 )
 
@@ -14,11 +17,23 @@ type User struct {
 	LastName  string     `json:"last_name" db:"last_name"`
 	Role      string     `json:"role" db:"role"`
 	IsActive  bool       `json:"is_active" db:"is_active"`
+	Version   int        `json:"version" db:"version"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
+// UserListFields are the fields users can be filtered and sorted by.
+var UserListFields = query.Schema{
+	{Name: "email", Column: "email", Type: query.String, Filterable: true, Sortable: true},
+	{Name: "first_name", Column: "first_name", Type: query.String, Filterable: true, Sortable: true},
+	{Name: "last_name", Column: "last_name", Type: query.String, Filterable: true, Sortable: true},
+	{Name: "role", Column: "role", Type: query.String, Ops: []query.Op{query.Eq, query.Ne, query.In, query.NotIn}, Filterable: true, Sortable: true},
+	{Name: "is_active", Column: "is_active", Type: query.Bool, Filterable: true},
+	{Name: "created_at", Column: "created_at", Type: query.Time, Filterable: true, Sortable: true},
+	{Name: "updated_at", Column: "updated_at", Type: query.Time, Filterable: true, Sortable: true},
+}
+
 type UserResponse struct {
 	ID        uuid.UUID `json:"id"`
 	Email     string    `json:"email"`
@@ -26,6 +41,7 @@ type UserResponse struct {
 	LastName  string    `json:"last_name"`
 	Role      string    `json:"role"`
 	IsActive  bool      `json:"is_active"`
+	Version   int       `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -45,6 +61,13 @@ type UpdateUserRequest struct {
 	IsActive  *bool   `json:"is_active" binding:"omitempty"`
 }
 
+// UpdateProfileRequest holds the fields users may change on their own
+// profile; role and status are left to admins.
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name" binding:"omitempty"`
+	LastName  *string `json:"last_name" binding:"omitempty"`
+}
+
 type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
 	Password  string `json:"password" binding:"required,min=8"`
@@ -52,9 +75,12 @@ type RegisterRequest struct {
 	LastName  string `json:"last_name" binding:"required"`
 }
 
+// LoginRequest logs a user in. The anonymous cart of CartToken, when
+// given, is merged into the cart of the user.
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required"`
+	CartToken string `json:"cart_token"`
 }
 
 type RefreshTokenRequest struct {
@@ -68,12 +94,39 @@ type AuthResponse struct {
 	ExpiresIn    int64        `json:"expires_in"`
 }
 
+// ListParams select a page of a list. A zero Page selects cursor mode,
+// where After holds the verified position decoded from Cursor. Count is
+// "exact" (the default), "estimate" or "none". Filters and Sorts are parsed
+// from the filter[...] parameters and Sort against the resource's fields.
+// Query is a full-text search, ranked by relevance unless a sort is given,
+// and CategoryPath limits a list to a category subtree; only products
+// support them.
 type ListParams struct {
-	Page   int    `form:"page" binding:"omitempty,min=1"`
-	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
-	Search string `form:"search" binding:"omitempty"`
-	Sort   string `form:"sort" binding:"omitempty"`
-	Order  string `form:"order" binding:"omitempty,oneof=asc desc"`
+	Page   int                `form:"page" binding:"omitempty,min=1"`
+	Limit  int                `form:"limit" binding:"omitempty,min=1,max=100"`
+	Search string             `form:"search" binding:"omitempty"`
+	Query  string             `form:"q" binding:"omitempty,max=200"`
+	Sort   string             `form:"sort" binding:"omitempty"`
+	Order  string             `form:"order" binding:"omitempty,oneof=asc desc"`
+	Cursor string             `form:"cursor" binding:"omitempty"`
+	Count  string             `form:"count" binding:"omitempty,oneof=exact estimate none"`
+	After  *pagination.Cursor `form:"-" json:"after,omitempty"`
+
+	Filters []query.Filter `form:"-" json:"filters,omitempty"`
+	Sorts   []query.Sort   `form:"-" json:"sorts,omitempty"`
+
+	CategoryPath string `form:"-" json:"category_path,omitempty"`
+}
+
+// ListResult is one page of a list. Total is -1 when it was not counted.
+// Next and Prev are set in cursor mode when there are more rows in that
+// direction.
+type ListResult[T any] struct {
+	Items     []T                `json:"items" msgpack:"items"`
+	Total     int64              `json:"total" msgpack:"total"`
+	Estimated bool               `json:"estimated,omitempty" msgpack:"estimated,omitempty"`
+	Next      *pagination.Cursor `json:"next,omitempty" msgpack:"next,omitempty"`
+	Prev      *pagination.Cursor `json:"prev,omitempty" msgpack:"prev,omitempty"`
 }
 
 func (u *User) ToResponse() UserResponse {
@@ -84,7 +137,24 @@ func (u *User) ToResponse() UserResponse {
 		LastName:  u.LastName,
 		Role:      u.Role,
 		IsActive:  u.IsActive,
+		Version:   u.Version,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
 	}
 }
+
+// ToUpdateRequest returns the user as a fully populated update request, the
+// document PATCH requests are applied to.
+func (u *UserResponse) ToUpdateRequest() UpdateUserRequest {
+	return UpdateUserRequest{
+		FirstName: &u.FirstName,
+		LastName:  &u.LastName,
+		Role:      &u.Role,
+		IsActive:  &u.IsActive,
+	}
+}
+
+// ResourceVersion is used as the ETag of the user.
+func (u *UserResponse) ResourceVersion() int {
+	return u.Version
+}
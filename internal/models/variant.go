@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"suitemedia/pkg/money"
+
+	"github.com/google/uuid"
+)
+
+// ProductOption is a dimension a product varies in, such as size, with
+// the values it comes in.
+type ProductOption struct {
+	Name   string   `json:"name" binding:"required,max=50"`
+	Values []string `json:"values" binding:"required,min=1,max=50,dive,required,max=50"`
+}
+
+// Variant is a purchasable version of a product with one value of each of
+// the product's options. A nil Price inherits the product price.
+type Variant struct {
+	ID        uuid.UUID         `json:"id" db:"id"`
+	ProductID uuid.UUID         `json:"product_id" db:"product_id"`
+	SKU       string            `json:"sku" db:"sku"`
+	Options   map[string]string `json:"options" db:"options"`
+	Price     *money.Money      `json:"price" db:"price"`
+	Stock     int               `json:"stock" db:"stock"`
+	Barcode   string            `json:"barcode" db:"barcode"`
+	ImageURL  string            `json:"image_url" db:"image_url"`
+	Position  int               `json:"position" db:"position"`
+	Version   int               `json:"version" db:"version"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// ResourceVersion is used as the ETag of the variant.
+func (v *Variant) ResourceVersion() int {
+	return v.Version
+}
+
+// VariantSummary aggregates the variants of a product in lists and
+// search results.
+type VariantSummary struct {
+	Count    int         `json:"count"`
+	Stock    int64       `json:"stock"`
+	MinPrice money.Money `json:"min_price"`
+	MaxPrice money.Money `json:"max_price"`
+}
+
+type CreateVariantRequest struct {
+	SKU      string            `json:"sku" binding:"required,max=64"`
+	Options  map[string]string `json:"options"`
+	Price    *money.Money      `json:"price"`
+	Stock    int               `json:"stock" binding:"gte=0"`
+	Barcode  string            `json:"barcode" binding:"omitempty,max=64"`
+	ImageURL string            `json:"image_url" binding:"omitempty,url"`
+	Position int               `json:"position"`
+}
+
+// UpdateVariantRequest changes a variant. Its options cannot change;
+// InheritPrice removes the price override.
+type UpdateVariantRequest struct {
+	SKU          *string      `json:"sku" binding:"omitempty,max=64"`
+	Price        *money.Money `json:"price"`
+	InheritPrice bool         `json:"inherit_price"`
+	Stock        *int         `json:"stock" binding:"omitempty,gte=0"`
+	Barcode      *string      `json:"barcode" binding:"omitempty,max=64"`
+	ImageURL     *string      `json:"image_url" binding:"omitempty,url|len=0"`
+	Position     *int         `json:"position"`
+}
+
+// GenerateVariantsRequest sets the options of a product and creates a
+// variant for every combination of their values. SKUs are SKUPrefix
+// followed by the slugs of the values.
+type GenerateVariantsRequest struct {
+	Options   []ProductOption `json:"options" binding:"required,min=1,max=3,dive"`
+	SKUPrefix string          `json:"sku_prefix" binding:"required,max=30"`
+}
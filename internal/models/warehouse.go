@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Warehouse is a location stock is kept at. Lower priorities are preferred
+// when allocating reservations; the default warehouse receives movements
+// that do not name one.
+type Warehouse struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Code      string     `json:"code" db:"code"`
+	Name      string     `json:"name" db:"name"`
+	Address   string     `json:"address" db:"address"`
+	Country   string     `json:"country" db:"country"`
+	Latitude  *float64   `json:"latitude" db:"latitude"`
+	Longitude *float64   `json:"longitude" db:"longitude"`
+	Priority  int        `json:"priority" db:"priority"`
+	IsActive  bool       `json:"is_active" db:"is_active"`
+	IsDefault bool       `json:"is_default" db:"is_default"`
+	Version   int        `json:"version" db:"version"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// ResourceVersion is used as the ETag of the warehouse.
+func (w *Warehouse) ResourceVersion() int {
+	return w.Version
+}
+
+// CreateWarehouseRequest creates a warehouse. Latitude and Longitude are
+// given together or not at all.
+type CreateWarehouseRequest struct {
+	Code      string   `json:"code" binding:"required,max=50"`
+	Name      string   `json:"name" binding:"required,max=255"`
+	Address   string   `json:"address" binding:"omitempty,max=500"`
+	Country   string   `json:"country" binding:"omitempty,iso3166_1_alpha2"`
+	Latitude  *float64 `json:"latitude" binding:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required_with=Latitude,omitempty,min=-180,max=180"`
+	Priority  int      `json:"priority"`
+	IsDefault bool     `json:"is_default"`
+}
+
+// UpdateWarehouseRequest changes a warehouse. Making it the default
+// removes the flag from the previous default warehouse.
+type UpdateWarehouseRequest struct {
+	Code      *string  `json:"code" binding:"omitempty,max=50"`
+	Name      *string  `json:"name" binding:"omitempty,max=255"`
+	Address   *string  `json:"address" binding:"omitempty,max=500"`
+	Country   *string  `json:"country" binding:"omitempty,iso3166_1_alpha2|len=0"`
+	Latitude  *float64 `json:"latitude" binding:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required_with=Latitude,omitempty,min=-180,max=180"`
+	Priority  *int     `json:"priority"`
+	IsActive  *bool    `json:"is_active"`
+	IsDefault *bool    `json:"is_default"`
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"suitemedia/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// CartRepository persists the carts of logged-in users. Anonymous carts
+// are only kept in the cache.
+type CartRepository interface {
+	// GetByUser returns the cart of a user with its items in the order
+	// they were added.
+	GetByUser(ctx context.Context, userID uuid.UUID) (*models.Cart, error)
+	// Save creates or replaces the cart of cart.UserID with its items.
+	Save(ctx context.Context, cart *models.Cart) error
+	// DeleteByUser deletes the cart of a user, failing with ErrNotFound
+	// when the user has no cart.
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type cartRepository struct {
+	db *sql.DB
+}
+
+func NewCartRepository(db *sql.DB) CartRepository {
+	return &cartRepository{db: db}
+}
+
+func (r *cartRepository) GetByUser(ctx context.Context, userID uuid.UUID) (*models.Cart, error) {
+	q := conn(ctx, r.db)
+	cart := &models.Cart{UserID: &userID, Items: make([]*models.CartItem, 0)}
+	err := q.QueryRowContext(ctx,
+		`SELECT id, promotion_codes, updated_at FROM carts WHERE user_id = $1`, userID,
+	).Scan(&cart.ID, (*pq.StringArray)(&cart.PromotionCodes), &cart.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, product_id, variant_id, quantity, unit_price, currency, added_at
+		FROM cart_items WHERE cart_id = $1 ORDER BY added_at, id
+	`, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &models.CartItem{}
+		err := rows.Scan(
+			&item.ID, &item.ProductID, &item.VariantID, &item.Quantity,
+			&item.UnitPrice.Amount, &item.UnitPrice.Currency, &item.AddedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		cart.Items = append(cart.Items, item)
+	}
+
+	return cart, rows.Err()
+}
+
+func (r *cartRepository) Save(ctx context.Context, cart *models.Cart) error {
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		err := q.QueryRowContext(ctx, `
+			INSERT INTO carts (id, user_id, promotion_codes) VALUES ($1, $2, COALESCE($3::text[], '{}'))
+			ON CONFLICT (user_id) DO UPDATE SET promotion_codes = EXCLUDED.promotion_codes, updated_at = CURRENT_TIMESTAMP
+			RETURNING id, updated_at
+		`, cart.ID, cart.UserID, pq.StringArray(cart.PromotionCodes)).Scan(&cart.ID, &cart.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if _, err := q.ExecContext(ctx, `DELETE FROM cart_items WHERE cart_id = $1`, cart.ID); err != nil {
+			return err
+		}
+		for _, item := range cart.Items {
+			_, err := q.ExecContext(ctx, `
+				INSERT INTO cart_items (id, cart_id, product_id, variant_id, quantity, unit_price, currency, added_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			`, item.ID, cart.ID, item.ProductID, item.VariantID, item.Quantity,
+				item.UnitPrice.Amount, item.UnitPrice.Currency, item.AddedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *cartRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM carts WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
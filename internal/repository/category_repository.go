@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"suitemedia/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type CategoryRepository interface {
+	// Create saves category below its ParentID, whose path must be given
+	// as parentPath, and sets its ID and Path.
+	Create(ctx context.Context, category *models.Category, parentPath string) error
+	GetByID(ctx context.Context, id string) (*models.Category, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Category, error)
+	// List returns every category ordered by position and name.
+	List(ctx context.Context) ([]*models.Category, error)
+	// Update saves category if its Version still matches the stored
+	// version. When its Path changed the paths of all descendants are
+	// moved along, and products in the category take its new name. It
+	// returns the IDs of the renamed products.
+	Update(ctx context.Context, category *models.Category) ([]uuid.UUID, error)
+	// Delete soft-deletes the category, failing with ErrInUse while it has
+	// subcategories or products. A non-zero version must match the stored
+	// version.
+	Delete(ctx context.Context, id string, version int) error
+}
+
+type categoryRepository struct {
+	db *sql.DB
+}
+
+func NewCategoryRepository(db *sql.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
+
+const categoryColumns = `id, parent_id, name, slug, path, position, version, created_at, updated_at`
+
+func scanCategory(row rowScanner) (*models.Category, error) {
+	category := &models.Category{}
+	err := row.Scan(
+		&category.ID, &category.ParentID, &category.Name, &category.Slug, &category.Path,
+		&category.Position, &category.Version, &category.CreatedAt, &category.UpdatedAt,
+	)
+	return category, err
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *models.Category, parentPath string) error {
+	query := `
+		INSERT INTO categories (id, parent_id, name, slug, path, position)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING version, created_at, updated_at
+	`
+
+	category.ID = uuid.New()
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	category.Path = parentPath + category.ID.String() + "/"
+
+	return r.db.QueryRowContext(ctx, query,
+		category.ID, category.ParentID, category.Name, category.Slug, category.Path, category.Position,
+	).Scan(&category.Version, &category.CreatedAt, &category.UpdatedAt)
+}
+
+func (r *categoryRepository) GetByID(ctx context.Context, id string) (*models.Category, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE id = $1 AND deleted_at IS NULL`
+
+	category, err := scanCategory(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return category, err
+}
+
+func (r *categoryRepository) GetBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE slug = $1 AND deleted_at IS NULL`
+
+	category, err := scanCategory(r.db.QueryRowContext(ctx, query, slug))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return category, err
+}
+
+func (r *categoryRepository) List(ctx context.Context) ([]*models.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM categories WHERE deleted_at IS NULL ORDER BY position, name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]*models.Category, 0)
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+func (r *categoryRepository) Update(ctx context.Context, category *models.Category) ([]uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var oldPath string
+	var version int
+	err = tx.QueryRowContext(ctx,
+		`SELECT path, version FROM categories WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, category.ID,
+	).Scan(&oldPath, &version)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version != category.Version {
+		return nil, ErrVersionConflict
+	}
+
+	query := `
+		UPDATE categories
+		SET parent_id = $1, name = $2, slug = $3, path = $4, position = $5,
+			version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+		RETURNING version, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query,
+		category.ParentID, category.Name, category.Slug, category.Path, category.Position, category.ID,
+	).Scan(&category.Version, &category.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if category.Path != oldPath {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE categories
+			SET path = $1 || substr(path, length($2) + 1), updated_at = CURRENT_TIMESTAMP
+			WHERE path LIKE $2 || '%' AND id <> $3
+		`, category.Path, oldPath, category.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE products SET category = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE category_id = $2 AND category IS DISTINCT FROM $1
+		RETURNING id
+	`, category.Name, category.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var renamed []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		renamed = append(renamed, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return renamed, tx.Commit()
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, id string, version int) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+
+	var inUse bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = $1 AND deleted_at IS NULL)
+			OR EXISTS (SELECT 1 FROM products WHERE category_id = $1 AND deleted_at IS NULL)
+	`, id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrInUse
+	}
+
+	query := `
+		UPDATE categories SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return notFoundOrConflict(ctx, r.db, "categories", id)
+	}
+
+	return nil
+}
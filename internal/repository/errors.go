@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is returned when a record does not exist or has been deleted.
+	ErrNotFound = errors.New("record not found")
+	// ErrVersionConflict is returned when a record was modified after the
+	// version the caller based its change on.
+	ErrVersionConflict = errors.New("record was modified concurrently")
+	// ErrInvalidCursor is returned when a cursor was issued for a different
+	// sort order than the one requested.
+	ErrInvalidCursor = errors.New("cursor does not match the requested sort")
+	// ErrInUse is returned when deleting a record others still refer to.
+	ErrInUse = errors.New("record is still in use")
+	// ErrDuplicate is returned when a write would break a unique index.
+	ErrDuplicate = errors.New("record already exists")
+	// ErrInsufficientStock is returned when a movement or reservation
+	// needs more stock than is available.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrVariantRequired is returned for stock movements of a product
+	// with variants, whose stock is kept per variant.
+	ErrVariantRequired = errors.New("stock of a product with variants is kept per variant")
+	// ErrLimitReached is returned when redeeming a promotion that was used
+	// as often as it may be.
+	ErrLimitReached = errors.New("usage limit reached")
+)
+
+// isUniqueViolation reports whether err is a unique index violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// notFoundOrConflict explains why a versioned write matched no rows: the
+// record is gone, or its version has moved on.
+func notFoundOrConflict(ctx context.Context, db *sql.DB, table string, id interface{}) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM ` + table + ` WHERE id = $1 AND deleted_at IS NULL)`
+	if err := db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrVersionConflict
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,552 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"suitemedia/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type InventoryRepository interface {
+	// Record applies movement to the stock of its item at its warehouse, or
+	// the default warehouse when WarehouseID is zero, and appends it to the
+	// ledger. It fails with ErrInsufficientStock when the
+	// stock at the warehouse would become negative, or when a sale would
+	// take stock reserved there.
+	Record(ctx context.Context, movement *models.InventoryMovement) error
+	// SetStock records the adjustment at the default warehouse that brings
+	// the stock of item across warehouses to stock. It returns nil when the
+	// stock already matches.
+	SetStock(ctx context.Context, item models.StockItem, stock int, createdBy *uuid.UUID, note string) (*models.InventoryMovement, error)
+	// Transfer moves stock of item that is not reserved from one warehouse
+	// to another, recording a movement out of the first and one into the
+	// second.
+	Transfer(ctx context.Context, transfer *StockTransfer) ([]*models.InventoryMovement, error)
+	// Levels returns the stock of a product, or of each of its variants,
+	// with the stock at each warehouse.
+	Levels(ctx context.Context, productID string) ([]*models.StockLevel, error)
+	// LockLocations locks the stock of item until the transaction ends and
+	// returns its stock at each warehouse.
+	LockLocations(ctx context.Context, item models.StockItem) ([]*models.LocationStock, error)
+	ListMovements(ctx context.Context, productID string, params models.ListParams) (*models.ListResult[*models.InventoryMovement], error)
+	// Reserve holds stock at the warehouse of reservation for ttl, failing
+	// with ErrInsufficientStock when less is available there.
+	Reserve(ctx context.Context, reservation *models.Reservation, ttl time.Duration) error
+	// Release deletes a reservation of the product.
+	Release(ctx context.Context, productID, id string) error
+	// Commit deletes a reservation of the product that has not expired and
+	// records its quantity as a sale at its warehouse.
+	Commit(ctx context.Context, productID, id, reference string, createdBy *uuid.UUID) (*models.InventoryMovement, error)
+	// DeleteExpired deletes expired reservations and returns how many
+	// there were.
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// StockTransfer moves Quantity of Item between warehouses.
+type StockTransfer struct {
+	Item      models.StockItem
+	From      uuid.UUID
+	To        uuid.UUID
+	Quantity  int
+	Reference string
+	Note      string
+	CreatedBy *uuid.UUID
+}
+
+type inventoryRepository struct {
+	db *sql.DB
+}
+
+func NewInventoryRepository(db *sql.DB) InventoryRepository {
+	return &inventoryRepository{db: db}
+}
+
+const movementColumns = `
+	id, product_id, variant_id, warehouse_id, kind, quantity, stock_after, COALESCE(reference, ''), COALESCE(note, ''),
+	created_by, created_at
+`
+
+func scanMovement(row rowScanner) (*models.InventoryMovement, error) {
+	movement := &models.InventoryMovement{}
+	err := row.Scan(
+		&movement.ID, &movement.ProductID, &movement.VariantID, &movement.WarehouseID, &movement.Kind, &movement.Quantity,
+		&movement.StockAfter, &movement.Reference, &movement.Note, &movement.CreatedBy, &movement.CreatedAt,
+	)
+	return movement, err
+}
+
+func movementSortValue(movement *models.InventoryMovement, field string) interface{} {
+	if field == "created_at" {
+		return movement.CreatedAt
+	}
+	return movement.ID
+}
+
+// lockStock locks the row holding the stock of item across warehouses for
+// the rest of the transaction and returns the stock. Every change to the
+// stock of an item takes this lock first, so changes to the same item are
+// serialized.
+func lockStock(ctx context.Context, q dbtx, item models.StockItem) (int, error) {
+	var stock int
+	var err error
+	if item.VariantID != nil {
+		err = q.QueryRowContext(ctx, `
+			SELECT stock FROM product_variants WHERE id = $1 AND product_id = $2 AND deleted_at IS NULL FOR UPDATE
+		`, *item.VariantID, item.ProductID).Scan(&stock)
+	} else {
+		var hasVariants bool
+		err = q.QueryRowContext(ctx, `
+			SELECT stock, EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = products.id AND v.deleted_at IS NULL)
+			FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+		`, item.ProductID).Scan(&stock, &hasVariants)
+		if err == nil && hasVariants {
+			return 0, ErrVariantRequired
+		}
+	}
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return stock, err
+}
+
+// lockWarehouseStock locks the stock of item at a warehouse, creating it
+// empty when the warehouse has not held the item before, and returns it.
+func lockWarehouseStock(ctx context.Context, q dbtx, warehouseID uuid.UUID, item models.StockItem) (int, error) {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO warehouse_stock (warehouse_id, product_id, variant_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING
+	`, warehouseID, item.ProductID, item.VariantID)
+	if err != nil {
+		return 0, err
+	}
+
+	var stock int
+	err = q.QueryRowContext(ctx, `
+		SELECT stock FROM warehouse_stock
+		WHERE warehouse_id = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3
+		FOR UPDATE
+	`, warehouseID, item.ProductID, item.VariantID).Scan(&stock)
+	return stock, err
+}
+
+// defaultWarehouse returns the ID of the default warehouse.
+func defaultWarehouse(ctx context.Context, q dbtx) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := q.QueryRowContext(ctx, `SELECT id FROM warehouses WHERE is_default AND deleted_at IS NULL`).Scan(&id)
+	return id, err
+}
+
+// reservedStock returns the quantity of item at a warehouse held by
+// reservations that have not expired.
+func reservedStock(ctx context.Context, q dbtx, item models.StockItem, warehouseID uuid.UUID) (int, error) {
+	var reserved int
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(quantity), 0) FROM stock_reservations
+		WHERE product_id = $1 AND variant_id IS NOT DISTINCT FROM $2 AND warehouse_id = $3
+			AND expires_at > CURRENT_TIMESTAMP
+	`, item.ProductID, item.VariantID, warehouseID).Scan(&reserved)
+	return reserved, err
+}
+
+// applyMovement sets the stock of the locked item to stockAfter and its
+// stock at the warehouse of movement to warehouseStockAfter, and appends
+// movement to the ledger.
+func applyMovement(ctx context.Context, q dbtx, movement *models.InventoryMovement, stockAfter, warehouseStockAfter int) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE warehouse_stock SET stock = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE warehouse_id = $2 AND product_id = $3 AND variant_id IS NOT DISTINCT FROM $4
+	`, warehouseStockAfter, movement.WarehouseID, movement.ProductID, movement.VariantID)
+	if err != nil {
+		return err
+	}
+
+	// The stock of a variant is part of its product, so the version of
+	// the product changes either way.
+	if movement.VariantID != nil {
+		_, err = q.ExecContext(ctx, `UPDATE product_variants SET stock = $1 WHERE id = $2`, stockAfter, *movement.VariantID)
+		if err == nil {
+			err = q.QueryRowContext(ctx, `
+				UPDATE products SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1
+				RETURNING version
+			`, movement.ProductID).Scan(&movement.ProductVersion)
+		}
+	} else {
+		err = q.QueryRowContext(ctx, `
+			UPDATE products SET stock = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2
+			RETURNING version
+		`, stockAfter, movement.ProductID).Scan(&movement.ProductVersion)
+	}
+	if err != nil {
+		return err
+	}
+
+	movement.ID = uuid.New()
+	movement.StockAfter = stockAfter
+	return q.QueryRowContext(ctx, `
+		INSERT INTO inventory_movements
+			(id, product_id, variant_id, warehouse_id, kind, quantity, stock_after, reference, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10)
+		RETURNING created_at
+	`, movement.ID, movement.ProductID, movement.VariantID, movement.WarehouseID, movement.Kind, movement.Quantity,
+		stockAfter, movement.Reference, movement.Note, movement.CreatedBy,
+	).Scan(&movement.CreatedAt)
+}
+
+func (r *inventoryRepository) Record(ctx context.Context, movement *models.InventoryMovement) error {
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		stock, err := lockStock(ctx, q, movement.StockItem)
+		if err != nil {
+			return err
+		}
+		if movement.WarehouseID == uuid.Nil {
+			if movement.WarehouseID, err = defaultWarehouse(ctx, q); err != nil {
+				return err
+			}
+		}
+		warehouseStock, err := lockWarehouseStock(ctx, q, movement.WarehouseID, movement.StockItem)
+		if err != nil {
+			return err
+		}
+
+		after := warehouseStock + movement.Quantity
+		if after < 0 {
+			return ErrInsufficientStock
+		}
+		if movement.Kind == models.MovementSale {
+			reserved, err := reservedStock(ctx, q, movement.StockItem, movement.WarehouseID)
+			if err != nil {
+				return err
+			}
+			if after < reserved {
+				return ErrInsufficientStock
+			}
+		}
+
+		return applyMovement(ctx, q, movement, stock+movement.Quantity, after)
+	})
+}
+
+func (r *inventoryRepository) SetStock(ctx context.Context, item models.StockItem, stock int, createdBy *uuid.UUID, note string) (*models.InventoryMovement, error) {
+	var movement *models.InventoryMovement
+	err := withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		current, err := lockStock(ctx, q, item)
+		if err != nil || current == stock {
+			return err
+		}
+		warehouseID, err := defaultWarehouse(ctx, q)
+		if err != nil {
+			return err
+		}
+		warehouseStock, err := lockWarehouseStock(ctx, q, warehouseID, item)
+		if err != nil {
+			return err
+		}
+
+		delta := stock - current
+		if warehouseStock+delta < 0 {
+			return ErrInsufficientStock
+		}
+		movement = &models.InventoryMovement{
+			StockItem:   item,
+			WarehouseID: warehouseID,
+			Kind:        models.MovementAdjustment,
+			Quantity:    delta,
+			Note:        note,
+			CreatedBy:   createdBy,
+		}
+		return applyMovement(ctx, q, movement, stock, warehouseStock+delta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+func (r *inventoryRepository) Transfer(ctx context.Context, transfer *StockTransfer) ([]*models.InventoryMovement, error) {
+	out := &models.InventoryMovement{
+		StockItem:   transfer.Item,
+		WarehouseID: transfer.From,
+		Kind:        models.MovementTransfer,
+		Quantity:    -transfer.Quantity,
+		Reference:   transfer.Reference,
+		Note:        transfer.Note,
+		CreatedBy:   transfer.CreatedBy,
+	}
+	in := *out
+	in.WarehouseID = transfer.To
+	in.Quantity = transfer.Quantity
+
+	err := withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		stock, err := lockStock(ctx, q, transfer.Item)
+		if err != nil {
+			return err
+		}
+		from, err := lockWarehouseStock(ctx, q, transfer.From, transfer.Item)
+		if err != nil {
+			return err
+		}
+		reserved, err := reservedStock(ctx, q, transfer.Item, transfer.From)
+		if err != nil {
+			return err
+		}
+		if from-reserved < transfer.Quantity {
+			return ErrInsufficientStock
+		}
+		to, err := lockWarehouseStock(ctx, q, transfer.To, transfer.Item)
+		if err != nil {
+			return err
+		}
+
+		if err := applyMovement(ctx, q, out, stock, from-transfer.Quantity); err != nil {
+			return err
+		}
+		return applyMovement(ctx, q, &in, stock, to+transfer.Quantity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []*models.InventoryMovement{out, &in}, nil
+}
+
+// locationQuery selects the stock of the items of a product at each
+// warehouse, with the quantity reserved there.
+const locationQuery = `
+	SELECT ws.variant_id, ws.warehouse_id, w.code, ws.stock, (
+		SELECT COALESCE(SUM(r.quantity), 0) FROM stock_reservations r
+		WHERE r.product_id = ws.product_id AND r.variant_id IS NOT DISTINCT FROM ws.variant_id
+			AND r.warehouse_id = ws.warehouse_id AND r.expires_at > CURRENT_TIMESTAMP
+	)
+	FROM warehouse_stock ws
+	JOIN warehouses w ON w.id = ws.warehouse_id
+	WHERE ws.product_id = $1 AND w.deleted_at IS NULL
+`
+
+// queryLocations returns the stock at each warehouse of the items of a
+// product, keyed by variant ID or uuid.Nil for a product without variants.
+func queryLocations(ctx context.Context, q dbtx, query string, args ...interface{}) (map[uuid.UUID][]*models.LocationStock, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := make(map[uuid.UUID][]*models.LocationStock)
+	for rows.Next() {
+		var variantID *uuid.UUID
+		location := &models.LocationStock{}
+		err := rows.Scan(&variantID, &location.WarehouseID, &location.WarehouseCode, &location.OnHand, &location.Reserved)
+		if err != nil {
+			return nil, err
+		}
+		location.Available = max(location.OnHand-location.Reserved, 0)
+
+		key := uuid.Nil
+		if variantID != nil {
+			key = *variantID
+		}
+		locations[key] = append(locations[key], location)
+	}
+
+	return locations, rows.Err()
+}
+
+func (r *inventoryRepository) Levels(ctx context.Context, productID string) ([]*models.StockLevel, error) {
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, ErrNotFound
+	}
+
+	query := `
+		SELECT product_id, variant_id, sku, stock, (
+			SELECT COALESCE(SUM(r.quantity), 0) FROM stock_reservations r
+			WHERE r.product_id = items.product_id AND r.variant_id IS NOT DISTINCT FROM items.variant_id
+				AND r.expires_at > CURRENT_TIMESTAMP
+		)
+		FROM (
+			SELECT p.id AS product_id, NULL::uuid AS variant_id, '' AS sku, p.stock, 0 AS position
+			FROM products p
+			WHERE p.id = $1 AND p.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.deleted_at IS NULL)
+			UNION ALL
+			SELECT v.product_id, v.id, v.sku, v.stock, v.position
+			FROM product_variants v
+			WHERE v.product_id = $1 AND v.deleted_at IS NULL
+		) items
+		ORDER BY position, sku
+	`
+
+	q := conn(ctx, r.db)
+	rows, err := q.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := make([]*models.StockLevel, 0)
+	for rows.Next() {
+		level := &models.StockLevel{Locations: make([]*models.LocationStock, 0)}
+		if err := rows.Scan(&level.ProductID, &level.VariantID, &level.SKU, &level.OnHand, &level.Reserved); err != nil {
+			return nil, err
+		}
+		level.Available = max(level.OnHand-level.Reserved, 0)
+		levels = append(levels, level)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	locations, err := queryLocations(ctx, q, locationQuery+` ORDER BY w.priority, w.code`, productID)
+	if err != nil {
+		return nil, err
+	}
+	for _, level := range levels {
+		key := uuid.Nil
+		if level.VariantID != nil {
+			key = *level.VariantID
+		}
+		for _, location := range locations[key] {
+			if location.OnHand > 0 || location.Reserved > 0 {
+				level.Locations = append(level.Locations, location)
+			}
+		}
+	}
+
+	return levels, nil
+}
+
+func (r *inventoryRepository) LockLocations(ctx context.Context, item models.StockItem) ([]*models.LocationStock, error) {
+	q := conn(ctx, r.db)
+	if _, err := lockStock(ctx, q, item); err != nil {
+		return nil, err
+	}
+
+	locations, err := queryLocations(ctx, q,
+		locationQuery+` AND ws.variant_id IS NOT DISTINCT FROM $2 ORDER BY w.priority, w.code`,
+		item.ProductID, item.VariantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key := uuid.Nil
+	if item.VariantID != nil {
+		key = *item.VariantID
+	}
+	return locations[key], nil
+}
+
+func (r *inventoryRepository) ListMovements(ctx context.Context, productID string, params models.ListParams) (*models.ListResult[*models.InventoryMovement], error) {
+	q := newLedgerQuery("inventory_movements", movementColumns, models.MovementListFields, params)
+	q.where = append(q.where, "product_id = "+q.arg(productID))
+
+	return listPage(ctx, r.db, q, params, scanMovement, movementSortValue)
+}
+
+func (r *inventoryRepository) Reserve(ctx context.Context, reservation *models.Reservation, ttl time.Duration) error {
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		if _, err := lockStock(ctx, q, reservation.StockItem); err != nil {
+			return err
+		}
+		stock, err := lockWarehouseStock(ctx, q, reservation.WarehouseID, reservation.StockItem)
+		if err != nil {
+			return err
+		}
+		reserved, err := reservedStock(ctx, q, reservation.StockItem, reservation.WarehouseID)
+		if err != nil {
+			return err
+		}
+		if stock-reserved < reservation.Quantity {
+			return ErrInsufficientStock
+		}
+
+		reservation.ID = uuid.New()
+		return q.QueryRowContext(ctx, `
+			INSERT INTO stock_reservations (id, product_id, variant_id, warehouse_id, quantity, reference, expires_at)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), CURRENT_TIMESTAMP + make_interval(secs => $7))
+			RETURNING expires_at, created_at
+		`, reservation.ID, reservation.ProductID, reservation.VariantID, reservation.WarehouseID, reservation.Quantity,
+			reservation.Reference, ttl.Seconds(),
+		).Scan(&reservation.ExpiresAt, &reservation.CreatedAt)
+	})
+}
+
+func (r *inventoryRepository) Release(ctx context.Context, productID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+	if _, err := uuid.Parse(productID); err != nil {
+		return ErrNotFound
+	}
+
+	result, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM stock_reservations WHERE id = $1 AND product_id = $2`, id, productID,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *inventoryRepository) Commit(ctx context.Context, productID, id, reference string, createdBy *uuid.UUID) (*models.InventoryMovement, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, ErrNotFound
+	}
+
+	movement := &models.InventoryMovement{Kind: models.MovementSale, Reference: reference, CreatedBy: createdBy}
+	err := withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		var quantity int
+		var reserved string
+		err := q.QueryRowContext(ctx, `
+			DELETE FROM stock_reservations
+			WHERE id = $1 AND product_id = $2 AND expires_at > CURRENT_TIMESTAMP
+			RETURNING product_id, variant_id, warehouse_id, quantity, COALESCE(reference, '')
+		`, id, productID).Scan(&movement.ProductID, &movement.VariantID, &movement.WarehouseID, &quantity, &reserved)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if movement.Reference == "" {
+			movement.Reference = reserved
+		}
+		movement.Quantity = -quantity
+
+		stock, err := lockStock(ctx, q, movement.StockItem)
+		if err != nil {
+			return err
+		}
+		warehouseStock, err := lockWarehouseStock(ctx, q, movement.WarehouseID, movement.StockItem)
+		if err != nil {
+			return err
+		}
+		if warehouseStock < quantity {
+			return ErrInsufficientStock
+		}
+		return applyMovement(ctx, q, movement, stock-quantity, warehouseStock-quantity)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+func (r *inventoryRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM stock_reservations WHERE expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
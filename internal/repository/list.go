@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/pagination"
+	"suitemedia/pkg/query"
+)
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// sortKey is a field lists are ordered by, with the column or expression
+// it maps to. Cursor values are cast to typ when compared against it.
+type sortKey struct {
+	field  string
+	column string
+	desc   bool
+	typ    string
+}
+
+// listQuery describes a filtered, sorted list over one table. The sort
+// must end with a unique column so that keyset pagination is stable.
+type listQuery struct {
+	table   string
+	columns string
+	where   []string
+	args    []interface{}
+	sort    []sortKey
+	// sortSpec identifies the sort in cursors, so a cursor cannot be
+	// reused with a different order.
+	sortSpec string
+}
+
+// newListQuery creates a query over the rows of a soft-deleted table that
+// match the filters of params, in the requested order.
+func newListQuery(table, columns string, schema query.Schema, params models.ListParams) *listQuery {
+	q := newLedgerQuery(table, columns, schema, params)
+	q.where = append([]string{"deleted_at IS NULL"}, q.where...)
+	return q
+}
+
+// newLedgerQuery is newListQuery for append-only tables, whose rows are
+// never deleted.
+func newLedgerQuery(table, columns string, schema query.Schema, params models.ListParams) *listQuery {
+	q := &listQuery{table: table, columns: columns}
+	q.sort, q.sortSpec = listSort(schema, params.Sorts)
+	q.where = append(q.where, schema.Where(params.Filters, q.arg)...)
+	return q
+}
+
+// listSort builds the sort for the requested fields, newest first by
+// default. The ID is appended as a unique tiebreaker.
+func listSort(schema query.Schema, sorts []query.Sort) ([]sortKey, string) {
+	if len(sorts) == 0 {
+		sorts = []query.Sort{{Field: "created_at", Desc: true}}
+	}
+
+	keys := make([]sortKey, 0, len(sorts)+1)
+	for _, sort := range sorts {
+		if field, ok := schema.Field(sort.Field); ok {
+			keys = append(keys, sortKey{field: field.Name, column: field.Column, desc: sort.Desc, typ: field.SQLType()})
+		}
+	}
+	keys = append(keys, sortKey{field: "id", column: "id", desc: true, typ: "uuid"})
+	return keys, sortSpec(keys)
+}
+
+// sortSpec describes keys in the sort parameter syntax.
+func sortSpec(keys []sortKey) string {
+	spec := make([]string, len(keys))
+	for i, key := range keys {
+		spec[i] = key.field
+		if key.desc {
+			spec[i] = "-" + key.field
+		}
+	}
+	return strings.Join(spec, ",")
+}
+
+// withoutFilters returns params without the filters on fields.
+func withoutFilters(params models.ListParams, fields ...string) models.ListParams {
+	filters := make([]query.Filter, 0, len(params.Filters))
+	for _, filter := range params.Filters {
+		if !slices.Contains(fields, filter.Field) {
+			filters = append(filters, filter)
+		}
+	}
+	params.Filters = filters
+	return params
+}
+
+// arg adds a query argument and returns its placeholder.
+func (q *listQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *listQuery) whereClause() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.where, " AND ")
+}
+
+func (q *listQuery) orderBy(reverse bool) string {
+	terms := make([]string, len(q.sort))
+	for i, key := range q.sort {
+		direction := "ASC"
+		if key.desc != reverse {
+			direction = "DESC"
+		}
+		terms[i] = key.column + " " + direction
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// keysetCondition matches rows after values in sort order, or before them
+// when backward, expanded as (a > x) OR (a = x AND b > y) OR ... so that
+// columns may be sorted in different directions.
+func (q *listQuery) keysetCondition(values []interface{}, backward bool) string {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = q.arg(value) + "::" + q.sort[i].typ
+	}
+
+	disjuncts := make([]string, len(q.sort))
+	for i, key := range q.sort {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, q.sort[j].column+" = "+placeholders[j])
+		}
+		op := ">"
+		if key.desc != backward {
+			op = "<"
+		}
+		parts = append(parts, key.column+" "+op+" "+placeholders[i])
+		disjuncts[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+	return "(" + strings.Join(disjuncts, " OR ") + ")"
+}
+
+// listPage runs q for the page or cursor position in params. sortValue
+// returns the value of a sort field of an item, for building cursors.
+func listPage[T any](ctx context.Context, db *sql.DB, q *listQuery, params models.ListParams, scan func(rowScanner) (T, error), sortValue func(item T, field string) interface{}) (*models.ListResult[T], error) {
+	result := &models.ListResult[T]{Items: make([]T, 0)}
+	keyOf := func(item T) []interface{} {
+		values := make([]interface{}, len(q.sort))
+		for i, key := range q.sort {
+			values[i] = sortValue(item, key.field)
+		}
+		return values
+	}
+
+	var err error
+	result.Total, result.Estimated, err = countRows(ctx, db, params.Count, q.table+q.whereClause(), q.args)
+	if err != nil {
+		return nil, err
+	}
+
+	after := params.After
+	backward := after != nil && after.Backward
+	if params.Page < 1 && after != nil {
+		if after.Sort != q.sortSpec || len(after.Values) != len(q.sort) {
+			return nil, ErrInvalidCursor
+		}
+		q.where = append(q.where, q.keysetCondition(after.Values, backward))
+	}
+
+	query := `SELECT ` + q.columns + ` FROM ` + q.table + q.whereClause() + q.orderBy(backward)
+	if params.Page >= 1 {
+		query += fmt.Sprintf(` LIMIT %s OFFSET %s`, q.arg(params.Limit), q.arg((params.Page-1)*params.Limit))
+	} else {
+		// Fetch one extra row to learn whether another page follows.
+		query += ` LIMIT ` + q.arg(params.Limit+1)
+	}
+
+	rows, err := db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if params.Page >= 1 {
+		return result, nil
+	}
+
+	more := len(result.Items) > params.Limit
+	if more {
+		result.Items = result.Items[:params.Limit]
+	}
+	if backward {
+		for i, j := 0, len(result.Items)-1; i < j; i, j = i+1, j-1 {
+			result.Items[i], result.Items[j] = result.Items[j], result.Items[i]
+		}
+	}
+
+	if n := len(result.Items); n > 0 {
+		if more && !backward || backward {
+			result.Next = pagination.NewCursor(q.sortSpec, false, keyOf(result.Items[n-1])...)
+		}
+		if more && backward || !backward && after != nil {
+			result.Prev = pagination.NewCursor(q.sortSpec, true, keyOf(result.Items[0])...)
+		}
+	}
+
+	return result, nil
+}
+
+// countRows counts the rows of from (a table and WHERE clause) according
+// to mode. Estimates come from the query planner and avoid scanning large
+// tables; mode "none" skips counting and returns -1.
+func countRows(ctx context.Context, db *sql.DB, mode, from string, args []interface{}) (int64, bool, error) {
+	switch mode {
+	case "none":
+		return -1, false, nil
+	case "estimate":
+		var raw []byte
+		if err := db.QueryRowContext(ctx, `EXPLAIN (FORMAT JSON) SELECT 1 FROM `+from, args...).Scan(&raw); err != nil {
+			return 0, false, err
+		}
+		var plans []struct {
+			Plan struct {
+				Rows float64 `json:"Plan Rows"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal(raw, &plans); err != nil || len(plans) == 0 {
+			return 0, false, fmt.Errorf("failed to parse query plan: %v", err)
+		}
+		return int64(plans[0].Plan.Rows), true, nil
+	}
+
+	var total int64
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+from, args...).Scan(&total)
+	return total, false, err
+}
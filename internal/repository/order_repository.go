@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/money"
+
+	"github.com/google/uuid"
+)
+
+type OrderRepository interface {
+	// Create inserts order with its items and the creation in its history.
+	Create(ctx context.Context, order *models.Order) error
+	// GetByID returns the order with its items and history.
+	GetByID(ctx context.Context, id string) (*models.Order, error)
+	// List returns a page of orders with their items, only those of userID
+	// when it is not empty.
+	List(ctx context.Context, userID string, params models.ListParams) (*models.ListResult[*models.Order], error)
+	// UpdateStatus saves the status of order if its Version still matches
+	// the stored version, increments it and records change in the history.
+	UpdateStatus(ctx context.Context, order *models.Order, change *models.OrderStatusChange) error
+	// ListExpired returns up to limit pending orders whose stock reservations
+	// have expired.
+	ListExpired(ctx context.Context, limit int) ([]*models.Order, error)
+	// HasOrders reports whether userID has placed an order that was not
+	// cancelled.
+	HasOrders(ctx context.Context, userID string) (bool, error)
+}
+
+type orderRepository struct {
+	db *sql.DB
+}
+
+func NewOrderRepository(db *sql.DB) OrderRepository {
+	return &orderRepository{db: db}
+}
+
+// orderColumns include the items, discounts and taxes of the order as
+// JSON, so that lists need no query per order.
+const orderColumns = `
+	id, user_id, status, item_count, subtotal, discount_total, shipping, tax_total, prices_include_tax, total, currency,
+	COALESCE(tax_country, ''), COALESCE(tax_region, ''), COALESCE(note, ''), expires_at, version, created_at, updated_at,
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'id', i.id, 'product_id', i.product_id, 'variant_id', i.variant_id, 'warehouse_id', i.warehouse_id,
+			'reservation_id', i.reservation_id, 'sku', COALESCE(i.sku, ''), 'name', i.name,
+			'unit_price', i.unit_price, 'quantity', i.quantity, 'discount', i.discount, 'tax', i.tax, 'total', i.total
+		) ORDER BY i.position)
+		FROM order_items i WHERE i.order_id = orders.id
+	), '[]'),
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'promotion_id', d.promotion_id, 'code', COALESCE(d.code, ''), 'name', d.name, 'kind', d.kind, 'amount', d.amount
+		) ORDER BY d.position)
+		FROM order_discounts d WHERE d.order_id = orders.id
+	), '[]'),
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'country', t.country, 'region', COALESCE(t.region, ''), 'class', COALESCE(t.class, ''), 'rate', t.rate,
+			'taxable', t.taxable, 'amount', t.amount
+		) ORDER BY t.position)
+		FROM order_taxes t WHERE t.order_id = orders.id
+	), '[]')
+`
+
+// orderItemJSON is an item in the items column of orderColumns.
+type orderItemJSON struct {
+	ID            uuid.UUID  `json:"id"`
+	ProductID     uuid.UUID  `json:"product_id"`
+	VariantID     *uuid.UUID `json:"variant_id"`
+	WarehouseID   uuid.UUID  `json:"warehouse_id"`
+	ReservationID *uuid.UUID `json:"reservation_id"`
+	SKU           string     `json:"sku"`
+	Name          string     `json:"name"`
+	UnitPrice     int64      `json:"unit_price"`
+	Quantity      int        `json:"quantity"`
+	Discount      int64      `json:"discount"`
+	Tax           int64      `json:"tax"`
+	Total         int64      `json:"total"`
+}
+
+// orderDiscountJSON is a discount in the discounts column of orderColumns.
+type orderDiscountJSON struct {
+	PromotionID uuid.UUID `json:"promotion_id"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Amount      int64     `json:"amount"`
+}
+
+// orderTaxJSON is a tax line in the taxes column of orderColumns.
+type orderTaxJSON struct {
+	Country string `json:"country"`
+	Region  string `json:"region"`
+	Class   string `json:"class"`
+	Rate    string `json:"rate"`
+	Taxable int64  `json:"taxable"`
+	Amount  int64  `json:"amount"`
+}
+
+func scanOrder(row rowScanner) (*models.Order, error) {
+	order := &models.Order{}
+	var currency string
+	var items, discounts, taxes []byte
+	err := row.Scan(
+		&order.ID, &order.UserID, &order.Status, &order.ItemCount, &order.Subtotal.Amount, &order.Discount.Amount,
+		&order.Shipping.Amount, &order.Tax.Amount, &order.PricesIncludeTax, &order.Total.Amount, &currency,
+		&order.Country, &order.Region, &order.Note, &order.ExpiresAt, &order.Version, &order.CreatedAt, &order.UpdatedAt,
+		&items, &discounts, &taxes,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range []*money.Money{&order.Subtotal, &order.Discount, &order.Shipping, &order.Tax, &order.Total} {
+		m.Currency = currency
+	}
+
+	var raw []orderItemJSON
+	if err := json.Unmarshal(items, &raw); err != nil {
+		return nil, err
+	}
+	order.Items = make([]*models.OrderItem, len(raw))
+	for i, item := range raw {
+		order.Items[i] = &models.OrderItem{
+			ID:            item.ID,
+			ProductID:     item.ProductID,
+			VariantID:     item.VariantID,
+			WarehouseID:   item.WarehouseID,
+			ReservationID: item.ReservationID,
+			SKU:           item.SKU,
+			Name:          item.Name,
+			UnitPrice:     money.New(item.UnitPrice, currency),
+			Quantity:      item.Quantity,
+			Discount:      money.New(item.Discount, currency),
+			Tax:           money.New(item.Tax, currency),
+			Total:         money.New(item.Total, currency),
+		}
+	}
+
+	var rawDiscounts []orderDiscountJSON
+	if err := json.Unmarshal(discounts, &rawDiscounts); err != nil {
+		return nil, err
+	}
+	order.Discounts = make([]*models.AppliedDiscount, len(rawDiscounts))
+	for i, discount := range rawDiscounts {
+		order.Discounts[i] = &models.AppliedDiscount{
+			PromotionID: discount.PromotionID,
+			Code:        discount.Code,
+			Name:        discount.Name,
+			Kind:        discount.Kind,
+			Amount:      money.New(discount.Amount, currency),
+		}
+	}
+
+	var rawTaxes []orderTaxJSON
+	if err := json.Unmarshal(taxes, &rawTaxes); err != nil {
+		return nil, err
+	}
+	order.Taxes = make([]*models.TaxLine, len(rawTaxes))
+	for i, line := range rawTaxes {
+		order.Taxes[i] = &models.TaxLine{
+			Country: line.Country,
+			Region:  line.Region,
+			Class:   line.Class,
+			Rate:    line.Rate,
+			Taxable: money.New(line.Taxable, currency),
+			Amount:  money.New(line.Amount, currency),
+		}
+	}
+	return order, nil
+}
+
+func orderSortValue(order *models.Order, field string) interface{} {
+	switch field {
+	case "total":
+		return order.Total.Amount
+	case "created_at":
+		return order.CreatedAt
+	case "updated_at":
+		return order.UpdatedAt
+	}
+	return order.ID
+}
+
+// recordStatusChange appends change to the history of an order.
+func recordStatusChange(ctx context.Context, q dbtx, orderID uuid.UUID, change *models.OrderStatusChange) error {
+	return q.QueryRowContext(ctx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, note, changed_by)
+		VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), $5)
+		RETURNING created_at
+	`, orderID, change.From, change.To, change.Note, change.ChangedBy).Scan(&change.CreatedAt)
+}
+
+func (r *orderRepository) Create(ctx context.Context, order *models.Order) error {
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		err := q.QueryRowContext(ctx, `
+			INSERT INTO orders (
+				id, user_id, status, item_count, subtotal, discount_total, shipping, tax_total, prices_include_tax, total,
+				currency, tax_country, tax_region, note, expires_at
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''), $15)
+			RETURNING version, created_at, updated_at
+		`, order.ID, order.UserID, order.Status, order.ItemCount, order.Subtotal.Amount, order.Discount.Amount,
+			order.Shipping.Amount, order.Tax.Amount, order.PricesIncludeTax, order.Total.Amount, order.Subtotal.Currency,
+			order.Country, order.Region, order.Note, order.ExpiresAt,
+		).Scan(&order.Version, &order.CreatedAt, &order.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		for i, item := range order.Items {
+			_, err := q.ExecContext(ctx, `
+				INSERT INTO order_items (
+					id, order_id, product_id, variant_id, warehouse_id, reservation_id, sku, name,
+					unit_price, quantity, discount, tax, total, position
+				)
+				VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12, $13, $14)
+			`, item.ID, order.ID, item.ProductID, item.VariantID, item.WarehouseID, item.ReservationID, item.SKU, item.Name,
+				item.UnitPrice.Amount, item.Quantity, item.Discount.Amount, item.Tax.Amount, item.Total.Amount, i)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i, discount := range order.Discounts {
+			_, err := q.ExecContext(ctx, `
+				INSERT INTO order_discounts (order_id, position, promotion_id, code, name, kind, amount)
+				VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+			`, order.ID, i, discount.PromotionID, discount.Code, discount.Name, discount.Kind, discount.Amount.Amount)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i, line := range order.Taxes {
+			_, err := q.ExecContext(ctx, `
+				INSERT INTO order_taxes (order_id, position, country, region, class, rate, taxable, amount)
+				VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8)
+			`, order.ID, i, line.Country, line.Region, line.Class, line.Rate, line.Taxable.Amount, line.Amount.Amount)
+			if err != nil {
+				return err
+			}
+		}
+
+		change := &models.OrderStatusChange{To: order.Status, ChangedBy: &order.UserID}
+		if err := recordStatusChange(ctx, q, order.ID, change); err != nil {
+			return err
+		}
+		order.History = []*models.OrderStatusChange{change}
+		return nil
+	})
+}
+
+func (r *orderRepository) GetByID(ctx context.Context, id string) (*models.Order, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	q := conn(ctx, r.db)
+	order, err := scanOrder(q.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT COALESCE(from_status, ''), to_status, COALESCE(note, ''), changed_by, created_at
+		FROM order_status_history WHERE order_id = $1 ORDER BY id
+	`, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	order.History = make([]*models.OrderStatusChange, 0)
+	for rows.Next() {
+		change := &models.OrderStatusChange{}
+		if err := rows.Scan(&change.From, &change.To, &change.Note, &change.ChangedBy, &change.CreatedAt); err != nil {
+			return nil, err
+		}
+		order.History = append(order.History, change)
+	}
+
+	return order, rows.Err()
+}
+
+func (r *orderRepository) List(ctx context.Context, userID string, params models.ListParams) (*models.ListResult[*models.Order], error) {
+	q := newLedgerQuery("orders", orderColumns, models.OrderListFields, params)
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			return &models.ListResult[*models.Order]{Items: make([]*models.Order, 0)}, nil
+		}
+		q.where = append(q.where, "user_id = "+q.arg(userID))
+	}
+
+	return listPage(ctx, r.db, q, params, scanOrder, orderSortValue)
+}
+
+func (r *orderRepository) UpdateStatus(ctx context.Context, order *models.Order, change *models.OrderStatusChange) error {
+	query := `
+		UPDATE orders
+		SET status = $1, expires_at = CASE WHEN $1 = 'pending' THEN expires_at END,
+			version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND version = $3
+		RETURNING version, updated_at
+	`
+
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		err := q.QueryRowContext(ctx, query, order.Status, order.ID, order.Version).Scan(&order.Version, &order.UpdatedAt)
+		if err == sql.ErrNoRows {
+			var exists bool
+			if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`, order.ID).Scan(&exists); err != nil {
+				return err
+			}
+			if exists {
+				return ErrVersionConflict
+			}
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if order.Status != models.OrderPending {
+			order.ExpiresAt = nil
+		}
+
+		if err := recordStatusChange(ctx, q, order.ID, change); err != nil {
+			return err
+		}
+		order.History = append(order.History, change)
+		return nil
+	})
+}
+
+func (r *orderRepository) ListExpired(ctx context.Context, limit int) ([]*models.Order, error) {
+	query := `
+		SELECT ` + orderColumns + ` FROM orders
+		WHERE status = 'pending' AND expires_at <= CURRENT_TIMESTAMP
+		ORDER BY expires_at LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]*models.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+func (r *orderRepository) HasOrders(ctx context.Context, userID string) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM orders WHERE user_id = $1 AND status <> 'cancelled')
+	`, userID).Scan(&exists)
+	return exists, err
+}
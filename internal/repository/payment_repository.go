@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/payment"
+
+	"github.com/google/uuid"
+)
+
+type PaymentRepository interface {
+	Create(ctx context.Context, p *models.Payment) error
+	// GetByIntent returns the payment for an intent of provider. Within a
+	// transaction the payment stays locked until it ends, so that webhooks
+	// about the same intent are applied one at a time.
+	GetByIntent(ctx context.Context, provider, intentID string) (*models.Payment, error)
+	// ListByOrder returns the payments of an order, oldest first.
+	ListByOrder(ctx context.Context, orderID uuid.UUID) ([]*models.Payment, error)
+	// Update saves the status and failure reason of a payment.
+	Update(ctx context.Context, p *models.Payment) error
+	// RecordEvent records a webhook event of provider and reports whether
+	// it is new, false meaning it was handled before.
+	RecordEvent(ctx context.Context, provider string, event *payment.Event, payload []byte) (bool, error)
+}
+
+type paymentRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRepository(db *sql.DB) PaymentRepository {
+	return &paymentRepository{db: db}
+}
+
+const paymentColumns = `
+	id, order_id, provider, intent_id, amount, currency, status,
+	COALESCE(client_secret, ''), COALESCE(failure_reason, ''), created_at, updated_at
+`
+
+func scanPayment(row rowScanner) (*models.Payment, error) {
+	p := &models.Payment{}
+	err := row.Scan(
+		&p.ID, &p.OrderID, &p.Provider, &p.IntentID, &p.Amount.Amount, &p.Amount.Currency, &p.Status,
+		&p.ClientSecret, &p.FailureReason, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *paymentRepository) Create(ctx context.Context, p *models.Payment) error {
+	query := `
+		INSERT INTO payments (id, order_id, provider, intent_id, amount, currency, status, client_secret, failure_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''))
+		RETURNING created_at, updated_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		p.ID, p.OrderID, p.Provider, p.IntentID, p.Amount.Amount, p.Amount.Currency, p.Status,
+		p.ClientSecret, p.FailureReason,
+	).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *paymentRepository) GetByIntent(ctx context.Context, provider, intentID string) (*models.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE provider = $1 AND intent_id = $2`
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		query += ` FOR UPDATE`
+	}
+
+	p, err := scanPayment(conn(ctx, r.db).QueryRowContext(ctx, query, provider, intentID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return p, err
+}
+
+func (r *paymentRepository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]*models.Payment, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx,
+		`SELECT `+paymentColumns+` FROM payments WHERE order_id = $1 ORDER BY created_at, id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := make([]*models.Payment, 0)
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
+
+func (r *paymentRepository) Update(ctx context.Context, p *models.Payment) error {
+	query := `
+		UPDATE payments SET status = $1, failure_reason = NULLIF($2, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+		RETURNING updated_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, p.Status, p.FailureReason, p.ID).Scan(&p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *paymentRepository) RecordEvent(ctx context.Context, provider string, event *payment.Event, payload []byte) (bool, error) {
+	query := `
+		INSERT INTO payment_events (provider, event_id, type, intent_id, payload)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, event_id) DO NOTHING
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, provider, event.ID, event.Type, event.IntentID, payload)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	return inserted == 1, err
+}
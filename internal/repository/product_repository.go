@@ -3,16 +3,43 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"suitemedia/internal/models"
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/query"
+	"suitemedia/pkg/suggest"
+
+	"github.com/google/uuid"
 )
 
 type ProductRepository interface {
+	// Create inserts product together with its explicit prices. Products
+	// start without stock, which only changes through inventory movements.
 	Create(ctx context.Context, product *models.Product) error
 	GetByID(ctx context.Context, id string) (*models.Product, error)
-	List(ctx context.Context, params models.ListParams) ([]*models.Product, int64, error)
+	List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Product], error)
+	// Facets counts the products matching params by category, by price
+	// bucket starting at each of priceBuckets (in ascending order) and by
+	// availability.
+	Facets(ctx context.Context, params models.ListParams, priceBuckets []int64) (*models.ProductFacets, error)
+	// Suggest returns product names and categories starting with or
+	// similar to q, most similar first.
+	Suggest(ctx context.Context, q string, limit int) ([]suggest.Suggestion, error)
+	// SuggestionTerms returns every distinct product name and category.
+	SuggestionTerms(ctx context.Context) ([]suggest.Term, error)
+	// TermInUse reports whether a product still has the name or category.
+	TermInUse(ctx context.Context, term suggest.Term) (bool, error)
+	// Update saves product, replacing its explicit prices but not its
+	// stock, if its Version still matches the stored version and
+	// increments it.
 	Update(ctx context.Context, product *models.Product) error
-	Delete(ctx context.Context, id string) error
+	// Delete soft-deletes the product. A non-zero version must match the
+	// stored version.
+	Delete(ctx context.Context, id string, version int) error
 }
 
 type productRepository struct {
@@ -23,27 +50,414 @@ func NewProductRepository(db *sql.DB) ProductRepository {
 	return &productRepository{db: db}
 }
 
+// productColumns include the explicit prices of the product and a summary
+// of its variants as JSON, so that lists need no query per product.
+const productColumns = `
+	id, name, COALESCE(description, ''), price, currency, stock, COALESCE(category, ''), category_id,
+	tax_class, COALESCE(image_url, ''), is_active, created_by, version, created_at, updated_at, options,
+	COALESCE((
+		SELECT json_agg(json_build_object('amount', pp.amount, 'currency', pp.currency) ORDER BY pp.currency)
+		FROM product_prices pp WHERE pp.product_id = products.id
+	), '[]'),
+	(
+		SELECT json_build_object(
+			'count', COUNT(*), 'stock', COALESCE(SUM(v.stock), 0),
+			'min_price', MIN(COALESCE(v.price, products.price)), 'max_price', MAX(COALESCE(v.price, products.price))
+		)
+		FROM product_variants v WHERE v.product_id = products.id AND v.deleted_at IS NULL
+	)
+`
+
+// productJSON holds the columns of productColumns returned as JSON.
+type productJSON struct {
+	options, prices, variants []byte
+}
+
+// productFields returns the scan destinations of productColumns.
+func productFields(product *models.Product, raw *productJSON) []interface{} {
+	return []interface{}{
+		&product.ID, &product.Name, &product.Description, &product.Price.Amount, &product.Price.Currency, &product.Stock,
+		&product.Category, &product.CategoryID, &product.TaxClass, &product.ImageURL, &product.IsActive, &product.CreatedBy,
+		&product.Version,
+		&product.CreatedAt, &product.UpdatedAt, &raw.options, &raw.prices, &raw.variants,
+	}
+}
+
+func (raw *productJSON) decode(product *models.Product) error {
+	if err := json.Unmarshal(raw.options, &product.Options); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw.prices, &product.Prices); err != nil {
+		return err
+	}
+
+	var variants struct {
+		Count    int   `json:"count"`
+		Stock    int64 `json:"stock"`
+		MinPrice int64 `json:"min_price"`
+		MaxPrice int64 `json:"max_price"`
+	}
+	if err := json.Unmarshal(raw.variants, &variants); err != nil {
+		return err
+	}
+	if variants.Count > 0 {
+		product.Variants = &models.VariantSummary{
+			Count:    variants.Count,
+			Stock:    variants.Stock,
+			MinPrice: money.New(variants.MinPrice, product.Price.Currency),
+			MaxPrice: money.New(variants.MaxPrice, product.Price.Currency),
+		}
+	}
+	return nil
+}
+
+func scanProduct(row rowScanner) (*models.Product, error) {
+	product := &models.Product{}
+	var raw productJSON
+	if err := row.Scan(productFields(product, &raw)...); err != nil {
+		return nil, err
+	}
+	return product, raw.decode(product)
+}
+
 func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
-	// Implementation placeholder
+	query := `
+		INSERT INTO products (id, name, description, price, currency, category, category_id, tax_class, image_url, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11)
+		RETURNING stock, version, created_at, updated_at
+	`
+
+	product.ID = uuid.New()
+
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		err := conn(ctx, r.db).QueryRowContext(ctx, query,
+			product.ID, product.Name, product.Description, product.Price.Amount, product.Price.Currency,
+			product.Category, product.CategoryID, product.TaxClass, product.ImageURL, product.IsActive, product.CreatedBy,
+		).Scan(&product.Stock, &product.Version, &product.CreatedAt, &product.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return replacePrices(ctx, conn(ctx, r.db), product.ID, product.Prices)
+	})
+}
+
+// replacePrices replaces the explicit prices of a product.
+func replacePrices(ctx context.Context, tx dbtx, productID uuid.UUID, prices []money.Money) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_prices WHERE product_id = $1`, productID); err != nil {
+		return err
+	}
+	for _, price := range prices {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO product_prices (product_id, currency, amount) VALUES ($1, $2, $3)`,
+			productID, price.Currency, price.Amount,
+		)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// touchProduct bumps the version of a product after a change to its
+// variants, which are part of the product but not its row.
+func touchProduct(ctx context.Context, q dbtx, productID uuid.UUID) error {
+	_, err := q.ExecContext(ctx,
+		`UPDATE products SET version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, productID,
+	)
+	return err
+}
+
 func (r *productRepository) GetByID(ctx context.Context, id string) (*models.Product, error) {
-	// Implementation placeholder
-	return nil, nil
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	query := `SELECT ` + productColumns + ` FROM products WHERE id = $1 AND deleted_at IS NULL`
+
+	product, err := scanProduct(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return product, err
+}
+
+func (r *productRepository) List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Product], error) {
+	q, tsquery, relevance := newProductQuery(params)
+	if tsquery == "" {
+		return listPage(ctx, r.db, q, params, scanProduct, productSortValue)
+	}
+
+	q.columns += fmt.Sprintf(", %s, ts_headline('english', COALESCE(description, ''), %s, '%s')", relevance, tsquery, headlineOptions)
+	if len(params.Sorts) == 0 {
+		q.sort = []sortKey{
+			{field: "relevance", column: relevance, desc: true, typ: "real"},
+			{field: "id", column: "id", desc: true, typ: "uuid"},
+		}
+		q.sortSpec = sortSpec(q.sort)
+	}
+
+	return listPage(ctx, r.db, q, params, scanProductSearchResult, productSortValue)
 }
 
-func (r *productRepository) List(ctx context.Context, params models.ListParams) ([]*models.Product, int64, error) {
-	// Implementation placeholder
-	return nil, 0, nil
+// newProductQuery builds the query for the products matching the filters
+// and searches of params. For a full-text query it also returns the
+// tsquery and relevance expressions.
+func newProductQuery(params models.ListParams) (q *listQuery, tsquery, relevance string) {
+	q = newListQuery("products", productColumns, models.ProductListFields, params)
+	if params.Search != "" {
+		search := q.arg("%" + params.Search + "%")
+		q.where = append(q.where, fmt.Sprintf("(name ILIKE %[1]s OR description ILIKE %[1]s)", search))
+	}
+	if params.CategoryPath != "" {
+		q.where = append(q.where, fmt.Sprintf(
+			"category_id IN (SELECT id FROM categories WHERE path LIKE %s AND deleted_at IS NULL)", q.arg(params.CategoryPath+"%"),
+		))
+	}
+	if params.Query == "" {
+		return q, "", ""
+	}
+
+	// Full-text matches rank by ts_rank; names that only match through
+	// trigram similarity, typically typos, rank by similarity alone.
+	tsquery = searchQuery(params.Query, q.arg)
+	text := q.arg(params.Query)
+	relevance = fmt.Sprintf("(ts_rank(search_vector, %s) + similarity(name, %s))", tsquery, text)
+	q.where = append(q.where, fmt.Sprintf("(search_vector @@ %s OR name %% %s)", tsquery, text))
+
+	return q, tsquery, relevance
+}
+
+// maxCategoryFacets bounds the number of categories Facets returns.
+const maxCategoryFacets = 50
+
+func (r *productRepository) Facets(ctx context.Context, params models.ListParams, priceBuckets []int64) (*models.ProductFacets, error) {
+	facets := &models.ProductFacets{
+		Categories: make([]models.CategoryFacet, 0),
+		Prices:     make([]models.PriceBucket, len(priceBuckets)),
+	}
+
+	// Products are counted by category ID, so that categories sharing a
+	// name are kept apart and each facet can be selected with the
+	// category_id filter.
+	q, _, _ := newProductQuery(withoutFilters(params, "category", "category_id"))
+	query := `
+		SELECT f.category_id, COALESCE(c.name, ''), COALESCE(c.slug, ''), f.count
+		FROM (
+			SELECT category_id, COUNT(*) AS count FROM products` + q.whereClause() + `
+			GROUP BY category_id ORDER BY 2 DESC, 1 LIMIT ` + strconv.Itoa(maxCategoryFacets) + `
+		) f
+		LEFT JOIN categories c ON c.id = f.category_id
+		ORDER BY f.count DESC, 2`
+	rows, err := r.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var facet models.CategoryFacet
+		if err := rows.Scan(&facet.ID, &facet.Name, &facet.Slug, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets.Categories = append(facets.Categories, facet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(priceBuckets) > 0 {
+		for i, min := range priceBuckets {
+			facets.Prices[i].Min = min
+			if i+1 < len(priceBuckets) {
+				facets.Prices[i].Max = &priceBuckets[i+1]
+			}
+		}
+
+		// width_bucket returns the number of thresholds at or below the
+		// price, so bucket i counts prices from priceBuckets[i-1].
+		q, _, _ = newProductQuery(withoutFilters(params, "price"))
+		thresholds := make([]string, len(priceBuckets))
+		for i, min := range priceBuckets {
+			thresholds[i] = q.arg(min) + "::bigint"
+		}
+		query = fmt.Sprintf(`SELECT width_bucket(price, ARRAY[%s]), COUNT(*) FROM products%s GROUP BY 1`,
+			strings.Join(thresholds, ", "), q.whereClause())
+		rows, err := r.db.QueryContext(ctx, query, q.args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var bucket int
+			var count int64
+			if err := rows.Scan(&bucket, &count); err != nil {
+				return nil, err
+			}
+			if bucket > 0 {
+				facets.Prices[bucket-1].Count = count
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	q, _, _ = newProductQuery(withoutFilters(params, "stock"))
+	query = fmt.Sprintf(`SELECT COUNT(*) FILTER (WHERE %[1]s > 0), COUNT(*) FILTER (WHERE %[1]s <= 0) FROM products%[2]s`,
+		models.ProductStockColumn, q.whereClause())
+	if err := r.db.QueryRowContext(ctx, query, q.args...).Scan(&facets.Availability.InStock, &facets.Availability.OutOfStock); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+func (r *productRepository) Suggest(ctx context.Context, q string, limit int) ([]suggest.Suggestion, error) {
+	prefix := query.EscapeLike(q) + "%"
+	query := `
+		SELECT text, kind FROM (
+			SELECT name AS text, 'product' AS kind, similarity(name, $1) AS score
+			FROM products
+			WHERE deleted_at IS NULL AND (name ILIKE $2 OR name % $1)
+			UNION ALL
+			SELECT category, 'category', similarity(category, $1)
+			FROM products
+			WHERE deleted_at IS NULL AND (category ILIKE $2 OR category % $1)
+		) matches
+		GROUP BY text, kind
+		ORDER BY MAX(score) DESC, text
+		LIMIT $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, q, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := make([]suggest.Suggestion, 0, limit)
+	for rows.Next() {
+		var suggestion suggest.Suggestion
+		if err := rows.Scan(&suggestion.Text, &suggestion.Kind); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions, rows.Err()
+}
+
+func (r *productRepository) SuggestionTerms(ctx context.Context) ([]suggest.Term, error) {
+	query := `
+		SELECT DISTINCT name, 'product' FROM products WHERE deleted_at IS NULL
+		UNION
+		SELECT DISTINCT category, 'category' FROM products WHERE deleted_at IS NULL AND category <> ''
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := make([]suggest.Term, 0)
+	for rows.Next() {
+		var term suggest.Term
+		if err := rows.Scan(&term.Text, &term.Kind); err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+
+	return terms, rows.Err()
+}
+
+func (r *productRepository) TermInUse(ctx context.Context, term suggest.Term) (bool, error) {
+	column := "name"
+	if term.Kind == suggest.KindCategory {
+		column = "category"
+	}
+
+	var inUse bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM products WHERE `+column+` = $1 AND deleted_at IS NULL)`, term.Text,
+	).Scan(&inUse)
+	return inUse, err
+}
+
+func scanProductSearchResult(row rowScanner) (*models.Product, error) {
+	product := &models.Product{}
+	var raw productJSON
+	if err := row.Scan(append(productFields(product, &raw), &product.Relevance, &product.Headline)...); err != nil {
+		return nil, err
+	}
+	return product, raw.decode(product)
+}
+
+func productSortValue(product *models.Product, field string) interface{} {
+	switch field {
+	case "name":
+		return product.Name
+	case "category":
+		return product.Category
+	case "price":
+		return product.Price.Amount
+	case "stock":
+		if product.Variants != nil {
+			return product.Variants.Stock
+		}
+		return product.Stock
+	case "created_at":
+		return product.CreatedAt
+	case "updated_at":
+		return product.UpdatedAt
+	case "relevance":
+		return product.Relevance
+	}
+	return product.ID
 }
 
 func (r *productRepository) Update(ctx context.Context, product *models.Product) error {
-	// Implementation placeholder
-	return nil
+	query := `
+		UPDATE products
+		SET name = $1, description = $2, price = $3, currency = $4, category = $5, category_id = $6,
+			tax_class = $7, image_url = NULLIF($8, ''), is_active = $9, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 AND version = $11 AND deleted_at IS NULL
+		RETURNING version, updated_at
+	`
+
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		err := conn(ctx, r.db).QueryRowContext(ctx, query,
+			product.Name, product.Description, product.Price.Amount, product.Price.Currency, product.Category,
+			product.CategoryID, product.TaxClass, product.ImageURL, product.IsActive, product.ID, product.Version,
+		).Scan(&product.Version, &product.UpdatedAt)
+		if err == sql.ErrNoRows {
+			return notFoundOrConflict(ctx, r.db, "products", product.ID)
+		}
+		if err != nil {
+			return err
+		}
+		return replacePrices(ctx, conn(ctx, r.db), product.ID, product.Prices)
+	})
 }
 
-func (r *productRepository) Delete(ctx context.Context, id string) error {
-	// Implementation placeholder
+func (r *productRepository) Delete(ctx context.Context, id string, version int) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+
+	query := `
+		UPDATE products SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return notFoundOrConflict(ctx, r.db, "products", id)
+	}
+
 	return nil
 }
@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/money"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type PromotionRepository interface {
+	// Create inserts promotion, failing with ErrDuplicate when its code is
+	// taken.
+	Create(ctx context.Context, promotion *models.Promotion) error
+	GetByID(ctx context.Context, id string) (*models.Promotion, error)
+	// GetByCode returns the promotion with code, regardless of case.
+	GetByCode(ctx context.Context, code string) (*models.Promotion, error)
+	List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Promotion], error)
+	// ListApplicable returns the active promotions without a code, which
+	// apply automatically, and the active promotions with one of codes.
+	ListApplicable(ctx context.Context, codes []string) ([]*models.Promotion, error)
+	// Update saves promotion if its Version still matches the stored
+	// version and increments it.
+	Update(ctx context.Context, promotion *models.Promotion) error
+	// Delete soft-deletes the promotion. A non-zero version must match the
+	// stored version.
+	Delete(ctx context.Context, id string, version int) error
+	// CountRedemptions returns how often userID redeemed each of
+	// promotionIDs, leaving out those never redeemed.
+	CountRedemptions(ctx context.Context, userID uuid.UUID, promotionIDs []uuid.UUID) (map[uuid.UUID]int, error)
+	// Redeem records that orderID of userID redeemed amount of a promotion,
+	// failing with ErrLimitReached when that would exceed its usage limit
+	// or its limit per user. The promotion stays locked until the
+	// transaction ends, so concurrent redemptions cannot both take the
+	// last use.
+	Redeem(ctx context.Context, promotionID, userID, orderID uuid.UUID, amount int64) error
+	// ReleaseOrder deletes the redemptions of an order, giving the uses
+	// back.
+	ReleaseOrder(ctx context.Context, orderID uuid.UUID) error
+}
+
+type promotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) PromotionRepository {
+	return &promotionRepository{db: db}
+}
+
+const promotionColumns = `
+	id, COALESCE(code, ''), name, COALESCE(description, ''), kind, percent_off, amount_off, min_subtotal, COALESCE(currency, ''),
+	product_ids, category_ids, first_order_only, starts_at, ends_at, stackable, priority,
+	usage_limit, per_user_limit, times_used, is_active, version, created_at, updated_at
+`
+
+func scanPromotion(row rowScanner) (*models.Promotion, error) {
+	promotion := &models.Promotion{}
+	var amountOff, minSubtotal sql.NullInt64
+	var usageLimit, perUserLimit sql.NullInt32
+	var currency string
+	var productIDs, categoryIDs pq.StringArray
+	err := row.Scan(
+		&promotion.ID, &promotion.Code, &promotion.Name, &promotion.Description, &promotion.Kind, &promotion.PercentOff,
+		&amountOff, &minSubtotal, &currency, &productIDs, &categoryIDs, &promotion.FirstOrderOnly,
+		&promotion.StartsAt, &promotion.EndsAt, &promotion.Stackable, &promotion.Priority,
+		&usageLimit, &perUserLimit, &promotion.TimesUsed, &promotion.IsActive,
+		&promotion.Version, &promotion.CreatedAt, &promotion.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if amountOff.Valid {
+		amount := money.New(amountOff.Int64, currency)
+		promotion.AmountOff = &amount
+	}
+	if minSubtotal.Valid {
+		amount := money.New(minSubtotal.Int64, currency)
+		promotion.MinSubtotal = &amount
+	}
+	if usageLimit.Valid {
+		limit := int(usageLimit.Int32)
+		promotion.UsageLimit = &limit
+	}
+	if perUserLimit.Valid {
+		limit := int(perUserLimit.Int32)
+		promotion.PerUserLimit = &limit
+	}
+	if promotion.ProductIDs, err = parseUUIDs(productIDs); err != nil {
+		return nil, err
+	}
+	if promotion.CategoryIDs, err = parseUUIDs(categoryIDs); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+func parseUUIDs(values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, len(values))
+	for i, value := range values {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func uuidArray(ids []uuid.UUID) pq.StringArray {
+	values := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+	return values
+}
+
+// promotionArgs returns the columns of promotion written by Create and
+// Update, in the order both list them.
+func promotionArgs(promotion *models.Promotion) []interface{} {
+	var amountOff, minSubtotal sql.NullInt64
+	currency := ""
+	if promotion.AmountOff != nil {
+		amountOff = sql.NullInt64{Int64: promotion.AmountOff.Amount, Valid: true}
+		currency = promotion.AmountOff.Currency
+	}
+	if promotion.MinSubtotal != nil {
+		minSubtotal = sql.NullInt64{Int64: promotion.MinSubtotal.Amount, Valid: true}
+		currency = promotion.MinSubtotal.Currency
+	}
+
+	return []interface{}{
+		promotion.Code, promotion.Name, promotion.Description, promotion.Kind, promotion.PercentOff,
+		amountOff, minSubtotal, currency, uuidArray(promotion.ProductIDs), uuidArray(promotion.CategoryIDs),
+		promotion.FirstOrderOnly, promotion.StartsAt, promotion.EndsAt, promotion.Stackable, promotion.Priority,
+		promotion.UsageLimit, promotion.PerUserLimit, promotion.IsActive,
+	}
+}
+
+func (r *promotionRepository) Create(ctx context.Context, promotion *models.Promotion) error {
+	query := `
+		INSERT INTO promotions (
+			code, name, description, kind, percent_off, amount_off, min_subtotal, currency, product_ids, category_ids,
+			first_order_only, starts_at, ends_at, stackable, priority, usage_limit, per_user_limit, is_active, id
+		)
+		VALUES (
+			NULLIF($1, ''), $2, NULLIF($3, ''), $4, $5, $6, $7, NULLIF($8, ''), $9, $10,
+			$11, $12, $13, $14, $15, $16, $17, $18, $19
+		)
+		RETURNING version, created_at, updated_at
+	`
+
+	promotion.ID = uuid.New()
+	args := append(promotionArgs(promotion), promotion.ID)
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&promotion.Version, &promotion.CreatedAt, &promotion.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *promotionRepository) GetByID(ctx context.Context, id string) (*models.Promotion, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE id = $1 AND deleted_at IS NULL`
+
+	promotion, err := scanPromotion(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return promotion, err
+}
+
+func (r *promotionRepository) GetByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE UPPER(code) = UPPER($1) AND deleted_at IS NULL`
+
+	promotion, err := scanPromotion(conn(ctx, r.db).QueryRowContext(ctx, query, code))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return promotion, err
+}
+
+func (r *promotionRepository) List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Promotion], error) {
+	q := newListQuery("promotions", promotionColumns, models.PromotionListFields, params)
+	return listPage(ctx, r.db, q, params, scanPromotion, promotionSortValue)
+}
+
+func promotionSortValue(promotion *models.Promotion, field string) interface{} {
+	switch field {
+	case "code":
+		return promotion.Code
+	case "priority":
+		return promotion.Priority
+	case "times_used":
+		return promotion.TimesUsed
+	case "created_at":
+		return promotion.CreatedAt
+	}
+	return promotion.ID
+}
+
+func (r *promotionRepository) ListApplicable(ctx context.Context, codes []string) ([]*models.Promotion, error) {
+	query := `
+		SELECT ` + promotionColumns + ` FROM promotions
+		WHERE is_active AND deleted_at IS NULL AND (code IS NULL OR UPPER(code) = ANY($1))
+		ORDER BY priority, created_at, id
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.StringArray(codes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := make([]*models.Promotion, 0)
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	return promotions, rows.Err()
+}
+
+func (r *promotionRepository) Update(ctx context.Context, promotion *models.Promotion) error {
+	query := `
+		UPDATE promotions
+		SET code = NULLIF($1, ''), name = $2, description = NULLIF($3, ''), kind = $4, percent_off = $5,
+			amount_off = $6, min_subtotal = $7, currency = NULLIF($8, ''), product_ids = $9, category_ids = $10,
+			first_order_only = $11, starts_at = $12, ends_at = $13, stackable = $14, priority = $15,
+			usage_limit = $16, per_user_limit = $17, is_active = $18,
+			version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $19 AND version = $20 AND deleted_at IS NULL
+		RETURNING version, updated_at
+	`
+
+	args := append(promotionArgs(promotion), promotion.ID, promotion.Version)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&promotion.Version, &promotion.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return notFoundOrConflict(ctx, r.db, "promotions", promotion.ID)
+	}
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *promotionRepository) Delete(ctx context.Context, id string, version int) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+
+	query := `
+		UPDATE promotions SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return notFoundOrConflict(ctx, r.db, "promotions", id)
+	}
+
+	return nil
+}
+
+func (r *promotionRepository) CountRedemptions(ctx context.Context, userID uuid.UUID, promotionIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	counts := make(map[uuid.UUID]int)
+	if len(promotionIDs) == 0 {
+		return counts, nil
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT promotion_id, COUNT(*) FROM promotion_redemptions
+		WHERE user_id = $1 AND promotion_id = ANY($2::uuid[])
+		GROUP BY promotion_id
+	`, userID, uuidArray(promotionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func (r *promotionRepository) Redeem(ctx context.Context, promotionID, userID, orderID uuid.UUID, amount int64) error {
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+
+		// Taking a use locks the promotion, so the count per user below
+		// cannot change before the redemption is recorded.
+		var perUserLimit sql.NullInt32
+		err := q.QueryRowContext(ctx, `
+			UPDATE promotions SET times_used = times_used + 1
+			WHERE id = $1 AND (usage_limit IS NULL OR times_used < usage_limit)
+			RETURNING per_user_limit
+		`, promotionID).Scan(&perUserLimit)
+		if err == sql.ErrNoRows {
+			return ErrLimitReached
+		}
+		if err != nil {
+			return err
+		}
+
+		if perUserLimit.Valid {
+			var used int
+			err := q.QueryRowContext(ctx,
+				`SELECT COUNT(*) FROM promotion_redemptions WHERE promotion_id = $1 AND user_id = $2`,
+				promotionID, userID,
+			).Scan(&used)
+			if err != nil {
+				return err
+			}
+			if used >= int(perUserLimit.Int32) {
+				return ErrLimitReached
+			}
+		}
+
+		_, err = q.ExecContext(ctx, `
+			INSERT INTO promotion_redemptions (promotion_id, user_id, order_id, amount)
+			VALUES ($1, $2, $3, $4)
+		`, promotionID, userID, orderID, amount)
+		return err
+	})
+}
+
+func (r *promotionRepository) ReleaseOrder(ctx context.Context, orderID uuid.UUID) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		WITH released AS (
+			DELETE FROM promotion_redemptions WHERE order_id = $1 RETURNING promotion_id
+		)
+		UPDATE promotions p SET times_used = p.times_used - counts.count
+		FROM (SELECT promotion_id, COUNT(*) AS count FROM released GROUP BY promotion_id) counts
+		WHERE p.id = counts.promotion_id
+	`, orderID)
+	return err
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type RateRepository interface {
+	// List returns the exchange rates from the base currency by currency,
+	// as decimal strings, and when a rate last changed.
+	List(ctx context.Context) (map[string]string, *time.Time, error)
+	// Upsert adds or replaces the rates of the given currencies.
+	Upsert(ctx context.Context, rates map[string]string) error
+}
+
+type rateRepository struct {
+	db *sql.DB
+}
+
+func NewRateRepository(db *sql.DB) RateRepository {
+	return &rateRepository{db: db}
+}
+
+func (r *rateRepository) List(ctx context.Context) (map[string]string, *time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT currency, rate::text, updated_at FROM exchange_rates`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	rates := make(map[string]string)
+	var updatedAt *time.Time
+	for rows.Next() {
+		var currency, rate string
+		var changed time.Time
+		if err := rows.Scan(&currency, &rate, &changed); err != nil {
+			return nil, nil, err
+		}
+		rates[currency] = trimDecimal(rate)
+		if updatedAt == nil || changed.After(*updatedAt) {
+			updatedAt = &changed
+		}
+	}
+
+	return rates, updatedAt, rows.Err()
+}
+
+func (r *rateRepository) Upsert(ctx context.Context, rates map[string]string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for currency, rate := range rates {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO exchange_rates (currency, rate, updated_at) VALUES ($1, $2, CURRENT_TIMESTAMP)
+			ON CONFLICT (currency) DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at
+		`, currency, rate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// trimDecimal removes the trailing zeros NUMERIC pads rates with.
+func trimDecimal(value string) string {
+	if !strings.Contains(value, ".") {
+		return value
+	}
+	return strings.TrimSuffix(strings.TrimRight(value, "0"), ".")
+}
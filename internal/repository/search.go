@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// headlineOptions configure the excerpts ts_headline returns.
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=10, MaxFragments=2"
+
+// searchQuery returns a tsquery expression for a web search style query
+// (quoted phrases, "or", "-" to exclude). A trailing word that is still
+// being typed is matched as a prefix, so "wireless hea" finds "wireless
+// headphones".
+func searchQuery(q string, arg func(value interface{}) string) string {
+	head, last := splitLastWord(q)
+	if last == "" {
+		return fmt.Sprintf("websearch_to_tsquery('english', %s)", arg(q))
+	}
+	return fmt.Sprintf("(websearch_to_tsquery('english', %s) && to_tsquery('english', %s))", arg(head), arg(last+":*"))
+}
+
+// splitLastWord splits off the last word of q if q ends in it. Words that
+// are excluded with "-" or part of a quoted phrase are not split off.
+func splitLastWord(q string) (string, string) {
+	i := strings.LastIndexFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	start := 0
+	if i >= 0 {
+		_, size := utf8.DecodeRuneInString(q[i:])
+		start = i + size
+	}
+	head, last := q[:start], q[start:]
+
+	if last == "" || strings.HasSuffix(head, "-") || strings.Count(head, `"`)%2 == 1 {
+		return q, ""
+	}
+	return head, last
+}
@@ -0,0 +1,23 @@
+package repository
+
+import "testing"
+
+func TestSplitLastWord(t *testing.T) {
+	tests := []struct {
+		q, head, last string
+	}{
+		{"wireless hea", "wireless ", "hea"},
+		{"café", "", "café"},
+		{"wireless ", "wireless ", ""},
+		{"headphones -wir", "headphones -wir", ""},
+		{`"noise cancel`, `"noise cancel`, ""},
+		{`"noise cancelling" hea`, `"noise cancelling" `, "hea"},
+	}
+
+	for _, tt := range tests {
+		head, last := splitLastWord(tt.q)
+		if head != tt.head || last != tt.last {
+			t.Errorf("splitLastWord(%q): expected (%q, %q), got (%q, %q)", tt.q, tt.head, tt.last, head, last)
+		}
+	}
+}
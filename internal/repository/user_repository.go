@@ -14,9 +14,13 @@ type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	GetByID(ctx context.Context, id string) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
-	List(ctx context.Context, params models.ListParams) ([]*models.User, int64, error)
+	List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.User], error)
+	// Update saves user if its Version still matches the stored version
+	// and increments it.
 	Update(ctx context.Context, user *models.User) error
-	Delete(ctx context.Context, id string) error
+	// Delete soft-deletes the user. A non-zero version must match the
+	// stored version.
+	Delete(ctx context.Context, id string, version int) error
 }
 
 type userRepository struct {
@@ -31,21 +35,25 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
 		INSERT INTO users (id, email, password, first_name, last_name, role, is_active)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING created_at, updated_at
+		RETURNING version, created_at, updated_at
 	`
 
 	user.ID = uuid.New()
 
 	err := r.db.QueryRowContext(ctx, query,
 		user.ID, user.Email, user.Password, user.FirstName, user.LastName, user.Role, user.IsActive,
-	).Scan(&user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.Version, &user.CreatedAt, &user.UpdatedAt)
 
 	return err
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
 	query := `
-		SELECT id, email, password, first_name, last_name, role, is_active, created_at, updated_at, deleted_at
+		SELECT id, email, password, first_name, last_name, role, is_active, version, created_at, updated_at, deleted_at
 		FROM users
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -53,11 +61,11 @@ func (r *userRepository) GetByID(ctx context.Context, id string) (*models.User,
 	user := &models.User{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
-		&user.Role, &user.IsActive, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
+		&user.Role, &user.IsActive, &user.Version, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrNotFound
 	}
 
 	return user, err
@@ -65,7 +73,7 @@ func (r *userRepository) GetByID(ctx context.Context, id string) (*models.User,
 
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, password, first_name, last_name, role, is_active, created_at, updated_at, deleted_at
+		SELECT id, email, password, first_name, last_name, role, is_active, version, created_at, updated_at, deleted_at
 		FROM users
 		WHERE email = $1 AND deleted_at IS NULL
 	`
@@ -73,7 +81,7 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 	user := &models.User{}
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
-		&user.Role, &user.IsActive, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
+		&user.Role, &user.IsActive, &user.Version, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -83,78 +91,79 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return user, err
 }
 
-func (r *userRepository) List(ctx context.Context, params models.ListParams) ([]*models.User, int64, error) {
-	offset := (params.Page - 1) * params.Limit
-
-	// Count total
-	var total int64
-	countQuery := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
-	if params.Search != "" {
-		countQuery += ` AND (first_name ILIKE $1 OR last_name ILIKE $1 OR email ILIKE $1)`
-		r.db.QueryRowContext(ctx, countQuery, "%"+params.Search+"%").Scan(&total)
-	} else {
-		r.db.QueryRowContext(ctx, countQuery).Scan(&total)
-	}
-
-	// Get users
-	query := `
-		SELECT id, email, password, first_name, last_name, role, is_active, created_at, updated_at
-		FROM users
-		WHERE deleted_at IS NULL
-	`
-
+func (r *userRepository) List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.User], error) {
+	q := newListQuery("users", `id, email, password, first_name, last_name, role, is_active, version, created_at, updated_at`, models.UserListFields, params)
 	if params.Search != "" {
-		query += ` AND (first_name ILIKE $1 OR last_name ILIKE $1 OR email ILIKE $1)`
+		search := q.arg("%" + params.Search + "%")
+		q.where = append(q.where, fmt.Sprintf("(first_name ILIKE %[1]s OR last_name ILIKE %[1]s OR email ILIKE %[1]s)", search))
 	}
 
-	query += ` ORDER BY created_at DESC LIMIT $2 OFFSET $3`
-
-	var rows *sql.Rows
-	var err error
-
-	if params.Search != "" {
-		rows, err = r.db.QueryContext(ctx, query, "%"+params.Search+"%", params.Limit, offset)
-	} else {
-		rows, err = r.db.QueryContext(ctx, query, params.Limit, offset)
-	}
+	return listPage(ctx, r.db, q, params, scanListedUser, userSortValue)
+}
 
-	if err != nil {
-		return nil, 0, err
-	}
-	defer rows.Close()
-
-	users := make([]*models.User, 0)
-	for rows.Next() {
-		user := &models.User{}
-		err := rows.Scan(
-			&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
-			&user.Role, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
-		)
-		if err != nil {
-			return nil, 0, err
-		}
-		users = append(users, user)
+func userSortValue(user *models.User, field string) interface{} {
+	switch field {
+	case "email":
+		return user.Email
+	case "first_name":
+		return user.FirstName
+	case "last_name":
+		return user.LastName
+	case "role":
+		return user.Role
+	case "created_at":
+		return user.CreatedAt
+	case "updated_at":
+		return user.UpdatedAt
 	}
+	return user.ID
+}
 
-	return users, total, nil
+func scanListedUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
+		&user.Role, &user.IsActive, &user.Version, &user.CreatedAt, &user.UpdatedAt,
+	)
+	return user, err
 }
 
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users
-		SET first_name = $1, last_name = $2, role = $3, is_active = $4, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $5 AND deleted_at IS NULL
+		SET first_name = $1, last_name = $2, role = $3, is_active = $4,
+			version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND version = $6 AND deleted_at IS NULL
+		RETURNING version, updated_at
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
-		user.FirstName, user.LastName, user.Role, user.IsActive, user.ID,
-	)
+	err := r.db.QueryRowContext(ctx, query,
+		user.FirstName, user.LastName, user.Role, user.IsActive, user.ID, user.Version,
+	).Scan(&user.Version, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return notFoundOrConflict(ctx, r.db, "users", user.ID)
+	}
 
 	return err
 }
 
-func (r *userRepository) Delete(ctx context.Context, id string) error {
-	query := `UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err
+func (r *userRepository) Delete(ctx context.Context, id string, version int) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+
+	query := `
+		UPDATE users SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return notFoundOrConflict(ctx, r.db, "users", id)
+	}
+
+	return nil
 }
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/money"
+
+	"github.com/google/uuid"
+)
+
+type VariantRepository interface {
+	// Create inserts variant without stock, failing with ErrDuplicate when
+	// its SKU or option combination is taken.
+	Create(ctx context.Context, variant *models.Variant) error
+	GetByID(ctx context.Context, productID, id string) (*models.Variant, error)
+	// ListByProduct returns the variants of a product ordered by position
+	// and SKU.
+	ListByProduct(ctx context.Context, productID string) ([]*models.Variant, error)
+	// Update saves variant, except for its stock, if its Version still
+	// matches the stored version and increments it.
+	Update(ctx context.Context, variant *models.Variant) error
+	// Delete soft-deletes the variant. A non-zero version must match the
+	// stored version.
+	Delete(ctx context.Context, productID, id string, version int) error
+	// Generate sets the options of a product, creates the variants in
+	// create and deletes those in remove in one transaction.
+	Generate(ctx context.Context, productID uuid.UUID, options []models.ProductOption, create []*models.Variant, remove []uuid.UUID) error
+}
+
+type variantRepository struct {
+	db *sql.DB
+}
+
+func NewVariantRepository(db *sql.DB) VariantRepository {
+	return &variantRepository{db: db}
+}
+
+// variantColumns select a variant together with the currency of its
+// product, which its price is in.
+const variantColumns = `
+	v.id, v.product_id, v.sku, v.options, v.price, p.currency, v.stock, COALESCE(v.barcode, ''),
+	COALESCE(v.image_url, ''), v.position, v.version, v.created_at, v.updated_at
+	FROM product_variants v JOIN products p ON p.id = v.product_id
+`
+
+func scanVariant(row rowScanner) (*models.Variant, error) {
+	variant := &models.Variant{}
+	var options []byte
+	var price sql.NullInt64
+	var currency string
+	err := row.Scan(
+		&variant.ID, &variant.ProductID, &variant.SKU, &options, &price, &currency, &variant.Stock, &variant.Barcode,
+		&variant.ImageURL, &variant.Position, &variant.Version, &variant.CreatedAt, &variant.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if price.Valid {
+		override := money.New(price.Int64, currency)
+		variant.Price = &override
+	}
+	return variant, json.Unmarshal(options, &variant.Options)
+}
+
+func insertVariant(ctx context.Context, db dbtx, variant *models.Variant) error {
+	query := `
+		INSERT INTO product_variants (id, product_id, sku, options, price, barcode, image_url, position)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8)
+		RETURNING stock, version, created_at, updated_at
+	`
+
+	options, err := json.Marshal(variant.Options)
+	if err != nil {
+		return err
+	}
+	variant.ID = uuid.New()
+
+	err = db.QueryRowContext(ctx, query,
+		variant.ID, variant.ProductID, variant.SKU, options, variantPrice(variant),
+		variant.Barcode, variant.ImageURL, variant.Position,
+	).Scan(&variant.Stock, &variant.Version, &variant.CreatedAt, &variant.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// variantPrice returns the price override of variant as a column value.
+func variantPrice(variant *models.Variant) sql.NullInt64 {
+	if variant.Price == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: variant.Price.Amount, Valid: true}
+}
+
+func (r *variantRepository) Create(ctx context.Context, variant *models.Variant) error {
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		if err := insertVariant(ctx, tx, variant); err != nil {
+			return err
+		}
+		return touchProduct(ctx, tx, variant.ProductID)
+	})
+}
+
+func (r *variantRepository) GetByID(ctx context.Context, productID, id string) (*models.Variant, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, ErrNotFound
+	}
+
+	query := `SELECT ` + variantColumns + ` WHERE v.id = $1 AND v.product_id = $2 AND v.deleted_at IS NULL`
+
+	variant, err := scanVariant(conn(ctx, r.db).QueryRowContext(ctx, query, id, productID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+
+	return variant, err
+}
+
+func (r *variantRepository) ListByProduct(ctx context.Context, productID string) ([]*models.Variant, error) {
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, ErrNotFound
+	}
+
+	query := `SELECT ` + variantColumns + ` WHERE v.product_id = $1 AND v.deleted_at IS NULL ORDER BY v.position, v.sku`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := make([]*models.Variant, 0)
+	for rows.Next() {
+		variant, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+
+	return variants, rows.Err()
+}
+
+func (r *variantRepository) Update(ctx context.Context, variant *models.Variant) error {
+	query := `
+		UPDATE product_variants
+		SET sku = $1, price = $2, barcode = NULLIF($3, ''), image_url = NULLIF($4, ''), position = $5,
+			version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6 AND version = $7 AND deleted_at IS NULL
+		RETURNING version, updated_at
+	`
+
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		err := tx.QueryRowContext(ctx, query,
+			variant.SKU, variantPrice(variant), variant.Barcode, variant.ImageURL, variant.Position,
+			variant.ID, variant.Version,
+		).Scan(&variant.Version, &variant.UpdatedAt)
+		if err == sql.ErrNoRows {
+			return notFoundOrConflict(ctx, r.db, "product_variants", variant.ID)
+		}
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		if err != nil {
+			return err
+		}
+		return touchProduct(ctx, tx, variant.ProductID)
+	})
+}
+
+func (r *variantRepository) Delete(ctx context.Context, productID, id string, version int) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+	if _, err := uuid.Parse(productID); err != nil {
+		return ErrNotFound
+	}
+
+	query := `
+		UPDATE product_variants SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND product_id = $2 AND ($3 = 0 OR version = $3) AND deleted_at IS NULL
+	`
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		result, err := tx.ExecContext(ctx, query, id, productID, version)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return notFoundOrConflict(ctx, r.db, "product_variants", id)
+		}
+		return touchProduct(ctx, tx, uuid.MustParse(productID))
+	})
+}
+
+func (r *variantRepository) Generate(ctx context.Context, productID uuid.UUID, options []models.ProductOption, create []*models.Variant, remove []uuid.UUID) error {
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := conn(ctx, r.db)
+		result, err := tx.ExecContext(ctx, `
+			UPDATE products SET options = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2 AND deleted_at IS NULL
+		`, encoded, productID)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return ErrNotFound
+		}
+
+		// Variants are removed first so that their SKUs can be reused.
+		for _, id := range remove {
+			_, err := tx.ExecContext(ctx, `UPDATE product_variants SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+			if err != nil {
+				return err
+			}
+		}
+		for _, variant := range create {
+			if err := insertVariant(ctx, tx, variant); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"suitemedia/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type WarehouseRepository interface {
+	// Create inserts warehouse, failing with ErrDuplicate when its code is
+	// taken. A default warehouse replaces the previous default.
+	Create(ctx context.Context, warehouse *models.Warehouse) error
+	GetByID(ctx context.Context, id string) (*models.Warehouse, error)
+	// GetDefault returns the warehouse receiving movements that do not
+	// name one.
+	GetDefault(ctx context.Context) (*models.Warehouse, error)
+	// List returns every warehouse ordered by priority and code.
+	List(ctx context.Context) ([]*models.Warehouse, error)
+	// Update saves warehouse if its Version still matches the stored
+	// version and increments it. A default warehouse replaces the previous
+	// default.
+	Update(ctx context.Context, warehouse *models.Warehouse) error
+	// Delete soft-deletes the warehouse, failing with ErrInUse while it
+	// holds or reserves stock. A non-zero version must match the stored
+	// version.
+	Delete(ctx context.Context, id string, version int) error
+}
+
+type warehouseRepository struct {
+	db *sql.DB
+}
+
+func NewWarehouseRepository(db *sql.DB) WarehouseRepository {
+	return &warehouseRepository{db: db}
+}
+
+const warehouseColumns = `
+	id, code, name, COALESCE(address, ''), COALESCE(country, ''), latitude, longitude, priority, is_active, is_default,
+	version, created_at, updated_at
+`
+
+func scanWarehouse(row rowScanner) (*models.Warehouse, error) {
+	warehouse := &models.Warehouse{}
+	err := row.Scan(
+		&warehouse.ID, &warehouse.Code, &warehouse.Name, &warehouse.Address, &warehouse.Country,
+		&warehouse.Latitude, &warehouse.Longitude, &warehouse.Priority, &warehouse.IsActive, &warehouse.IsDefault,
+		&warehouse.Version, &warehouse.CreatedAt, &warehouse.UpdatedAt,
+	)
+	return warehouse, err
+}
+
+// clearDefault removes the default flag from warehouses other than id.
+func clearDefault(ctx context.Context, q dbtx, id uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `
+		UPDATE warehouses SET is_default = false, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE is_default AND id <> $1 AND deleted_at IS NULL
+	`, id)
+	return err
+}
+
+func (r *warehouseRepository) Create(ctx context.Context, warehouse *models.Warehouse) error {
+	query := `
+		INSERT INTO warehouses (id, code, name, address, country, latitude, longitude, priority, is_active, is_default)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9, $10)
+		RETURNING version, created_at, updated_at
+	`
+
+	warehouse.ID = uuid.New()
+
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		if warehouse.IsDefault {
+			if err := clearDefault(ctx, q, warehouse.ID); err != nil {
+				return err
+			}
+		}
+
+		err := q.QueryRowContext(ctx, query,
+			warehouse.ID, warehouse.Code, warehouse.Name, warehouse.Address, warehouse.Country,
+			warehouse.Latitude, warehouse.Longitude, warehouse.Priority, warehouse.IsActive, warehouse.IsDefault,
+		).Scan(&warehouse.Version, &warehouse.CreatedAt, &warehouse.UpdatedAt)
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	})
+}
+
+func (r *warehouseRepository) GetByID(ctx context.Context, id string) (*models.Warehouse, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses WHERE id = $1 AND deleted_at IS NULL`
+
+	warehouse, err := scanWarehouse(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return warehouse, err
+}
+
+func (r *warehouseRepository) GetDefault(ctx context.Context) (*models.Warehouse, error) {
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses WHERE is_default AND deleted_at IS NULL`
+
+	warehouse, err := scanWarehouse(conn(ctx, r.db).QueryRowContext(ctx, query))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return warehouse, err
+}
+
+func (r *warehouseRepository) List(ctx context.Context) ([]*models.Warehouse, error) {
+	query := `SELECT ` + warehouseColumns + ` FROM warehouses WHERE deleted_at IS NULL ORDER BY priority, code`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := make([]*models.Warehouse, 0)
+	for rows.Next() {
+		warehouse, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+
+	return warehouses, rows.Err()
+}
+
+func (r *warehouseRepository) Update(ctx context.Context, warehouse *models.Warehouse) error {
+	query := `
+		UPDATE warehouses
+		SET code = $1, name = $2, address = NULLIF($3, ''), country = NULLIF($4, ''), latitude = $5, longitude = $6,
+			priority = $7, is_active = $8, is_default = $9, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 AND version = $11 AND deleted_at IS NULL
+		RETURNING version, updated_at
+	`
+
+	return withinTx(ctx, r.db, func(ctx context.Context) error {
+		q := conn(ctx, r.db)
+		if warehouse.IsDefault {
+			if err := clearDefault(ctx, q, warehouse.ID); err != nil {
+				return err
+			}
+		}
+
+		err := q.QueryRowContext(ctx, query,
+			warehouse.Code, warehouse.Name, warehouse.Address, warehouse.Country, warehouse.Latitude, warehouse.Longitude,
+			warehouse.Priority, warehouse.IsActive, warehouse.IsDefault, warehouse.ID, warehouse.Version,
+		).Scan(&warehouse.Version, &warehouse.UpdatedAt)
+		if err == sql.ErrNoRows {
+			return notFoundOrConflict(ctx, r.db, "warehouses", warehouse.ID)
+		}
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	})
+}
+
+func (r *warehouseRepository) Delete(ctx context.Context, id string, version int) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrNotFound
+	}
+
+	var inUse bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM warehouse_stock WHERE warehouse_id = $1 AND stock > 0)
+			OR EXISTS (SELECT 1 FROM stock_reservations WHERE warehouse_id = $1 AND expires_at > CURRENT_TIMESTAMP)
+	`, id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrInUse
+	}
+
+	query := `
+		UPDATE warehouses SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2) AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return notFoundOrConflict(ctx, r.db, "warehouses", id)
+	}
+
+	return nil
+}
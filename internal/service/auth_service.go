@@ -8,6 +8,7 @@ import (
 	"suitemedia/config"
 	"suitemedia/internal/models"
 	"suitemedia/internal/repository"
+	"suitemedia/pkg/logger"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -28,12 +29,16 @@ type AuthService interface {
 type authService struct {
 	userRepo repository.UserRepository
 	jwtCfg   config.JWTConfig
+	carts    CartService
+	logger   *logger.Logger
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtCfg config.JWTConfig) AuthService {
+func NewAuthService(userRepo repository.UserRepository, jwtCfg config.JWTConfig, carts CartService, logger *logger.Logger) AuthService {
 	return &authService{
 		userRepo: userRepo,
 		jwtCfg:   jwtCfg,
+		carts:    carts,
+		logger:   logger,
 	}
 }
 
@@ -101,6 +106,12 @@ func (s *authService) Login(ctx context.Context, req models.LoginRequest) (*mode
 		return nil, ErrInvalidCredentials
 	}
 
+	// Carry the cart the user filled before logging in over. A failed
+	// merge leaves the guest cart in place and does not fail the login.
+	if err := s.carts.Merge(ctx, req.CartToken, user.ID.String()); err != nil {
+		s.logger.Warn("Failed to merge guest cart", "user_id", user.ID.String(), "error", err)
+	}
+
 	// Generate tokens
 	accessToken, err := s.generateAccessToken(user)
 	if err != nil {
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"suitemedia/config"
+	"suitemedia/pkg/cache"
+)
+
+// cacheOptions builds read-through cache options from configuration. A TTL
+// of zero disables caching for that family of keys.
+func cacheOptions(cfg config.CacheConfig, name string, ttlSeconds int, notFound error) cache.Options {
+	codec, err := cache.CodecByName(cfg.Codec)
+	if err != nil {
+		codec = cache.JSON
+	}
+
+	return cache.Options{
+		Name:        name,
+		TTL:         time.Duration(ttlSeconds) * time.Second,
+		Jitter:      float64(cfg.JitterPercent) / 100,
+		NotFound:    notFound,
+		NegativeTTL: time.Duration(cfg.NegativeTTLSeconds) * time.Second,
+		Codec:       codec,
+	}
+}
+
+// listCacheKey identifies a list page by the current list generation and a
+// hash of the query parameters.
+func listCacheKey(ctx context.Context, kv cache.Cache, generationKey string, params interface{}) string {
+	data, _ := json.Marshal(params)
+	sum := sha1.Sum(data)
+	return cache.Generation(ctx, kv, generationKey) + ":" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,635 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"suitemedia/config"
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+	"suitemedia/pkg/cache"
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/tax"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCartItemNotFound   = errors.New("cart item not found")
+	ErrCartFull           = errors.New("cart has the maximum number of items")
+	ErrCartQuantity       = errors.New("quantity exceeds the maximum per cart item")
+	ErrProductUnavailable = errors.New("product is not available")
+)
+
+const anonymousCartPrefix = "carts:anonymous:"
+
+// CartOwner identifies a cart: the cart of UserID when it is set, and the
+// anonymous cart of Token otherwise. An empty Token means the shopper has
+// no cart yet; saving one issues a token.
+type CartOwner struct {
+	UserID string
+	Token  string
+}
+
+type CartService interface {
+	// Get returns the cart of owner after revalidating it, or an empty cart
+	// when there is none.
+	Get(ctx context.Context, owner CartOwner) (*models.Cart, error)
+	AddItem(ctx context.Context, owner CartOwner, req models.AddCartItemRequest) (*models.Cart, error)
+	UpdateItem(ctx context.Context, owner CartOwner, itemID string, req models.UpdateCartItemRequest) (*models.Cart, error)
+	RemoveItem(ctx context.Context, owner CartOwner, itemID string) (*models.Cart, error)
+	Clear(ctx context.Context, owner CartOwner) error
+	// Take deletes the saved cart of userID when it is checked out,
+	// failing with ErrCartChanged when there is none, as after another
+	// checkout of the same cart.
+	Take(ctx context.Context, userID string) error
+	// ApplyPromotion adds a promotion code to the cart, failing with
+	// ErrPromotionNotFound for unknown or inactive codes and with
+	// ErrPromotionLimitReached for codes that are used up. Codes whose
+	// conditions the cart does not meet yet are kept, and the pricing of
+	// the cart says why they do not apply.
+	ApplyPromotion(ctx context.Context, owner CartOwner, req models.ApplyPromotionRequest) (*models.Cart, error)
+	// RemovePromotion removes a promotion code from the cart.
+	RemovePromotion(ctx context.Context, owner CartOwner, code string) (*models.Cart, error)
+	// Merge moves the anonymous cart of token into the cart of userID,
+	// adding up the quantities of items in both and keeping the promotion
+	// codes of both. An unknown or expired token is ignored.
+	Merge(ctx context.Context, token, userID string) error
+}
+
+type cartService struct {
+	cartRepo   repository.CartRepository
+	products   ProductService
+	variants   VariantService
+	currencies CurrencyService
+	pricing    PricingService
+	cache      cache.Cache
+	userCarts  *cache.Typed[*models.Cart]
+	cfg        config.CartConfig
+}
+
+func NewCartService(cartRepo repository.CartRepository, products ProductService, variants VariantService, currencies CurrencyService, pricing PricingService, kv cache.Cache, cacheCfg config.CacheConfig, cfg config.CartConfig) CartService {
+	return &cartService{
+		cartRepo:   cartRepo,
+		products:   products,
+		variants:   variants,
+		currencies: currencies,
+		pricing:    pricing,
+		cache:      kv,
+		userCarts:  cache.NewTyped[*models.Cart](kv, cacheOptions(cacheCfg, "carts", cacheCfg.TTLSeconds, nil)),
+		cfg:        cfg,
+	}
+}
+
+func (s *cartService) Get(ctx context.Context, owner CartOwner) (*models.Cart, error) {
+	cart, err := s.load(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.revalidate(ctx, cart); err != nil {
+		return nil, err
+	}
+	if len(cart.Issues) > 0 {
+		if err := s.save(ctx, owner, cart); err != nil {
+			return nil, err
+		}
+	}
+	return cart, nil
+}
+
+func (s *cartService) AddItem(ctx context.Context, owner CartOwner, req models.AddCartItemRequest) (*models.Cart, error) {
+	productID, err := uuid.Parse(req.ProductID)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	var variantID *uuid.UUID
+	if req.VariantID != "" {
+		id, err := uuid.Parse(req.VariantID)
+		if err != nil {
+			return nil, ErrVariantNotFound
+		}
+		variantID = &id
+	}
+
+	line, err := s.cartProduct(ctx, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.load(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	item := findCartItem(cart, productID, variantID)
+	quantity := req.Quantity
+	if item != nil {
+		quantity += item.Quantity
+	}
+	if err := s.checkQuantity(quantity, line); err != nil {
+		return nil, err
+	}
+
+	if item == nil {
+		if len(cart.Items) >= s.cfg.MaxItems {
+			return nil, ErrCartFull
+		}
+		item = &models.CartItem{
+			ID:        uuid.New(),
+			ProductID: productID,
+			VariantID: variantID,
+			AddedAt:   time.Now().UTC(),
+		}
+		cart.Items = append(cart.Items, item)
+	}
+	item.Quantity = quantity
+	item.UnitPrice = line.price
+
+	return s.update(ctx, owner, cart)
+}
+
+func (s *cartService) UpdateItem(ctx context.Context, owner CartOwner, itemID string, req models.UpdateCartItemRequest) (*models.Cart, error) {
+	cart, err := s.load(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	index := cartItemIndex(cart, itemID)
+	if index < 0 {
+		return nil, ErrCartItemNotFound
+	}
+	item := cart.Items[index]
+
+	line, err := s.cartProduct(ctx, item.ProductID, item.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkQuantity(req.Quantity, line); err != nil {
+		return nil, err
+	}
+	item.Quantity = req.Quantity
+	item.UnitPrice = line.price
+
+	return s.update(ctx, owner, cart)
+}
+
+func (s *cartService) RemoveItem(ctx context.Context, owner CartOwner, itemID string) (*models.Cart, error) {
+	cart, err := s.load(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	index := cartItemIndex(cart, itemID)
+	if index < 0 {
+		return nil, ErrCartItemNotFound
+	}
+	cart.Items = append(cart.Items[:index], cart.Items[index+1:]...)
+
+	return s.update(ctx, owner, cart)
+}
+
+func (s *cartService) ApplyPromotion(ctx context.Context, owner CartOwner, req models.ApplyPromotionRequest) (*models.Cart, error) {
+	var userID *uuid.UUID
+	if owner.UserID != "" {
+		id, err := uuid.Parse(owner.UserID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		userID = &id
+	}
+	p, err := s.pricing.Promotion(ctx, req.Code, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.load(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(cart.PromotionCodes, p.Code) {
+		cart.PromotionCodes = append(cart.PromotionCodes, p.Code)
+	}
+
+	return s.update(ctx, owner, cart)
+}
+
+func (s *cartService) RemovePromotion(ctx context.Context, owner CartOwner, code string) (*models.Cart, error) {
+	cart, err := s.load(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	index := slices.Index(cart.PromotionCodes, normalizePromotionCode(code))
+	if index < 0 {
+		return nil, ErrPromotionNotFound
+	}
+	cart.PromotionCodes = slices.Delete(cart.PromotionCodes, index, index+1)
+
+	return s.update(ctx, owner, cart)
+}
+
+func (s *cartService) Clear(ctx context.Context, owner CartOwner) error {
+	if owner.UserID == "" {
+		if owner.Token == "" {
+			return nil
+		}
+		return s.cache.Delete(ctx, anonymousCartKey(owner.Token))
+	}
+
+	userID, err := uuid.Parse(owner.UserID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if err := s.cartRepo.DeleteByUser(ctx, userID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	s.userCarts.Invalidate(ctx, userID.String())
+	return nil
+}
+
+func (s *cartService) Take(ctx context.Context, userID string) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	err = s.cartRepo.DeleteByUser(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrCartChanged
+	}
+	if err != nil {
+		return err
+	}
+	s.userCarts.Invalidate(ctx, userID)
+	return nil
+}
+
+func (s *cartService) Merge(ctx context.Context, token, userID string) error {
+	if token == "" {
+		return nil
+	}
+	anonymous, err := s.loadAnonymous(ctx, token)
+	if err != nil || (len(anonymous.Items) == 0 && len(anonymous.PromotionCodes) == 0) {
+		return err
+	}
+
+	owner := CartOwner{UserID: userID}
+	cart, err := s.load(ctx, owner)
+	if err != nil {
+		return err
+	}
+	mergeCartItems(cart, anonymous.Items, s.cfg.MaxItems, s.cfg.MaxQuantity)
+	for _, code := range anonymous.PromotionCodes {
+		if !slices.Contains(cart.PromotionCodes, code) {
+			cart.PromotionCodes = append(cart.PromotionCodes, code)
+		}
+	}
+
+	// Revalidation drops lines that are no longer available and reduces
+	// merged quantities to the stock.
+	if _, err := s.update(ctx, owner, cart); err != nil {
+		return err
+	}
+	return s.cache.Delete(ctx, anonymousCartKey(token))
+}
+
+// update revalidates and saves cart after a change.
+func (s *cartService) update(ctx context.Context, owner CartOwner, cart *models.Cart) (*models.Cart, error) {
+	if err := s.revalidate(ctx, cart); err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, owner, cart); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+func (s *cartService) checkQuantity(quantity int, line *cartProduct) error {
+	if quantity > s.cfg.MaxQuantity {
+		return ErrCartQuantity
+	}
+	if quantity > line.stock {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// load returns the stored cart of owner, or a new empty cart.
+func (s *cartService) load(ctx context.Context, owner CartOwner) (*models.Cart, error) {
+	if owner.UserID == "" {
+		if owner.Token == "" {
+			return newCart(nil), nil
+		}
+		return s.loadAnonymous(ctx, owner.Token)
+	}
+
+	userID, err := uuid.Parse(owner.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	cart, err := s.userCarts.Get(ctx, userID.String(), func(ctx context.Context) (*models.Cart, error) {
+		cart, err := s.cartRepo.GetByUser(ctx, userID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return newCart(&userID), nil
+		}
+		return cart, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The loaded cart may be shared with concurrent requests for the same
+	// user, and revalidation changes it.
+	return cloneCart(cart), nil
+}
+
+// loadAnonymous returns the anonymous cart of token, or a new empty cart
+// when it does not exist or has expired.
+func (s *cartService) loadAnonymous(ctx context.Context, token string) (*models.Cart, error) {
+	raw, err := s.cache.Get(ctx, anonymousCartKey(token))
+	if errors.Is(err, cache.ErrMiss) {
+		return newCart(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &models.Cart{}
+	if err := json.Unmarshal([]byte(raw), cart); err != nil {
+		return nil, err
+	}
+	if cart.Items == nil {
+		cart.Items = make([]*models.CartItem, 0)
+	}
+	if cart.PromotionCodes == nil {
+		cart.PromotionCodes = make([]string, 0)
+	}
+	return cart, nil
+}
+
+// save stores cart for owner. Saving the first anonymous cart of a shopper
+// issues a token, which is returned in cart.Token this once.
+func (s *cartService) save(ctx context.Context, owner CartOwner, cart *models.Cart) error {
+	if owner.UserID != "" {
+		if err := s.cartRepo.Save(ctx, cart); err != nil {
+			return err
+		}
+		s.userCarts.Invalidate(ctx, owner.UserID)
+		return nil
+	}
+
+	token := owner.Token
+	if token == "" {
+		var err error
+		if token, err = newCartToken(); err != nil {
+			return err
+		}
+		cart.Token = token
+	}
+
+	ttl := time.Duration(s.cfg.AnonymousTTLHours) * time.Hour
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl)
+	cart.UpdatedAt, cart.ExpiresAt = now, &expiresAt
+
+	stored := *cart
+	stored.Token, stored.Issues, stored.Pricing = "", nil, nil
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(ctx, anonymousCartKey(token), string(data), ttl)
+}
+
+// cartProduct is what a cart line shows of the product or variant it
+// refers to.
+type cartProduct struct {
+	name       string
+	sku        string
+	imageURL   string
+	categoryID *uuid.UUID
+	taxClass   string
+	price      money.Money
+	stock      int
+}
+
+// cartProduct looks up the product or variant of a cart line, failing when
+// it cannot be bought.
+func (s *cartService) cartProduct(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) (*cartProduct, error) {
+	product, err := s.products.GetByID(ctx, productID.String())
+	if err != nil {
+		return nil, err
+	}
+	if !product.IsActive {
+		return nil, ErrProductUnavailable
+	}
+
+	line := &cartProduct{
+		name:       product.Name,
+		imageURL:   product.ImageURL,
+		categoryID: product.CategoryID,
+		taxClass:   product.TaxClass,
+		price:      product.Price,
+		stock:      product.Stock,
+	}
+	if variantID == nil {
+		if product.Variants != nil {
+			return nil, ErrVariantRequired
+		}
+		return line, nil
+	}
+
+	variant, err := s.variants.Get(ctx, productID.String(), variantID.String())
+	if err != nil {
+		return nil, err
+	}
+	line.sku, line.stock = variant.SKU, variant.Stock
+	if variant.ImageURL != "" {
+		line.imageURL = variant.ImageURL
+	}
+	if variant.Price != nil {
+		line.price = *variant.Price
+	}
+	return line, nil
+}
+
+// revalidate checks every line of cart against the current products,
+// dropping, reducing or repricing lines as needed and recording why in
+// cart.Issues, then recomputes the totals and prices the cart.
+func (s *cartService) revalidate(ctx context.Context, cart *models.Cart) error {
+	cart.Issues = make([]models.CartIssue, 0)
+	items := make([]*models.CartItem, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		line, err := s.cartProduct(ctx, item.ProductID, item.VariantID)
+		switch {
+		case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrProductUnavailable),
+			errors.Is(err, ErrVariantNotFound), errors.Is(err, ErrVariantRequired):
+			cart.Issues = append(cart.Issues, cartIssue(item, models.CartIssueUnavailable))
+			continue
+		case err != nil:
+			return err
+		}
+
+		issues, keep := applyCartProduct(item, line)
+		cart.Issues = append(cart.Issues, issues...)
+		if keep {
+			items = append(items, item)
+		}
+	}
+	cart.Items = items
+
+	if err := cartTotals(cart, s.currencies.Base()); err != nil {
+		return err
+	}
+	pricing, err := s.pricing.Price(ctx, cart, tax.Address{})
+	if err != nil {
+		return err
+	}
+	cart.Pricing = pricing
+	return nil
+}
+
+// applyCartProduct brings item in line with the product it refers to. It
+// reports whether the item stays in the cart and the issues found.
+func applyCartProduct(item *models.CartItem, line *cartProduct) ([]models.CartIssue, bool) {
+	var issues []models.CartIssue
+	if line.stock <= 0 {
+		return append(issues, cartIssue(item, models.CartIssueOutOfStock)), false
+	}
+	if item.Quantity > line.stock {
+		issue := cartIssue(item, models.CartIssueQuantityReduced)
+		issue.PreviousQuantity = item.Quantity
+		issues = append(issues, issue)
+		item.Quantity = line.stock
+	}
+	if item.UnitPrice != line.price {
+		previous := item.UnitPrice
+		issue := cartIssue(item, models.CartIssuePriceChanged)
+		issue.PreviousPrice = &previous
+		issues = append(issues, issue)
+		item.UnitPrice = line.price
+	}
+
+	item.Name = line.name
+	item.SKU = line.sku
+	item.ImageURL = line.imageURL
+	item.CategoryID = line.categoryID
+	item.TaxClass = line.taxClass
+	item.Available = line.stock
+	item.Total = item.UnitPrice.Mul(int64(item.Quantity))
+	return issues, true
+}
+
+// cartTotals sets the item count and subtotal of cart.
+func cartTotals(cart *models.Cart, currency string) error {
+	cart.ItemCount = 0
+	cart.Subtotal = money.New(0, currency)
+	for _, item := range cart.Items {
+		subtotal, err := cart.Subtotal.Add(item.Total)
+		if err != nil {
+			return err
+		}
+		cart.Subtotal = subtotal
+		cart.ItemCount += item.Quantity
+	}
+	return nil
+}
+
+// mergeCartItems adds items to cart, adding up the quantities of lines for
+// the same product and variant up to maxQuantity. Items that would take
+// the cart over maxItems lines are left out.
+func mergeCartItems(cart *models.Cart, items []*models.CartItem, maxItems, maxQuantity int) {
+	for _, item := range items {
+		existing := findCartItem(cart, item.ProductID, item.VariantID)
+		if existing == nil {
+			if len(cart.Items) >= maxItems {
+				continue
+			}
+			merged := *item
+			merged.Quantity = min(merged.Quantity, maxQuantity)
+			cart.Items = append(cart.Items, &merged)
+			continue
+		}
+		existing.Quantity = min(existing.Quantity+item.Quantity, maxQuantity)
+	}
+}
+
+func cartIssue(item *models.CartItem, code string) models.CartIssue {
+	return models.CartIssue{
+		ItemID:    item.ID,
+		ProductID: item.ProductID,
+		VariantID: item.VariantID,
+		Code:      code,
+	}
+}
+
+func findCartItem(cart *models.Cart, productID uuid.UUID, variantID *uuid.UUID) *models.CartItem {
+	for _, item := range cart.Items {
+		if item.ProductID == productID && sameVariant(item.VariantID, variantID) {
+			return item
+		}
+	}
+	return nil
+}
+
+func sameVariant(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func cartItemIndex(cart *models.Cart, itemID string) int {
+	id, err := uuid.Parse(itemID)
+	if err != nil {
+		return -1
+	}
+	for i, item := range cart.Items {
+		if item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func newCart(userID *uuid.UUID) *models.Cart {
+	return &models.Cart{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Items:          make([]*models.CartItem, 0),
+		PromotionCodes: make([]string, 0),
+		UpdatedAt:      time.Now().UTC(),
+	}
+}
+
+func cloneCart(cart *models.Cart) *models.Cart {
+	clone := *cart
+	clone.Items = make([]*models.CartItem, len(cart.Items))
+	for i, item := range cart.Items {
+		copied := *item
+		clone.Items[i] = &copied
+	}
+	clone.PromotionCodes = slices.Clone(cart.PromotionCodes)
+	return &clone
+}
+
+// newCartToken returns a random token identifying an anonymous cart.
+func newCartToken() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// anonymousCartKey is the cache key of an anonymous cart. Tokens are
+// hashed so the cache does not hold them.
+func anonymousCartKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return anonymousCartPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"testing"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/money"
+
+	"github.com/google/uuid"
+)
+
+func TestApplyCartProduct(t *testing.T) {
+	price := money.New(1000, "USD")
+	cases := []struct {
+		name     string
+		quantity int
+		line     cartProduct
+		keep     bool
+		codes    []string
+		want     int
+	}{
+		{"unchanged", 2, cartProduct{price: price, stock: 5}, true, nil, 2},
+		{"out of stock", 2, cartProduct{price: price, stock: 0}, false, []string{models.CartIssueOutOfStock}, 2},
+		{"reduced", 4, cartProduct{price: price, stock: 3}, true, []string{models.CartIssueQuantityReduced}, 3},
+		{"repriced", 1, cartProduct{price: money.New(1200, "USD"), stock: 3}, true, []string{models.CartIssuePriceChanged}, 1},
+		{"reduced and repriced", 5, cartProduct{price: money.New(900, "USD"), stock: 1}, true,
+			[]string{models.CartIssueQuantityReduced, models.CartIssuePriceChanged}, 1},
+	}
+	for _, tc := range cases {
+		item := &models.CartItem{ID: uuid.New(), Quantity: tc.quantity, UnitPrice: price}
+		issues, keep := applyCartProduct(item, &tc.line)
+		if keep != tc.keep {
+			t.Errorf("%s: Expected keep %v, got %v", tc.name, tc.keep, keep)
+		}
+		if len(issues) != len(tc.codes) {
+			t.Errorf("%s: Expected issues %v, got %+v", tc.name, tc.codes, issues)
+			continue
+		}
+		for i, issue := range issues {
+			if issue.Code != tc.codes[i] || issue.ItemID != item.ID {
+				t.Errorf("%s: Expected issue %s for the item, got %+v", tc.name, tc.codes[i], issue)
+			}
+		}
+		if item.Quantity != tc.want {
+			t.Errorf("%s: Expected quantity %d, got %d", tc.name, tc.want, item.Quantity)
+		}
+		if keep && (item.UnitPrice != tc.line.price || item.Total.Amount != tc.line.price.Amount*int64(tc.want)) {
+			t.Errorf("%s: Expected the current price and total, got %+v and %+v", tc.name, item.UnitPrice, item.Total)
+		}
+	}
+}
+
+func TestMergeCartItems(t *testing.T) {
+	shirt, mug := uuid.New(), uuid.New()
+	small, large := uuid.New(), uuid.New()
+
+	cart := newCart(nil)
+	cart.Items = []*models.CartItem{
+		{ID: uuid.New(), ProductID: shirt, VariantID: &small, Quantity: 2},
+		{ID: uuid.New(), ProductID: mug, Quantity: 8},
+	}
+	mergeCartItems(cart, []*models.CartItem{
+		{ID: uuid.New(), ProductID: shirt, VariantID: &small, Quantity: 3},
+		{ID: uuid.New(), ProductID: mug, Quantity: 5},
+		{ID: uuid.New(), ProductID: shirt, VariantID: &large, Quantity: 1},
+		{ID: uuid.New(), ProductID: uuid.New(), Quantity: 1},
+	}, 3, 10)
+
+	if len(cart.Items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(cart.Items))
+	}
+	if got := cart.Items[0].Quantity; got != 5 {
+		t.Errorf("Expected merged quantity 5, got %d", got)
+	}
+	if got := cart.Items[1].Quantity; got != 10 {
+		t.Errorf("Expected quantity capped at 10, got %d", got)
+	}
+	if got := cart.Items[2]; got.ProductID != shirt || *got.VariantID != large {
+		t.Errorf("Expected the other variant to be added as a line, got %+v", got)
+	}
+}
+
+func TestCartTotals(t *testing.T) {
+	cart := newCart(nil)
+	cart.Items = []*models.CartItem{
+		{Quantity: 2, Total: money.New(2000, "USD")},
+		{Quantity: 1, Total: money.New(550, "USD")},
+	}
+	if err := cartTotals(cart, "USD"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cart.ItemCount != 3 || cart.Subtotal != money.New(2550, "USD") {
+		t.Errorf("Expected 3 items for 2550 USD, got %d for %+v", cart.ItemCount, cart.Subtotal)
+	}
+}
+
+func TestAnonymousCartKey(t *testing.T) {
+	token, err := newCartToken()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	other, _ := newCartToken()
+	if token == other {
+		t.Errorf("Expected distinct tokens")
+	}
+	key := anonymousCartKey(token)
+	if key != anonymousCartKey(token) || key == anonymousCartKey(other) {
+		t.Errorf("Expected keys to identify tokens")
+	}
+	if len(key) != len(anonymousCartPrefix)+64 {
+		t.Errorf("Expected a hashed token in the key, got %s", key)
+	}
+}
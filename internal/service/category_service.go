@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"suitemedia/config"
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+	"suitemedia/pkg/cache"
+	"suitemedia/pkg/suggest"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCategoryNotFound       = errors.New("category not found")
+	ErrCategoryParentNotFound = errors.New("parent category not found")
+	ErrCategorySlugExists     = errors.New("category slug already exists")
+	ErrCategoryInUse          = errors.New("category has subcategories or products")
+	ErrCategoryCycle          = errors.New("category cannot be moved below itself")
+	ErrInvalidSlug            = errors.New("slug may only contain lowercase letters, digits and single dashes")
+)
+
+var (
+	slugPattern   = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+const categoryTreeKey = "tree"
+
+type CategoryService interface {
+	// Tree returns the top-level categories with their descendants nested
+	// in Children, each level ordered by position and name.
+	Tree(ctx context.Context) ([]*models.Category, error)
+	// Get returns the category with the given ID or slug.
+	Get(ctx context.Context, ref string) (*models.Category, error)
+	// FindOrCreate returns the category whose slug matches name, creating
+	// a top-level category when there is none.
+	FindOrCreate(ctx context.Context, name string) (*models.Category, error)
+	Create(ctx context.Context, req models.CreateCategoryRequest) (*models.Category, error)
+	// Update and Delete fail with ErrVersionConflict unless version is zero
+	// or matches the current version of the category.
+	Update(ctx context.Context, id string, version int, req models.UpdateCategoryRequest) (*models.Category, error)
+	Delete(ctx context.Context, id string, version int) error
+}
+
+type categoryService struct {
+	categoryRepo repository.CategoryRepository
+	cache        cache.Cache
+	tree         *cache.Typed[[]*models.Category]
+	suggestions  suggest.Index
+	// invalidateProduct drops a product whose category was renamed from
+	// the cache of the product service.
+	invalidateProduct func(ctx context.Context, id string)
+}
+
+// NewCategoryService creates the category service. Renames call
+// invalidateProduct for every product that carries the category name,
+// which is usually the Invalidate method of the product service.
+func NewCategoryService(categoryRepo repository.CategoryRepository, kv cache.Cache, cfg config.CacheConfig, suggestions suggest.Index, invalidateProduct func(ctx context.Context, id string)) CategoryService {
+	return &categoryService{
+		categoryRepo:      categoryRepo,
+		cache:             kv,
+		tree:              cache.NewTyped[[]*models.Category](kv, cacheOptions(cfg, "categories", cfg.ListTTLSeconds, nil)),
+		suggestions:       suggestions,
+		invalidateProduct: invalidateProduct,
+	}
+}
+
+func (s *categoryService) Tree(ctx context.Context) ([]*models.Category, error) {
+	return s.tree.Get(ctx, categoryTreeKey, func(ctx context.Context) ([]*models.Category, error) {
+		categories, err := s.categoryRepo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return buildCategoryTree(categories), nil
+	})
+}
+
+func (s *categoryService) Get(ctx context.Context, ref string) (*models.Category, error) {
+	var category *models.Category
+	var err error
+	if _, parseErr := uuid.Parse(ref); parseErr == nil {
+		category, err = s.categoryRepo.GetByID(ctx, ref)
+	} else {
+		category, err = s.categoryRepo.GetBySlug(ctx, ref)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrCategoryNotFound
+	}
+	return category, err
+}
+
+func (s *categoryService) FindOrCreate(ctx context.Context, name string) (*models.Category, error) {
+	category, err := s.categoryRepo.GetBySlug(ctx, slugify(name))
+	if errors.Is(err, repository.ErrNotFound) {
+		return s.Create(ctx, models.CreateCategoryRequest{Name: name})
+	}
+	return category, err
+}
+
+func (s *categoryService) Create(ctx context.Context, req models.CreateCategoryRequest) (*models.Category, error) {
+	category := &models.Category{
+		Name:     strings.Join(strings.Fields(req.Name), " "),
+		Slug:     req.Slug,
+		Position: req.Position,
+	}
+	if category.Slug == "" {
+		category.Slug = slugify(category.Name)
+	}
+	if err := s.checkSlug(ctx, category.Slug); err != nil {
+		return nil, err
+	}
+
+	var parentPath string
+	if req.ParentID != "" {
+		parent, err := s.categoryRepo.GetByID(ctx, req.ParentID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrCategoryParentNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		category.ParentID = &parent.ID
+		parentPath = parent.Path
+	}
+
+	if err := s.categoryRepo.Create(ctx, category, parentPath); err != nil {
+		return nil, err
+	}
+	s.tree.Invalidate(ctx, categoryTreeKey)
+
+	return category, nil
+}
+
+func (s *categoryService) Update(ctx context.Context, id string, version int, req models.UpdateCategoryRequest) (*models.Category, error) {
+	category, err := s.categoryRepo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && category.Version != version {
+		return nil, ErrVersionConflict
+	}
+	renamed := false
+
+	if req.Name != nil {
+		name := strings.Join(strings.Fields(*req.Name), " ")
+		renamed = name != category.Name
+		category.Name = name
+	}
+	if req.Slug != nil && *req.Slug != category.Slug {
+		if err := s.checkSlug(ctx, *req.Slug); err != nil {
+			return nil, err
+		}
+		category.Slug = *req.Slug
+	}
+	if req.Position != nil {
+		category.Position = *req.Position
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == "" {
+			category.ParentID = nil
+			category.Path = "/" + category.ID.String() + "/"
+		} else {
+			parent, err := s.categoryRepo.GetByID(ctx, *req.ParentID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrCategoryParentNotFound
+			}
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(parent.Path, category.Path) {
+				return nil, ErrCategoryCycle
+			}
+			category.ParentID = &parent.ID
+			category.Path = parent.Path + category.ID.String() + "/"
+		}
+	}
+
+	products, err := s.categoryRepo.Update(ctx, category)
+	if err != nil {
+		return nil, categoryRepoError(err)
+	}
+	s.tree.Invalidate(ctx, categoryTreeKey)
+
+	if renamed {
+		// Products carry the category name, so cached products and lists
+		// are stale. The old name leaves the suggestion index on its next
+		// rebuild.
+		for _, id := range products {
+			s.invalidateProduct(ctx, id.String())
+		}
+		cache.BumpGeneration(ctx, s.cache, productListGenerationKey)
+		s.suggestions.Add(ctx, suggest.Term{Kind: suggest.KindCategory, Text: category.Name})
+	}
+
+	return category, nil
+}
+
+func (s *categoryService) Delete(ctx context.Context, id string, version int) error {
+	if err := s.categoryRepo.Delete(ctx, id, version); err != nil {
+		return categoryRepoError(err)
+	}
+	s.tree.Invalidate(ctx, categoryTreeKey)
+
+	return nil
+}
+
+// checkSlug verifies that slug is well-formed and not taken.
+func (s *categoryService) checkSlug(ctx context.Context, slug string) error {
+	if !slugPattern.MatchString(slug) {
+		return ErrInvalidSlug
+	}
+
+	_, err := s.categoryRepo.GetBySlug(ctx, slug)
+	if err == nil {
+		return ErrCategorySlugExists
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// categoryRepoError translates repository errors for writes to service
+// errors.
+func categoryRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrCategoryNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionConflict
+	case errors.Is(err, repository.ErrInUse):
+		return ErrCategoryInUse
+	}
+	return err
+}
+
+// buildCategoryTree nests categories below their parents, keeping their
+// order within each level.
+func buildCategoryTree(categories []*models.Category) []*models.Category {
+	byID := make(map[uuid.UUID]*models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	roots := make([]*models.Category, 0)
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+			continue
+		}
+		if parent, ok := byID[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, category)
+		}
+	}
+	return roots
+}
+
+// slugify derives a slug from a name the same way the category migration
+// does, so that names differing only in case or punctuation share a slug.
+func slugify(name string) string {
+	return strings.Trim(slugSeparator.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
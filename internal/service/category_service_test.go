@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"suitemedia/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Home & Garden":  "home-garden",
+		"  Laptops  ":    "laptops",
+		"USB-C Cables!!": "usb-c-cables",
+		"Café":           "caf",
+		"***":            "",
+	}
+	for name, want := range cases {
+		if got := slugify(name); got != want {
+			t.Errorf("Expected slugify(%q) to be %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	root := &models.Category{ID: uuid.New(), Name: "Electronics"}
+	child := &models.Category{ID: uuid.New(), ParentID: &root.ID, Name: "Phones"}
+	grandchild := &models.Category{ID: uuid.New(), ParentID: &child.ID, Name: "Cases"}
+	other := &models.Category{ID: uuid.New(), Name: "Books"}
+
+	tree := buildCategoryTree([]*models.Category{root, grandchild, other, child})
+
+	if len(tree) != 2 || tree[0] != root || tree[1] != other {
+		t.Fatalf("Expected roots [Electronics Books], got %v", tree)
+	}
+	if len(root.Children) != 1 || root.Children[0] != child {
+		t.Errorf("Expected Electronics to contain Phones, got %v", root.Children)
+	}
+	if len(child.Children) != 1 || child.Children[0] != grandchild {
+		t.Errorf("Expected Phones to contain Cases, got %v", child.Children)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"suitemedia/config"
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+	"suitemedia/pkg/cache"
+	"suitemedia/pkg/money"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrNoExchangeRate  = errors.New("no exchange rate for currency")
+	ErrInvalidRate     = errors.New("exchange rates must be positive decimals for currencies other than the base currency")
+)
+
+const exchangeRatesKey = "current"
+
+type CurrencyService interface {
+	// Base returns the currency products are priced in.
+	Base() string
+	// Rates returns the exchange rates from the base currency.
+	Rates(ctx context.Context) (*models.ExchangeRates, error)
+	// SetRates adds or replaces the exchange rates of the given currencies.
+	SetRates(ctx context.Context, rates map[string]json.Number) (*models.ExchangeRates, error)
+	// LoadRatesFile sets the exchange rates in a JSON file mapping currency
+	// codes to rates.
+	LoadRatesFile(ctx context.Context, path string) error
+	// Convert returns amount in currency, rounded half to even to its
+	// minor unit.
+	Convert(ctx context.Context, amount money.Money, currency string) (money.Money, error)
+}
+
+type currencyService struct {
+	rateRepo repository.RateRepository
+	base     string
+	rates    *cache.Typed[*models.ExchangeRates]
+}
+
+func NewCurrencyService(rateRepo repository.RateRepository, kv cache.Cache, cfg config.CacheConfig, base string) CurrencyService {
+	return &currencyService{
+		rateRepo: rateRepo,
+		base:     base,
+		rates:    cache.NewTyped[*models.ExchangeRates](kv, cacheOptions(cfg, "exchange_rates", cfg.TTLSeconds, nil)),
+	}
+}
+
+func (s *currencyService) Base() string {
+	return s.base
+}
+
+func (s *currencyService) Rates(ctx context.Context) (*models.ExchangeRates, error) {
+	return s.rates.Get(ctx, exchangeRatesKey, func(ctx context.Context) (*models.ExchangeRates, error) {
+		stored, updatedAt, err := s.rateRepo.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		rates := &models.ExchangeRates{Base: s.base, Rates: make(map[string]json.Number, len(stored)), UpdatedAt: updatedAt}
+		for currency, rate := range stored {
+			rates.Rates[currency] = json.Number(rate)
+		}
+		return rates, nil
+	})
+}
+
+func (s *currencyService) SetRates(ctx context.Context, rates map[string]json.Number) (*models.ExchangeRates, error) {
+	values := make(map[string]string, len(rates))
+	for currency, rate := range rates {
+		if !money.IsCurrency(currency) || currency == s.base {
+			return nil, ErrInvalidRate
+		}
+		if _, err := money.ParseRate(rate.String()); err != nil {
+			return nil, ErrInvalidRate
+		}
+		values[currency] = rate.String()
+	}
+
+	if err := s.rateRepo.Upsert(ctx, values); err != nil {
+		return nil, err
+	}
+	s.rates.Invalidate(ctx, exchangeRatesKey)
+
+	return s.Rates(ctx)
+}
+
+func (s *currencyService) LoadRatesFile(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var rates map[string]json.Number
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := s.SetRates(ctx, rates); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (s *currencyService) Convert(ctx context.Context, amount money.Money, currency string) (money.Money, error) {
+	if !money.IsCurrency(currency) {
+		return money.Money{}, ErrUnknownCurrency
+	}
+	if amount.Currency == currency {
+		return amount, nil
+	}
+
+	rates, err := s.Rates(ctx)
+	if err != nil {
+		return money.Money{}, err
+	}
+	from, err := s.rate(rates, amount.Currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+	to, err := s.rate(rates, currency)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	// Convert through the base currency in a single step so that the
+	// result is only rounded once.
+	return amount.Convert(new(big.Rat).Quo(to, from), currency), nil
+}
+
+// rate returns the amount of currency one unit of the base currency buys.
+func (s *currencyService) rate(rates *models.ExchangeRates, currency string) (*big.Rat, error) {
+	if currency == s.base {
+		return big.NewRat(1, 1), nil
+	}
+	rate, ok := rates.Rates[currency]
+	if !ok {
+		return nil, ErrNoExchangeRate
+	}
+	return money.ParseRate(rate.String())
+}
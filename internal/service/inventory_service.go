@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"suitemedia/config"
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+	"suitemedia/pkg/allocation"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInsufficientStock   = errors.New("insufficient stock")
+	ErrVariantRequired     = errors.New("stock of a product with variants is kept per variant")
+	ErrInvalidQuantity     = errors.New("quantity must be positive, or non-zero for adjustments")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrUnknownStrategy     = errors.New("unknown allocation strategy")
+)
+
+type InventoryService interface {
+	// Levels returns the stock of a product, or of each of its variants.
+	Levels(ctx context.Context, productID string) ([]*models.StockLevel, error)
+	// Movements lists the inventory ledger of a product.
+	Movements(ctx context.Context, productID string, params models.ListParams) (*models.ListResult[*models.InventoryMovement], error)
+	Record(ctx context.Context, productID, createdBy string, req models.RecordMovementRequest) (*models.InventoryMovement, error)
+	// Transfer moves stock that is not reserved between warehouses and
+	// returns the movements out of one and into the other.
+	Transfer(ctx context.Context, productID, createdBy string, req models.TransferStockRequest) ([]*models.InventoryMovement, error)
+	// Reserve holds stock at one warehouse until the reservation is
+	// committed, released or expires.
+	Reserve(ctx context.Context, productID string, req models.ReserveStockRequest) (*models.Reservation, error)
+	Release(ctx context.Context, productID, id string) error
+	// Commit records a reservation that has not expired as a sale.
+	Commit(ctx context.Context, productID, id, createdBy string, req models.CommitReservationRequest) (*models.InventoryMovement, error)
+	// ExpireReservations deletes expired reservations, returning their
+	// stock, and reports how many there were.
+	ExpireReservations(ctx context.Context) (int64, error)
+}
+
+type inventoryService struct {
+	inventoryRepo repository.InventoryRepository
+	warehouseRepo repository.WarehouseRepository
+	tx            repository.Transactor
+	products      ProductService
+	cfg           config.InventoryConfig
+}
+
+func NewInventoryService(inventoryRepo repository.InventoryRepository, warehouseRepo repository.WarehouseRepository, tx repository.Transactor, products ProductService, cfg config.InventoryConfig) InventoryService {
+	return &inventoryService{
+		inventoryRepo: inventoryRepo,
+		warehouseRepo: warehouseRepo,
+		tx:            tx,
+		products:      products,
+		cfg:           cfg,
+	}
+}
+
+func (s *inventoryService) Levels(ctx context.Context, productID string) ([]*models.StockLevel, error) {
+	if _, err := s.products.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.inventoryRepo.Levels(ctx, productID)
+}
+
+func (s *inventoryService) Movements(ctx context.Context, productID string, params models.ListParams) (*models.ListResult[*models.InventoryMovement], error) {
+	if _, err := s.products.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	result, err := s.inventoryRepo.ListMovements(ctx, productID, params)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, ErrInvalidCursor
+	}
+	return result, err
+}
+
+func (s *inventoryService) Record(ctx context.Context, productID, createdBy string, req models.RecordMovementRequest) (*models.InventoryMovement, error) {
+	quantity, err := signedQuantity(req.Kind, req.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.stockItem(ctx, productID, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	warehouse, err := s.warehouse(ctx, req.WarehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	movement := &models.InventoryMovement{
+		StockItem:   item,
+		WarehouseID: warehouse.ID,
+		Kind:        req.Kind,
+		Quantity:    quantity,
+		Reference:   req.Reference,
+		Note:        req.Note,
+		CreatedBy:   userID(createdBy),
+	}
+	if err := s.inventoryRepo.Record(ctx, movement); err != nil {
+		return nil, inventoryRepoError(err, item)
+	}
+	s.products.Invalidate(ctx, productID)
+
+	return movement, nil
+}
+
+func (s *inventoryService) Transfer(ctx context.Context, productID, createdBy string, req models.TransferStockRequest) ([]*models.InventoryMovement, error) {
+	item, err := s.stockItem(ctx, productID, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.warehouse(ctx, req.FromWarehouseID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.warehouse(ctx, req.ToWarehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	movements, err := s.inventoryRepo.Transfer(ctx, &repository.StockTransfer{
+		Item:      item,
+		From:      from.ID,
+		To:        to.ID,
+		Quantity:  req.Quantity,
+		Reference: req.Reference,
+		Note:      req.Note,
+		CreatedBy: userID(createdBy),
+	})
+	if err != nil {
+		return nil, inventoryRepoError(err, item)
+	}
+	s.products.Invalidate(ctx, productID)
+
+	return movements, nil
+}
+
+func (s *inventoryService) Reserve(ctx context.Context, productID string, req models.ReserveStockRequest) (*models.Reservation, error) {
+	item, err := s.stockItem(ctx, productID, req.VariantID)
+	if err != nil {
+		return nil, err
+	}
+	name := req.Strategy
+	if name == "" {
+		name = s.cfg.AllocationStrategy
+	}
+	strategy, ok := allocation.Lookup(name)
+	if !ok {
+		return nil, ErrUnknownStrategy
+	}
+
+	ttl := req.TTLSeconds
+	if ttl == 0 {
+		ttl = s.cfg.ReservationTTLSeconds
+	}
+	reservation := &models.Reservation{
+		StockItem: item,
+		Quantity:  req.Quantity,
+		Reference: req.Reference,
+	}
+
+	if req.WarehouseID != "" {
+		warehouse, err := s.warehouse(ctx, req.WarehouseID)
+		if err != nil {
+			return nil, err
+		}
+		if !warehouse.IsActive {
+			return nil, ErrWarehouseInactive
+		}
+		reservation.WarehouseID = warehouse.ID
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if req.WarehouseID == "" {
+			// The stock of the item stays locked from the choice of the
+			// warehouse until the reservation is written.
+			locations, err := s.inventoryRepo.LockLocations(ctx, item)
+			if err != nil {
+				return err
+			}
+			warehouses, err := s.warehouseRepo.List(ctx)
+			if err != nil {
+				return err
+			}
+
+			allocationReq := allocation.Request{Quantity: req.Quantity}
+			if req.Latitude != nil && req.Longitude != nil {
+				allocationReq.Destination = &allocation.Point{Latitude: *req.Latitude, Longitude: *req.Longitude}
+			}
+			chosen, ok := allocation.Choose(strategy, allocationReq, allocationLocations(locations, warehouses))
+			if !ok {
+				return repository.ErrInsufficientStock
+			}
+			reservation.WarehouseID = uuid.MustParse(chosen.ID)
+		}
+		return s.inventoryRepo.Reserve(ctx, reservation, time.Duration(ttl)*time.Second)
+	})
+	if err != nil {
+		return nil, inventoryRepoError(err, item)
+	}
+
+	return reservation, nil
+}
+
+func (s *inventoryService) Release(ctx context.Context, productID, id string) error {
+	err := s.inventoryRepo.Release(ctx, productID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrReservationNotFound
+	}
+	return err
+}
+
+func (s *inventoryService) Commit(ctx context.Context, productID, id, createdBy string, req models.CommitReservationRequest) (*models.InventoryMovement, error) {
+	movement, err := s.inventoryRepo.Commit(ctx, productID, id, req.Reference, userID(createdBy))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, inventoryRepoError(err, models.StockItem{})
+	}
+	s.products.Invalidate(ctx, productID)
+
+	return movement, nil
+}
+
+func (s *inventoryService) ExpireReservations(ctx context.Context) (int64, error) {
+	return s.inventoryRepo.DeleteExpired(ctx)
+}
+
+// stockItem returns the item of the product, or of its variant when
+// variantID is set, that a movement or reservation applies to.
+func (s *inventoryService) stockItem(ctx context.Context, productID, variantID string) (models.StockItem, error) {
+	product, err := s.products.GetByID(ctx, productID)
+	if err != nil {
+		return models.StockItem{}, err
+	}
+
+	item := models.StockItem{ProductID: product.ID}
+	if variantID != "" {
+		id, err := uuid.Parse(variantID)
+		if err != nil {
+			return models.StockItem{}, ErrVariantNotFound
+		}
+		item.VariantID = &id
+	}
+	return item, nil
+}
+
+// warehouse returns the warehouse with the given ID, or the default
+// warehouse when id is empty.
+func (s *inventoryService) warehouse(ctx context.Context, id string) (*models.Warehouse, error) {
+	var warehouse *models.Warehouse
+	var err error
+	if id == "" {
+		warehouse, err = s.warehouseRepo.GetDefault(ctx)
+	} else {
+		warehouse, err = s.warehouseRepo.GetByID(ctx, id)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrWarehouseNotFound
+	}
+	return warehouse, err
+}
+
+// allocationLocations returns the active warehouses among warehouses with
+// their stock available for item, as locations to allocate from.
+func allocationLocations(locations []*models.LocationStock, warehouses []*models.Warehouse) []allocation.Location {
+	available := make(map[uuid.UUID]int, len(locations))
+	for _, location := range locations {
+		available[location.WarehouseID] = location.Available
+	}
+
+	result := make([]allocation.Location, 0, len(warehouses))
+	for _, warehouse := range warehouses {
+		if !warehouse.IsActive {
+			continue
+		}
+		location := allocation.Location{
+			ID:        warehouse.ID.String(),
+			Available: available[warehouse.ID],
+			Priority:  warehouse.Priority,
+		}
+		if warehouse.Latitude != nil && warehouse.Longitude != nil {
+			location.Position = &allocation.Point{Latitude: *warehouse.Latitude, Longitude: *warehouse.Longitude}
+		}
+		result = append(result, location)
+	}
+	return result
+}
+
+// signedQuantity returns the change in stock of a movement of kind for the
+// quantity of a request.
+func signedQuantity(kind string, quantity int) (int, error) {
+	switch {
+	case kind == models.MovementAdjustment && quantity != 0:
+		return quantity, nil
+	case kind == models.MovementAdjustment || quantity <= 0:
+		return 0, ErrInvalidQuantity
+	case kind == models.MovementSale:
+		return -quantity, nil
+	}
+	return quantity, nil
+}
+
+// userID returns a pointer to the parsed ID of a user, or nil when id is
+// not one.
+func userID(id string) *uuid.UUID {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// inventoryRepoError translates repository errors for movements and
+// reservations of item to service errors.
+func inventoryRepoError(err error, item models.StockItem) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound) && item.VariantID != nil:
+		return ErrVariantNotFound
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrProductNotFound
+	case errors.Is(err, repository.ErrInsufficientStock):
+		return ErrInsufficientStock
+	case errors.Is(err, repository.ErrVariantRequired):
+		return ErrVariantRequired
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"suitemedia/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestSignedQuantity(t *testing.T) {
+	cases := []struct {
+		kind     string
+		quantity int
+		want     int
+		err      error
+	}{
+		{models.MovementReceipt, 5, 5, nil},
+		{models.MovementReturn, 2, 2, nil},
+		{models.MovementSale, 3, -3, nil},
+		{models.MovementAdjustment, -4, -4, nil},
+		{models.MovementAdjustment, 4, 4, nil},
+		{models.MovementAdjustment, 0, 0, ErrInvalidQuantity},
+		{models.MovementSale, -3, 0, ErrInvalidQuantity},
+		{models.MovementReceipt, 0, 0, ErrInvalidQuantity},
+	}
+	for _, tc := range cases {
+		got, err := signedQuantity(tc.kind, tc.quantity)
+		if err != tc.err {
+			t.Errorf("Expected error %v for %s of %d, got %v", tc.err, tc.kind, tc.quantity, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Expected %d for %s of %d, got %d", tc.want, tc.kind, tc.quantity, got)
+		}
+	}
+}
+
+func TestAllocationLocations(t *testing.T) {
+	lat, lon := -6.2, 106.8
+	main := &models.Warehouse{ID: uuid.New(), Priority: 1, IsActive: true, Latitude: &lat, Longitude: &lon}
+	empty := &models.Warehouse{ID: uuid.New(), Priority: 2, IsActive: true}
+	closed := &models.Warehouse{ID: uuid.New(), Priority: 0, IsActive: false}
+	stock := []*models.LocationStock{
+		{WarehouseID: main.ID, OnHand: 10, Reserved: 3, Available: 7},
+		{WarehouseID: closed.ID, OnHand: 50, Available: 50},
+	}
+
+	locations := allocationLocations(stock, []*models.Warehouse{closed, main, empty})
+	if len(locations) != 2 {
+		t.Fatalf("Expected 2 active locations, got %d", len(locations))
+	}
+	if locations[0].ID != main.ID.String() || locations[0].Available != 7 || locations[0].Position == nil {
+		t.Errorf("Expected main warehouse with 7 available and a position, got %+v", locations[0])
+	}
+	if locations[1].Available != 0 || locations[1].Position != nil {
+		t.Errorf("Expected empty warehouse with nothing available and no position, got %+v", locations[1])
+	}
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"suitemedia/config"
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/tax"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrCartEmpty         = errors.New("cart is empty")
+	ErrCartChanged       = errors.New("cart changed since it was last shown")
+	ErrInvalidTransition = errors.New("order cannot move to this status")
+)
+
+// expiredOrderBatch is the number of expired orders cancelled per query.
+const expiredOrderBatch = 100
+
+type OrderService interface {
+	// Checkout turns the cart of userID into a pending order, reserving the
+	// stock of its items and redeeming the promotions it gets, and empties
+	// the cart. The order is taxed for delivery to the country and region
+	// of req, or to the default tax address. It fails with ErrCartChanged
+	// when revalidating the cart changed it, so the shopper can review the
+	// changes first, or when the cart was checked out meanwhile, and with
+	// ErrPromotionLimitReached when a promotion was
+	// used up meanwhile.
+	Checkout(ctx context.Context, userID string, req models.CheckoutRequest) (*models.Order, error)
+	Get(ctx context.Context, id string) (*models.Order, error)
+	// GetForUser returns an order of userID.
+	GetForUser(ctx context.Context, userID, id string) (*models.Order, error)
+	List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Order], error)
+	// ListForUser returns the order history of userID.
+	ListForUser(ctx context.Context, userID string, params models.ListParams) (*models.ListResult[*models.Order], error)
+	// Transition moves an order to another status, failing with
+	// ErrInvalidTransition unless the state machine allows it and with
+	// ErrVersionConflict unless version is zero or matches the order.
+	// Paying records the sale of the reserved stock, cancelling releases
+	// it along with the promotions redeemed, and refunding an order that
+	// has not shipped returns the stock.
+	Transition(ctx context.Context, id string, version int, changedBy string, req models.OrderTransitionRequest) (*models.Order, error)
+	// Cancel cancels a pending order of userID.
+	Cancel(ctx context.Context, userID, id string) (*models.Order, error)
+	// ExpirePending cancels pending orders whose reservations expired and
+	// reports how many there were.
+	ExpirePending(ctx context.Context) (int, error)
+}
+
+type orderService struct {
+	orderRepo     repository.OrderRepository
+	promotionRepo repository.PromotionRepository
+	tx            repository.Transactor
+	carts         CartService
+	pricing       PricingService
+	inventory     InventoryService
+	cfg           config.OrderConfig
+}
+
+func NewOrderService(orderRepo repository.OrderRepository, promotionRepo repository.PromotionRepository, tx repository.Transactor, carts CartService, pricing PricingService, inventory InventoryService, cfg config.OrderConfig) OrderService {
+	return &orderService{
+		orderRepo:     orderRepo,
+		promotionRepo: promotionRepo,
+		tx:            tx,
+		carts:         carts,
+		pricing:       pricing,
+		inventory:     inventory,
+		cfg:           cfg,
+	}
+}
+
+func (s *orderService) Checkout(ctx context.Context, userID string, req models.CheckoutRequest) (*models.Order, error) {
+	owner := CartOwner{UserID: userID}
+	cart, err := s.carts.Get(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.Issues) > 0 {
+		return nil, ErrCartChanged
+	}
+	if len(cart.Items) == 0 {
+		return nil, ErrCartEmpty
+	}
+	// The cart was priced for the default tax address.
+	if req.Country != "" {
+		address := tax.Address{Country: req.Country, Region: req.Region}
+		if cart.Pricing, err = s.pricing.Price(ctx, cart, address); err != nil {
+			return nil, err
+		}
+	}
+
+	order := newOrder(cart, req.Note)
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// Deleting the cart first locks it, so a concurrent checkout of
+		// the same cart waits here and then finds it gone.
+		if err := s.carts.Take(ctx, userID); err != nil {
+			return err
+		}
+		for _, item := range order.Items {
+			reservation, err := s.inventory.Reserve(ctx, item.ProductID.String(), models.ReserveStockRequest{
+				VariantID:  uuidString(item.VariantID),
+				Quantity:   item.Quantity,
+				Reference:  order.ID.String(),
+				TTLSeconds: s.cfg.PaymentTimeoutSeconds,
+			})
+			if err != nil {
+				return err
+			}
+			item.WarehouseID, item.ReservationID = reservation.WarehouseID, &reservation.ID
+			if order.ExpiresAt == nil || reservation.ExpiresAt.Before(*order.ExpiresAt) {
+				order.ExpiresAt = &reservation.ExpiresAt
+			}
+		}
+
+		if err := s.orderRepo.Create(ctx, order); err != nil {
+			return err
+		}
+		for _, discount := range order.Discounts {
+			err := s.promotionRepo.Redeem(ctx, discount.PromotionID, order.UserID, order.ID, discount.Amount.Amount)
+			if err != nil {
+				return promotionRepoError(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *orderService) Get(ctx context.Context, id string) (*models.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	return order, err
+}
+
+func (s *orderService) GetForUser(ctx context.Context, userID, id string) (*models.Order, error) {
+	order, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID.String() != userID {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func (s *orderService) List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Order], error) {
+	return s.list(ctx, "", params)
+}
+
+func (s *orderService) ListForUser(ctx context.Context, userID string, params models.ListParams) (*models.ListResult[*models.Order], error) {
+	return s.list(ctx, userID, params)
+}
+
+func (s *orderService) list(ctx context.Context, userID string, params models.ListParams) (*models.ListResult[*models.Order], error) {
+	result, err := s.orderRepo.List(ctx, userID, params)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, ErrInvalidCursor
+	}
+	return result, err
+}
+
+func (s *orderService) Transition(ctx context.Context, id string, version int, changedBy string, req models.OrderTransitionRequest) (*models.Order, error) {
+	order, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && order.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	if err := s.transition(ctx, order, req.Status, userID(changedBy), req.Note); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *orderService) Cancel(ctx context.Context, userID, id string) (*models.Order, error) {
+	order, err := s.GetForUser(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.transition(ctx, order, models.OrderCancelled, &order.UserID, "Cancelled by the customer"); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *orderService) ExpirePending(ctx context.Context) (int, error) {
+	orders, err := s.orderRepo.ListExpired(ctx, expiredOrderBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, order := range orders {
+		err := s.transition(ctx, order, models.OrderCancelled, nil, "Payment window expired")
+		// An order paid or cancelled meanwhile no longer needs expiring.
+		if errors.Is(err, ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// transition moves order to status with the stock changes it implies, all
+// in one transaction.
+func (s *orderService) transition(ctx context.Context, order *models.Order, status string, changedBy *uuid.UUID, note string) error {
+	if !order.CanTransition(status) {
+		return ErrInvalidTransition
+	}
+	change := &models.OrderStatusChange{From: order.Status, To: status, Note: note, ChangedBy: changedBy}
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		switch {
+		case status == models.OrderPaid:
+			err = s.sellStock(ctx, order, changedBy)
+		case status == models.OrderCancelled:
+			if err = s.releaseStock(ctx, order); err == nil {
+				err = s.promotionRepo.ReleaseOrder(ctx, order.ID)
+			}
+		case status == models.OrderRefunded && (order.Status == models.OrderPaid || order.Status == models.OrderFulfilled):
+			err = s.returnStock(ctx, order, changedBy)
+		}
+		if err != nil {
+			return err
+		}
+
+		order.Status = status
+		return s.orderRepo.UpdateStatus(ctx, order, change)
+	})
+	if err != nil {
+		order.Status = change.From
+		return orderRepoError(err)
+	}
+	return nil
+}
+
+// sellStock records the sale of the items of order, committing their
+// reservations. Stock whose reservation has expired is sold from the same
+// warehouse if it is still available.
+func (s *orderService) sellStock(ctx context.Context, order *models.Order, changedBy *uuid.UUID) error {
+	for _, item := range order.Items {
+		reference := order.ID.String()
+		if item.ReservationID != nil {
+			_, err := s.inventory.Commit(ctx, item.ProductID.String(), item.ReservationID.String(), uuidString(changedBy),
+				models.CommitReservationRequest{Reference: reference})
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, ErrReservationNotFound) {
+				return err
+			}
+		}
+
+		_, err := s.inventory.Record(ctx, item.ProductID.String(), uuidString(changedBy), models.RecordMovementRequest{
+			VariantID:   uuidString(item.VariantID),
+			WarehouseID: item.WarehouseID.String(),
+			Kind:        models.MovementSale,
+			Quantity:    item.Quantity,
+			Reference:   reference,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseStock releases the reservations of order that have not expired.
+func (s *orderService) releaseStock(ctx context.Context, order *models.Order) error {
+	for _, item := range order.Items {
+		if item.ReservationID == nil {
+			continue
+		}
+		err := s.inventory.Release(ctx, item.ProductID.String(), item.ReservationID.String())
+		if err != nil && !errors.Is(err, ErrReservationNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// returnStock puts the items of a refunded order that never shipped back
+// into stock at the warehouses they were sold from.
+func (s *orderService) returnStock(ctx context.Context, order *models.Order, changedBy *uuid.UUID) error {
+	for _, item := range order.Items {
+		_, err := s.inventory.Record(ctx, item.ProductID.String(), uuidString(changedBy), models.RecordMovementRequest{
+			VariantID:   uuidString(item.VariantID),
+			WarehouseID: item.WarehouseID.String(),
+			Kind:        models.MovementReturn,
+			Quantity:    item.Quantity,
+			Reference:   order.ID.String(),
+			Note:        "Order refunded",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newOrder returns a pending order for the items of a revalidated cart,
+// keeping their current names and prices and the discounts, shipping and
+// tax of its pricing.
+func newOrder(cart *models.Cart, note string) *models.Order {
+	order := &models.Order{
+		ID:        uuid.New(),
+		UserID:    *cart.UserID,
+		Status:    models.OrderPending,
+		Items:     make([]*models.OrderItem, len(cart.Items)),
+		ItemCount: cart.ItemCount,
+		Subtotal:  cart.Subtotal,
+		Discount:  money.New(0, cart.Subtotal.Currency),
+		Shipping:  money.New(0, cart.Subtotal.Currency),
+		Tax:       money.New(0, cart.Subtotal.Currency),
+		Total:     cart.Subtotal,
+		Discounts: make([]*models.AppliedDiscount, 0),
+		Taxes:     make([]*models.TaxLine, 0),
+		Note:      note,
+	}
+	if pricing := cart.Pricing; pricing != nil {
+		order.Discount, order.Shipping, order.Total = pricing.DiscountTotal, pricing.Shipping, pricing.Total
+		order.Discounts = pricing.Discounts
+		order.Tax, order.PricesIncludeTax, order.Taxes = pricing.Tax, pricing.PricesIncludeTax, pricing.Taxes
+		order.Country, order.Region = pricing.Country, pricing.Region
+	}
+	for i, item := range cart.Items {
+		order.Items[i] = &models.OrderItem{
+			ID:        uuid.New(),
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			UnitPrice: item.UnitPrice,
+			Quantity:  item.Quantity,
+			Discount:  money.New(0, item.Total.Currency),
+			Tax:       money.New(0, item.Total.Currency),
+			Total:     item.Total,
+		}
+		if cart.Pricing != nil {
+			order.Items[i].Discount, order.Items[i].Tax = cart.Pricing.Lines[i].Discount, cart.Pricing.Lines[i].Tax
+		}
+	}
+	return order
+}
+
+// uuidString returns id as a string, or an empty string when it is nil.
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// orderRepoError translates repository errors for orders to service
+// errors.
+func orderRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrOrderNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionConflict
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"testing"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/money"
+
+	"github.com/google/uuid"
+)
+
+func TestNewOrder(t *testing.T) {
+	userID, variantID := uuid.New(), uuid.New()
+	cart := newCart(&userID)
+	cart.Items = []*models.CartItem{
+		{ProductID: uuid.New(), Quantity: 2, UnitPrice: money.New(1000, "USD"), Total: money.New(2000, "USD"), Name: "Mug"},
+		{ProductID: uuid.New(), VariantID: &variantID, Quantity: 1, UnitPrice: money.New(2500, "USD"), Total: money.New(2500, "USD"), Name: "Shirt", SKU: "SHIRT-M"},
+	}
+	if err := cartTotals(cart, "USD"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	order := newOrder(cart, "Leave at the door")
+	if order.UserID != userID || order.Status != models.OrderPending || order.Note != "Leave at the door" {
+		t.Errorf("Expected a pending order of the user, got %+v", order)
+	}
+	if order.ItemCount != 3 || order.Subtotal != money.New(4500, "USD") || order.Total != order.Subtotal {
+		t.Errorf("Expected 3 items for 4500 USD, got %d for %+v", order.ItemCount, order.Subtotal)
+	}
+	if len(order.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(order.Items))
+	}
+	shirt := order.Items[1]
+	if shirt.Name != "Shirt" || shirt.SKU != "SHIRT-M" || *shirt.VariantID != variantID || shirt.UnitPrice != money.New(2500, "USD") {
+		t.Errorf("Expected the cart line to be copied, got %+v", shirt)
+	}
+	if shirt.ID == uuid.Nil || shirt.ID == order.Items[0].ID {
+		t.Errorf("Expected distinct item IDs")
+	}
+}
+
+func TestNewOrderWithPricing(t *testing.T) {
+	userID, promotionID := uuid.New(), uuid.New()
+	cart := newCart(&userID)
+	cart.Items = []*models.CartItem{
+		{ID: uuid.New(), ProductID: uuid.New(), Quantity: 1, UnitPrice: money.New(2000, "USD"), Total: money.New(2000, "USD")},
+	}
+	if err := cartTotals(cart, "USD"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	cart.Pricing = &models.PriceBreakdown{
+		Subtotal:      cart.Subtotal,
+		Discounts:     []*models.AppliedDiscount{{PromotionID: promotionID, Code: "SAVE5", Amount: money.New(500, "USD")}},
+		DiscountTotal: money.New(500, "USD"),
+		Shipping:      money.New(300, "USD"),
+		Tax:           money.New(180, "USD"),
+		Taxes:         []*models.TaxLine{{Country: "US", Rate: "10", Taxable: money.New(1800, "USD"), Amount: money.New(180, "USD")}},
+		Country:       "US",
+		Total:         money.New(1980, "USD"),
+		Lines:         []*models.LinePrice{{ItemID: cart.Items[0].ID, Discount: money.New(500, "USD"), Tax: money.New(150, "USD")}},
+	}
+
+	order := newOrder(cart, "")
+	if order.Discount != money.New(500, "USD") || order.Shipping != money.New(300, "USD") || order.Total != money.New(1980, "USD") {
+		t.Errorf("Expected the pricing of the cart, got discount %+v, shipping %+v, total %+v", order.Discount, order.Shipping, order.Total)
+	}
+	if len(order.Discounts) != 1 || order.Discounts[0].PromotionID != promotionID {
+		t.Errorf("Expected the applied discount to be kept, got %+v", order.Discounts)
+	}
+	if order.Items[0].Discount != money.New(500, "USD") || order.Items[0].Total != money.New(2000, "USD") {
+		t.Errorf("Expected the line discount next to the gross total, got %+v", order.Items[0])
+	}
+	if order.Tax != money.New(180, "USD") || len(order.Taxes) != 1 || order.Country != "US" || order.Items[0].Tax != money.New(150, "USD") {
+		t.Errorf("Expected the tax of the cart, got %+v in %+v", order.Tax, order.Taxes)
+	}
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+	"suitemedia/pkg/payment"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOrderNotPayable = errors.New("order is not awaiting payment")
+	ErrOrderNotPaid    = errors.New("order has no captured payment")
+	ErrInvalidWebhook  = errors.New("invalid payment webhook")
+	ErrUnknownProvider = errors.New("unknown payment provider")
+)
+
+type PaymentService interface {
+	// Pay starts paying for a pending order of userID and returns the
+	// payment with the client secret the customer completes it with. A
+	// payment still awaiting the customer is returned again.
+	Pay(ctx context.Context, userID, orderID string) (*models.Payment, error)
+	// List returns the payments of an order.
+	List(ctx context.Context, orderID string) ([]*models.Payment, error)
+	// Refund refunds the captured payment of an order and moves the order
+	// to refunded.
+	Refund(ctx context.Context, orderID, refundedBy string, req models.RefundOrderRequest) (*models.Order, error)
+	// HandleWebhook verifies a webhook of provider and applies the event it
+	// reports to the payment and its order. Events are applied once no
+	// matter how often they are delivered, and events about unknown
+	// intents are ignored.
+	HandleWebhook(ctx context.Context, provider string, payload []byte, header http.Header) error
+}
+
+type paymentService struct {
+	paymentRepo repository.PaymentRepository
+	tx          repository.Transactor
+	orders      OrderService
+	provider    payment.Provider
+}
+
+func NewPaymentService(paymentRepo repository.PaymentRepository, tx repository.Transactor, orders OrderService, provider payment.Provider) PaymentService {
+	return &paymentService{
+		paymentRepo: paymentRepo,
+		tx:          tx,
+		orders:      orders,
+		provider:    provider,
+	}
+}
+
+func (s *paymentService) Pay(ctx context.Context, userID, orderID string) (*models.Payment, error) {
+	order, err := s.orders.GetForUser(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderPending {
+		return nil, ErrOrderNotPayable
+	}
+
+	payments, err := s.paymentRepo.ListByOrder(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		if p.Provider != s.provider.Name() {
+			continue
+		}
+		switch p.Status {
+		case payment.StatusRequiresPayment, payment.StatusAuthorized:
+			return p, nil
+		case payment.StatusCaptured:
+			return nil, ErrOrderNotPayable
+		}
+	}
+
+	intent, err := s.provider.CreateIntent(ctx, payment.IntentRequest{Amount: order.Total, Reference: order.ID.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &models.Payment{
+		ID:           uuid.New(),
+		OrderID:      order.ID,
+		Provider:     s.provider.Name(),
+		IntentID:     intent.ID,
+		Amount:       intent.Amount,
+		Status:       intent.Status,
+		ClientSecret: intent.ClientSecret,
+	}
+	if err := s.paymentRepo.Create(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *paymentService) List(ctx context.Context, orderID string) ([]*models.Payment, error) {
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return s.paymentRepo.ListByOrder(ctx, order.ID)
+}
+
+func (s *paymentService) Refund(ctx context.Context, orderID, refundedBy string, req models.RefundOrderRequest) (*models.Order, error) {
+	order, err := s.orders.Get(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !order.CanTransition(models.OrderRefunded) {
+		return nil, ErrInvalidTransition
+	}
+
+	payments, err := s.paymentRepo.ListByOrder(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	var captured *models.Payment
+	for _, p := range payments {
+		if p.Status == payment.StatusCaptured {
+			captured = p
+		}
+	}
+	if captured == nil {
+		return nil, ErrOrderNotPaid
+	}
+	if captured.Provider != s.provider.Name() {
+		return nil, ErrUnknownProvider
+	}
+
+	// The refund at the provider cannot be rolled back, but refunding is
+	// idempotent, so a failure below is fixed by retrying.
+	if _, err := s.provider.Refund(ctx, captured.IntentID); err != nil {
+		return nil, err
+	}
+
+	note := req.Note
+	if note == "" {
+		note = "Payment refunded"
+	}
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		p, err := s.paymentRepo.GetByIntent(ctx, captured.Provider, captured.IntentID)
+		if err != nil {
+			return err
+		}
+		if p.Status != payment.StatusRefunded {
+			p.Status = payment.StatusRefunded
+			if err := s.paymentRepo.Update(ctx, p); err != nil {
+				return err
+			}
+		}
+
+		order, err = s.orders.Transition(ctx, orderID, 0, refundedBy, models.OrderTransitionRequest{Status: models.OrderRefunded, Note: note})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *paymentService) HandleWebhook(ctx context.Context, provider string, payload []byte, header http.Header) error {
+	if provider != s.provider.Name() {
+		return ErrUnknownProvider
+	}
+	event, err := s.provider.VerifyWebhook(payload, header)
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		return ErrInvalidWebhook
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// Recording the event first makes a concurrent delivery of the
+		// same event wait for this one and then skip it.
+		recorded, err := s.paymentRepo.RecordEvent(ctx, provider, event, payload)
+		if err != nil || !recorded {
+			return err
+		}
+
+		p, err := s.paymentRepo.GetByIntent(ctx, provider, event.IntentID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return s.apply(ctx, p, event)
+	})
+}
+
+// apply changes p and its order for event. Events that no longer change
+// anything, such as a failure reported after the capture, are ignored.
+func (s *paymentService) apply(ctx context.Context, p *models.Payment, event *payment.Event) error {
+	switch event.Type {
+	case payment.EventAuthorized:
+		if p.Status != payment.StatusRequiresPayment {
+			return nil
+		}
+		if _, err := s.provider.Capture(ctx, p.IntentID); err != nil {
+			return err
+		}
+		return s.captured(ctx, p)
+	case payment.EventCaptured:
+		if p.Status != payment.StatusRequiresPayment && p.Status != payment.StatusAuthorized {
+			return nil
+		}
+		return s.captured(ctx, p)
+	case payment.EventFailed:
+		if p.Status != payment.StatusRequiresPayment && p.Status != payment.StatusAuthorized {
+			return nil
+		}
+		p.Status, p.FailureReason = payment.StatusFailed, event.FailureReason
+		return s.paymentRepo.Update(ctx, p)
+	case payment.EventRefunded:
+		if p.Status == payment.StatusRefunded {
+			return nil
+		}
+		wasCaptured := p.Status == payment.StatusCaptured
+		p.Status = payment.StatusRefunded
+		if err := s.paymentRepo.Update(ctx, p); err != nil {
+			return err
+		}
+		if !wasCaptured {
+			return nil
+		}
+
+		_, err := s.orders.Transition(ctx, p.OrderID.String(), 0, "", models.OrderTransitionRequest{
+			Status: models.OrderRefunded,
+			Note:   "Payment refunded at the provider",
+		})
+		if errors.Is(err, ErrInvalidTransition) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// captured records the capture of p and marks its order paid. When the
+// order can no longer be paid for, because it was cancelled, another
+// payment paid it first or its stock ran out meanwhile, the payment is
+// refunded instead.
+func (s *paymentService) captured(ctx context.Context, p *models.Payment) error {
+	p.Status = payment.StatusCaptured
+	if err := s.paymentRepo.Update(ctx, p); err != nil {
+		return err
+	}
+
+	orderID := p.OrderID.String()
+	_, err := s.orders.Transition(ctx, orderID, 0, "", models.OrderTransitionRequest{Status: models.OrderPaid, Note: "Payment captured"})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrInsufficientStock):
+		_, err := s.orders.Transition(ctx, orderID, 0, "", models.OrderTransitionRequest{
+			Status: models.OrderCancelled,
+			Note:   "Stock ran out before payment",
+		})
+		if err != nil {
+			return err
+		}
+	case !errors.Is(err, ErrInvalidTransition):
+		return err
+	}
+
+	if _, err := s.provider.Refund(ctx, p.IntentID); err != nil {
+		return err
+	}
+	p.Status = payment.StatusRefunded
+	return s.paymentRepo.Update(ctx, p)
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"suitemedia/pkg/payment"
+)
+
+func TestHandleWebhookRejectsUnverifiedRequests(t *testing.T) {
+	provider := payment.NewFake(payment.Settings{WebhookSecret: "secret", WebhookTolerance: time.Minute})
+	s := NewPaymentService(nil, nil, nil, provider)
+	payload := []byte(`{"id":"evt_1","type":"payment.captured","intent_id":"pi_1"}`)
+
+	header := http.Header{}
+	header.Set(payment.FakeSignatureHeader, payment.Sign("secret", time.Now(), payload))
+	if err := s.HandleWebhook(context.Background(), "other", payload, header); err != ErrUnknownProvider {
+		t.Errorf("Expected ErrUnknownProvider, got %v", err)
+	}
+
+	header.Set(payment.FakeSignatureHeader, payment.Sign("wrong", time.Now(), payload))
+	if err := s.HandleWebhook(context.Background(), payment.Fake, payload, header); err != ErrInvalidWebhook {
+		t.Errorf("Expected ErrInvalidWebhook for a bad signature, got %v", err)
+	}
+
+	header.Set(payment.FakeSignatureHeader, payment.Sign("secret", time.Now().Add(-time.Hour), payload))
+	if err := s.HandleWebhook(context.Background(), payment.Fake, payload, header); err != ErrInvalidWebhook {
+		t.Errorf("Expected ErrInvalidWebhook for a stale signature, got %v", err)
+	}
+}
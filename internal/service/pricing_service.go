@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"suitemedia/config"
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/promotion"
+	"suitemedia/pkg/tax"
+
+	"github.com/google/uuid"
+)
+
+type PricingService interface {
+	// Price works out what a revalidated cart costs: its subtotal, the
+	// promotions it gets, with its codes and those that apply
+	// automatically, shipping, the tax for delivery to address and the
+	// total. An address without a country is replaced by the default tax
+	// address, and no tax is charged when there is none. Codes that do not
+	// apply are listed with the reason.
+	Price(ctx context.Context, cart *models.Cart, address tax.Address) (*models.PriceBreakdown, error)
+	// Promotion returns the active promotion with code, failing with
+	// ErrPromotionNotFound when there is none and with
+	// ErrPromotionLimitReached when it has been used up, overall or by
+	// userID.
+	Promotion(ctx context.Context, code string, userID *uuid.UUID) (*models.Promotion, error)
+}
+
+type pricingService struct {
+	promotionRepo repository.PromotionRepository
+	orderRepo     repository.OrderRepository
+	categories    CategoryService
+	taxes         tax.Calculator
+	taxCfg        config.TaxConfig
+	cfg           config.OrderConfig
+}
+
+func NewPricingService(promotionRepo repository.PromotionRepository, orderRepo repository.OrderRepository, categories CategoryService, taxes tax.Calculator, taxCfg config.TaxConfig, cfg config.OrderConfig) PricingService {
+	return &pricingService{
+		promotionRepo: promotionRepo,
+		orderRepo:     orderRepo,
+		categories:    categories,
+		taxes:         taxes,
+		taxCfg:        taxCfg,
+		cfg:           cfg,
+	}
+}
+
+func (s *pricingService) Price(ctx context.Context, cart *models.Cart, address tax.Address) (*models.PriceBreakdown, error) {
+	currency := cart.Subtotal.Currency
+	basket := promotion.Basket{
+		Currency: currency,
+		Lines:    make([]promotion.Line, len(cart.Items)),
+		Shipping: money.New(0, currency),
+		Now:      time.Now(),
+	}
+	if len(cart.Items) > 0 {
+		basket.Shipping.Amount = int64(s.cfg.ShippingFee)
+	}
+	for i, item := range cart.Items {
+		basket.Lines[i] = promotion.Line{ID: item.ID.String(), ProductID: item.ProductID.String(), Amount: item.Total}
+	}
+
+	promotions, err := s.promotionRepo.ListApplicable(ctx, cart.PromotionCodes)
+	if err != nil {
+		return nil, err
+	}
+	usable, rejected, err := s.usable(ctx, promotions, cart.PromotionCodes, cart.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if needsFirstOrder(usable) {
+		if basket.FirstOrder, err = s.firstOrder(ctx, cart.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if needsCategories(usable) {
+		if err := s.lineCategories(ctx, cart.Items, basket.Lines); err != nil {
+			return nil, err
+		}
+	}
+
+	rules := make([]*promotion.Promotion, len(usable))
+	byRule := make(map[*promotion.Promotion]*models.Promotion, len(usable))
+	for i, p := range usable {
+		rules[i] = p.Rule()
+		byRule[rules[i]] = p
+	}
+	result := promotion.Apply(rules, basket)
+
+	breakdown := priceBreakdown(cart, basket, result, byRule)
+	breakdown.RejectedPromotions = append(rejected, breakdown.RejectedPromotions...)
+
+	if address.Country == "" {
+		address = tax.Address{Country: s.taxCfg.DefaultCountry, Region: s.taxCfg.DefaultRegion}
+	}
+	address.Country, address.Region = strings.ToUpper(address.Country), strings.ToUpper(address.Region)
+	if address.Country == "" {
+		return breakdown, nil
+	}
+	taxes, err := s.taxes.Calculate(ctx, taxRequest(cart, breakdown, result, address))
+	if err != nil {
+		return nil, err
+	}
+	breakdown.Country, breakdown.Region = address.Country, address.Region
+	addTax(breakdown, taxes)
+	return breakdown, nil
+}
+
+func (s *pricingService) Promotion(ctx context.Context, code string, userID *uuid.UUID) (*models.Promotion, error) {
+	p, err := s.promotionRepo.GetByCode(ctx, normalizePromotionCode(code))
+	if err != nil {
+		return nil, promotionRepoError(err)
+	}
+	if !p.IsActive {
+		return nil, ErrPromotionNotFound
+	}
+
+	usable, _, err := s.usable(ctx, []*models.Promotion{p}, nil, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(usable) == 0 {
+		return nil, ErrPromotionLimitReached
+	}
+	return p, nil
+}
+
+// usable leaves out the promotions that have been used up, overall or by
+// userID, and rejects the codes entered that are not among promotions.
+func (s *pricingService) usable(ctx context.Context, promotions []*models.Promotion, codes []string, userID *uuid.UUID) ([]*models.Promotion, []*models.RejectedPromotion, error) {
+	rejected := make([]*models.RejectedPromotion, 0)
+	for _, code := range codes {
+		found := slices.ContainsFunc(promotions, func(p *models.Promotion) bool { return strings.EqualFold(p.Code, code) })
+		if !found {
+			rejected = append(rejected, &models.RejectedPromotion{Code: code, Reason: promotion.ReasonUnavailable})
+		}
+	}
+
+	var limited []uuid.UUID
+	for _, p := range promotions {
+		if p.PerUserLimit != nil {
+			limited = append(limited, p.ID)
+		}
+	}
+	redeemed := map[uuid.UUID]int{}
+	if userID != nil && len(limited) > 0 {
+		var err error
+		if redeemed, err = s.promotionRepo.CountRedemptions(ctx, *userID, limited); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	usable := make([]*models.Promotion, 0, len(promotions))
+	for _, p := range promotions {
+		usedUp := (p.UsageLimit != nil && p.TimesUsed >= *p.UsageLimit) ||
+			(p.PerUserLimit != nil && redeemed[p.ID] >= *p.PerUserLimit)
+		if !usedUp {
+			usable = append(usable, p)
+			continue
+		}
+		if p.Code != "" {
+			rejected = append(rejected, &models.RejectedPromotion{Code: p.Code, Reason: promotion.ReasonUsageLimit})
+		}
+	}
+	return usable, rejected, nil
+}
+
+// firstOrder reports whether userID has not ordered yet. Anonymous
+// shoppers are treated as new; checkout prices the cart again once they
+// have signed in.
+func (s *pricingService) firstOrder(ctx context.Context, userID *uuid.UUID) (bool, error) {
+	if userID == nil {
+		return true, nil
+	}
+	ordered, err := s.orderRepo.HasOrders(ctx, userID.String())
+	return !ordered, err
+}
+
+// lineCategories sets the categories of each line to the category of its
+// product and those above it.
+func (s *pricingService) lineCategories(ctx context.Context, items []*models.CartItem, lines []promotion.Line) error {
+	paths := make(map[uuid.UUID][]string)
+	for i, item := range items {
+		if item.CategoryID == nil {
+			continue
+		}
+		ids, ok := paths[*item.CategoryID]
+		if !ok {
+			category, err := s.categories.Get(ctx, item.CategoryID.String())
+			if errors.Is(err, ErrCategoryNotFound) {
+				paths[*item.CategoryID] = nil
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			ids = strings.FieldsFunc(category.Path, func(r rune) bool { return r == '/' })
+			paths[*item.CategoryID] = ids
+		}
+		lines[i].CategoryIDs = ids
+	}
+	return nil
+}
+
+func needsFirstOrder(promotions []*models.Promotion) bool {
+	return slices.ContainsFunc(promotions, func(p *models.Promotion) bool { return p.FirstOrderOnly })
+}
+
+func needsCategories(promotions []*models.Promotion) bool {
+	return slices.ContainsFunc(promotions, func(p *models.Promotion) bool { return len(p.CategoryIDs) > 0 })
+}
+
+// priceBreakdown itemizes the result of applying promotions to the basket
+// of cart. Only rejected promotions with a code are listed, since shoppers
+// do not know about the others.
+func priceBreakdown(cart *models.Cart, basket promotion.Basket, result *promotion.Result, byRule map[*promotion.Promotion]*models.Promotion) *models.PriceBreakdown {
+	currency := basket.Currency
+	breakdown := &models.PriceBreakdown{
+		Subtotal:           basket.Subtotal(),
+		Discounts:          make([]*models.AppliedDiscount, 0, len(result.Discounts)),
+		DiscountTotal:      result.Total,
+		Shipping:           basket.Shipping,
+		Tax:                money.New(0, currency),
+		Lines:              make([]*models.LinePrice, len(cart.Items)),
+		Taxes:              make([]*models.TaxLine, 0),
+		RejectedPromotions: make([]*models.RejectedPromotion, 0),
+	}
+	breakdown.Total = money.New(breakdown.Subtotal.Amount-result.Total.Amount+basket.Shipping.Amount, currency)
+
+	for _, discount := range result.Discounts {
+		p := byRule[discount.Promotion]
+		breakdown.Discounts = append(breakdown.Discounts, &models.AppliedDiscount{
+			PromotionID: p.ID,
+			Code:        p.Code,
+			Name:        p.Name,
+			Kind:        p.Kind,
+			Amount:      discount.Amount,
+		})
+	}
+	for _, rejection := range result.Rejections {
+		if rejection.Promotion.Code != "" {
+			breakdown.RejectedPromotions = append(breakdown.RejectedPromotions,
+				&models.RejectedPromotion{Code: rejection.Promotion.Code, Reason: rejection.Reason})
+		}
+	}
+	for i, item := range cart.Items {
+		discount := money.New(result.LineDiscounts[item.ID.String()].Amount, currency)
+		breakdown.Lines[i] = &models.LinePrice{
+			ItemID:   item.ID,
+			Subtotal: item.Total,
+			Discount: discount,
+			Tax:      money.New(0, currency),
+			Total:    money.New(item.Total.Amount-discount.Amount, currency),
+		}
+	}
+	return breakdown
+}
+
+// shippingLine is the ID of the shipping line in tax requests.
+const shippingLine = "shipping"
+
+// taxRequest asks for the tax on the lines of breakdown after discounts,
+// each under the tax class of its product, and on the shipping left to
+// pay.
+func taxRequest(cart *models.Cart, breakdown *models.PriceBreakdown, result *promotion.Result, address tax.Address) tax.Request {
+	req := tax.Request{
+		Currency: breakdown.Subtotal.Currency,
+		Address:  address,
+		Lines:    make([]tax.Line, 0, len(cart.Items)+1),
+	}
+	for i, item := range cart.Items {
+		class := item.TaxClass
+		if class == "" {
+			class = tax.ClassStandard
+		}
+		req.Lines = append(req.Lines, tax.Line{ID: item.ID.String(), Class: class, Amount: breakdown.Lines[i].Total})
+	}
+	if shipping := breakdown.Shipping.Amount - result.ShippingDiscount.Amount; shipping > 0 {
+		req.Lines = append(req.Lines, tax.Line{
+			ID:     shippingLine,
+			Class:  tax.ClassShipping,
+			Amount: money.New(shipping, req.Currency),
+		})
+	}
+	return req
+}
+
+// addTax records the tax worked out for breakdown on its lines and adds
+// it to the total unless the prices already included it.
+func addTax(breakdown *models.PriceBreakdown, taxes *tax.Result) {
+	breakdown.Tax = taxes.Total
+	breakdown.PricesIncludeTax = taxes.Inclusive
+	if !taxes.Inclusive {
+		breakdown.Total.Amount += taxes.Total.Amount
+	}
+
+	byLine := make(map[string]money.Money, len(taxes.Lines))
+	for _, line := range taxes.Lines {
+		byLine[line.ID] = line.Tax
+	}
+	for _, line := range breakdown.Lines {
+		if amount, ok := byLine[line.ItemID.String()]; ok {
+			line.Tax = amount
+		}
+	}
+	for _, charge := range taxes.Charges {
+		breakdown.Taxes = append(breakdown.Taxes, &models.TaxLine{
+			Country: charge.Country,
+			Region:  charge.Region,
+			Class:   charge.Class,
+			Rate:    charge.Rate,
+			Taxable: charge.Taxable,
+			Amount:  charge.Amount,
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"suitemedia/internal/models"
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/promotion"
+	"suitemedia/pkg/tax"
+
+	"github.com/google/uuid"
+)
+
+func TestPriceBreakdown(t *testing.T) {
+	cart := newCart(nil)
+	cart.Items = []*models.CartItem{
+		{ID: uuid.New(), ProductID: uuid.New(), Quantity: 1, Total: money.New(3000, "USD")},
+		{ID: uuid.New(), ProductID: uuid.New(), Quantity: 2, Total: money.New(1000, "USD")},
+	}
+	basket := promotion.Basket{Currency: "USD", Shipping: money.New(500, "USD"), Now: time.Now()}
+	for _, item := range cart.Items {
+		basket.Lines = append(basket.Lines, promotion.Line{ID: item.ID.String(), ProductID: item.ProductID.String(), Amount: item.Total})
+	}
+
+	tenOff := &models.Promotion{ID: uuid.New(), Code: "TEN", Name: "10% off", Kind: promotion.Percentage, PercentOff: 10, Stackable: true}
+	shipping := &models.Promotion{ID: uuid.New(), Name: "Free shipping", Kind: promotion.FreeShipping, Stackable: true}
+	minimum := money.New(10000, "USD")
+	big := &models.Promotion{ID: uuid.New(), Code: "BIG", Name: "Big spender", Kind: promotion.Percentage, PercentOff: 20, MinSubtotal: &minimum}
+	byRule := map[*promotion.Promotion]*models.Promotion{}
+	var rules []*promotion.Promotion
+	for _, p := range []*models.Promotion{tenOff, shipping, big} {
+		rule := p.Rule()
+		byRule[rule] = p
+		rules = append(rules, rule)
+	}
+
+	breakdown := priceBreakdown(cart, basket, promotion.Apply(rules, basket), byRule)
+	if breakdown.Subtotal != money.New(4000, "USD") || breakdown.DiscountTotal != money.New(900, "USD") {
+		t.Errorf("Expected 900 USD off 4000 USD, got %+v off %+v", breakdown.DiscountTotal, breakdown.Subtotal)
+	}
+	if breakdown.Total != money.New(3600, "USD") {
+		t.Errorf("Expected a total of 3600 USD, got %+v", breakdown.Total)
+	}
+	if len(breakdown.Discounts) != 2 || breakdown.Discounts[0].Code != "TEN" || breakdown.Discounts[1].PromotionID != shipping.ID {
+		t.Errorf("Expected the code and free shipping to apply, got %+v", breakdown.Discounts)
+	}
+	if len(breakdown.RejectedPromotions) != 1 || breakdown.RejectedPromotions[0].Reason != promotion.ReasonMinSubtotal {
+		t.Errorf("Expected BIG to be rejected for its minimum, got %+v", breakdown.RejectedPromotions)
+	}
+	line := breakdown.Lines[0]
+	if line.ItemID != cart.Items[0].ID || line.Discount != money.New(300, "USD") || line.Total != money.New(2700, "USD") {
+		t.Errorf("Expected 300 USD off the first line, got %+v", line)
+	}
+}
+
+func TestPriceBreakdownTax(t *testing.T) {
+	cart := newCart(nil)
+	cart.Items = []*models.CartItem{
+		{ID: uuid.New(), ProductID: uuid.New(), Quantity: 1, Total: money.New(2000, "USD")},
+		{ID: uuid.New(), ProductID: uuid.New(), Quantity: 1, Total: money.New(1000, "USD"), TaxClass: "food"},
+	}
+	basket := promotion.Basket{Currency: "USD", Shipping: money.New(500, "USD"), Now: time.Now()}
+	for _, item := range cart.Items {
+		basket.Lines = append(basket.Lines, promotion.Line{ID: item.ID.String(), ProductID: item.ProductID.String(), Amount: item.Total})
+	}
+	half := &models.Promotion{ID: uuid.New(), Name: "Half off", Kind: promotion.Percentage, PercentOff: 50}
+	result := promotion.Apply([]*promotion.Promotion{half.Rule()}, basket)
+	breakdown := priceBreakdown(cart, basket, result, map[*promotion.Promotion]*models.Promotion{result.Discounts[0].Promotion: half})
+
+	rules, err := tax.ParseRules(map[string]string{"US": "10", "US/food": "0"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	calculator, err := tax.NewRuleCalculator(tax.Settings{Rules: rules})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	req := taxRequest(cart, breakdown, result, tax.Address{Country: "US"})
+	if len(req.Lines) != 3 || req.Lines[0].Class != tax.ClassStandard || req.Lines[2].Class != tax.ClassShipping {
+		t.Fatalf("Expected the lines and shipping to be taxed under their classes, got %+v", req.Lines)
+	}
+	taxes, err := calculator.Calculate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	addTax(breakdown, taxes)
+
+	// 10% of the discounted 1000 USD line and of 500 USD shipping.
+	if breakdown.Tax != money.New(150, "USD") || breakdown.Total != money.New(2150, "USD") {
+		t.Errorf("Expected 150 USD of tax on top of 2000 USD, got %+v for %+v", breakdown.Tax, breakdown.Total)
+	}
+	if breakdown.Lines[0].Tax != money.New(100, "USD") || !breakdown.Lines[1].Tax.IsZero() {
+		t.Errorf("Expected the tax on each line, got %+v and %+v", breakdown.Lines[0], breakdown.Lines[1])
+	}
+	if len(breakdown.Taxes) != 2 || breakdown.Taxes[0].Taxable != money.New(1500, "USD") {
+		t.Errorf("Expected a tax line per rate, got %+v", breakdown.Taxes)
+	}
+}
@@ -2,50 +2,477 @@ package service
 
 import (
 	"context"
+	"errors"
+	"strings"
 
+	"suitemedia/config"
 	"suitemedia/internal/models"
 	"suitemedia/internal/repository"
-	"suitemedia/pkg/redis"
+	"suitemedia/pkg/cache"
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/suggest"
+	"suitemedia/pkg/tax"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrPriceCurrency   = errors.New("price must be in the base currency")
+	ErrNegativePrice   = errors.New("prices must not be negative")
+	ErrDuplicatePrice  = errors.New("prices must be in distinct currencies other than the base currency")
 )
 
+const productListGenerationKey = "products:list:generation"
+
 type ProductService interface {
-	List(ctx context.Context, params models.ListParams) ([]*models.Product, int64, error)
+	List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Product], error)
+	Facets(ctx context.Context, params models.ListParams, priceBuckets []int64) (*models.ProductFacets, error)
+	// Localize returns copies of products priced in currency, using their
+	// explicit price in it when there is one and converting Price
+	// otherwise. Variant price ranges are converted as well.
+	Localize(ctx context.Context, currency string, products []*models.Product) ([]*models.Product, error)
+	// Suggest returns up to limit product names and categories for a
+	// search being typed.
+	Suggest(ctx context.Context, q string, limit int) ([]suggest.Suggestion, error)
+	// RecordPopularity counts a search for or view of text, raising it in
+	// suggestions.
+	RecordPopularity(ctx context.Context, text string)
+	// RebuildSuggestions rebuilds the suggestion index from the database.
+	RebuildSuggestions(ctx context.Context) error
 	GetByID(ctx context.Context, id string) (*models.Product, error)
-	Create(ctx context.Context, req models.CreateProductRequest) (*models.Product, error)
-	Update(ctx context.Context, id string, req models.UpdateProductRequest) (*models.Product, error)
-	Delete(ctx context.Context, id string) error
+	// GetLatest returns the product read from the database rather than the
+	// cache, for changes that must be computed against its current version.
+	GetLatest(ctx context.Context, id string) (*models.Product, error)
+	Create(ctx context.Context, createdBy string, req models.CreateProductRequest) (*models.Product, error)
+	// Update and Delete fail with ErrVersionConflict unless version is zero
+	// or matches the current version of the product.
+	Update(ctx context.Context, id string, version int, req models.UpdateProductRequest) (*models.Product, error)
+	Delete(ctx context.Context, id string, version int) error
+	// Invalidate drops the cached product and list pages after a change
+	// to data they include, such as its variants.
+	Invalidate(ctx context.Context, id string)
 }
 
 type productService struct {
-	productRepo repository.ProductRepository
-	redis       *redis.Client
+	productRepo   repository.ProductRepository
+	inventoryRepo repository.InventoryRepository
+	tx            repository.Transactor
+	categories    CategoryService
+	currencies    CurrencyService
+	cache         cache.Cache
+	products      *cache.Typed[*models.Product]
+	pages         *cache.Typed[*models.ListResult[*models.Product]]
+	facets        *cache.Typed[*models.ProductFacets]
+	suggestions   suggest.Index
 }
 
-func NewProductService(productRepo repository.ProductRepository, redis *redis.Client) ProductService {
+func NewProductService(productRepo repository.ProductRepository, inventoryRepo repository.InventoryRepository, tx repository.Transactor, categories CategoryService, currencies CurrencyService, kv cache.Cache, cfg config.CacheConfig, suggestions suggest.Index) ProductService {
 	return &productService{
-		productRepo: productRepo,
-		redis:       redis,
+		productRepo:   productRepo,
+		inventoryRepo: inventoryRepo,
+		tx:            tx,
+		categories:    categories,
+		currencies:    currencies,
+		cache:         kv,
+		suggestions:   suggestions,
+		products:      cache.NewTyped[*models.Product](kv, cacheOptions(cfg, "products", cfg.TTLSeconds, ErrProductNotFound)),
+		pages:         cache.NewTyped[*models.ListResult[*models.Product]](kv, cacheOptions(cfg, "products:list", cfg.ListTTLSeconds, nil)),
+		facets:        cache.NewTyped[*models.ProductFacets](kv, cacheOptions(cfg, "products:facets", cfg.FacetTTLSeconds, nil)),
+	}
+}
+
+func (s *productService) List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Product], error) {
+	key := listCacheKey(ctx, s.cache, productListGenerationKey, params)
+	return s.pages.Get(ctx, key, func(ctx context.Context) (*models.ListResult[*models.Product], error) {
+		result, err := s.productRepo.List(ctx, params)
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, ErrInvalidCursor
+		}
+		return result, err
+	})
+}
+
+func (s *productService) Facets(ctx context.Context, params models.ListParams, priceBuckets []int64) (*models.ProductFacets, error) {
+	// Only the search and filters affect facets; dropping the rest keeps
+	// the cache key independent of pagination.
+	params = models.ListParams{Search: params.Search, Query: params.Query, Filters: params.Filters, CategoryPath: params.CategoryPath}
+	key := listCacheKey(ctx, s.cache, productListGenerationKey, struct {
+		Params       models.ListParams `json:"params"`
+		PriceBuckets []int64           `json:"price_buckets"`
+	}{params, priceBuckets})
+
+	return s.facets.Get(ctx, key, func(ctx context.Context) (*models.ProductFacets, error) {
+		return s.productRepo.Facets(ctx, params, priceBuckets)
+	})
+}
+
+func (s *productService) Localize(ctx context.Context, currency string, products []*models.Product) ([]*models.Product, error) {
+	localized := make([]*models.Product, len(products))
+	for i, product := range products {
+		// Products may be shared with concurrent readers of the cache, so
+		// they are copied rather than changed.
+		copied := *product
+		localized[i] = &copied
+
+		price, err := s.localizePrice(ctx, product, product.Price, currency)
+		if err != nil {
+			return nil, err
+		}
+		copied.Price = price
+
+		if product.Variants != nil {
+			summary := *product.Variants
+			if summary.MinPrice, err = s.localizePrice(ctx, product, summary.MinPrice, currency); err != nil {
+				return nil, err
+			}
+			if summary.MaxPrice, err = s.localizePrice(ctx, product, summary.MaxPrice, currency); err != nil {
+				return nil, err
+			}
+			copied.Variants = &summary
+		}
 	}
+	return localized, nil
 }
 
-func (s *productService) List(ctx context.Context, params models.ListParams) ([]*models.Product, int64, error) {
-	return s.productRepo.List(ctx, params)
+// localizePrice returns amount, a price of product, in currency. The
+// product price uses the explicit price in currency when there is one;
+// other amounts, such as variant prices, are converted.
+func (s *productService) localizePrice(ctx context.Context, product *models.Product, amount money.Money, currency string) (money.Money, error) {
+	if amount == product.Price {
+		for _, price := range product.Prices {
+			if price.Currency == currency {
+				return price, nil
+			}
+		}
+	}
+	return s.currencies.Convert(ctx, amount, currency)
 }
 
 func (s *productService) GetByID(ctx context.Context, id string) (*models.Product, error) {
-	return s.productRepo.GetByID(ctx, id)
+	return s.products.Get(ctx, id, func(ctx context.Context) (*models.Product, error) {
+		return s.GetLatest(ctx, id)
+	})
+}
+
+func (s *productService) GetLatest(ctx context.Context, id string) (*models.Product, error) {
+	product, err := s.productRepo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrProductNotFound
+	}
+	return product, err
+}
+
+func (s *productService) Create(ctx context.Context, createdBy string, req models.CreateProductRequest) (*models.Product, error) {
+	creatorID, err := uuid.Parse(createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPrices(*req.Price, req.Prices); err != nil {
+		return nil, err
+	}
+
+	product := &models.Product{
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       *req.Price,
+		Prices:      append(make([]money.Money, 0, len(req.Prices)), req.Prices...),
+		Options:     make([]models.ProductOption, 0),
+		TaxClass:    taxClass(req.TaxClass),
+		ImageURL:    req.ImageURL,
+		IsActive:    true,
+		CreatedBy:   creatorID,
+	}
+	if err := s.setCategory(ctx, product, req.CategoryID, req.Category); err != nil {
+		return nil, err
+	}
+
+	// The initial stock is received into the ledger along with the
+	// product.
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.productRepo.Create(ctx, product); err != nil || req.Stock == 0 {
+			return err
+		}
+		receipt := &models.InventoryMovement{
+			StockItem: models.StockItem{ProductID: product.ID},
+			Kind:      models.MovementReceipt,
+			Quantity:  req.Stock,
+			Note:      "Initial stock",
+			CreatedBy: &creatorID,
+		}
+		if err := s.inventoryRepo.Record(ctx, receipt); err != nil {
+			return err
+		}
+		product.Stock = receipt.StockAfter
+		product.Version = receipt.ProductVersion
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	cache.BumpGeneration(ctx, s.cache, productListGenerationKey)
+	s.suggestions.Add(ctx, productTerms(product)...)
+
+	return product, nil
+}
+
+func (s *productService) Update(ctx context.Context, id string, version int, req models.UpdateProductRequest) (*models.Product, error) {
+	// Read from the database rather than the cache so the update is
+	// applied to the latest version of the record.
+	product, err := s.productRepo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && product.Version != version {
+		return nil, ErrVersionConflict
+	}
+	previous := productTerms(product)
+
+	if req.Name != nil {
+		product.Name = *req.Name
+	}
+	if req.Description != nil {
+		product.Description = *req.Description
+	}
+	if req.Price != nil {
+		product.Price = *req.Price
+	}
+	if req.Prices != nil {
+		product.Prices = req.Prices
+	}
+	if req.Price != nil || req.Prices != nil {
+		if err := s.checkPrices(product.Price, product.Prices); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case req.CategoryID != nil && *req.CategoryID != categoryIDString(product.CategoryID):
+		if err := s.setCategory(ctx, product, *req.CategoryID, ""); err != nil {
+			return nil, err
+		}
+	case req.Category != nil && *req.Category != product.Category:
+		if err := s.setCategory(ctx, product, "", *req.Category); err != nil {
+			return nil, err
+		}
+	}
+	if req.TaxClass != nil {
+		product.TaxClass = taxClass(*req.TaxClass)
+	}
+	if req.ImageURL != nil {
+		product.ImageURL = *req.ImageURL
+	}
+	if req.IsActive != nil {
+		product.IsActive = *req.IsActive
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.productRepo.Update(ctx, product); err != nil {
+			return err
+		}
+		if req.Stock == nil || *req.Stock == product.Stock {
+			return nil
+		}
+		item := models.StockItem{ProductID: product.ID}
+		adjustment, err := s.inventoryRepo.SetStock(ctx, item, *req.Stock, nil, "Stock set by product update")
+		if err != nil {
+			return err
+		}
+		if adjustment != nil {
+			product.Stock = adjustment.StockAfter
+			product.Version = adjustment.ProductVersion
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, productRepoError(err)
+	}
+	s.Invalidate(ctx, id)
+	s.reindex(ctx, previous, productTerms(product))
+
+	return product, nil
 }
 
-func (s *productService) Create(ctx context.Context, req models.CreateProductRequest) (*models.Product, error) {
-	// Implementation placeholder
-	return nil, nil
+func (s *productService) Delete(ctx context.Context, id string, version int) error {
+	product, err := s.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return productRepoError(err)
+	}
+
+	if err := s.productRepo.Delete(ctx, id, version); err != nil {
+		return productRepoError(err)
+	}
+	s.Invalidate(ctx, id)
+	s.reindex(ctx, productTerms(product), nil)
+
+	return nil
+}
+
+// productRepoError translates repository errors for writes to service
+// errors.
+func productRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrProductNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionConflict
+	case errors.Is(err, repository.ErrVariantRequired):
+		return ErrVariantRequired
+	}
+	return err
+}
+
+// invalidate drops the cached product and every cached list page.
+func (s *productService) Invalidate(ctx context.Context, id string) {
+	s.products.Invalidate(ctx, id)
+	cache.BumpGeneration(ctx, s.cache, productListGenerationKey)
+}
+
+func (s *productService) Suggest(ctx context.Context, q string, limit int) ([]suggest.Suggestion, error) {
+	suggestions, err := s.suggestions.Search(ctx, q, limit)
+	if err != nil {
+		suggestions = nil
+	}
+	if len(suggestions) >= limit {
+		return suggestions, nil
+	}
+
+	// Fill up with similar terms from the database, which also finds
+	// misspelled terms the prefix index cannot.
+	similar, err := s.productRepo.Suggest(ctx, q, limit)
+	if err != nil {
+		if len(suggestions) > 0 {
+			return suggestions, nil
+		}
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(suggestions))
+	for _, suggestion := range suggestions {
+		seen[suggestion.Kind+":"+suggest.Normalize(suggestion.Text)] = true
+	}
+	for _, suggestion := range similar {
+		if len(suggestions) == limit {
+			break
+		}
+		if key := suggestion.Kind + ":" + suggest.Normalize(suggestion.Text); !seen[key] {
+			seen[key] = true
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions, nil
 }
 
-func (s *productService) Update(ctx context.Context, id string, req models.UpdateProductRequest) (*models.Product, error) {
-	// Implementation placeholder
-	return nil, nil
+func (s *productService) RecordPopularity(ctx context.Context, text string) {
+	s.suggestions.Record(ctx, text)
 }
 
-func (s *productService) Delete(ctx context.Context, id string) error {
-	return s.productRepo.Delete(ctx, id)
+func (s *productService) RebuildSuggestions(ctx context.Context) error {
+	terms, err := s.productRepo.SuggestionTerms(ctx)
+	if err != nil {
+		return err
+	}
+	return s.suggestions.Replace(ctx, terms)
+}
+
+// reindex updates the suggestion index after a product's terms changed
+// from previous to current. Terms other products still use are kept. The
+// index is maintained on a best-effort basis and rebuilt on startup.
+func (s *productService) reindex(ctx context.Context, previous, current []suggest.Term) {
+	s.suggestions.Add(ctx, current...)
+
+	for _, term := range previous {
+		if containsTerm(current, term) {
+			continue
+		}
+		if inUse, err := s.productRepo.TermInUse(ctx, term); err == nil && !inUse {
+			s.suggestions.Remove(ctx, term)
+		}
+	}
+}
+
+func productTerms(product *models.Product) []suggest.Term {
+	terms := []suggest.Term{{Kind: suggest.KindProduct, Text: product.Name}}
+	if product.Category != "" {
+		terms = append(terms, suggest.Term{Kind: suggest.KindCategory, Text: product.Category})
+	}
+	return terms
+}
+
+func containsTerm(terms []suggest.Term, term suggest.Term) bool {
+	for _, t := range terms {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPrices verifies that price is in the base currency and the
+// explicit prices are in distinct other currencies, none negative.
+func (s *productService) checkPrices(price money.Money, prices []money.Money) error {
+	if price.Currency != s.currencies.Base() {
+		return ErrPriceCurrency
+	}
+
+	if price.IsNegative() {
+		return ErrNegativePrice
+	}
+
+	seen := map[string]bool{price.Currency: true}
+	for _, p := range prices {
+		if p.IsNegative() {
+			return ErrNegativePrice
+		}
+		if seen[p.Currency] {
+			return ErrDuplicatePrice
+		}
+		seen[p.Currency] = true
+	}
+	return nil
+}
+
+// setCategory assigns the category with the given ID to product or, when
+// id is empty, the category named name, creating it at the top level so
+// clients that only send names keep working. Both empty clears it.
+func (s *productService) setCategory(ctx context.Context, product *models.Product, id, name string) error {
+	var category *models.Category
+	var err error
+	switch {
+	case id != "":
+		category, err = s.categories.Get(ctx, id)
+	case name != "":
+		category, err = s.categories.FindOrCreate(ctx, name)
+	default:
+		product.CategoryID = nil
+		product.Category = ""
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	product.CategoryID = &category.ID
+	product.Category = category.Name
+	return nil
+}
+
+func categoryIDString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// taxClass normalizes the tax class of a product, which is the standard
+// class unless one is named.
+func taxClass(class string) string {
+	class = strings.ToLower(strings.TrimSpace(class))
+	if class == "" {
+		return tax.ClassStandard
+	}
+	return class
 }
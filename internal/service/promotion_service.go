@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+	"suitemedia/pkg/money"
+	"suitemedia/pkg/promotion"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPromotionNotFound     = errors.New("promotion not found")
+	ErrPromotionCodeExists   = errors.New("promotion code already exists")
+	ErrPromotionLimitReached = errors.New("promotion has reached its usage limit")
+	ErrPromotionWindow       = errors.New("promotion must end after it starts")
+	ErrPromotionAmount       = errors.New("promotion amounts must be positive and in the base currency")
+)
+
+type PromotionService interface {
+	List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Promotion], error)
+	Get(ctx context.Context, id string) (*models.Promotion, error)
+	Create(ctx context.Context, req models.CreatePromotionRequest) (*models.Promotion, error)
+	// Update and Delete fail with ErrVersionConflict unless version is zero
+	// or matches the current version of the promotion.
+	Update(ctx context.Context, id string, version int, req models.UpdatePromotionRequest) (*models.Promotion, error)
+	Delete(ctx context.Context, id string, version int) error
+}
+
+type promotionService struct {
+	promotionRepo repository.PromotionRepository
+	currencies    CurrencyService
+}
+
+func NewPromotionService(promotionRepo repository.PromotionRepository, currencies CurrencyService) PromotionService {
+	return &promotionService{
+		promotionRepo: promotionRepo,
+		currencies:    currencies,
+	}
+}
+
+func (s *promotionService) List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.Promotion], error) {
+	result, err := s.promotionRepo.List(ctx, params)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, ErrInvalidCursor
+	}
+	return result, err
+}
+
+func (s *promotionService) Get(ctx context.Context, id string) (*models.Promotion, error) {
+	p, err := s.promotionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, promotionRepoError(err)
+	}
+	return p, nil
+}
+
+func (s *promotionService) Create(ctx context.Context, req models.CreatePromotionRequest) (*models.Promotion, error) {
+	p := &models.Promotion{
+		Code:           normalizePromotionCode(req.Code),
+		Name:           req.Name,
+		Description:    req.Description,
+		Kind:           req.Kind,
+		PercentOff:     req.PercentOff,
+		AmountOff:      req.AmountOff,
+		MinSubtotal:    req.MinSubtotal,
+		ProductIDs:     parseUUIDList(req.ProductIDs),
+		CategoryIDs:    parseUUIDList(req.CategoryIDs),
+		FirstOrderOnly: req.FirstOrderOnly,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		Stackable:      req.Stackable,
+		Priority:       req.Priority,
+		UsageLimit:     req.UsageLimit,
+		PerUserLimit:   req.PerUserLimit,
+		IsActive:       true,
+	}
+	if err := s.check(p); err != nil {
+		return nil, err
+	}
+
+	if err := s.promotionRepo.Create(ctx, p); err != nil {
+		return nil, promotionRepoError(err)
+	}
+
+	return p, nil
+}
+
+func (s *promotionService) Update(ctx context.Context, id string, version int, req models.UpdatePromotionRequest) (*models.Promotion, error) {
+	p, err := s.promotionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, promotionRepoError(err)
+	}
+	if version != 0 && p.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	if req.Code != nil {
+		p.Code = normalizePromotionCode(*req.Code)
+	}
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Description != nil {
+		p.Description = *req.Description
+	}
+	if req.PercentOff != nil {
+		p.PercentOff = *req.PercentOff
+	}
+	if req.AmountOff != nil {
+		p.AmountOff = req.AmountOff
+	}
+	if req.MinSubtotal != nil {
+		p.MinSubtotal = req.MinSubtotal
+	}
+	if req.ProductIDs != nil {
+		p.ProductIDs = parseUUIDList(req.ProductIDs)
+	}
+	if req.CategoryIDs != nil {
+		p.CategoryIDs = parseUUIDList(req.CategoryIDs)
+	}
+	if req.FirstOrderOnly != nil {
+		p.FirstOrderOnly = *req.FirstOrderOnly
+	}
+	if req.StartsAt != nil {
+		p.StartsAt = req.StartsAt
+	}
+	if req.EndsAt != nil {
+		p.EndsAt = req.EndsAt
+	}
+	if req.Stackable != nil {
+		p.Stackable = *req.Stackable
+	}
+	if req.Priority != nil {
+		p.Priority = *req.Priority
+	}
+	if req.UsageLimit != nil {
+		p.UsageLimit = optionalLimit(*req.UsageLimit)
+	}
+	if req.PerUserLimit != nil {
+		p.PerUserLimit = optionalLimit(*req.PerUserLimit)
+	}
+	if req.IsActive != nil {
+		p.IsActive = *req.IsActive
+	}
+	if err := s.check(p); err != nil {
+		return nil, err
+	}
+
+	if err := s.promotionRepo.Update(ctx, p); err != nil {
+		return nil, promotionRepoError(err)
+	}
+
+	return p, nil
+}
+
+func (s *promotionService) Delete(ctx context.Context, id string, version int) error {
+	if err := s.promotionRepo.Delete(ctx, id, version); err != nil {
+		return promotionRepoError(err)
+	}
+	return nil
+}
+
+// check validates p and drops the settings its kind does not use. A zero
+// minimum subtotal is no minimum.
+func (s *promotionService) check(p *models.Promotion) error {
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return ErrPromotionWindow
+	}
+
+	switch p.Kind {
+	case promotion.Percentage:
+		p.AmountOff = nil
+	case promotion.Fixed:
+		p.PercentOff = 0
+	case promotion.FreeShipping:
+		p.PercentOff, p.AmountOff = 0, nil
+	}
+	if p.MinSubtotal != nil && p.MinSubtotal.Amount == 0 {
+		p.MinSubtotal = nil
+	}
+
+	base := s.currencies.Base()
+	if p.AmountOff != nil && !validPromotionAmount(*p.AmountOff, base, false) {
+		return ErrPromotionAmount
+	}
+	if p.MinSubtotal != nil && !validPromotionAmount(*p.MinSubtotal, base, true) {
+		return ErrPromotionAmount
+	}
+	return nil
+}
+
+// validPromotionAmount reports whether amount is in the base currency and
+// positive, or not negative when zero is allowed.
+func validPromotionAmount(amount money.Money, base string, zero bool) bool {
+	if amount.Currency != base {
+		return false
+	}
+	return amount.Amount > 0 || (zero && amount.Amount == 0)
+}
+
+// normalizePromotionCode trims a promotion code and makes it uppercase, the
+// form carts keep codes in.
+func normalizePromotionCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// parseUUIDList parses IDs the request binding has already validated.
+func parseUUIDList(values []string) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, value := range values {
+		if id, err := uuid.Parse(value); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// optionalLimit returns a pointer to limit, or nil when it is zero.
+func optionalLimit(limit int) *int {
+	if limit == 0 {
+		return nil
+	}
+	return &limit
+}
+
+// promotionRepoError translates repository errors for promotions to
+// service errors.
+func promotionRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrPromotionNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionConflict
+	case errors.Is(err, repository.ErrDuplicate):
+		return ErrPromotionCodeExists
+	case errors.Is(err, repository.ErrLimitReached):
+		return ErrPromotionLimitReached
+	}
+	return err
+}
@@ -4,57 +4,91 @@ import (
 	"context"
 	"errors"
 
+	"suitemedia/config"
 	"suitemedia/internal/models"
 	"suitemedia/internal/repository"
-	"suitemedia/pkg/redis"
+	"suitemedia/pkg/cache"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrVersionConflict = errors.New("resource has been modified")
+	ErrInvalidCursor   = errors.New("cursor is not valid for this list")
 )
 
 type UserService interface {
-	List(ctx context.Context, params models.ListParams) ([]*models.UserResponse, int64, error)
+	List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.UserResponse], error)
 	GetByID(ctx context.Context, id string) (*models.UserResponse, error)
+	// GetLatest returns the user read from the database rather than the
+	// cache, for changes that must be computed against its current version.
+	GetLatest(ctx context.Context, id string) (*models.UserResponse, error)
 	Create(ctx context.Context, req models.CreateUserRequest) (*models.UserResponse, error)
-	Update(ctx context.Context, id string, req models.UpdateUserRequest) (*models.UserResponse, error)
-	Delete(ctx context.Context, id string) error
+	// Update and Delete fail with ErrVersionConflict unless version is zero
+	// or matches the current version of the user.
+	Update(ctx context.Context, id string, version int, req models.UpdateUserRequest) (*models.UserResponse, error)
+	Delete(ctx context.Context, id string, version int) error
 }
 
+const userListGenerationKey = "users:list:generation"
+
 type userService struct {
 	userRepo repository.UserRepository
-	redis    *redis.Client
+	cache    cache.Cache
+	users    *cache.Typed[*models.UserResponse]
+	pages    *cache.Typed[*models.ListResult[*models.UserResponse]]
 }
 
-func NewUserService(userRepo repository.UserRepository, redis *redis.Client) UserService {
+func NewUserService(userRepo repository.UserRepository, kv cache.Cache, cfg config.CacheConfig) UserService {
 	return &userService{
 		userRepo: userRepo,
-		redis:    redis,
+		cache:    kv,
+		users:    cache.NewTyped[*models.UserResponse](kv, cacheOptions(cfg, "users", cfg.TTLSeconds, ErrUserNotFound)),
+		pages:    cache.NewTyped[*models.ListResult[*models.UserResponse]](kv, cacheOptions(cfg, "users:list", cfg.ListTTLSeconds, nil)),
 	}
 }
 
-func (s *userService) List(ctx context.Context, params models.ListParams) ([]*models.UserResponse, int64, error) {
-	users, total, err := s.userRepo.List(ctx, params)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	responses := make([]*models.UserResponse, len(users))
-	for i, user := range users {
-		resp := user.ToResponse()
-		responses[i] = &resp
-	}
-
-	return responses, total, nil
+func (s *userService) List(ctx context.Context, params models.ListParams) (*models.ListResult[*models.UserResponse], error) {
+	key := listCacheKey(ctx, s.cache, userListGenerationKey, params)
+	return s.pages.Get(ctx, key, func(ctx context.Context) (*models.ListResult[*models.UserResponse], error) {
+		result, err := s.userRepo.List(ctx, params)
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, ErrInvalidCursor
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		responses := make([]*models.UserResponse, len(result.Items))
+		for i, user := range result.Items {
+			resp := user.ToResponse()
+			responses[i] = &resp
+		}
+		return &models.ListResult[*models.UserResponse]{
+			Items:     responses,
+			Total:     result.Total,
+			Estimated: result.Estimated,
+			Next:      result.Next,
+			Prev:      result.Prev,
+		}, nil
+	})
 }
 
 func (s *userService) GetByID(ctx context.Context, id string) (*models.UserResponse, error) {
+	return s.users.Get(ctx, id, func(ctx context.Context) (*models.UserResponse, error) {
+		return s.GetLatest(ctx, id)
+	})
+}
+
+func (s *userService) GetLatest(ctx context.Context, id string) (*models.UserResponse, error) {
 	user, err := s.userRepo.GetByID(ctx, id)
-	if err != nil {
+	if errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrUserNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	resp := user.ToResponse()
 	return &resp, nil
@@ -93,16 +127,20 @@ func (s *userService) Create(ctx context.Context, req models.CreateUserRequest)
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
+	cache.BumpGeneration(ctx, s.cache, userListGenerationKey)
 
 	resp := user.ToResponse()
 	return &resp, nil
 }
 
-func (s *userService) Update(ctx context.Context, id string, req models.UpdateUserRequest) (*models.UserResponse, error) {
+func (s *userService) Update(ctx context.Context, id string, version int, req models.UpdateUserRequest) (*models.UserResponse, error) {
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
+	if version != 0 && user.Version != version {
+		return nil, ErrVersionConflict
+	}
 
 	if req.FirstName != nil {
 		user.FirstName = *req.FirstName
@@ -118,18 +156,37 @@ func (s *userService) Update(ctx context.Context, id string, req models.UpdateUs
 	}
 
 	if err := s.userRepo.Update(ctx, user); err != nil {
-		return nil, err
+		return nil, userRepoError(err)
 	}
+	s.invalidate(ctx, id)
 
 	resp := user.ToResponse()
 	return &resp, nil
 }
 
-func (s *userService) Delete(ctx context.Context, id string) error {
-	_, err := s.userRepo.GetByID(ctx, id)
-	if err != nil {
+func (s *userService) Delete(ctx context.Context, id string, version int) error {
+	if err := s.userRepo.Delete(ctx, id, version); err != nil {
+		return userRepoError(err)
+	}
+	s.invalidate(ctx, id)
+
+	return nil
+}
+
+// userRepoError translates repository errors for writes to service errors.
+func userRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
 		return ErrUserNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionConflict
 	}
+	return err
+}
 
-	return s.userRepo.Delete(ctx, id)
+// invalidate drops the cached user and every cached list page. Cache
+// failures are ignored; entries then expire with their TTL.
+func (s *userService) invalidate(ctx context.Context, id string) {
+	s.users.Invalidate(ctx, id)
+	cache.BumpGeneration(ctx, s.cache, userListGenerationKey)
 }
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrVariantNotFound       = errors.New("variant not found")
+	ErrVariantExists         = errors.New("a variant with this SKU or these options already exists")
+	ErrInvalidVariantOptions = errors.New("variant options must have one allowed value for each product option")
+	ErrInvalidProductOptions = errors.New("option names and the values of each option must be distinct")
+	ErrTooManyVariants       = errors.New("options have too many combinations")
+)
+
+// maxGeneratedVariants bounds the number of option combinations Generate
+// creates variants for.
+const maxGeneratedVariants = 100
+
+type VariantService interface {
+	List(ctx context.Context, productID string) ([]*models.Variant, error)
+	Get(ctx context.Context, productID, id string) (*models.Variant, error)
+	Create(ctx context.Context, productID string, req models.CreateVariantRequest) (*models.Variant, error)
+	// Generate replaces the options of a product and returns its variants
+	// after creating those for new option combinations and deleting those
+	// for combinations that are no longer possible.
+	Generate(ctx context.Context, productID string, req models.GenerateVariantsRequest) ([]*models.Variant, error)
+	// Update and Delete fail with ErrVersionConflict unless version is zero
+	// or matches the current version of the variant.
+	Update(ctx context.Context, productID, id string, version int, req models.UpdateVariantRequest) (*models.Variant, error)
+	Delete(ctx context.Context, productID, id string, version int) error
+}
+
+type variantService struct {
+	variantRepo   repository.VariantRepository
+	inventoryRepo repository.InventoryRepository
+	tx            repository.Transactor
+	products      ProductService
+}
+
+func NewVariantService(variantRepo repository.VariantRepository, inventoryRepo repository.InventoryRepository, tx repository.Transactor, products ProductService) VariantService {
+	return &variantService{
+		variantRepo:   variantRepo,
+		inventoryRepo: inventoryRepo,
+		tx:            tx,
+		products:      products,
+	}
+}
+
+func (s *variantService) List(ctx context.Context, productID string) ([]*models.Variant, error) {
+	if _, err := s.products.GetByID(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.variantRepo.ListByProduct(ctx, productID)
+}
+
+func (s *variantService) Get(ctx context.Context, productID, id string) (*models.Variant, error) {
+	variant, err := s.variantRepo.GetByID(ctx, productID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrVariantNotFound
+	}
+	return variant, err
+}
+
+func (s *variantService) Create(ctx context.Context, productID string, req models.CreateVariantRequest) (*models.Variant, error) {
+	product, err := s.products.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Options == nil {
+		req.Options = map[string]string{}
+	}
+	if !matchesOptions(product.Options, req.Options) {
+		return nil, ErrInvalidVariantOptions
+	}
+
+	variant := &models.Variant{
+		ProductID: product.ID,
+		SKU:       strings.TrimSpace(req.SKU),
+		Options:   req.Options,
+		Price:     req.Price,
+		Barcode:   req.Barcode,
+		ImageURL:  req.ImageURL,
+		Position:  req.Position,
+	}
+	if err := checkVariantPrice(product, variant); err != nil {
+		return nil, err
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.variantRepo.Create(ctx, variant); err != nil || req.Stock == 0 {
+			return err
+		}
+		receipt := &models.InventoryMovement{
+			StockItem: models.StockItem{ProductID: product.ID, VariantID: &variant.ID},
+			Kind:      models.MovementReceipt,
+			Quantity:  req.Stock,
+			Note:      "Initial stock",
+		}
+		if err := s.inventoryRepo.Record(ctx, receipt); err != nil {
+			return err
+		}
+		variant.Stock = receipt.StockAfter
+		return nil
+	})
+	if err != nil {
+		return nil, variantRepoError(err)
+	}
+	s.products.Invalidate(ctx, productID)
+
+	return variant, nil
+}
+
+func (s *variantService) Generate(ctx context.Context, productID string, req models.GenerateVariantsRequest) ([]*models.Variant, error) {
+	product, err := s.products.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !distinctOptions(req.Options) {
+		return nil, ErrInvalidProductOptions
+	}
+
+	combinations := optionCombinations(req.Options)
+	if len(combinations) > maxGeneratedVariants {
+		return nil, ErrTooManyVariants
+	}
+
+	existing, err := s.variantRepo.ListByProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	byOptions := make(map[string]*models.Variant, len(existing))
+	for _, variant := range existing {
+		byOptions[optionsKey(variant.Options)] = variant
+	}
+
+	create := make([]*models.Variant, 0)
+	for i, options := range combinations {
+		key := optionsKey(options)
+		if _, ok := byOptions[key]; ok {
+			delete(byOptions, key)
+			continue
+		}
+		create = append(create, &models.Variant{
+			ProductID: product.ID,
+			SKU:       generatedSKU(req.SKUPrefix, req.Options, options),
+			Options:   options,
+			Position:  i,
+		})
+	}
+	remove := make([]uuid.UUID, 0, len(byOptions))
+	for _, variant := range byOptions {
+		remove = append(remove, variant.ID)
+	}
+
+	err = s.variantRepo.Generate(ctx, product.ID, req.Options, create, remove)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, variantRepoError(err)
+	}
+	s.products.Invalidate(ctx, productID)
+
+	return s.variantRepo.ListByProduct(ctx, productID)
+}
+
+func (s *variantService) Update(ctx context.Context, productID, id string, version int, req models.UpdateVariantRequest) (*models.Variant, error) {
+	product, err := s.products.GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := s.variantRepo.GetByID(ctx, productID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrVariantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && variant.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	if req.SKU != nil {
+		variant.SKU = strings.TrimSpace(*req.SKU)
+	}
+	if req.InheritPrice {
+		variant.Price = nil
+	} else if req.Price != nil {
+		variant.Price = req.Price
+	}
+	if req.Barcode != nil {
+		variant.Barcode = *req.Barcode
+	}
+	if req.ImageURL != nil {
+		variant.ImageURL = *req.ImageURL
+	}
+	if req.Position != nil {
+		variant.Position = *req.Position
+	}
+	if err := checkVariantPrice(product, variant); err != nil {
+		return nil, err
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.variantRepo.Update(ctx, variant); err != nil {
+			return err
+		}
+		if req.Stock == nil || *req.Stock == variant.Stock {
+			return nil
+		}
+		item := models.StockItem{ProductID: product.ID, VariantID: &variant.ID}
+		adjustment, err := s.inventoryRepo.SetStock(ctx, item, *req.Stock, nil, "Stock set by variant update")
+		if err != nil {
+			return err
+		}
+		if adjustment != nil {
+			variant.Stock = adjustment.StockAfter
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, variantRepoError(err)
+	}
+	s.products.Invalidate(ctx, productID)
+
+	return variant, nil
+}
+
+func (s *variantService) Delete(ctx context.Context, productID, id string, version int) error {
+	if err := s.variantRepo.Delete(ctx, productID, id, version); err != nil {
+		return variantRepoError(err)
+	}
+	s.products.Invalidate(ctx, productID)
+
+	return nil
+}
+
+// variantRepoError translates repository errors for writes to service
+// errors.
+func variantRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrVariantNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionConflict
+	case errors.Is(err, repository.ErrDuplicate):
+		return ErrVariantExists
+	}
+	return err
+}
+
+// checkVariantPrice verifies that a price override is in the currency of
+// the product and not negative.
+func checkVariantPrice(product *models.Product, variant *models.Variant) error {
+	if variant.Price == nil {
+		return nil
+	}
+	if variant.Price.Currency != product.Price.Currency {
+		return ErrPriceCurrency
+	}
+	if variant.Price.IsNegative() {
+		return ErrNegativePrice
+	}
+	return nil
+}
+
+// matchesOptions reports whether values holds one allowed value for each
+// of options and nothing else.
+func matchesOptions(options []models.ProductOption, values map[string]string) bool {
+	if len(values) != len(options) {
+		return false
+	}
+	for _, option := range options {
+		value, ok := values[option.Name]
+		if !ok || !slices.Contains(option.Values, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// distinctOptions reports whether option names, and the values of each
+// option, are unique.
+func distinctOptions(options []models.ProductOption) bool {
+	names := make(map[string]bool, len(options))
+	for _, option := range options {
+		if names[option.Name] {
+			return false
+		}
+		names[option.Name] = true
+
+		values := make(map[string]bool, len(option.Values))
+		for _, value := range option.Values {
+			if values[value] {
+				return false
+			}
+			values[value] = true
+		}
+	}
+	return true
+}
+
+// optionCombinations returns every combination of one value of each
+// option, varying the last option fastest.
+func optionCombinations(options []models.ProductOption) []map[string]string {
+	combinations := []map[string]string{{}}
+	for _, option := range options {
+		next := make([]map[string]string, 0, len(combinations)*len(option.Values))
+		for _, combination := range combinations {
+			for _, value := range option.Values {
+				extended := make(map[string]string, len(combination)+1)
+				for name, v := range combination {
+					extended[name] = v
+				}
+				extended[option.Name] = value
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// optionsKey identifies an option combination independently of map order.
+func optionsKey(options map[string]string) string {
+	key, _ := json.Marshal(options)
+	return string(key)
+}
+
+// generatedSKU joins prefix and the slugs of the option values in option
+// order, such as "TEE-RED-XL".
+func generatedSKU(prefix string, options []models.ProductOption, values map[string]string) string {
+	parts := []string{strings.TrimSpace(prefix)}
+	for _, option := range options {
+		parts = append(parts, slugify(values[option.Name]))
+	}
+	return strings.ToUpper(strings.Join(parts, "-"))
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	"suitemedia/internal/models"
+)
+
+var teeOptions = []models.ProductOption{
+	{Name: "color", Values: []string{"Red", "Navy Blue"}},
+	{Name: "size", Values: []string{"M", "XL"}},
+}
+
+func TestOptionCombinations(t *testing.T) {
+	combinations := optionCombinations(teeOptions)
+	if len(combinations) != 4 {
+		t.Fatalf("Expected 4 combinations, got %d", len(combinations))
+	}
+	if got := optionsKey(combinations[1]); got != `{"color":"Red","size":"XL"}` {
+		t.Errorf("Expected Red/XL second, got %s", got)
+	}
+	if got := generatedSKU("tee", teeOptions, combinations[2]); got != "TEE-NAVY-BLUE-M" {
+		t.Errorf("Expected SKU TEE-NAVY-BLUE-M, got %s", got)
+	}
+}
+
+func TestMatchesOptions(t *testing.T) {
+	cases := []struct {
+		values map[string]string
+		want   bool
+	}{
+		{map[string]string{"color": "Red", "size": "M"}, true},
+		{map[string]string{"color": "Green", "size": "M"}, false},
+		{map[string]string{"color": "Red"}, false},
+		{map[string]string{"color": "Red", "size": "M", "fit": "slim"}, false},
+	}
+	for _, tc := range cases {
+		if got := matchesOptions(teeOptions, tc.values); got != tc.want {
+			t.Errorf("Expected matchesOptions(%v) to be %v", tc.values, tc.want)
+		}
+	}
+
+	if !matchesOptions(nil, map[string]string{}) {
+		t.Error("Expected empty options to match a product without options")
+	}
+}
+
+func TestDistinctOptions(t *testing.T) {
+	if !distinctOptions(teeOptions) {
+		t.Error("Expected options to be distinct")
+	}
+	if distinctOptions([]models.ProductOption{{Name: "size", Values: []string{"M"}}, {Name: "size", Values: []string{"L"}}}) {
+		t.Error("Expected repeated option names to be rejected")
+	}
+	if distinctOptions([]models.ProductOption{{Name: "size", Values: []string{"M", "M"}}}) {
+		t.Error("Expected repeated values to be rejected")
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"suitemedia/internal/models"
+	"suitemedia/internal/repository"
+)
+
+var (
+	ErrWarehouseNotFound   = errors.New("warehouse not found")
+	ErrWarehouseCodeExists = errors.New("warehouse code already exists")
+	ErrWarehouseInUse      = errors.New("warehouse still holds or reserves stock")
+	ErrWarehouseInactive   = errors.New("warehouse is not active")
+	ErrDefaultWarehouse    = errors.New("the default warehouse must stay active; make another warehouse the default first")
+)
+
+type WarehouseService interface {
+	// List returns every warehouse ordered by priority and code.
+	List(ctx context.Context) ([]*models.Warehouse, error)
+	Get(ctx context.Context, id string) (*models.Warehouse, error)
+	Create(ctx context.Context, req models.CreateWarehouseRequest) (*models.Warehouse, error)
+	// Update and Delete fail with ErrVersionConflict unless version is zero
+	// or matches the current version of the warehouse.
+	Update(ctx context.Context, id string, version int, req models.UpdateWarehouseRequest) (*models.Warehouse, error)
+	Delete(ctx context.Context, id string, version int) error
+}
+
+type warehouseService struct {
+	warehouseRepo repository.WarehouseRepository
+}
+
+func NewWarehouseService(warehouseRepo repository.WarehouseRepository) WarehouseService {
+	return &warehouseService{
+		warehouseRepo: warehouseRepo,
+	}
+}
+
+func (s *warehouseService) List(ctx context.Context) ([]*models.Warehouse, error) {
+	return s.warehouseRepo.List(ctx)
+}
+
+func (s *warehouseService) Get(ctx context.Context, id string) (*models.Warehouse, error) {
+	warehouse, err := s.warehouseRepo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrWarehouseNotFound
+	}
+	return warehouse, err
+}
+
+func (s *warehouseService) Create(ctx context.Context, req models.CreateWarehouseRequest) (*models.Warehouse, error) {
+	warehouse := &models.Warehouse{
+		Code:      normalizeWarehouseCode(req.Code),
+		Name:      req.Name,
+		Address:   req.Address,
+		Country:   strings.ToUpper(req.Country),
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Priority:  req.Priority,
+		IsActive:  true,
+		IsDefault: req.IsDefault,
+	}
+
+	if err := s.warehouseRepo.Create(ctx, warehouse); err != nil {
+		return nil, warehouseRepoError(err)
+	}
+
+	return warehouse, nil
+}
+
+func (s *warehouseService) Update(ctx context.Context, id string, version int, req models.UpdateWarehouseRequest) (*models.Warehouse, error) {
+	warehouse, err := s.warehouseRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, warehouseRepoError(err)
+	}
+	if version != 0 && warehouse.Version != version {
+		return nil, ErrVersionConflict
+	}
+	wasDefault := warehouse.IsDefault
+
+	if req.Code != nil {
+		warehouse.Code = normalizeWarehouseCode(*req.Code)
+	}
+	if req.Name != nil {
+		warehouse.Name = *req.Name
+	}
+	if req.Address != nil {
+		warehouse.Address = *req.Address
+	}
+	if req.Country != nil {
+		warehouse.Country = strings.ToUpper(*req.Country)
+	}
+	if req.Latitude != nil {
+		warehouse.Latitude, warehouse.Longitude = req.Latitude, req.Longitude
+	}
+	if req.Priority != nil {
+		warehouse.Priority = *req.Priority
+	}
+	if req.IsActive != nil {
+		warehouse.IsActive = *req.IsActive
+	}
+	if req.IsDefault != nil {
+		warehouse.IsDefault = *req.IsDefault
+	}
+	// There is always a default warehouse, and it receives stock, so it
+	// cannot be unset or deactivated directly.
+	if (wasDefault && !warehouse.IsDefault) || (warehouse.IsDefault && !warehouse.IsActive) {
+		return nil, ErrDefaultWarehouse
+	}
+
+	if err := s.warehouseRepo.Update(ctx, warehouse); err != nil {
+		return nil, warehouseRepoError(err)
+	}
+
+	return warehouse, nil
+}
+
+func (s *warehouseService) Delete(ctx context.Context, id string, version int) error {
+	warehouse, err := s.warehouseRepo.GetByID(ctx, id)
+	if err != nil {
+		return warehouseRepoError(err)
+	}
+	if warehouse.IsDefault {
+		return ErrDefaultWarehouse
+	}
+
+	if err := s.warehouseRepo.Delete(ctx, id, version); err != nil {
+		return warehouseRepoError(err)
+	}
+
+	return nil
+}
+
+// normalizeWarehouseCode trims a warehouse code and makes it uppercase, so
+// codes are unique regardless of case.
+func normalizeWarehouseCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// warehouseRepoError translates repository errors for warehouses to
+// service errors.
+func warehouseRepoError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrWarehouseNotFound
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionConflict
+	case errors.Is(err, repository.ErrDuplicate):
+		return ErrWarehouseCodeExists
+	case errors.Is(err, repository.ErrInUse):
+		return ErrWarehouseInUse
+	}
+	return err
+}
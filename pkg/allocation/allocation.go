@@ -0,0 +1,147 @@
+// Package allocation chooses the location, such as a warehouse, that a
+// quantity of stock is taken from. Strategies rank the locations that
+// have enough stock available; new strategies can be registered by name.
+package allocation
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Names of the built-in strategies.
+const (
+	Priority = "priority"
+	Largest  = "largest"
+	Nearest  = "nearest"
+)
+
+// Point is a position in degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Location is a place stock can be taken from. Lower priorities are
+// preferred; Position is nil when the location is not known.
+type Location struct {
+	ID        string
+	Available int
+	Priority  int
+	Position  *Point
+}
+
+// Request asks for Quantity to be taken from one location, optionally to
+// be shipped to Destination.
+type Request struct {
+	Quantity    int
+	Destination *Point
+}
+
+// Strategy orders locations: Less reports whether a is preferred to b for
+// req. Locations neither prefers are ordered by priority, then ID.
+type Strategy interface {
+	Less(req Request, a, b Location) bool
+}
+
+// StrategyFunc adapts a function to a Strategy.
+type StrategyFunc func(req Request, a, b Location) bool
+
+func (f StrategyFunc) Less(req Request, a, b Location) bool {
+	return f(req, a, b)
+}
+
+var strategies = map[string]Strategy{
+	Priority: StrategyFunc(byPriority),
+	Largest:  StrategyFunc(byLargest),
+	Nearest:  StrategyFunc(byDistance),
+}
+
+// Register makes strategy available under name, replacing any strategy of
+// that name. It is meant to be called during initialization and must not
+// race with Lookup.
+func Register(name string, strategy Strategy) {
+	strategies[strings.ToLower(name)] = strategy
+}
+
+// Lookup returns the strategy registered under name.
+func Lookup(name string) (Strategy, bool) {
+	strategy, ok := strategies[strings.ToLower(name)]
+	return strategy, ok
+}
+
+// Names returns the names of the registered strategies in order.
+func Names() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Choose returns the location strategy prefers among those with at least
+// req.Quantity available, and false when there is none.
+func Choose(strategy Strategy, req Request, locations []Location) (Location, bool) {
+	candidates := make([]Location, 0, len(locations))
+	for _, location := range locations {
+		if location.Available >= req.Quantity {
+			candidates = append(candidates, location)
+		}
+	}
+	if len(candidates) == 0 {
+		return Location{}, false
+	}
+
+	return slices.MinFunc(candidates, func(a, b Location) int {
+		switch {
+		case strategy.Less(req, a, b):
+			return -1
+		case strategy.Less(req, b, a):
+			return 1
+		case a.Priority != b.Priority:
+			return a.Priority - b.Priority
+		}
+		return strings.Compare(a.ID, b.ID)
+	}), true
+}
+
+func byPriority(_ Request, a, b Location) bool {
+	return a.Priority < b.Priority
+}
+
+func byLargest(_ Request, a, b Location) bool {
+	return a.Available > b.Available
+}
+
+// byDistance prefers locations closer to the destination, and locations
+// with a known position to those without. Without a destination no
+// location is preferred.
+func byDistance(req Request, a, b Location) bool {
+	if req.Destination == nil || a.Position == nil {
+		return false
+	}
+	if b.Position == nil {
+		return true
+	}
+	return Distance(*req.Destination, *a.Position) < Distance(*req.Destination, *b.Position)
+}
+
+// earthRadius is the mean radius of the Earth in kilometers.
+const earthRadius = 6371.0
+
+// Distance returns the great-circle distance between two points in
+// kilometers.
+func Distance(from, to Point) float64 {
+	lat1, lat2 := radians(from.Latitude), radians(to.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(to.Longitude - from.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
@@ -0,0 +1,76 @@
+package allocation
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	jakarta  = &Point{Latitude: -6.2, Longitude: 106.8}
+	surabaya = &Point{Latitude: -7.25, Longitude: 112.75}
+	medan    = &Point{Latitude: 3.6, Longitude: 98.67}
+)
+
+var locations = []Location{
+	{ID: "jkt", Available: 5, Priority: 2, Position: jakarta},
+	{ID: "sby", Available: 20, Priority: 1, Position: surabaya},
+	{ID: "mdn", Available: 8, Priority: 3, Position: medan},
+	{ID: "pop-up", Available: 50, Priority: 4},
+}
+
+func TestChoose(t *testing.T) {
+	cases := []struct {
+		strategy string
+		req      Request
+		want     string
+	}{
+		{Priority, Request{Quantity: 1}, "sby"},
+		{Priority, Request{Quantity: 25}, "pop-up"},
+		{Largest, Request{Quantity: 1}, "pop-up"},
+		{Nearest, Request{Quantity: 1, Destination: &Point{Latitude: -6.9, Longitude: 107.6}}, "jkt"},
+		{Nearest, Request{Quantity: 6, Destination: &Point{Latitude: -6.9, Longitude: 107.6}}, "sby"},
+		{Nearest, Request{Quantity: 30, Destination: jakarta}, "pop-up"},
+		// Without a destination every location is as near, so priority decides.
+		{Nearest, Request{Quantity: 1}, "sby"},
+	}
+	for _, tc := range cases {
+		strategy, ok := Lookup(tc.strategy)
+		if !ok {
+			t.Fatalf("Expected strategy %s to be registered", tc.strategy)
+		}
+		got, ok := Choose(strategy, tc.req, locations)
+		if !ok || got.ID != tc.want {
+			t.Errorf("Expected %s to choose %s for %+v, got %s", tc.strategy, tc.want, tc.req, got.ID)
+		}
+	}
+
+	if _, ok := Choose(strategies[Priority], Request{Quantity: 51}, locations); ok {
+		t.Errorf("Expected no location to have 51 available")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("Alphabetical", StrategyFunc(func(_ Request, a, b Location) bool { return a.ID < b.ID }))
+	defer delete(strategies, "alphabetical")
+
+	strategy, ok := Lookup("alphabetical")
+	if !ok {
+		t.Fatalf("Expected registered strategy to be found")
+	}
+	if got, _ := Choose(strategy, Request{Quantity: 1}, locations); got.ID != "jkt" {
+		t.Errorf("Expected jkt, got %s", got.ID)
+	}
+	if names := Names(); len(names) != 4 || names[0] != "alphabetical" {
+		t.Errorf("Expected 4 sorted names, got %v", names)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	got := Distance(*jakarta, *surabaya)
+	if math.Abs(got-663) > 5 {
+		t.Errorf("Expected about 663 km from Jakarta to Surabaya, got %.0f", got)
+	}
+	if got := Distance(*medan, *medan); got != 0 {
+		t.Errorf("Expected 0 km to the same point, got %f", got)
+	}
+}
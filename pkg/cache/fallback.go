@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"suitemedia/pkg/logger"
+)
+
+// ErrFallbackActive is reported by Fallback.Ping while the primary cache is
+// unavailable and requests are served from memory.
+var ErrFallbackActive = errors.New("primary cache unavailable, serving from in-memory cache")
+
+// maxStaleKeys bounds the number of keys written while the primary is
+// unavailable that are remembered for deletion from it on reconnect.
+const maxStaleKeys = 100000
+
+// Dialer connects to the primary cache.
+type Dialer func(ctx context.Context) (Cache, error)
+
+// Fallback serves from a primary cache (Redis) when it is reachable and
+// from an in-memory cache otherwise. A background loop keeps checking the
+// primary and switches back as soon as it recovers. Keys written in the
+// meantime are deleted from the primary before it serves again, so that it
+// does not serve entries that were invalidated while it was unavailable.
+type Fallback struct {
+	dial     Dialer
+	memory   *Memory
+	interval time.Duration
+	logger   *logger.Logger
+
+	mu        sync.Mutex
+	primary   Cache
+	connected atomic.Bool
+	cancel    context.CancelFunc
+	// stale holds the keys written to memory while disconnected, and
+	// overflow whether some were not recorded because there were too many.
+	stale    map[string]struct{}
+	overflow bool
+}
+
+func NewFallback(dial Dialer, memory *Memory, interval time.Duration, logger *logger.Logger) *Fallback {
+	return &Fallback{
+		dial:     dial,
+		memory:   memory,
+		interval: interval,
+		logger:   logger,
+		stale:    make(map[string]struct{}),
+	}
+}
+
+// Start makes one connection attempt synchronously and then keeps
+// monitoring the primary in the background until Close is called.
+func (f *Fallback) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	f.cancel = cancel
+
+	f.check(ctx)
+	if !f.Connected() {
+		f.logger.Warn("Primary cache unavailable, using in-memory cache")
+	}
+
+	go func() {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				f.check(ctx)
+			}
+		}
+	}()
+}
+
+func (f *Fallback) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, f.interval)
+	defer cancel()
+
+	f.mu.Lock()
+	primary := f.primary
+	f.mu.Unlock()
+
+	if primary == nil {
+		client, err := f.dial(ctx)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.primary = client
+		f.mu.Unlock()
+		primary = client
+	}
+
+	healthy := primary.Ping(ctx) == nil
+	switch {
+	case healthy && !f.connected.Load():
+		if f.reconnect(ctx, primary) {
+			f.logger.Info("Primary cache connected")
+		}
+	case !healthy && f.connected.Swap(false):
+		f.logger.Warn("Primary cache lost, falling back to in-memory cache")
+	}
+}
+
+// reconnect deletes the keys written while disconnected from primary and
+// switches to it once none are left. It reports whether it switched.
+func (f *Fallback) reconnect(ctx context.Context, primary Cache) bool {
+	// Writes may still go to memory while stale keys are being deleted,
+	// so the switch happens only once a pass finds nothing new.
+	for attempt := 0; attempt < 3; attempt++ {
+		f.mu.Lock()
+		if len(f.stale) == 0 {
+			if f.overflow {
+				f.logger.Warn("Too many cache writes while the primary cache was unavailable; some entries may be stale until they expire")
+				f.overflow = false
+			}
+			f.connected.Store(true)
+			f.mu.Unlock()
+			return true
+		}
+		keys := make([]string, 0, len(f.stale))
+		for key := range f.stale {
+			keys = append(keys, key)
+		}
+		f.stale = make(map[string]struct{})
+		f.mu.Unlock()
+
+		for i, key := range keys {
+			if err := primary.Delete(ctx, key); err != nil {
+				f.mu.Lock()
+				for _, key := range keys[i:] {
+					f.stale[key] = struct{}{}
+				}
+				f.mu.Unlock()
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// write returns the cache a write to key goes to, recording key for
+// deletion from the primary when that is the in-memory cache.
+func (f *Fallback) write(key string) Cache {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.connected.Load() && f.primary != nil {
+		return f.primary
+	}
+	if len(f.stale) < maxStaleKeys {
+		f.stale[key] = struct{}{}
+	} else if _, ok := f.stale[key]; !ok {
+		f.overflow = true
+	}
+	return f.memory
+}
+
+// Connected reports whether requests are currently served by the primary.
+func (f *Fallback) Connected() bool {
+	return f.connected.Load()
+}
+
+// Primary returns the primary cache when it is connected, for callers that
+// need features the in-memory cache does not provide.
+func (f *Fallback) Primary() (Cache, bool) {
+	if !f.Connected() {
+		return nil, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.primary, f.primary != nil
+}
+
+func (f *Fallback) active() Cache {
+	if primary, ok := f.Primary(); ok {
+		return primary
+	}
+	return f.memory
+}
+
+func (f *Fallback) Get(ctx context.Context, key string) (string, error) {
+	return f.active().Get(ctx, key)
+}
+
+func (f *Fallback) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return f.write(key).Set(ctx, key, value, expiration)
+}
+
+func (f *Fallback) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return f.write(key).SetNX(ctx, key, value, expiration)
+}
+
+func (f *Fallback) Delete(ctx context.Context, key string) error {
+	return f.write(key).Delete(ctx, key)
+}
+
+// Ping returns ErrFallbackActive while the primary is unavailable, so a
+// non-critical health check reports the cache as degraded.
+func (f *Fallback) Ping(ctx context.Context) error {
+	primary, ok := f.Primary()
+	if !ok {
+		return ErrFallbackActive
+	}
+	return primary.Ping(ctx)
+}
+
+func (f *Fallback) Close() error {
+	if f.cancel != nil {
+		f.cancel()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.primary != nil {
+		return f.primary.Close()
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"suitemedia/pkg/logger"
+)
+
+type flakyCache struct {
+	*Memory
+	down atomic.Bool
+}
+
+func (f *flakyCache) Ping(ctx context.Context) error {
+	if f.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestFallbackSwitchesToPrimaryWhenAvailable(t *testing.T) {
+	ctx := context.Background()
+	primary := &flakyCache{Memory: NewMemory(10)}
+	var reachable atomic.Bool
+
+	fallback := NewFallback(func(ctx context.Context) (Cache, error) {
+		if !reachable.Load() {
+			return nil, errors.New("connection refused")
+		}
+		return primary, nil
+	}, NewMemory(10), 10*time.Millisecond, logger.NewLogger("error"))
+	fallback.Start(ctx)
+	defer fallback.Close()
+
+	if fallback.Connected() {
+		t.Fatal("Expected fallback to start disconnected")
+	}
+	if err := fallback.Ping(ctx); err != ErrFallbackActive {
+		t.Errorf("Expected ErrFallbackActive, got %v", err)
+	}
+
+	fallback.Set(ctx, "key", "memory", 0)
+	reachable.Store(true)
+	waitFor(t, fallback.Connected)
+
+	if _, err := fallback.Get(ctx, "key"); err != ErrMiss {
+		t.Errorf("Expected primary to serve reads, got %v", err)
+	}
+
+	primary.down.Store(true)
+	waitFor(t, func() bool { return !fallback.Connected() })
+
+	if value, _ := fallback.Get(ctx, "key"); value != "memory" {
+		t.Errorf("Expected memory to serve reads again, got %q", value)
+	}
+}
+
+func TestFallbackDeletesKeysWrittenWhileDisconnected(t *testing.T) {
+	ctx := context.Background()
+	primary := &flakyCache{Memory: NewMemory(10)}
+
+	fallback := NewFallback(func(ctx context.Context) (Cache, error) {
+		return primary, nil
+	}, NewMemory(10), 10*time.Millisecond, logger.NewLogger("error"))
+	fallback.Start(ctx)
+	defer fallback.Close()
+
+	fallback.Set(ctx, "generation", "old", 0)
+	fallback.Set(ctx, "entry", "old", 0)
+
+	primary.down.Store(true)
+	waitFor(t, func() bool { return !fallback.Connected() })
+	fallback.Set(ctx, "generation", "new", 0)
+	fallback.Delete(ctx, "entry")
+
+	primary.down.Store(false)
+	waitFor(t, fallback.Connected)
+
+	for _, key := range []string{"generation", "entry"} {
+		if value, err := fallback.Get(ctx, key); err != ErrMiss {
+			t.Errorf("Expected %s to be deleted from the primary, got %q, %v", key, value, err)
+		}
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
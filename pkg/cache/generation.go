@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Generation returns the current generation token stored under key,
+// creating one if none exists. Embedding the token in cache keys lets a
+// whole family of entries, such as list pages, be invalidated at once by
+// BumpGeneration. Entries written under an old token become unreachable
+// even if a slow reader stores them after the bump. Concurrent first
+// callers agree on the token that was stored first.
+func Generation(ctx context.Context, c Cache, key string) string {
+	if token, err := c.Get(ctx, key); err == nil && token != "" {
+		return token
+	}
+
+	token := uuid.NewString()
+	if ok, err := c.SetNX(ctx, key, token, 0); err == nil && !ok {
+		if stored, err := c.Get(ctx, key); err == nil && stored != "" {
+			return stored
+		}
+	}
+	return token
+}
+
+// BumpGeneration replaces the generation token stored under key.
+func BumpGeneration(ctx context.Context, c Cache, key string) error {
+	return c.Set(ctx, key, uuid.NewString(), 0)
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryGetSet(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10)
+
+	if _, err := m.Get(ctx, "missing"); err != ErrMiss {
+		t.Errorf("Expected ErrMiss, got %v", err)
+	}
+
+	m.Set(ctx, "key", 42, 0)
+	value, err := m.Get(ctx, "key")
+	if err != nil || value != "42" {
+		t.Errorf("Expected 42, got %q (%v)", value, err)
+	}
+
+	m.Delete(ctx, "key")
+	if _, err := m.Get(ctx, "key"); err != ErrMiss {
+		t.Errorf("Expected ErrMiss after delete, got %v", err)
+	}
+}
+
+func TestMemoryExpiration(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10)
+
+	m.Set(ctx, "key", "value", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := m.Get(ctx, "key"); err != ErrMiss {
+		t.Errorf("Expected expired key to miss, got %v", err)
+	}
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2)
+
+	m.Set(ctx, "a", "1", 0)
+	m.Set(ctx, "b", "2", 0)
+	m.Get(ctx, "a")
+	m.Set(ctx, "c", "3", 0)
+
+	if m.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", m.Len())
+	}
+	if _, err := m.Get(ctx, "b"); err != ErrMiss {
+		t.Error("Expected least recently used key to be evicted")
+	}
+	if _, err := m.Get(ctx, "a"); err != nil {
+		t.Error("Expected recently used key to be kept")
+	}
+}
+
+func TestMemorySetNX(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10)
+
+	if ok, _ := m.SetNX(ctx, "key", "first", 10*time.Millisecond); !ok {
+		t.Error("Expected SetNX on a missing key to succeed")
+	}
+	if ok, _ := m.SetNX(ctx, "key", "second", 0); ok {
+		t.Error("Expected SetNX on an existing key to fail")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := m.SetNX(ctx, "key", "third", 0); !ok {
+		t.Error("Expected SetNX on an expired key to succeed")
+	}
+	if value, _ := m.Get(ctx, "key"); value != "third" {
+		t.Errorf("Expected third, got %q", value)
+	}
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+var requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_requests_total",
+	Help: "Read-through cache lookups by cache name and result.",
+}, []string{"cache", "result"})
+
+const (
+	markerValue    = 'v'
+	markerNotFound = 'n'
+)
+
+// Options configure a Typed cache.
+type Options struct {
+	// Name labels metrics and prefixes keys.
+	Name string
+	// TTL is how long values are cached. Zero or less disables caching.
+	TTL time.Duration
+	// Jitter spreads expirations by up to ±Jitter×TTL so entries written
+	// together do not expire together.
+	Jitter float64
+	// NotFound is the error a loader returns for missing records. Such
+	// results are cached for NegativeTTL and returned as NotFound.
+	NotFound    error
+	NegativeTTL time.Duration
+	Codec       Codec
+}
+
+// Typed is a read-through cache for values of type T. Concurrent misses for
+// the same key are collapsed into a single load.
+type Typed[T any] struct {
+	cache Cache
+	opts  Options
+	group singleflight.Group
+}
+
+func NewTyped[T any](cache Cache, opts Options) *Typed[T] {
+	if opts.Codec == nil {
+		opts.Codec = JSON
+	}
+	return &Typed[T]{cache: cache, opts: opts}
+}
+
+// Get returns the cached value for key, calling load on a miss and caching
+// its result. Cache errors are treated as misses so that an unavailable
+// cache never fails a request. Without a TTL every call loads.
+func (t *Typed[T]) Get(ctx context.Context, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	if t.opts.TTL <= 0 {
+		return load(ctx)
+	}
+
+	var zero T
+	fullKey := t.opts.Name + ":" + key
+
+	if raw, err := t.cache.Get(ctx, fullKey); err == nil && len(raw) > 0 {
+		switch raw[0] {
+		case markerNotFound:
+			requestsTotal.WithLabelValues(t.opts.Name, "negative_hit").Inc()
+			return zero, t.opts.NotFound
+		case markerValue:
+			var value T
+			if err := t.opts.Codec.Unmarshal([]byte(raw[1:]), &value); err == nil {
+				requestsTotal.WithLabelValues(t.opts.Name, "hit").Inc()
+				return value, nil
+			}
+		}
+	}
+	requestsTotal.WithLabelValues(t.opts.Name, "miss").Inc()
+
+	result, err, _ := t.group.Do(fullKey, func() (interface{}, error) {
+		// The load is shared by every waiting caller, so it must not be
+		// cancelled when the first caller goes away.
+		loadCtx := context.WithoutCancel(ctx)
+
+		value, err := load(loadCtx)
+		if err != nil {
+			if t.opts.NotFound != nil && errors.Is(err, t.opts.NotFound) && t.opts.NegativeTTL > 0 {
+				t.cache.Set(loadCtx, fullKey, string(markerNotFound), t.opts.NegativeTTL)
+			}
+			return nil, err
+		}
+
+		if data, err := t.opts.Codec.Marshal(value); err == nil {
+			t.cache.Set(loadCtx, fullKey, string(markerValue)+string(data), t.ttl())
+		}
+		return value, nil
+	})
+	if err != nil {
+		return zero, err
+	}
+
+	return result.(T), nil
+}
+
+// Invalidate removes keys so the next Get reloads them.
+func (t *Typed[T]) Invalidate(ctx context.Context, keys ...string) error {
+	var firstErr error
+	for _, key := range keys {
+		if err := t.cache.Delete(ctx, t.opts.Name+":"+key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (t *Typed[T]) ttl() time.Duration {
+	if t.opts.Jitter <= 0 {
+		return t.opts.TTL
+	}
+	spread := float64(t.opts.TTL) * t.opts.Jitter
+	return t.opts.TTL + time.Duration((rand.Float64()*2-1)*spread)
+}
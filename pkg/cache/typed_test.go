@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type item struct {
+	Name string `json:"name" msgpack:"name"`
+}
+
+func TestTypedReadThrough(t *testing.T) {
+	ctx := context.Background()
+	typed := NewTyped[*item](NewMemory(10), Options{Name: "items", TTL: time.Minute, Codec: Msgpack})
+
+	var loads int
+	load := func(ctx context.Context) (*item, error) {
+		loads++
+		return &item{Name: "widget"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := typed.Get(ctx, "1", load)
+		if err != nil || value.Name != "widget" {
+			t.Fatalf("Expected widget, got %+v (%v)", value, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected 1 load, got %d", loads)
+	}
+
+	typed.Invalidate(ctx, "1")
+	typed.Get(ctx, "1", load)
+	if loads != 2 {
+		t.Errorf("Expected reload after invalidation, got %d loads", loads)
+	}
+}
+
+func TestTypedZeroTTLDisablesCaching(t *testing.T) {
+	ctx := context.Background()
+	typed := NewTyped[*item](NewMemory(10), Options{Name: "items"})
+
+	var loads int
+	load := func(ctx context.Context) (*item, error) {
+		loads++
+		return &item{Name: "widget"}, nil
+	}
+	typed.Get(ctx, "1", load)
+	typed.Get(ctx, "1", load)
+	if loads != 2 {
+		t.Errorf("Expected every call to load without a TTL, got %d loads", loads)
+	}
+}
+
+func TestTypedNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	errNotFound := errors.New("not found")
+	typed := NewTyped[*item](NewMemory(10), Options{
+		Name:        "items",
+		TTL:         time.Minute,
+		NotFound:    errNotFound,
+		NegativeTTL: time.Minute,
+	})
+
+	var loads int
+	load := func(ctx context.Context) (*item, error) {
+		loads++
+		return nil, errNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := typed.Get(ctx, "missing", load); err != errNotFound {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected missing record to be cached, got %d loads", loads)
+	}
+}
+
+func TestTypedCollapsesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	typed := NewTyped[string](NewMemory(10), Options{Name: "items", TTL: time.Minute})
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		loads.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			typed.Get(ctx, "key", load)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Errorf("Expected a single load, got %d", loads.Load())
+	}
+}
+
+func TestGeneration(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10)
+
+	first := Generation(ctx, m, "gen")
+	if Generation(ctx, m, "gen") != first {
+		t.Error("Expected generation to be stable")
+	}
+
+	BumpGeneration(ctx, m, "gen")
+	if Generation(ctx, m, "gen") == first {
+		t.Error("Expected generation to change after bump")
+	}
+}
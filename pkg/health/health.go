@@ -0,0 +1,192 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+const defaultTimeout = 2 * time.Second
+
+// Checker reports the health of a single dependency.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function such as (*sql.DB).PingContext to a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Options control how a registered check is run and how its failure
+// affects the overall status. A failing critical check makes the service
+// down; a failing non-critical check only degrades it.
+type Options struct {
+	Timeout  time.Duration
+	Critical bool
+}
+
+type CheckResult struct {
+	Status    Status    `json:"status"`
+	Critical  bool      `json:"critical"`
+	LatencyMs float64   `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type registeredCheck struct {
+	name    string
+	checker Checker
+	opts    Options
+}
+
+// Registry runs registered checks in parallel and caches the report for a
+// short time so that frequent probes do not hammer dependencies. It also
+// tracks the startup and draining state used by the probe endpoints.
+type Registry struct {
+	mu     sync.RWMutex
+	checks []registeredCheck
+
+	runMu    sync.Mutex
+	cacheTTL time.Duration
+	cached   *Report
+	cachedAt time.Time
+
+	started  atomic.Bool
+	draining atomic.Bool
+}
+
+func NewRegistry(cacheTTL time.Duration) *Registry {
+	return &Registry{cacheTTL: cacheTTL}
+}
+
+// Register adds a named check. A zero timeout uses the default of 2s.
+func (r *Registry) Register(name string, checker Checker, opts Options) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, registeredCheck{name: name, checker: checker, opts: opts})
+}
+
+// Run returns the current health report, reusing a cached report while it
+// is fresh. Concurrent callers wait for a single run instead of each
+// checking the dependencies. The checks do not stop when ctx is canceled,
+// so that a caller going away does not leave a failed report cached for
+// everyone; each check is bounded by its own timeout instead.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	if r.cached != nil && time.Since(r.cachedAt) < r.cacheTTL {
+		return *r.cached
+	}
+
+	report := r.run(context.WithoutCancel(ctx))
+	r.cached = &report
+	r.cachedAt = time.Now()
+
+	return report
+}
+
+func (r *Registry) run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]registeredCheck{}, r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check registeredCheck) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(checks))}
+	for i, check := range checks {
+		result := results[i]
+		report.Checks[check.name] = result
+
+		if result.Status != StatusUp {
+			if check.opts.Critical {
+				report.Status = StatusDown
+			} else if report.Status == StatusUp {
+				report.Status = StatusDegraded
+			}
+		}
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, check registeredCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, check.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", check.opts.Timeout)
+	}
+
+	result := CheckResult{
+		Status:    StatusUp,
+		Critical:  check.opts.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// MarkStarted records that startup work such as migrations has finished.
+func (r *Registry) MarkStarted() {
+	r.started.Store(true)
+}
+
+func (r *Registry) Started() bool {
+	return r.started.Load()
+}
+
+// SetDraining flips readiness to failing so load balancers stop routing new
+// traffic before the server shuts down.
+func (r *Registry) SetDraining(draining bool) {
+	r.draining.Store(draining)
+}
+
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
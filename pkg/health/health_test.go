@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegistryRun(t *testing.T) {
+	registry := NewRegistry(0)
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error { return nil }), Options{Critical: true})
+	registry.Register("cache", CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }), Options{})
+
+	report := registry.Run(context.Background())
+
+	if report.Status != StatusDegraded {
+		t.Errorf("Expected degraded status, got %s", report.Status)
+	}
+	if report.Checks["database"].Status != StatusUp {
+		t.Errorf("Expected database up, got %s", report.Checks["database"].Status)
+	}
+	if report.Checks["cache"].Error != "connection refused" {
+		t.Errorf("Expected cache error to be reported, got %q", report.Checks["cache"].Error)
+	}
+}
+
+func TestRegistryCriticalTimeout(t *testing.T) {
+	registry := NewRegistry(0)
+	registry.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}), Options{Timeout: 20 * time.Millisecond, Critical: true})
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Expected check to be bounded by its timeout, took %s", time.Since(start))
+	}
+	if report.Status != StatusDown {
+		t.Errorf("Expected down status, got %s", report.Status)
+	}
+}
+
+func TestRegistryCachesReport(t *testing.T) {
+	var calls atomic.Int32
+	registry := NewRegistry(time.Minute)
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}), Options{Critical: true})
+
+	for i := 0; i < 5; i++ {
+		registry.Run(context.Background())
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected a single check run, got %d", calls.Load())
+	}
+}
+
+func TestRegistryIgnoresCallerCancellation(t *testing.T) {
+	registry := NewRegistry(time.Minute)
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error { return ctx.Err() }), Options{Critical: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	registry.Run(ctx)
+
+	if report := registry.Run(context.Background()); report.Status != StatusUp {
+		t.Errorf("Expected a canceled caller not to cache a failure, got %+v", report.Checks["database"])
+	}
+}
+
+func TestRegistryLifecycle(t *testing.T) {
+	registry := NewRegistry(0)
+
+	if registry.Started() {
+		t.Error("Expected registry not to be started")
+	}
+	registry.MarkStarted()
+	if !registry.Started() {
+		t.Error("Expected registry to be started")
+	}
+
+	registry.SetDraining(true)
+	if !registry.Draining() {
+		t.Error("Expected registry to be draining")
+	}
+}
@@ -3,22 +3,69 @@ package logger
 import (
 	"log"
 	"os"
+	"sync/atomic"
 )
 
+const (
+	levelDebug int32 = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var levels = map[string]int32{
+	"debug": levelDebug,
+	"info":  levelInfo,
+	"warn":  levelWarn,
+	"error": levelError,
+}
+
+var levelNames = map[int32]string{
+	levelDebug: "debug",
+	levelInfo:  "info",
+	levelWarn:  "warn",
+	levelError: "error",
+}
+
 type Logger struct {
-	level string
+	level atomic.Int32
 }
 
 func NewLogger(level string) *Logger {
-	return &Logger{level: level}
+	l := &Logger{}
+	l.SetLevel(level)
+	return l
+}
+
+// SetLevel changes the minimum level that is written. It is safe to call
+// while other goroutines are logging; unknown levels fall back to info.
+func (l *Logger) SetLevel(level string) {
+	value, ok := levels[level]
+	if !ok {
+		value = levelInfo
+	}
+	l.level.Store(value)
+}
+
+// Level returns the name of the current minimum level.
+func (l *Logger) Level() string {
+	return levelNames[l.level.Load()]
+}
+
+func (l *Logger) enabled(level int32) bool {
+	return l.level.Load() <= level
 }
 
 func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
-	log.Printf("[INFO] %s %v", msg, keysAndValues)
+	if l.enabled(levelInfo) {
+		log.Printf("[INFO] %s %v", msg, keysAndValues)
+	}
 }
 
 func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
-	log.Printf("[ERROR] %s %v", msg, keysAndValues)
+	if l.enabled(levelError) {
+		log.Printf("[ERROR] %s %v", msg, keysAndValues)
+	}
 }
 
 func (l *Logger) Fatal(msg string, keysAndValues ...interface{}) {
@@ -27,11 +74,13 @@ func (l *Logger) Fatal(msg string, keysAndValues ...interface{}) {
 }
 
 func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
-	if l.level == "debug" {
+	if l.enabled(levelDebug) {
 		log.Printf("[DEBUG] %s %v", msg, keysAndValues)
 	}
 }
 
 func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
-	log.Printf("[WARN] %s %v", msg, keysAndValues)
+	if l.enabled(levelWarn) {
+		log.Printf("[WARN] %s %v", msg, keysAndValues)
+	}
 }
@@ -34,3 +34,16 @@ func TestLoggerWarn(t *testing.T) {
 	// Should not panic
 	log.Warn("Test warn message", "key", "value")
 }
+
+func TestLoggerSetLevel(t *testing.T) {
+	log := NewLogger("info")
+	log.SetLevel("debug")
+	if log.Level() != "debug" {
+		t.Errorf("Expected level debug, got %s", log.Level())
+	}
+
+	log.SetLevel("unknown")
+	if log.Level() != "info" {
+		t.Errorf("Expected unknown level to fall back to info, got %s", log.Level())
+	}
+}
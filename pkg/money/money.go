@@ -0,0 +1,174 @@
+// Package money represents amounts of money exactly, as an integer number
+// of minor units (such as cents) of an ISO 4217 currency, and converts
+// them between currencies with banker's rounding.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("money: currencies do not match")
+	ErrUnknownCurrency  = errors.New("money: unknown currency")
+	ErrInvalidAmount    = errors.New("money: invalid amount")
+)
+
+// Money is an amount in the minor unit of Currency, so 12.50 USD has an
+// Amount of 1250. It is written to JSON as {"amount":1250,"currency":"USD"}.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Parse parses a decimal amount in major units, such as "12.5", into
+// Money. Amounts with more decimals than the currency has are rejected.
+func Parse(amount, currency string) (Money, error) {
+	if !IsCurrency(currency) {
+		return Money{}, ErrUnknownCurrency
+	}
+
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok || strings.ContainsAny(amount, "eE/") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	value.Mul(value, pow10(Digits(currency)))
+	if !value.IsInt() || !value.Num().IsInt64() {
+		return Money{}, fmt.Errorf("%w: %q has too many decimals for %s", ErrInvalidAmount, amount, currency)
+	}
+
+	return Money{Amount: value.Num().Int64(), Currency: currency}, nil
+}
+
+// Decimal formats the amount in major units with the digits of the
+// currency, such as "12.50".
+func (m Money) Decimal() string {
+	digits := Digits(m.Currency)
+	abs := m.Amount
+	sign := ""
+	if abs < 0 {
+		abs, sign = -abs, "-"
+	}
+
+	s := strconv.FormatUint(uint64(abs), 10)
+	if digits == 0 {
+		return sign + s
+	}
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + other. Both must be in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other. Both must be in the same currency.
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}, nil
+}
+
+// Mul returns m multiplied by quantity.
+func (m Money) Mul(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// MulRat returns m multiplied by factor, rounded half to even to the
+// minor unit.
+func (m Money) MulRat(factor *big.Rat) Money {
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), factor)
+	return Money{Amount: RoundHalfEven(value), Currency: m.Currency}
+}
+
+// Convert returns m in currency to, where rate is the amount of to that
+// one major unit of m's currency buys. The result is rounded half to even
+// to the minor unit of to.
+func (m Money) Convert(rate *big.Rat, to string) Money {
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	value.Mul(value, pow10(Digits(to)))
+	value.Quo(value, pow10(Digits(m.Currency)))
+	return Money{Amount: RoundHalfEven(value), Currency: to}
+}
+
+// UnmarshalJSON decodes {"amount":1250,"currency":"USD"}, rejecting
+// unknown currencies.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   json.Number `json:"amount"`
+		Currency string      `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if !IsCurrency(raw.Currency) {
+		return fmt.Errorf("%w: %q", ErrUnknownCurrency, raw.Currency)
+	}
+	amount, err := strconv.ParseInt(raw.Amount.String(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: amount must be an integer number of minor units", ErrInvalidAmount)
+	}
+
+	m.Amount, m.Currency = amount, raw.Currency
+	return nil
+}
+
+// ParseRate parses a positive decimal exchange rate such as "0.9215".
+func ParseRate(rate string) (*big.Rat, error) {
+	value, ok := new(big.Rat).SetString(rate)
+	if !ok || value.Sign() <= 0 || strings.ContainsAny(rate, "/") {
+		return nil, fmt.Errorf("money: invalid rate %q", rate)
+	}
+	return value, nil
+}
+
+// RoundHalfEven rounds r to the nearest integer, and halves to the nearest
+// even integer, so that rounding errors do not accumulate in one
+// direction over many amounts.
+func RoundHalfEven(r *big.Rat) int64 {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Compare twice the remainder with the denominator to find out whether
+	// the fraction is below, at or above one half.
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	switch cmp := twice.Cmp(r.Denom()); {
+	case cmp > 0, cmp == 0 && quo.Bit(0) == 1:
+		if rem.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return quo.Int64()
+}
+
+func pow10(n int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+}
@@ -0,0 +1,120 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		amount, currency string
+		want             int64
+	}{
+		{"12.5", "USD", 1250},
+		{"0.01", "EUR", 1},
+		{"-3", "USD", -300},
+		{"1500", "JPY", 1500},
+		{"1.234", "KWD", 1234},
+	}
+	for _, tc := range cases {
+		m, err := Parse(tc.amount, tc.currency)
+		if err != nil {
+			t.Fatalf("Expected %s %s to parse, got %v", tc.amount, tc.currency, err)
+		}
+		if m.Amount != tc.want {
+			t.Errorf("Expected %s %s to be %d minor units, got %d", tc.amount, tc.currency, tc.want, m.Amount)
+		}
+	}
+
+	for _, amount := range []string{"1.005", "abc", "1e3", "1/3"} {
+		if _, err := Parse(amount, "USD"); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("Expected ErrInvalidAmount for %q, got %v", amount, err)
+		}
+	}
+	if _, err := Parse("1", "XYZ"); err != ErrUnknownCurrency {
+		t.Errorf("Expected ErrUnknownCurrency, got %v", err)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	cases := map[Money]string{
+		New(1250, "USD"): "12.50",
+		New(5, "USD"):    "0.05",
+		New(-5, "USD"):   "-0.05",
+		New(1500, "JPY"): "1500",
+		New(1, "KWD"):    "0.001",
+	}
+	for m, want := range cases {
+		if got := m.Decimal(); got != want {
+			t.Errorf("Expected %d %s to format as %s, got %s", m.Amount, m.Currency, want, got)
+		}
+	}
+}
+
+func TestRoundHalfEven(t *testing.T) {
+	cases := map[string]int64{
+		"0.5":  0,
+		"1.5":  2,
+		"2.5":  2,
+		"2.51": 3,
+		"2.49": 2,
+		"-0.5": 0,
+		"-1.5": -2,
+		"-2.5": -2,
+		"-2.6": -3,
+	}
+	for value, want := range cases {
+		r, _ := new(big.Rat).SetString(value)
+		if got := RoundHalfEven(r); got != want {
+			t.Errorf("Expected %s to round to %d, got %d", value, want, got)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	rate, _ := ParseRate("0.5")
+	// 0.25 USD is exactly 0.125 EUR, which rounds to the even 0.12.
+	if got := New(25, "USD").Convert(rate, "EUR"); got != New(12, "EUR") {
+		t.Errorf("Expected 12 EUR, got %v", got)
+	}
+	if got := New(75, "USD").Convert(rate, "EUR"); got != New(38, "EUR") {
+		t.Errorf("Expected 38 EUR, got %v", got)
+	}
+
+	rate, _ = ParseRate("149.5")
+	if got := New(1001, "USD").Convert(rate, "JPY"); got != New(1496, "JPY") {
+		t.Errorf("Expected 1496 JPY, got %v", got)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	sum, err := New(1050, "USD").Add(New(250, "USD"))
+	if err != nil || sum != New(1300, "USD") {
+		t.Errorf("Expected 1300 USD, got %v (%v)", sum, err)
+	}
+	if _, err := New(1, "USD").Sub(New(1, "EUR")); err != ErrCurrencyMismatch {
+		t.Errorf("Expected ErrCurrencyMismatch, got %v", err)
+	}
+	if got := New(333, "USD").Mul(3); got != New(999, "USD") {
+		t.Errorf("Expected 999 USD, got %v", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, _ := json.Marshal(New(1250, "USD"))
+	if string(data) != `{"amount":1250,"currency":"USD"}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`{"amount":999,"currency":"EUR"}`), &m); err != nil || m != New(999, "EUR") {
+		t.Errorf("Expected 999 EUR, got %v (%v)", m, err)
+	}
+	for _, doc := range []string{`{"amount":1,"currency":"usd"}`, `{"amount":1.5,"currency":"USD"}`, `{"amount":1}`} {
+		if err := json.Unmarshal([]byte(doc), &m); err == nil {
+			t.Errorf("Expected error for %s", doc)
+		}
+	}
+}
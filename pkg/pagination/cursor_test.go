@@ -0,0 +1,42 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSignerRoundTrip(t *testing.T) {
+	signer := NewSigner("secret")
+	id := uuid.New()
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 123456000, time.UTC)
+
+	token := signer.Encode(NewCursor("-created_at", true, createdAt, id))
+	cursor, err := signer.Decode(token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if cursor.Sort != "-created_at" || !cursor.Backward {
+		t.Errorf("Expected sort and direction to round trip, got %+v", cursor)
+	}
+	if cursor.Values[0] != "2024-05-01T10:30:00.123456Z" || cursor.Values[1] != id.String() {
+		t.Errorf("Expected normalized values, got %v", cursor.Values)
+	}
+}
+
+func TestSignerRejectsTampering(t *testing.T) {
+	token := NewSigner("secret").Encode(NewCursor("-created_at", false, "x"))
+
+	if _, err := NewSigner("other").Decode(token); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected cursor signed with another secret to be rejected, got %v", err)
+	}
+	if _, err := NewSigner("secret").Decode("e30." + token[len(token)-22:]); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected modified payload to be rejected, got %v", err)
+	}
+	if _, err := NewSigner("secret").Decode("garbage"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected garbage to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Fake is the name of the fake provider.
+const Fake = "fake"
+
+// FakeSignatureHeader carries the signature of webhooks of the fake
+// provider.
+const FakeSignatureHeader = "Fake-Signature"
+
+// Operations of the fake provider that FailNext can make fail.
+const (
+	OperationCreate  = "create"
+	OperationCapture = "capture"
+	OperationRefund  = "refund"
+)
+
+// FakeProvider is a Provider that keeps intents in memory. Intents wait
+// for payment until Simulate acts out what the customer or a real
+// provider would do, producing the signed webhook it would send, and
+// FailNext makes calls fail to exercise error handling.
+type FakeProvider struct {
+	settings Settings
+	now      func() time.Time
+
+	mu       sync.Mutex
+	intents  map[string]*Intent
+	failures map[string]error
+}
+
+func NewFake(settings Settings) *FakeProvider {
+	return &FakeProvider{
+		settings: settings,
+		now:      time.Now,
+		intents:  make(map[string]*Intent),
+		failures: make(map[string]error),
+	}
+}
+
+func (f *FakeProvider) Name() string {
+	return Fake
+}
+
+func (f *FakeProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failure(OperationCreate); err != nil {
+		return nil, err
+	}
+
+	intent := &Intent{
+		ID:        "pi_fake_" + randomHex(12),
+		Amount:    req.Amount,
+		Status:    StatusRequiresPayment,
+		Reference: req.Reference,
+	}
+	intent.ClientSecret = intent.ID + "_secret_" + randomHex(12)
+	f.intents[intent.ID] = intent
+
+	copied := *intent
+	return &copied, nil
+}
+
+func (f *FakeProvider) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	return f.update(OperationCapture, intentID, StatusCaptured, StatusAuthorized)
+}
+
+func (f *FakeProvider) Refund(ctx context.Context, intentID string) (*Intent, error) {
+	return f.update(OperationRefund, intentID, StatusRefunded, StatusCaptured)
+}
+
+func (f *FakeProvider) VerifyWebhook(payload []byte, header http.Header) (*Event, error) {
+	err := VerifySignature(f.settings.WebhookSecret, header.Get(FakeSignatureHeader), payload, f.settings.WebhookTolerance, f.now())
+	if err != nil {
+		return nil, err
+	}
+
+	event := &Event{}
+	if err := json.Unmarshal(payload, event); err != nil || event.ID == "" || event.IntentID == "" {
+		return nil, fmt.Errorf("payment: malformed webhook payload: %w", ErrInvalidSignature)
+	}
+	return event, nil
+}
+
+// Simulate applies an event of eventType to an intent and returns the
+// webhook payload and headers the provider would send about it: the
+// customer authorizing the payment or it failing with reason, or the
+// provider capturing or refunding it outside this service.
+func (f *FakeProvider) Simulate(intentID, eventType, reason string) ([]byte, http.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, nil, ErrIntentNotFound
+	}
+
+	var status string
+	var from []string
+	switch eventType {
+	case EventAuthorized:
+		status, from = StatusAuthorized, []string{StatusRequiresPayment}
+	case EventCaptured:
+		status, from = StatusCaptured, []string{StatusRequiresPayment, StatusAuthorized}
+	case EventFailed:
+		status, from = StatusFailed, []string{StatusRequiresPayment, StatusAuthorized}
+	case EventRefunded:
+		status, from = StatusRefunded, []string{StatusCaptured}
+	default:
+		return nil, nil, fmt.Errorf("payment: unknown event type %q", eventType)
+	}
+	if !slices.Contains(from, intent.Status) {
+		return nil, nil, ErrInvalidState
+	}
+	intent.Status = status
+	if eventType == EventFailed {
+		if reason == "" {
+			reason = "card_declined"
+		}
+		intent.FailureReason = reason
+	}
+
+	now := f.now()
+	payload, err := json.Marshal(&Event{
+		ID:            "evt_fake_" + randomHex(12),
+		Type:          eventType,
+		IntentID:      intent.ID,
+		Reference:     intent.Reference,
+		Amount:        intent.Amount,
+		FailureReason: intent.FailureReason,
+		CreatedAt:     now.UTC(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(FakeSignatureHeader, Sign(f.settings.WebhookSecret, now, payload))
+	return payload, header, nil
+}
+
+// FailNext makes the next call of operation fail with err.
+func (f *FakeProvider) FailNext(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[operation] = err
+}
+
+// Intent returns a copy of an intent.
+func (f *FakeProvider) Intent(intentID string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	copied := *intent
+	return &copied, nil
+}
+
+// update moves an intent in status from to status, returning it unchanged
+// when it already is in status.
+func (f *FakeProvider) update(operation, intentID, status, from string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failure(operation); err != nil {
+		return nil, err
+	}
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, ErrIntentNotFound
+	}
+	switch intent.Status {
+	case status:
+	case from:
+		intent.Status = status
+	default:
+		return nil, ErrInvalidState
+	}
+
+	copied := *intent
+	return &copied, nil
+}
+
+// failure returns and clears the failure set for operation. f.mu must be
+// held.
+func (f *FakeProvider) failure(operation string) error {
+	err := f.failures[operation]
+	delete(f.failures, operation)
+	return err
+}
+
+func randomHex(n int) string {
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(data)
+}
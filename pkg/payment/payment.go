@@ -0,0 +1,131 @@
+// Package payment abstracts payment providers: creating payment intents,
+// capturing and refunding them, and verifying the webhooks providers send
+// when their state changes. Providers are registered by name; a fake
+// provider with a simulator is built in for development and tests.
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"suitemedia/pkg/money"
+)
+
+var (
+	ErrInvalidSignature = errors.New("payment: invalid webhook signature")
+	ErrIntentNotFound   = errors.New("payment: intent not found")
+	ErrInvalidState     = errors.New("payment: intent is not in a state that allows this")
+)
+
+// Statuses of an intent.
+const (
+	StatusRequiresPayment = "requires_payment"
+	StatusAuthorized      = "authorized"
+	StatusCaptured        = "captured"
+	StatusFailed          = "failed"
+	StatusRefunded        = "refunded"
+)
+
+// Types of webhook events.
+const (
+	EventAuthorized = "payment.authorized"
+	EventCaptured   = "payment.captured"
+	EventFailed     = "payment.failed"
+	EventRefunded   = "payment.refunded"
+)
+
+// IntentRequest asks for Amount to be paid. Reference identifies what is
+// paid for and is passed back in events.
+type IntentRequest struct {
+	Amount    money.Money
+	Reference string
+}
+
+// Intent is a payment at the provider. The customer completes it with
+// ClientSecret; it is then authorized and has to be captured.
+type Intent struct {
+	ID            string
+	Amount        money.Money
+	Status        string
+	ClientSecret  string
+	Reference     string
+	FailureReason string
+}
+
+// Event is a change to an intent reported by a webhook. IDs are unique per
+// provider, and providers may deliver an event more than once.
+type Event struct {
+	ID            string      `json:"id"`
+	Type          string      `json:"type"`
+	IntentID      string      `json:"intent_id"`
+	Reference     string      `json:"reference"`
+	Amount        money.Money `json:"amount"`
+	FailureReason string      `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+// Provider is a payment provider.
+type Provider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	// Capture collects an authorized intent. Capturing a captured intent
+	// returns it unchanged.
+	Capture(ctx context.Context, intentID string) (*Intent, error)
+	// Refund refunds a captured intent in full. Refunding a refunded
+	// intent returns it unchanged.
+	Refund(ctx context.Context, intentID string) (*Intent, error)
+	// VerifyWebhook checks the signature of a webhook request and returns
+	// the event it reports, failing with ErrInvalidSignature.
+	VerifyWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// Settings configure a provider. Webhooks signed longer than
+// WebhookTolerance ago are rejected.
+type Settings struct {
+	WebhookSecret    string
+	WebhookTolerance time.Duration
+}
+
+// Factory creates a provider from settings.
+type Factory func(settings Settings) (Provider, error)
+
+var providers = map[string]Factory{
+	Fake: func(settings Settings) (Provider, error) { return NewFake(settings), nil },
+}
+
+// Register makes factory available under name, replacing any provider of
+// that name. It is meant to be called during initialization and must not
+// race with Open.
+func Register(name string, factory Factory) {
+	providers[strings.ToLower(name)] = factory
+}
+
+// Open creates the provider registered under name.
+func Open(name string, settings Settings) (Provider, error) {
+	factory, ok := providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("payment: unknown provider %q", name)
+	}
+	return factory(settings)
+}
+
+// Names returns the names of the registered providers in order.
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Registered reports whether a provider is registered under name.
+func Registered(name string) bool {
+	_, ok := providers[strings.ToLower(name)]
+	return ok
+}
@@ -0,0 +1,104 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"suitemedia/pkg/money"
+)
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign("secret", now, payload)
+
+	cases := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		now     time.Time
+		ok      bool
+	}{
+		{"valid", "secret", header, payload, now, true},
+		{"rotated secret", "secret", header + ",v1=0123", payload, now.Add(time.Minute), true},
+		{"wrong secret", "other", header, payload, now, false},
+		{"tampered payload", "secret", header, []byte(`{"id":"evt_2"}`), now, false},
+		{"too old", "secret", header, payload, now.Add(10 * time.Minute), false},
+		{"missing timestamp", "secret", "v1=abc", payload, now, false},
+		{"missing signature", "secret", "t=1700000000", payload, now, false},
+	}
+	for _, tc := range cases {
+		err := VerifySignature(tc.secret, tc.header, tc.payload, 5*time.Minute, tc.now)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: Expected ok %v, got %v", tc.name, tc.ok, err)
+		}
+	}
+}
+
+func TestFakeProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := NewFake(Settings{WebhookSecret: "secret", WebhookTolerance: time.Minute})
+
+	intent, err := provider.CreateIntent(ctx, IntentRequest{Amount: money.New(2500, "USD"), Reference: "order-1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if intent.Status != StatusRequiresPayment || intent.ClientSecret == "" {
+		t.Errorf("Expected an intent awaiting payment with a client secret, got %+v", intent)
+	}
+	if _, err := provider.Capture(ctx, intent.ID); err != ErrInvalidState {
+		t.Errorf("Expected unauthorized intents not to be captured, got %v", err)
+	}
+
+	payload, header, err := provider.Simulate(intent.ID, EventAuthorized, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	event, err := provider.VerifyWebhook(payload, header)
+	if err != nil {
+		t.Fatalf("Expected the simulated webhook to verify, got %v", err)
+	}
+	if event.Type != EventAuthorized || event.IntentID != intent.ID || event.Reference != "order-1" {
+		t.Errorf("Expected an authorized event for the intent, got %+v", event)
+	}
+
+	header.Set(FakeSignatureHeader, Sign("wrong", time.Now(), payload))
+	if _, err := provider.VerifyWebhook(payload, header); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+
+	failure := errors.New("network down")
+	provider.FailNext(OperationCapture, failure)
+	if _, err := provider.Capture(ctx, intent.ID); err != failure {
+		t.Errorf("Expected the injected failure, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		captured, err := provider.Capture(ctx, intent.ID)
+		if err != nil || captured.Status != StatusCaptured {
+			t.Errorf("Expected capture %d to succeed, got %+v, %v", i+1, captured, err)
+		}
+	}
+
+	if _, _, err := provider.Simulate(intent.ID, EventFailed, ""); err != ErrInvalidState {
+		t.Errorf("Expected captured intents not to fail, got %v", err)
+	}
+	if refunded, err := provider.Refund(ctx, intent.ID); err != nil || refunded.Status != StatusRefunded {
+		t.Errorf("Expected refund to succeed, got %+v, %v", refunded, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	provider, err := Open("FAKE", Settings{WebhookSecret: "secret"})
+	if err != nil || provider.Name() != Fake {
+		t.Errorf("Expected the fake provider, got %v, %v", provider, err)
+	}
+	if _, err := Open("unknown", Settings{}); err == nil {
+		t.Errorf("Expected unknown providers to fail")
+	}
+	if !Registered(Fake) || Registered("unknown") {
+		t.Errorf("Expected only the fake provider to be registered, got %v", Names())
+	}
+}
@@ -0,0 +1,265 @@
+// Package promotion decides which promotions apply to a basket and what
+// they take off it. Discounts are spread over the lines they apply to, so
+// that every line knows its own discounted amount, and promotions are
+// applied in order of priority following their stacking rules.
+package promotion
+
+import (
+	"math/big"
+	"slices"
+	"sort"
+	"time"
+
+	"suitemedia/pkg/money"
+)
+
+// Kinds of promotions.
+const (
+	Percentage   = "percentage"
+	Fixed        = "fixed"
+	FreeShipping = "free_shipping"
+)
+
+// Reasons a promotion does not apply.
+const (
+	ReasonNotStarted      = "not_started"
+	ReasonExpired         = "expired"
+	ReasonFirstOrderOnly  = "first_order_only"
+	ReasonMinSubtotal     = "min_subtotal"
+	ReasonNoEligibleItems = "no_eligible_items"
+	ReasonNotStackable    = "not_stackable"
+	ReasonCurrency        = "currency_mismatch"
+	// Callers that track usage and availability report these.
+	ReasonUsageLimit  = "usage_limit"
+	ReasonUnavailable = "unavailable"
+)
+
+// Promotion takes PercentOff percent or AmountOff off the lines it applies
+// to, or waives shipping. It applies to every line unless it names
+// products or categories, and only while its conditions are met.
+// Promotions that are not Stackable are never combined with others.
+// Lower priorities are applied first.
+type Promotion struct {
+	ID         string
+	Code       string
+	Name       string
+	Kind       string
+	PercentOff int
+	AmountOff  money.Money
+
+	MinSubtotal    money.Money
+	ProductIDs     []string
+	CategoryIDs    []string
+	FirstOrderOnly bool
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+
+	Stackable bool
+	Priority  int
+}
+
+// Line is a line of a basket. CategoryIDs hold the category of the product
+// and the categories above it, so that a promotion for a category covers
+// its subcategories.
+type Line struct {
+	ID          string
+	ProductID   string
+	CategoryIDs []string
+	Amount      money.Money
+}
+
+// Basket is what promotions are applied to, in one currency. FirstOrder
+// tells whether the buyer has not ordered before.
+type Basket struct {
+	Currency   string
+	Lines      []Line
+	Shipping   money.Money
+	FirstOrder bool
+	Now        time.Time
+}
+
+// Subtotal returns the total of the lines of b.
+func (b Basket) Subtotal() money.Money {
+	subtotal := money.New(0, b.Currency)
+	for _, line := range b.Lines {
+		subtotal.Amount += line.Amount.Amount
+	}
+	return subtotal
+}
+
+// Discount is what an applied promotion takes off: Lines maps line IDs to
+// the amount taken off them, and Shipping is the shipping waived.
+type Discount struct {
+	Promotion *Promotion
+	Amount    money.Money
+	Lines     map[string]money.Money
+	Shipping  money.Money
+}
+
+// Rejection explains why a promotion was not applied.
+type Rejection struct {
+	Promotion *Promotion
+	Reason    string
+}
+
+// Result lists the applied and rejected promotions. LineDiscounts and
+// ShippingDiscount add up the discounts of every applied promotion, and
+// Total is their sum.
+type Result struct {
+	Discounts        []Discount
+	Rejections       []Rejection
+	LineDiscounts    map[string]money.Money
+	ShippingDiscount money.Money
+	Total            money.Money
+}
+
+// Check returns the reason p does not apply to b, or an empty string when
+// it does.
+func (p *Promotion) Check(b Basket) string {
+	switch {
+	case p.StartsAt != nil && b.Now.Before(*p.StartsAt):
+		return ReasonNotStarted
+	case p.EndsAt != nil && !b.Now.Before(*p.EndsAt):
+		return ReasonExpired
+	case (p.Kind == Fixed && p.AmountOff.Currency != b.Currency) ||
+		(p.MinSubtotal.Amount > 0 && p.MinSubtotal.Currency != b.Currency):
+		return ReasonCurrency
+	case p.FirstOrderOnly && !b.FirstOrder:
+		return ReasonFirstOrderOnly
+	case b.Subtotal().Amount < p.MinSubtotal.Amount:
+		return ReasonMinSubtotal
+	}
+
+	for _, line := range b.Lines {
+		if p.AppliesTo(line) {
+			return ""
+		}
+	}
+	return ReasonNoEligibleItems
+}
+
+// AppliesTo reports whether p covers line.
+func (p *Promotion) AppliesTo(line Line) bool {
+	if len(p.ProductIDs) == 0 && len(p.CategoryIDs) == 0 {
+		return true
+	}
+	if slices.Contains(p.ProductIDs, line.ProductID) {
+		return true
+	}
+	for _, id := range line.CategoryIDs {
+		if slices.Contains(p.CategoryIDs, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply applies promotions to b in order of priority, keeping the given
+// order among equal priorities. Each promotion discounts what earlier ones
+// left, so percentages compound and no line goes below zero. A promotion
+// that is not stackable is only applied when no other promotion was, and
+// stops any further ones.
+func Apply(promotions []*Promotion, b Basket) *Result {
+	ordered := slices.Clone(promotions)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Priority < ordered[j].Priority })
+
+	result := &Result{
+		LineDiscounts:    make(map[string]money.Money, len(b.Lines)),
+		ShippingDiscount: money.New(0, b.Currency),
+		Total:            money.New(0, b.Currency),
+	}
+	remaining := make(map[string]int64, len(b.Lines))
+	for _, line := range b.Lines {
+		remaining[line.ID] = line.Amount.Amount
+	}
+	shipping := b.Shipping.Amount
+	exclusive := false
+
+	for _, p := range ordered {
+		if reason := p.Check(b); reason != "" {
+			result.Rejections = append(result.Rejections, Rejection{Promotion: p, Reason: reason})
+			continue
+		}
+		if exclusive || (!p.Stackable && len(result.Discounts) > 0) {
+			result.Rejections = append(result.Rejections, Rejection{Promotion: p, Reason: ReasonNotStackable})
+			continue
+		}
+		exclusive = !p.Stackable
+
+		discount := Discount{
+			Promotion: p,
+			Lines:     make(map[string]money.Money),
+			Shipping:  money.New(0, b.Currency),
+		}
+		switch p.Kind {
+		case Percentage:
+			rate := big.NewRat(int64(p.PercentOff), 100)
+			for _, line := range b.Lines {
+				if !p.AppliesTo(line) {
+					continue
+				}
+				if amount := money.New(remaining[line.ID], b.Currency).MulRat(rate); amount.Amount > 0 {
+					discount.Lines[line.ID] = amount
+				}
+			}
+		case Fixed:
+			for id, amount := range spread(p, b.Lines, remaining, p.AmountOff.Amount) {
+				discount.Lines[id] = money.New(amount, b.Currency)
+			}
+		case FreeShipping:
+			discount.Shipping.Amount = shipping
+		}
+
+		var total int64
+		for id, amount := range discount.Lines {
+			remaining[id] -= amount.Amount
+			line := result.LineDiscounts[id]
+			result.LineDiscounts[id] = money.New(line.Amount+amount.Amount, b.Currency)
+			total += amount.Amount
+		}
+		shipping -= discount.Shipping.Amount
+		result.ShippingDiscount.Amount += discount.Shipping.Amount
+		total += discount.Shipping.Amount
+
+		discount.Amount = money.New(total, b.Currency)
+		result.Total.Amount += total
+		result.Discounts = append(result.Discounts, discount)
+	}
+
+	return result
+}
+
+// spread divides amount over the lines p applies to in proportion to what
+// is left of them, never taking more than that. Minor units lost to
+// rounding go to the lines with the largest fractions.
+func spread(p *Promotion, lines []Line, remaining map[string]int64, amount int64) map[string]int64 {
+	var eligible []Line
+	var base int64
+	for _, line := range lines {
+		if p.AppliesTo(line) && remaining[line.ID] > 0 {
+			eligible = append(eligible, line)
+			base += remaining[line.ID]
+		}
+	}
+	if base == 0 {
+		return nil
+	}
+	amount = min(amount, base)
+
+	shares := make(map[string]int64, len(eligible))
+	fractions := make(map[string]int64, len(eligible))
+	left := amount
+	for _, line := range eligible {
+		shares[line.ID] = amount * remaining[line.ID] / base
+		fractions[line.ID] = amount * remaining[line.ID] % base
+		left -= shares[line.ID]
+	}
+
+	// Fewer units are left than there are lines with a fraction, and
+	// those lines still have room for one more.
+	sort.SliceStable(eligible, func(i, j int) bool { return fractions[eligible[i].ID] > fractions[eligible[j].ID] })
+	for _, line := range eligible[:left] {
+		shares[line.ID]++
+	}
+	return shares
+}
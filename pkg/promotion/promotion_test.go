@@ -0,0 +1,120 @@
+package promotion
+
+import (
+	"testing"
+	"time"
+
+	"suitemedia/pkg/money"
+)
+
+func usd(amount int64) money.Money {
+	return money.New(amount, "USD")
+}
+
+func basket() Basket {
+	return Basket{
+		Currency: "USD",
+		Lines: []Line{
+			{ID: "mug", ProductID: "p-mug", CategoryIDs: []string{"c-home", "c-kitchen"}, Amount: usd(1000)},
+			{ID: "shirt", ProductID: "p-shirt", CategoryIDs: []string{"c-apparel"}, Amount: usd(3000)},
+		},
+		Shipping:   usd(500),
+		FirstOrder: true,
+		Now:        time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestCheck(t *testing.T) {
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	repeat := basket()
+	repeat.FirstOrder = false
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+		basket    Basket
+		want      string
+	}{
+		{"applies", Promotion{Kind: Percentage, PercentOff: 10}, basket(), ""},
+		{"not started", Promotion{Kind: Percentage, StartsAt: &start}, basket(), ReasonNotStarted},
+		{"ended", Promotion{Kind: Percentage, EndsAt: &end}, basket(), ReasonExpired},
+		{"first order", Promotion{Kind: Percentage, FirstOrderOnly: true}, repeat, ReasonFirstOrderOnly},
+		{"min subtotal met", Promotion{Kind: Percentage, MinSubtotal: usd(4000)}, basket(), ""},
+		{"min subtotal", Promotion{Kind: Percentage, MinSubtotal: usd(4001)}, basket(), ReasonMinSubtotal},
+		{"subcategory", Promotion{Kind: Percentage, CategoryIDs: []string{"c-home"}}, basket(), ""},
+		{"no eligible items", Promotion{Kind: Percentage, ProductIDs: []string{"p-hat"}}, basket(), ReasonNoEligibleItems},
+		{"currency", Promotion{Kind: Fixed, AmountOff: money.New(500, "EUR")}, basket(), ReasonCurrency},
+	}
+	for _, tt := range tests {
+		if got := tt.promotion.Check(tt.basket); got != tt.want {
+			t.Errorf("%s: Expected reason %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	percent := &Promotion{ID: "percent", Kind: Percentage, PercentOff: 10, Stackable: true}
+	fixed := &Promotion{ID: "fixed", Kind: Fixed, AmountOff: usd(1000), Stackable: true, Priority: 1}
+	shipping := &Promotion{ID: "shipping", Kind: FreeShipping, Stackable: true, Priority: 2}
+
+	result := Apply([]*Promotion{shipping, fixed, percent}, basket())
+	if len(result.Discounts) != 3 || len(result.Rejections) != 0 {
+		t.Fatalf("Expected 3 discounts, got %+v", result)
+	}
+	if result.Discounts[0].Promotion != percent || result.Discounts[0].Amount != usd(400) {
+		t.Errorf("Expected 10%% off first, got %+v", result.Discounts[0])
+	}
+	// The fixed discount is spread over what is left: 900 and 2700.
+	if result.LineDiscounts["mug"] != usd(100+250) || result.LineDiscounts["shirt"] != usd(300+750) {
+		t.Errorf("Expected line discounts of 350 and 1050, got %+v", result.LineDiscounts)
+	}
+	if result.ShippingDiscount != usd(500) || result.Total != usd(1900) {
+		t.Errorf("Expected 500 shipping and 1900 in total off, got %+v and %+v", result.ShippingDiscount, result.Total)
+	}
+}
+
+func TestApplyStacking(t *testing.T) {
+	stackable := &Promotion{ID: "stackable", Kind: Percentage, PercentOff: 10, Stackable: true}
+	exclusive := &Promotion{ID: "exclusive", Kind: Percentage, PercentOff: 50, Priority: 1}
+
+	result := Apply([]*Promotion{exclusive, stackable}, basket())
+	if len(result.Discounts) != 1 || result.Discounts[0].Promotion != stackable {
+		t.Errorf("Expected only the stackable promotion applied first, got %+v", result.Discounts)
+	}
+	if len(result.Rejections) != 1 || result.Rejections[0].Reason != ReasonNotStackable {
+		t.Errorf("Expected the exclusive promotion rejected, got %+v", result.Rejections)
+	}
+
+	exclusive.Priority = -1
+	result = Apply([]*Promotion{stackable, exclusive}, basket())
+	if len(result.Discounts) != 1 || result.Discounts[0].Promotion != exclusive || result.Total != usd(2000) {
+		t.Errorf("Expected only the exclusive promotion applied, got %+v", result.Discounts)
+	}
+}
+
+func TestApplyFixedCapped(t *testing.T) {
+	b := basket()
+	b.Lines = []Line{
+		{ID: "a", ProductID: "p-a", Amount: usd(1)},
+		{ID: "b", ProductID: "p-b", Amount: usd(1)},
+		{ID: "c", ProductID: "p-c", Amount: usd(1)},
+	}
+
+	result := Apply([]*Promotion{{Kind: Fixed, AmountOff: usd(2)}}, b)
+	var total int64
+	for id, discount := range result.LineDiscounts {
+		if discount.Amount > 1 {
+			t.Errorf("Expected line %s to be discounted at most its amount, got %d", id, discount.Amount)
+		}
+		total += discount.Amount
+	}
+	if total != 2 {
+		t.Errorf("Expected 2 off in total, got %d", total)
+	}
+
+	result = Apply([]*Promotion{{Kind: Fixed, AmountOff: usd(10)}}, b)
+	if result.Total != usd(3) {
+		t.Errorf("Expected the discount capped at the subtotal, got %+v", result.Total)
+	}
+}
@@ -0,0 +1,350 @@
+// Package query parses the filter and sort parameters of list endpoints,
+// such as ?filter[price][gte]=10&filter[category][in]=a,b&sort=-price,name,
+// against a whitelist of fields and turns them into parameterized SQL.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type is the type of a field. It determines how filter values are parsed,
+// which operators are allowed by default and how values are cast in SQL.
+type Type int
+
+const (
+	String Type = iota
+	Number
+	Integer
+	Int64
+	Bool
+	Time
+	UUID
+)
+
+// Op is a filter operator.
+type Op string
+
+const (
+	Eq       Op = "eq"
+	Ne       Op = "ne"
+	Gt       Op = "gt"
+	Gte      Op = "gte"
+	Lt       Op = "lt"
+	Lte      Op = "lte"
+	In       Op = "in"
+	NotIn    Op = "nin"
+	Contains Op = "contains"
+)
+
+// maxValues bounds the number of values an in or nin filter may list.
+const maxValues = 100
+
+var defaultOps = map[Type][]Op{
+	String:  {Eq, Ne, In, NotIn, Contains},
+	Number:  {Eq, Ne, Gt, Gte, Lt, Lte, In, NotIn},
+	Integer: {Eq, Ne, Gt, Gte, Lt, Lte, In, NotIn},
+	Int64:   {Eq, Ne, Gt, Gte, Lt, Lte, In, NotIn},
+	Bool:    {Eq, Ne},
+	Time:    {Eq, Ne, Gt, Gte, Lt, Lte},
+	UUID:    {Eq, Ne, In, NotIn},
+}
+
+var sqlTypes = map[Type]string{
+	String:  "text",
+	Number:  "numeric",
+	Integer: "integer",
+	Int64:   "bigint",
+	Bool:    "boolean",
+	Time:    "timestamp",
+	UUID:    "uuid",
+}
+
+// Field is a field clients may filter or sort by. Column is the SQL column
+// or expression it maps to. Ops restricts the allowed operators; when nil
+// every operator that makes sense for the type is allowed.
+type Field struct {
+	Name       string
+	Column     string
+	Type       Type
+	Ops        []Op
+	Filterable bool
+	Sortable   bool
+}
+
+// SQLType returns the SQL type values of the field are cast to.
+func (f Field) SQLType() string {
+	return sqlTypes[f.Type]
+}
+
+func (f Field) allows(op Op) bool {
+	ops := f.Ops
+	if ops == nil {
+		ops = defaultOps[f.Type]
+	}
+	for _, allowed := range ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema is the whitelist of fields of a resource.
+type Schema []Field
+
+// Field returns the field called name.
+func (s Schema) Field(name string) (Field, bool) {
+	for _, field := range s {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// Filter is a parsed filter condition. Values hold one value, or several
+// for in and nin, converted to the type of the field.
+type Filter struct {
+	Field  string        `json:"field"`
+	Op     Op            `json:"op"`
+	Values []interface{} `json:"values"`
+}
+
+// Sort is a parsed sort field.
+type Sort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// Problem describes why a query parameter was rejected.
+type Problem struct {
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+// Error is returned by Parse and lists every rejected parameter.
+type Error struct {
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Param + ": " + problem.Message
+	}
+	return "invalid query: " + strings.Join(messages, "; ")
+}
+
+func (e *Error) add(param, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, Problem{Param: param, Message: fmt.Sprintf(format, args...)})
+}
+
+// Parse reads the filter[field] and filter[field][op] parameters of values
+// and the comma-separated sort list, where a leading "-" sorts a field in
+// descending order. Filters without an operator compare for equality.
+func (s Schema) Parse(values url.Values, sortList string) ([]Filter, []Sort, error) {
+	problems := &Error{}
+
+	params := make([]string, 0, len(values))
+	for param := range values {
+		if strings.HasPrefix(param, "filter[") {
+			params = append(params, param)
+		}
+	}
+	// Parse in a stable order so equal queries produce equal filters.
+	sort.Strings(params)
+
+	filters := make([]Filter, 0, len(params))
+	for _, param := range params {
+		name, op, ok := splitFilterParam(param)
+		if !ok {
+			problems.add(param, "must be written as filter[field] or filter[field][operator]")
+			continue
+		}
+
+		field, ok := s.Field(name)
+		if !ok || !field.Filterable {
+			problems.add(param, "unknown filter field %q", name)
+			continue
+		}
+		if !field.allows(op) {
+			problems.add(param, "operator %q is not supported for %s", op, name)
+			continue
+		}
+
+		for _, raw := range values[param] {
+			filter, err := field.parseFilter(op, raw)
+			if err != nil {
+				problems.add(param, "%v", err)
+				continue
+			}
+			filters = append(filters, filter)
+		}
+	}
+
+	var sorts []Sort
+	if sortList != "" {
+		seen := make(map[string]bool)
+		for _, item := range strings.Split(sortList, ",") {
+			item = strings.TrimSpace(item)
+			desc := strings.HasPrefix(item, "-")
+			name := strings.TrimPrefix(item, "-")
+
+			field, ok := s.Field(name)
+			if !ok || !field.Sortable {
+				problems.add("sort", "unknown sort field %q", name)
+				continue
+			}
+			if seen[name] {
+				problems.add("sort", "field %q is listed more than once", name)
+				continue
+			}
+			seen[name] = true
+			sorts = append(sorts, Sort{Field: name, Desc: desc})
+		}
+	}
+
+	if len(problems.Problems) > 0 {
+		return nil, nil, problems
+	}
+	return filters, sorts, nil
+}
+
+// splitFilterParam splits "filter[name]" and "filter[name][op]".
+func splitFilterParam(param string) (string, Op, bool) {
+	rest := strings.TrimPrefix(param, "filter[")
+	end := strings.IndexByte(rest, ']')
+	if end <= 0 {
+		return "", "", false
+	}
+	name, rest := rest[:end], rest[end+1:]
+
+	if rest == "" {
+		return name, Eq, true
+	}
+	if len(rest) < 3 || rest[0] != '[' || rest[len(rest)-1] != ']' {
+		return "", "", false
+	}
+	return name, Op(rest[1 : len(rest)-1]), true
+}
+
+func (f Field) parseFilter(op Op, raw string) (Filter, error) {
+	items := []string{raw}
+	if op == In || op == NotIn {
+		items = strings.Split(raw, ",")
+		if len(items) > maxValues {
+			return Filter{}, fmt.Errorf("at most %d values are allowed", maxValues)
+		}
+	}
+
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		value, err := f.parseValue(strings.TrimSpace(item))
+		if err != nil {
+			return Filter{}, err
+		}
+		values[i] = value
+	}
+	return Filter{Field: f.Name, Op: op, Values: values}, nil
+}
+
+func (f Field) parseValue(raw string) (interface{}, error) {
+	switch f.Type {
+	case Number:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return value, nil
+	case Integer, Int64:
+		bits := 32
+		if f.Type == Int64 {
+			bits = 64
+		}
+		value, err := strconv.ParseInt(raw, 10, bits)
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, fmt.Errorf("%q is out of range", raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", raw)
+		}
+		return value, nil
+	case Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", raw)
+		}
+		return value, nil
+	case Time:
+		if value, err := time.Parse(time.RFC3339, raw); err == nil {
+			return value.UTC(), nil
+		}
+		value, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an RFC 3339 time or a date", raw)
+		}
+		return value, nil
+	case UUID:
+		value, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a UUID", raw)
+		}
+		return value.String(), nil
+	}
+	return raw, nil
+}
+
+// Where returns an SQL condition for every filter. arg adds a query
+// argument and returns its placeholder; values are never interpolated.
+// Filters must have been parsed from s.
+func (s Schema) Where(filters []Filter, arg func(value interface{}) string) []string {
+	conditions := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		field, ok := s.Field(filter.Field)
+		if !ok || len(filter.Values) == 0 {
+			continue
+		}
+		cast := "::" + field.SQLType()
+
+		switch filter.Op {
+		case In, NotIn:
+			placeholders := make([]string, len(filter.Values))
+			for i, value := range filter.Values {
+				placeholders[i] = arg(value) + cast
+			}
+			op := "IN"
+			if filter.Op == NotIn {
+				op = "NOT IN"
+			}
+			conditions = append(conditions, fmt.Sprintf("%s %s (%s)", field.Column, op, strings.Join(placeholders, ", ")))
+		case Contains:
+			pattern := "%" + EscapeLike(fmt.Sprint(filter.Values[0])) + "%"
+			conditions = append(conditions, fmt.Sprintf("%s ILIKE %s", field.Column, arg(pattern)))
+		default:
+			conditions = append(conditions, fmt.Sprintf("%s %s %s%s", field.Column, comparisons[filter.Op], arg(filter.Values[0]), cast))
+		}
+	}
+	return conditions
+}
+
+var comparisons = map[Op]string{
+	Eq:  "=",
+	Ne:  "IS DISTINCT FROM",
+	Gt:  ">",
+	Gte: ">=",
+	Lt:  "<",
+	Lte: "<=",
+}
+
+// EscapeLike escapes the LIKE wildcards in s.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,97 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+var schema = Schema{
+	{Name: "name", Column: "name", Type: String, Filterable: true, Sortable: true},
+	{Name: "price", Column: "price", Type: Number, Filterable: true, Sortable: true},
+	{Name: "category", Column: "category", Type: String, Ops: []Op{Eq, In}, Filterable: true},
+	{Name: "is_active", Column: "is_active", Type: Bool, Filterable: true},
+	{Name: "stock", Column: "stock", Type: Integer, Filterable: true},
+	{Name: "total", Column: "total", Type: Int64, Filterable: true},
+}
+
+func TestParse(t *testing.T) {
+	values, _ := url.ParseQuery("filter[price][gte]=10&filter[category][in]=a,b&filter[is_active]=true&page=2")
+	filters, sorts, err := schema.Parse(values, "-price,name")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedFilters := []Filter{
+		{Field: "category", Op: In, Values: []interface{}{"a", "b"}},
+		{Field: "is_active", Op: Eq, Values: []interface{}{true}},
+		{Field: "price", Op: Gte, Values: []interface{}{10.0}},
+	}
+	if !reflect.DeepEqual(filters, expectedFilters) {
+		t.Errorf("Expected filters %v, got %v", expectedFilters, filters)
+	}
+
+	expectedSorts := []Sort{{Field: "price", Desc: true}, {Field: "name"}}
+	if !reflect.DeepEqual(sorts, expectedSorts) {
+		t.Errorf("Expected sorts %v, got %v", expectedSorts, sorts)
+	}
+}
+
+func TestParseRejectsInvalidParameters(t *testing.T) {
+	values, _ := url.ParseQuery("filter[password]=x&filter[category][gt]=a&filter[price]=cheap&filter[name=x")
+	_, _, err := schema.Parse(values, "is_active,name,name")
+
+	var queryErr *Error
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("Expected *Error, got %v", err)
+	}
+	if len(queryErr.Problems) != 6 {
+		t.Errorf("Expected 6 problems, got %d: %v", len(queryErr.Problems), queryErr.Problems)
+	}
+}
+
+func TestParseIntegerRange(t *testing.T) {
+	values, _ := url.ParseQuery("filter[total][gte]=3000000000")
+	filters, _, err := schema.Parse(values, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if filters[0].Values[0] != int64(3000000000) {
+		t.Errorf("Expected 3000000000, got %v", filters[0].Values[0])
+	}
+
+	values, _ = url.ParseQuery("filter[stock][gte]=3000000000")
+	if _, _, err := schema.Parse(values, ""); err == nil {
+		t.Error("Expected an integer filter out of range to be rejected")
+	}
+}
+
+func TestWhere(t *testing.T) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := schema.Where([]Filter{
+		{Field: "category", Op: In, Values: []interface{}{"a", "b"}},
+		{Field: "name", Op: Contains, Values: []interface{}{"50%"}},
+		{Field: "price", Op: Lt, Values: []interface{}{20.0}},
+		{Field: "total", Op: Gte, Values: []interface{}{int64(3000000000)}},
+	}, arg)
+
+	expected := []string{
+		"category IN ($1::text, $2::text)",
+		"name ILIKE $3",
+		"price < $4::numeric",
+		"total >= $5::bigint",
+	}
+	if !reflect.DeepEqual(conditions, expected) {
+		t.Errorf("Expected %v, got %v", expected, conditions)
+	}
+	if args[2] != `%50\%%` {
+		t.Errorf("Expected escaped LIKE pattern, got %v", args[2])
+	}
+}
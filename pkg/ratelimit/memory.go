@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const sweepEvery = 1000
+
+type memoryEntry struct {
+	// tat is the GCRA theoretical arrival time.
+	tat time.Time
+	// window, current and previous hold sliding window counters.
+	window    int64
+	current   int
+	previous  int
+	expiresAt time.Time
+}
+
+// Memory is a process-local limiter used when Redis is not available.
+// Limits are then enforced per instance instead of across the cluster.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	calls   int
+	now     func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+}
+
+func (m *Memory) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	entry, ok := m.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+	}
+
+	if policy.Algorithm == SlidingWindow {
+		return m.slidingWindow(entry, now, policy), nil
+	}
+	return m.gcra(entry, now, policy), nil
+}
+
+func (m *Memory) gcra(entry *memoryEntry, now time.Time, policy Policy) Result {
+	interval := policy.Period / time.Duration(policy.Limit)
+	tolerance := interval * time.Duration(policy.burst())
+	result := Result{Limit: policy.Limit}
+
+	tat := entry.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(interval)
+	allowAt := newTat.Add(-tolerance)
+
+	if allowAt.After(now) {
+		result.RetryAfter = allowAt.Sub(now)
+		result.ResetAfter = tat.Sub(now)
+		return result
+	}
+
+	entry.tat = newTat
+	entry.expiresAt = newTat
+	result.Allowed = true
+	result.Remaining = int(now.Sub(allowAt) / interval)
+	result.ResetAfter = newTat.Sub(now)
+	return result
+}
+
+func (m *Memory) slidingWindow(entry *memoryEntry, now time.Time, policy Policy) Result {
+	period := policy.Period
+	window := now.UnixNano() / int64(period)
+	elapsed := time.Duration(now.UnixNano() - window*int64(period))
+	result := Result{Limit: policy.Limit, ResetAfter: period - elapsed}
+
+	switch window - entry.window {
+	case 0:
+	case 1:
+		entry.previous, entry.current = entry.current, 0
+	default:
+		entry.previous, entry.current = 0, 0
+	}
+	entry.window = window
+
+	weight := float64(period-elapsed) / float64(period)
+	count := float64(entry.previous)*weight + float64(entry.current)
+
+	if count+1 > float64(policy.Limit) {
+		result.RetryAfter = slidingRetryAfter(entry.previous, entry.current, policy.Limit, period, elapsed)
+		return result
+	}
+
+	entry.current++
+	entry.expiresAt = now.Add(2 * period)
+	result.Allowed = true
+	result.Remaining = int(math.Floor(float64(policy.Limit) - count - 1))
+	return result
+}
+
+// slidingRetryAfter returns when the weighted count will have dropped far
+// enough for one more request.
+func slidingRetryAfter(previous, current, limit int, period, elapsed time.Duration) time.Duration {
+	if current+1 > limit || previous == 0 {
+		return period - elapsed
+	}
+	needed := time.Duration(float64(period) * (1 - float64(limit-current-1)/float64(previous)))
+	return needed - elapsed
+}
+
+// sweep periodically drops expired entries so idle keys do not accumulate.
+func (m *Memory) sweep(now time.Time) {
+	m.calls++
+	if m.calls%sweepEvery != 0 {
+		return
+	}
+	for key, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+}
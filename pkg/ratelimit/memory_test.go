@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestMemory(now *time.Time) *Memory {
+	m := NewMemory()
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func TestMemoryGCRA(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	m := newTestMemory(&now)
+	policy := Policy{Algorithm: GCRA, Limit: 60, Period: time.Minute, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		result, _ := m.Allow(ctx, "key", policy)
+		if !result.Allowed {
+			t.Fatalf("Expected request %d within burst to be allowed", i+1)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("Expected %d remaining, got %d", 2-i, result.Remaining)
+		}
+	}
+
+	result, _ := m.Allow(ctx, "key", policy)
+	if result.Allowed {
+		t.Fatal("Expected request beyond burst to be rejected")
+	}
+	if result.RetryAfter != time.Second {
+		t.Errorf("Expected retry after 1s, got %s", result.RetryAfter)
+	}
+
+	now = now.Add(time.Second)
+	if result, _ := m.Allow(ctx, "key", policy); !result.Allowed {
+		t.Error("Expected request to be allowed after one emission interval")
+	}
+
+	if result, _ := m.Allow(ctx, "other", policy); !result.Allowed {
+		t.Error("Expected keys to be limited independently")
+	}
+}
+
+func TestMemorySlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000040, 0) // start of a one-minute window
+	m := newTestMemory(&now)
+	policy := Policy{Algorithm: SlidingWindow, Limit: 4, Period: time.Minute}
+
+	for i := 0; i < 4; i++ {
+		if result, _ := m.Allow(ctx, "key", policy); !result.Allowed {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+	if result, _ := m.Allow(ctx, "key", policy); result.Allowed {
+		t.Fatal("Expected fifth request to be rejected")
+	}
+
+	// Halfway through the next window the previous one still counts for 2.
+	now = now.Add(90 * time.Second)
+	for i := 0; i < 2; i++ {
+		if result, _ := m.Allow(ctx, "key", policy); !result.Allowed {
+			t.Fatalf("Expected request %d in the next window to be allowed", i+1)
+		}
+	}
+	result, _ := m.Allow(ctx, "key", policy)
+	if result.Allowed {
+		t.Fatal("Expected weighted count to reject the request")
+	}
+	if result.RetryAfter <= 0 {
+		t.Errorf("Expected a positive retry delay, got %s", result.RetryAfter)
+	}
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// GCRA (generic cell rate algorithm) spaces requests evenly over the
+	// period and allows short bursts of up to Burst requests.
+	GCRA = "gcra"
+	// SlidingWindow counts requests in the current and previous window and
+	// weights the previous one by how much of it still overlaps.
+	SlidingWindow = "sliding_window"
+)
+
+// Policy allows Limit requests per Period for each key.
+type Policy struct {
+	Algorithm string
+	Limit     int
+	Period    time.Duration
+	// Burst is the number of requests GCRA accepts at once. Zero means
+	// Limit.
+	Burst int
+}
+
+func (p Policy) burst() int {
+	if p.Burst <= 0 {
+		return p.Limit
+	}
+	return p.Burst
+}
+
+// Result describes the outcome of a single request against a policy.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a rejected caller should wait.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the full limit is available again.
+	ResetAfter time.Duration
+}
+
+// Limiter decides whether a request identified by key is allowed.
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy Policy) (Result, error)
+}
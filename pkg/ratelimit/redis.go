@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Scripter runs Lua scripts; *redis.Client from pkg/redis implements it.
+type Scripter interface {
+	Eval(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// Both scripts use the Redis server clock so that every API instance sees
+// the same time, and return {allowed, remaining, retry_after_ms,
+// reset_after_ms}.
+
+var gcraScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+  tat = now
+end
+local new_tat = tat + interval
+local allow_at = new_tat - tolerance
+
+if allow_at > now then
+  return {0, 0, allow_at - now, tat - now}
+end
+
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil(new_tat - now))
+return {1, math.floor((now - allow_at) / interval), 0, new_tat - now}
+`)
+
+var slidingWindowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+local period = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+local window = math.floor(now / period)
+local elapsed = now - window * period
+local current = tonumber(redis.call('HGET', KEYS[1], tostring(window)) or 0)
+local previous = tonumber(redis.call('HGET', KEYS[1], tostring(window - 1)) or 0)
+local count = previous * (period - elapsed) / period + current
+
+if count + 1 > limit then
+  local retry = period - elapsed
+  if current + 1 <= limit and previous > 0 then
+    retry = math.ceil(period * (1 - (limit - current - 1) / previous)) - elapsed
+  end
+  return {0, 0, retry, period - elapsed}
+end
+
+redis.call('HINCRBY', KEYS[1], tostring(window), 1)
+redis.call('HDEL', KEYS[1], tostring(window - 2))
+redis.call('PEXPIRE', KEYS[1], period * 2)
+return {1, math.floor(limit - count - 1), 0, period - elapsed}
+`)
+
+// Redis enforces limits across every API instance with atomic Lua scripts.
+type Redis struct {
+	client Scripter
+}
+
+func NewRedis(client Scripter) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	var (
+		raw interface{}
+		err error
+	)
+
+	if policy.Algorithm == SlidingWindow {
+		raw, err = r.client.Eval(ctx, slidingWindowScript, []string{key}, policy.Period.Milliseconds(), policy.Limit)
+	} else {
+		interval := policy.Period.Milliseconds() / int64(policy.Limit)
+		if interval < 1 {
+			interval = 1
+		}
+		raw, err = r.client.Eval(ctx, gcraScript, []string{key}, interval, interval*int64(policy.burst()))
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	values, ok := raw.([]interface{})
+	if !ok || len(values) != 4 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script result %v", raw)
+	}
+	ints := make([]int64, len(values))
+	for i, value := range values {
+		if ints[i], ok = value.(int64); !ok {
+			return Result{}, fmt.Errorf("ratelimit: unexpected script result %v", raw)
+		}
+	}
+
+	return Result{
+		Allowed:    ints[0] == 1,
+		Limit:      policy.Limit,
+		Remaining:  int(ints[1]),
+		RetryAfter: time.Duration(ints[2]) * time.Millisecond,
+		ResetAfter: time.Duration(ints[3]) * time.Millisecond,
+	}, nil
+}
+
+// Fallback uses Redis while it is reachable and the in-memory limiter when
+// it is not, so requests are never rejected because Redis is down.
+type Fallback struct {
+	primary func() (Scripter, bool)
+	memory  *Memory
+}
+
+// NewFallback takes a function returning the current Redis connection, if
+// any, such as one backed by cache.Fallback.Primary.
+func NewFallback(primary func() (Scripter, bool), memory *Memory) *Fallback {
+	return &Fallback{primary: primary, memory: memory}
+}
+
+func (f *Fallback) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	if client, ok := f.primary(); ok {
+		if result, err := NewRedis(client).Allow(ctx, key, policy); err == nil {
+			return result, nil
+		}
+	}
+	return f.memory.Allow(ctx, key, policy)
+}
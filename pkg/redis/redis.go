@@ -2,55 +2,243 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"suitemedia/config"
+	"suitemedia/pkg/cache"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrCrossSlot is returned in cluster mode when a multi-key operation
+// receives keys that do not share a hash tag.
+var ErrCrossSlot = errors.New("redis: keys must share a hash tag in cluster mode")
+
+var _ cache.Cache = (*Client)(nil)
+
+// Client wraps a standalone, Sentinel or Cluster connection behind the same
+// API and prefixes every key with the configured key prefix.
 type Client struct {
-	client    *redis.Client
+	client    redis.UniversalClient
 	keyPrefix string
+	cluster   bool
 }
 
 func NewClient(cfg config.RedisConfig) (*Client, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
+	opts, err := universalOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var client redis.UniversalClient
+	switch cfg.Mode {
+	case "cluster":
+		client = redis.NewClusterClient(opts.Cluster())
+	case "sentinel":
+		client = redis.NewFailoverClient(opts.Failover())
+	default:
+		client = redis.NewClient(opts.Simple())
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, err
 	}
 
 	return &Client{
 		client:    client,
 		keyPrefix: cfg.KeyPrefix,
+		cluster:   cfg.Mode == "cluster",
+	}, nil
+}
+
+func universalOptions(cfg config.RedisConfig) (*redis.UniversalOptions, error) {
+	tlsConfig, err := newTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := cfg.Addrs
+	if cfg.Mode == "" || cfg.Mode == "standalone" {
+		addrs = []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)}
+	}
+
+	return &redis.UniversalOptions{
+		Addrs:            addrs,
+		MasterName:       cfg.MasterName,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		SentinelUsername: cfg.SentinelUsername,
+		SentinelPassword: cfg.SentinelPassword,
+		DB:               cfg.DB,
+		TLSConfig:        tlsConfig,
+		DialTimeout:      time.Duration(cfg.DialTimeoutMs) * time.Millisecond,
+		ReadTimeout:      time.Duration(cfg.ReadTimeoutMs) * time.Millisecond,
+		WriteTimeout:     time.Duration(cfg.WriteTimeoutMs) * time.Millisecond,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
 	}, nil
 }
 
+func newTLSConfig(cfg config.RedisTLSConfig) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis CA file %s contains no certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// HashTag wraps tag in braces. Keys containing the same hash tag map to the
+// same cluster slot, which multi-key operations require in cluster mode,
+// e.g. "cart:" + HashTag(userID) + ":items".
+func HashTag(tag string) string {
+	return "{" + tag + "}"
+}
+
+// hashTag returns the part of key Redis Cluster hashes: the content of the
+// first non-empty {...} section, or the whole key.
+func hashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+// checkSlot verifies that keys can be used together in one command.
+func (c *Client) checkSlot(keys []string) error {
+	if !c.cluster || len(keys) < 2 {
+		return nil
+	}
+
+	tag := hashTag(keys[0])
+	for _, key := range keys[1:] {
+		if hashTag(key) != tag {
+			return ErrCrossSlot
+		}
+	}
+	return nil
+}
+
+func (c *Client) prefixed(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.keyPrefix + key
+	}
+	return prefixed
+}
+
 func (c *Client) Get(ctx context.Context, key string) (string, error) {
-	return c.client.Get(ctx, c.keyPrefix+key).Result()
+	value, err := c.client.Get(ctx, c.keyPrefix+key).Result()
+	if err == redis.Nil {
+		return "", cache.ErrMiss
+	}
+	return value, err
 }
 
 func (c *Client) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	return c.client.Set(ctx, c.keyPrefix+key, value, expiration).Err()
 }
 
+func (c *Client) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, c.keyPrefix+key, value, expiration).Result()
+}
+
 func (c *Client) Delete(ctx context.Context, key string) error {
 	return c.client.Del(ctx, c.keyPrefix+key).Err()
 }
 
-func (c *Client) Ping() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// MGet returns the values of the keys that exist. Keys are fetched in a
+// pipeline, so they may live in different cluster slots.
+func (c *Client) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, c.keyPrefix+key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(keys))
+	for i, cmd := range cmds {
+		if value, err := cmd.Result(); err == nil {
+			values[keys[i]] = value
+		}
+	}
+	return values, nil
+}
+
+// DeleteMany deletes keys in a pipeline, so they may live in different
+// cluster slots.
+func (c *Client) DeleteMany(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, c.keyPrefix+key)
+		}
+		return nil
+	})
+	return err
+}
+
+// Eval runs a Lua script atomically. Keys are prefixed before being passed
+// as KEYS; in cluster mode they must share a hash tag.
+func (c *Client) Eval(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	if err := c.checkSlot(keys); err != nil {
+		return nil, err
+	}
+
+	result, err := script.Run(ctx, c.client, c.prefixed(keys), args...).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return result, err
+}
+
+func (c *Client) Ping(ctx context.Context) error {
 	return c.client.Ping(ctx).Err()
 }
 
@@ -23,3 +23,60 @@ func TestNewClient(t *testing.T) {
 		t.Log("Redis connection failed as expected (no Redis server)")
 	}
 }
+
+func TestUniversalOptions(t *testing.T) {
+	opts, err := universalOptions(config.RedisConfig{Mode: "standalone", Host: "cache", Port: 6380})
+	if err != nil {
+		t.Fatalf("Failed to build options: %v", err)
+	}
+	if len(opts.Addrs) != 1 || opts.Addrs[0] != "cache:6380" {
+		t.Errorf("Expected standalone address cache:6380, got %v", opts.Addrs)
+	}
+	if opts.TLSConfig != nil {
+		t.Error("Expected TLS to be disabled")
+	}
+
+	opts, err = universalOptions(config.RedisConfig{
+		Mode:     "cluster",
+		Addrs:    []string{"node-1:6379", "node-2:6379"},
+		Username: "app",
+		TLS:      config.RedisTLSConfig{Enabled: true, ServerName: "cache.internal"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build options: %v", err)
+	}
+	if len(opts.Addrs) != 2 || opts.Username != "app" {
+		t.Errorf("Expected cluster seed nodes and ACL user, got %v %s", opts.Addrs, opts.Username)
+	}
+	if opts.TLSConfig == nil || opts.TLSConfig.ServerName != "cache.internal" {
+		t.Error("Expected TLS config with server name")
+	}
+}
+
+func TestNewTLSConfigInvalidCA(t *testing.T) {
+	_, err := newTLSConfig(config.RedisTLSConfig{Enabled: true, CAFile: "/nonexistent/ca.pem"})
+	if err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+}
+
+func TestHashTag(t *testing.T) {
+	cases := map[string]string{
+		"cart:" + HashTag("42") + ":items": "42",
+		"plain-key":                        "plain-key",
+		"empty:{}:tag":                     "empty:{}:tag",
+	}
+	for key, want := range cases {
+		if got := hashTag(key); got != want {
+			t.Errorf("hashTag(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	client := &Client{cluster: true}
+	if err := client.checkSlot([]string{"a:{42}", "b:{42}"}); err != nil {
+		t.Errorf("Expected keys sharing a hash tag to be accepted, got %v", err)
+	}
+	if err := client.checkSlot([]string{"a:{42}", "b:{43}"}); err != ErrCrossSlot {
+		t.Errorf("Expected ErrCrossSlot, got %v", err)
+	}
+}
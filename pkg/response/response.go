@@ -2,6 +2,8 @@ package response
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,11 +16,36 @@ type Response struct {
 	Meta    *Meta       `json:"meta,omitempty"`
 }
 
+// Meta describes a page of a list. Page and TotalPages are only set in page
+// mode and the cursors only in cursor mode. Total is omitted when the
+// client asked not to count and flagged when it is an estimate.
 type Meta struct {
-	Page       int   `json:"page"`
-	Limit      int   `json:"limit"`
-	Total      int64 `json:"total"`
-	TotalPages int   `json:"total_pages"`
+	Page           int    `json:"page,omitempty"`
+	Limit          int    `json:"limit"`
+	Total          *int64 `json:"total,omitempty"`
+	TotalPages     *int   `json:"total_pages,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+	NextCursor     string `json:"next_cursor,omitempty"`
+	PrevCursor     string `json:"prev_cursor,omitempty"`
+}
+
+// NewMeta creates list metadata. A negative total means the rows were not
+// counted; TotalPages is only set for pages selected by number.
+func NewMeta(page, limit int, total int64) Meta {
+	meta := Meta{Page: page, Limit: limit}
+	if total < 0 {
+		return meta
+	}
+
+	meta.Total = &total
+	if page > 0 {
+		totalPages := int(total) / limit
+		if int(total)%limit > 0 {
+			totalPages++
+		}
+		meta.TotalPages = &totalPages
+	}
+	return meta
 }
 
 type PaginatedData struct {
@@ -26,12 +53,62 @@ type PaginatedData struct {
 	Meta  Meta        `json:"meta"`
 }
 
+// Versioned is implemented by resources that carry a version number.
+// Success sends their version as ETag and answers conditional GET requests
+// with 304 Not Modified.
+type Versioned interface {
+	ResourceVersion() int
+}
+
+// ETag formats a resource version as a strong entity tag.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ParseETag returns the version held by an entity tag created by ETag.
+// Weak tags are accepted.
+func ParseETag(tag string) (int, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// etagListMatches reports whether an If-None-Match value matches etag using
+// weak comparison.
+func etagListMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func Success(c *gin.Context, data interface{}, statusCode ...int) {
 	code := http.StatusOK
 	if len(statusCode) > 0 {
 		code = statusCode[0]
 	}
 
+	if versioned, ok := data.(Versioned); ok {
+		etag := ETag(versioned.ResourceVersion())
+		c.Header("ETag", etag)
+
+		method := c.Request.Method
+		if (method == http.MethodGet || method == http.MethodHead) && etagListMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
 	c.JSON(code, Response{
 		Success: true,
 		Data:    data,
@@ -39,21 +116,16 @@ func Success(c *gin.Context, data interface{}, statusCode ...int) {
 }
 
 func SuccessPaginated(c *gin.Context, data interface{}, page, limit int, total int64) {
-	totalPages := int(total) / limit
-	if int(total)%limit > 0 {
-		totalPages++
-	}
+	SuccessList(c, data, NewMeta(page, limit, total))
+}
 
+// SuccessList writes a page of a list with the given metadata.
+func SuccessList(c *gin.Context, data interface{}, meta Meta) {
 	c.JSON(http.StatusOK, Response{
 		Success: true,
 		Data: PaginatedData{
 			Items: data,
-			Meta: Meta{
-				Page:       page,
-				Limit:      limit,
-				Total:      total,
-				TotalPages: totalPages,
-			},
+			Meta:  meta,
 		},
 	})
 }
@@ -70,3 +142,13 @@ func Error(c *gin.Context, statusCode int, message string, err error) {
 
 	c.JSON(statusCode, resp)
 }
+
+// ErrorWithData writes an error response that also carries a data payload,
+// for failures whose details are useful to the caller.
+func ErrorWithData(c *gin.Context, statusCode int, message string, data interface{}) {
+	c.JSON(statusCode, Response{
+		Success: false,
+		Message: message,
+		Data:    data,
+	})
+}
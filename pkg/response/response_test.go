@@ -54,3 +54,59 @@ type testError struct {
 func (e *testError) Error() string {
 	return e.Msg
 }
+
+type versioned struct {
+	Version int `json:"version"`
+}
+
+func (v versioned) ResourceVersion() int {
+	return v.Version
+}
+
+func TestSuccessETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", func(c *gin.Context) { Success(c, versioned{Version: 3}) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Header().Get("ETag") != `"3"` {
+		t.Errorf("Expected ETag \"3\", got %q", w.Header().Get("ETag"))
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", `"2", W/"3"`)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected empty 304, got %d with %q", w.Code, w.Body.String())
+	}
+}
+
+func TestParseETag(t *testing.T) {
+	if version, ok := ParseETag(ETag(7)); !ok || version != 7 {
+		t.Errorf("Expected version 7, got %d (%v)", version, ok)
+	}
+	for _, tag := range []string{"7", `"abc"`, `"0"`, ""} {
+		if _, ok := ParseETag(tag); ok {
+			t.Errorf("Expected %q to be rejected", tag)
+		}
+	}
+}
+
+func TestNewMeta(t *testing.T) {
+	meta := NewMeta(2, 10, 25)
+	if meta.Total == nil || *meta.Total != 25 || meta.TotalPages == nil || *meta.TotalPages != 3 {
+		t.Errorf("Expected total 25 over 3 pages, got %+v", meta)
+	}
+
+	meta = NewMeta(0, 10, 25)
+	if meta.TotalPages != nil {
+		t.Errorf("Expected no total pages in cursor mode, got %d", *meta.TotalPages)
+	}
+
+	meta = NewMeta(0, 10, -1)
+	if meta.Total != nil {
+		t.Errorf("Expected no total when rows were not counted, got %d", *meta.Total)
+	}
+}
@@ -0,0 +1,109 @@
+package suggest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is an in-process index, used when Redis is not available.
+type Memory struct {
+	mu         sync.Mutex
+	members    []string // sorted
+	popularity map[string]float64
+}
+
+func NewMemory() *Memory {
+	return &Memory{popularity: make(map[string]float64)}
+}
+
+func (m *Memory) Add(ctx context.Context, terms ...Term) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, term := range terms {
+		key := member(term)
+		i := sort.SearchStrings(m.members, key)
+		if i < len(m.members) && m.members[i] == key {
+			continue
+		}
+		m.members = append(m.members, "")
+		copy(m.members[i+1:], m.members[i:])
+		m.members[i] = key
+	}
+	return nil
+}
+
+func (m *Memory) Remove(ctx context.Context, terms ...Term) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, term := range terms {
+		key := member(term)
+		if i := sort.SearchStrings(m.members, key); i < len(m.members) && m.members[i] == key {
+			m.members = append(m.members[:i], m.members[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Replace(ctx context.Context, terms []Term) error {
+	members := make([]string, 0, len(terms))
+	for _, term := range terms {
+		members = append(members, member(term))
+	}
+	sort.Strings(members)
+
+	m.mu.Lock()
+	m.members = members
+	for normalized := range m.popularity {
+		if !m.indexed(normalized) {
+			delete(m.popularity, normalized)
+		}
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Record(ctx context.Context, text string) error {
+	normalized := Normalize(text)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.indexed(normalized) {
+		return nil
+	}
+	if _, ok := m.popularity[normalized]; ok || len(m.popularity) < maxPopularTerms {
+		m.popularity[normalized]++
+	}
+	return nil
+}
+
+// indexed reports whether a term with the normalized text is indexed. The
+// caller must hold m.mu.
+func (m *Memory) indexed(normalized string) bool {
+	prefix := normalized + "\x00"
+	i := sort.SearchStrings(m.members, prefix)
+	return i < len(m.members) && strings.HasPrefix(m.members[i], prefix)
+}
+
+func (m *Memory) Search(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
+	prefix = Normalize(prefix)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	suggestions := make([]Suggestion, 0, limit)
+	for i := sort.SearchStrings(m.members, prefix); i < len(m.members) && len(suggestions) < limit*candidatesPerResult; i++ {
+		if !strings.HasPrefix(m.members[i], prefix) {
+			break
+		}
+		normalized, term, ok := parseMember(m.members[i])
+		if !ok {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{Text: term.Text, Kind: term.Kind, Popularity: m.popularity[normalized]})
+	}
+	return rank(suggestions, limit), nil
+}
@@ -0,0 +1,57 @@
+package suggest
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemorySearch(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	m.Add(ctx,
+		Term{Kind: KindProduct, Text: "Wireless Headphones"},
+		Term{Kind: KindProduct, Text: "Wireless Mouse"},
+		Term{Kind: KindCategory, Text: "Wireless"},
+		Term{Kind: KindProduct, Text: "Wired Keyboard"},
+	)
+	m.Add(ctx, Term{Kind: KindProduct, Text: "Wireless Mouse"})
+
+	suggestions, _ := m.Search(ctx, "  WIRELESS ", 10)
+	if len(suggestions) != 3 {
+		t.Fatalf("Expected 3 suggestions, got %v", suggestions)
+	}
+	if suggestions[0].Text != "Wireless" || suggestions[0].Kind != KindCategory {
+		t.Errorf("Expected shortest match first, got %v", suggestions[0])
+	}
+
+	m.Record(ctx, "wireless mouse")
+	m.Record(ctx, "Wireless  Mouse")
+	suggestions, _ = m.Search(ctx, "wire", 2)
+	if len(suggestions) != 2 || suggestions[0].Text != "Wireless Mouse" || suggestions[0].Popularity != 2 {
+		t.Errorf("Expected popular term first, got %v", suggestions)
+	}
+
+	m.Remove(ctx, Term{Kind: KindProduct, Text: "Wireless Mouse"})
+	m.Replace(ctx, []Term{{Kind: KindProduct, Text: "Wired Keyboard"}})
+	if suggestions, _ = m.Search(ctx, "wireless", 10); len(suggestions) != 0 {
+		t.Errorf("Expected replaced index to drop stale terms, got %v", suggestions)
+	}
+}
+
+func TestMemoryRecordIgnoresUnindexedText(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	m.Add(ctx, Term{Kind: KindProduct, Text: "Wireless Mouse"})
+
+	m.Record(ctx, "wireless mouse")
+	m.Record(ctx, "buy cheap followers")
+	m.Record(ctx, "wireless")
+	if len(m.popularity) != 1 || m.popularity["wireless mouse"] != 1 {
+		t.Errorf("Expected only the indexed text to be counted, got %v", m.popularity)
+	}
+
+	m.Replace(ctx, []Term{{Kind: KindProduct, Text: "Wired Keyboard"}})
+	if len(m.popularity) != 0 {
+		t.Errorf("Expected replacing the index to drop stale popularity, got %v", m.popularity)
+	}
+}
@@ -0,0 +1,215 @@
+package suggest
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Scripter runs Lua scripts; *redis.Client from pkg/redis implements it.
+type Scripter interface {
+	Eval(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// The index is a sorted set with every member at score 0, so members are
+// ordered lexicographically and a prefix is a ZRANGEBYLEX range. The keys
+// share a hash tag so scripts may use them together in cluster mode.
+const (
+	indexKey      = "{suggest}:index"
+	popularityKey = "{suggest}:popularity"
+)
+
+// replaceBatchSize bounds the number of members written per script call
+// while rebuilding the index.
+const replaceBatchSize = 500
+
+var addScript = redis.NewScript(`
+for i = 1, #ARGV do
+  redis.call('ZADD', KEYS[1], 0, ARGV[i])
+end
+return #ARGV
+`)
+
+var removeScript = redis.NewScript(`
+for i = 1, #ARGV do
+  redis.call('ZREM', KEYS[1], ARGV[i])
+end
+return #ARGV
+`)
+
+var deleteScript = redis.NewScript(`
+return redis.call('DEL', KEYS[1])
+`)
+
+var swapScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 1 then
+  redis.call('RENAME', KEYS[2], KEYS[1])
+else
+  redis.call('DEL', KEYS[1])
+end
+return 1
+`)
+
+// recordScript counts a search for an indexed text, whose members start
+// with the text and a NUL byte, and keeps only the most popular texts.
+var recordScript = redis.NewScript(`
+local found = redis.call('ZRANGEBYLEX', KEYS[1], '[' .. ARGV[1] .. '\0', '(' .. ARGV[1] .. '\1', 'LIMIT', 0, 1)
+if #found == 0 then
+  return 0
+end
+redis.call('ZINCRBY', KEYS[2], 1, ARGV[1])
+redis.call('ZREMRANGEBYRANK', KEYS[2], 0, -tonumber(ARGV[2]) - 1)
+return 1
+`)
+
+// searchScript returns matching members each followed by the popularity
+// of its normalized text, the part before the first NUL byte.
+var searchScript = redis.NewScript(`
+local members = redis.call('ZRANGEBYLEX', KEYS[1], ARGV[1], ARGV[2], 'LIMIT', 0, tonumber(ARGV[3]))
+local result = {}
+for _, m in ipairs(members) do
+  local sep = string.find(m, '\0', 1, true)
+  result[#result + 1] = m
+  result[#result + 1] = redis.call('ZSCORE', KEYS[2], string.sub(m, 1, sep - 1)) or '0'
+end
+return result
+`)
+
+// Redis keeps the index in Redis so that every API instance shares it.
+type Redis struct {
+	client Scripter
+}
+
+func NewRedis(client Scripter) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Add(ctx context.Context, terms ...Term) error {
+	return r.write(ctx, addScript, indexKey, terms)
+}
+
+func (r *Redis) Remove(ctx context.Context, terms ...Term) error {
+	return r.write(ctx, removeScript, indexKey, terms)
+}
+
+// Replace builds the new index under a temporary key and renames it over
+// the current one, so searches never see a partial index.
+func (r *Redis) Replace(ctx context.Context, terms []Term) error {
+	tmpKey := indexKey + ":" + uuid.NewString()
+	for start := 0; start < len(terms); start += replaceBatchSize {
+		end := start + replaceBatchSize
+		if end > len(terms) {
+			end = len(terms)
+		}
+		if err := r.write(ctx, addScript, tmpKey, terms[start:end]); err != nil {
+			r.client.Eval(ctx, deleteScript, []string{tmpKey})
+			return err
+		}
+	}
+
+	_, err := r.client.Eval(ctx, swapScript, []string{indexKey, tmpKey})
+	return err
+}
+
+func (r *Redis) Record(ctx context.Context, text string) error {
+	_, err := r.client.Eval(ctx, recordScript, []string{indexKey, popularityKey}, Normalize(text), maxPopularTerms)
+	return err
+}
+
+func (r *Redis) Search(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
+	prefix = Normalize(prefix)
+	raw, err := r.client.Eval(ctx, searchScript, []string{indexKey, popularityKey},
+		"["+prefix, "["+prefix+"\xff", limit*candidatesPerResult)
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := raw.([]interface{})
+	if !ok && raw != nil {
+		return nil, fmt.Errorf("suggest: unexpected script result %v", raw)
+	}
+
+	suggestions := make([]Suggestion, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		m, _ := values[i].(string)
+		score, _ := values[i+1].(string)
+		_, term, ok := parseMember(m)
+		if !ok {
+			continue
+		}
+		popularity, _ := strconv.ParseFloat(score, 64)
+		suggestions = append(suggestions, Suggestion{Text: term.Text, Kind: term.Kind, Popularity: popularity})
+	}
+	return rank(suggestions, limit), nil
+}
+
+func (r *Redis) write(ctx context.Context, script *redis.Script, key string, terms []Term) error {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(terms))
+	for i, term := range terms {
+		members[i] = member(term)
+	}
+	_, err := r.client.Eval(ctx, script, []string{key}, members...)
+	return err
+}
+
+// Fallback searches the Redis index while Redis is reachable and the
+// in-memory index when it is not. Writes go to both, so the in-memory
+// index is warm when Redis becomes unavailable.
+type Fallback struct {
+	primary func() (Scripter, bool)
+	memory  *Memory
+}
+
+// NewFallback takes a function returning the current Redis connection, if
+// any, such as one backed by cache.Fallback.Primary.
+func NewFallback(primary func() (Scripter, bool), memory *Memory) *Fallback {
+	return &Fallback{primary: primary, memory: memory}
+}
+
+func (f *Fallback) Add(ctx context.Context, terms ...Term) error {
+	f.memory.Add(ctx, terms...)
+	if client, ok := f.primary(); ok {
+		return NewRedis(client).Add(ctx, terms...)
+	}
+	return nil
+}
+
+func (f *Fallback) Remove(ctx context.Context, terms ...Term) error {
+	f.memory.Remove(ctx, terms...)
+	if client, ok := f.primary(); ok {
+		return NewRedis(client).Remove(ctx, terms...)
+	}
+	return nil
+}
+
+func (f *Fallback) Replace(ctx context.Context, terms []Term) error {
+	f.memory.Replace(ctx, terms)
+	if client, ok := f.primary(); ok {
+		return NewRedis(client).Replace(ctx, terms)
+	}
+	return nil
+}
+
+func (f *Fallback) Record(ctx context.Context, text string) error {
+	f.memory.Record(ctx, text)
+	if client, ok := f.primary(); ok {
+		return NewRedis(client).Record(ctx, text)
+	}
+	return nil
+}
+
+func (f *Fallback) Search(ctx context.Context, prefix string, limit int) ([]Suggestion, error) {
+	if client, ok := f.primary(); ok {
+		if suggestions, err := NewRedis(client).Search(ctx, prefix, limit); err == nil {
+			return suggestions, nil
+		}
+	}
+	return f.memory.Search(ctx, prefix, limit)
+}
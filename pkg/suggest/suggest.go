@@ -0,0 +1,88 @@
+// Package suggest maintains a prefix index of search terms, such as
+// product names and categories, for autocompletion ranked by popularity.
+package suggest
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+const (
+	KindProduct  = "product"
+	KindCategory = "category"
+)
+
+// Term is an indexed text of a given kind.
+type Term struct {
+	Kind string
+	Text string
+}
+
+// Suggestion is a term matching a prefix. Popularity counts how often the
+// text was searched for or viewed.
+type Suggestion struct {
+	Text       string  `json:"text"`
+	Kind       string  `json:"kind"`
+	Popularity float64 `json:"popularity"`
+}
+
+// Index is a prefix index of terms. Adding a term twice has no effect.
+type Index interface {
+	Add(ctx context.Context, terms ...Term) error
+	Remove(ctx context.Context, terms ...Term) error
+	// Replace swaps the whole index for terms, dropping stale entries.
+	Replace(ctx context.Context, terms []Term) error
+	// Record increases the popularity of every term with text. Texts that
+	// are not indexed are ignored, so that arbitrary searches do not grow
+	// the index.
+	Record(ctx context.Context, text string) error
+	// Search returns up to limit terms starting with prefix, most popular
+	// first.
+	Search(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+}
+
+// maxPopularTerms bounds the number of texts whose popularity is kept.
+const maxPopularTerms = 100000
+
+// candidatesPerResult is how many prefix matches are ranked by popularity
+// for each requested suggestion.
+const candidatesPerResult = 10
+
+// Normalize lowercases text and collapses whitespace, the form terms are
+// matched and popularity is counted in.
+func Normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// member encodes a term so that members sort by normalized text.
+func member(term Term) string {
+	return Normalize(term.Text) + "\x00" + term.Kind + "\x00" + term.Text
+}
+
+func parseMember(m string) (normalized string, term Term, ok bool) {
+	parts := strings.SplitN(m, "\x00", 3)
+	if len(parts) != 3 {
+		return "", Term{}, false
+	}
+	return parts[0], Term{Kind: parts[1], Text: parts[2]}, true
+}
+
+// rank orders suggestions by popularity, then shorter and alphabetically
+// earlier text, and truncates them to limit.
+func rank(suggestions []Suggestion, limit int) []Suggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Popularity != b.Popularity {
+			return a.Popularity > b.Popularity
+		}
+		if len(a.Text) != len(b.Text) {
+			return len(a.Text) < len(b.Text)
+		}
+		return a.Text < b.Text
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
@@ -0,0 +1,81 @@
+package tax
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"suitemedia/pkg/money"
+)
+
+// Fake is the name of the fake calculator.
+const Fake = "fake"
+
+// FakeCalculator stands in for an external tax service in tests. It
+// charges a flat percentage on every line on top of its amount, keeps the
+// requests it receives, and FailNext makes a call fail the way a service
+// outage would.
+type FakeCalculator struct {
+	percent int64
+
+	mu       sync.Mutex
+	requests []Request
+	failure  error
+}
+
+// NewFake returns a calculator charging percent on every line.
+func NewFake(percent int64) *FakeCalculator {
+	return &FakeCalculator{percent: percent}
+}
+
+func (f *FakeCalculator) Name() string {
+	return Fake
+}
+
+func (f *FakeCalculator) Calculate(ctx context.Context, req Request) (*Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	if err := f.failure; err != nil {
+		f.failure = nil
+		return nil, err
+	}
+
+	rate := big.NewRat(f.percent, 100)
+	result := &Result{
+		Lines:   make([]LineTax, len(req.Lines)),
+		Charges: make([]Charge, 0, 1),
+		Total:   money.New(0, req.Currency),
+	}
+	taxable := money.New(0, req.Currency)
+	for i, line := range req.Lines {
+		tax := line.Amount.MulRat(rate)
+		result.Lines[i] = LineTax{ID: line.ID, Rate: formatPercent(big.NewRat(f.percent, 1)), Net: line.Amount, Tax: tax}
+		taxable.Amount += line.Amount.Amount
+		result.Total.Amount += tax.Amount
+	}
+	if len(req.Lines) > 0 {
+		result.Charges = append(result.Charges, Charge{
+			Country: req.Address.Country,
+			Region:  req.Address.Region,
+			Rate:    formatPercent(big.NewRat(f.percent, 1)),
+			Taxable: taxable,
+			Amount:  result.Total,
+		})
+	}
+	return result, nil
+}
+
+// FailNext makes the next call of Calculate fail with err.
+func (f *FakeCalculator) FailNext(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failure = err
+}
+
+// Requests returns the requests received so far.
+func (f *FakeCalculator) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
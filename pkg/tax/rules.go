@@ -0,0 +1,275 @@
+package tax
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"suitemedia/pkg/money"
+)
+
+// RuleBased is the name of the rule calculator.
+const RuleBased = "rules"
+
+// Rule charges Rate percent on lines of Class delivered to Country and
+// Region. An empty Region or Class matches any.
+type Rule struct {
+	Country string
+	Region  string
+	Class   string
+	Rate    string
+	rate    *big.Rat
+}
+
+// ParseRule parses a rule from a key of the form "COUNTRY",
+// "COUNTRY-REGION" or either followed by "/CLASS", such as "US-CA/food",
+// and a percentage between 0 and 100 such as "7.25".
+func ParseRule(key, percent string) (Rule, error) {
+	place, class, hasClass := strings.Cut(strings.TrimSpace(key), "/")
+	country, region, hasRegion := strings.Cut(place, "-")
+	rule := Rule{
+		Country: strings.ToUpper(country),
+		Region:  strings.ToUpper(region),
+		Class:   strings.ToLower(class),
+	}
+	if !isCountry(rule.Country) || (hasRegion && region == "") || (hasClass && class == "") {
+		return Rule{}, fmt.Errorf("tax: invalid rule %q: expected COUNTRY[-REGION][/CLASS]", key)
+	}
+
+	value, ok := new(big.Rat).SetString(strings.TrimSpace(percent))
+	if !ok || strings.Contains(percent, "/") || value.Sign() < 0 || value.Cmp(big.NewRat(100, 1)) > 0 {
+		return Rule{}, fmt.Errorf("tax: invalid rate %q for %q: expected a percentage between 0 and 100", percent, key)
+	}
+	rule.Rate = formatPercent(value)
+	rule.rate = value.Quo(value, big.NewRat(100, 1))
+	return rule, nil
+}
+
+// ParseRules parses rates keyed as for ParseRule, in order of their keys.
+func ParseRules(rates map[string]string) ([]Rule, error) {
+	keys := make([]string, 0, len(rates))
+	for key := range rates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rules := make([]Rule, 0, len(keys))
+	seen := make(map[string]string, len(keys))
+	for _, key := range keys {
+		rule, err := ParseRule(key, rates[key])
+		if err != nil {
+			return nil, err
+		}
+		normalized := rule.Country + "-" + rule.Region + "/" + rule.Class
+		if other, ok := seen[normalized]; ok {
+			return nil, fmt.Errorf("tax: rules %q and %q are the same", other, key)
+		}
+		seen[normalized] = key
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matches reports whether r applies to lines of class delivered to
+// address.
+func (r *Rule) matches(address Address, class string) bool {
+	return r.Country == strings.ToUpper(address.Country) &&
+		(r.Region == "" || r.Region == strings.ToUpper(address.Region)) &&
+		(r.Class == "" || r.Class == class)
+}
+
+// specificity ranks rules matching the same line: a region counts for
+// more than a class.
+func (r *Rule) specificity() int {
+	score := 0
+	if r.Region != "" {
+		score += 2
+	}
+	if r.Class != "" {
+		score++
+	}
+	return score
+}
+
+// RuleCalculator charges the rate of the most specific rule matching each
+// line. Lines no rule matches are not taxed.
+type RuleCalculator struct {
+	rules     []Rule
+	inclusive bool
+	rounding  string
+}
+
+func NewRuleCalculator(settings Settings) (*RuleCalculator, error) {
+	for _, rule := range settings.Rules {
+		if rule.rate == nil {
+			return nil, fmt.Errorf("tax: rule for %s was not made by ParseRule", rule.Country)
+		}
+	}
+	rounding := settings.Rounding
+	switch rounding {
+	case "":
+		rounding = RoundPerLine
+	case RoundPerLine, RoundPerOrder:
+	default:
+		return nil, fmt.Errorf("tax: unknown rounding %q", settings.Rounding)
+	}
+	return &RuleCalculator{rules: settings.Rules, inclusive: settings.PricesIncludeTax, rounding: rounding}, nil
+}
+
+func (c *RuleCalculator) Name() string {
+	return RuleBased
+}
+
+func (c *RuleCalculator) Calculate(ctx context.Context, req Request) (*Result, error) {
+	result := &Result{
+		Inclusive: c.inclusive,
+		Lines:     make([]LineTax, len(req.Lines)),
+		Charges:   make([]Charge, 0),
+		Total:     money.New(0, req.Currency),
+	}
+
+	matched := make([]*Rule, len(req.Lines))
+	exact := make([]*big.Rat, len(req.Lines))
+	for i, line := range req.Lines {
+		if line.Amount.Currency != req.Currency {
+			return nil, fmt.Errorf("tax: line %s is in %s, not %s", line.ID, line.Amount.Currency, req.Currency)
+		}
+		result.Lines[i] = LineTax{ID: line.ID, Net: line.Amount, Tax: money.New(0, req.Currency)}
+
+		rule := c.match(req.Address, line.Class)
+		if rule == nil {
+			continue
+		}
+		matched[i] = rule
+		result.Lines[i].Rate = rule.Rate
+		exact[i] = exactTax(line.Amount.Amount, rule.rate, c.inclusive)
+	}
+
+	var amounts []int64
+	if c.rounding == RoundPerOrder {
+		amounts = roundPerOrder(matched, exact)
+	} else {
+		amounts = make([]int64, len(exact))
+		for i, value := range exact {
+			if value != nil {
+				amounts[i] = money.RoundHalfEven(value)
+			}
+		}
+	}
+
+	charges := make(map[*Rule]int)
+	for i, line := range req.Lines {
+		rule := matched[i]
+		if rule == nil {
+			continue
+		}
+		lineTax := &result.Lines[i]
+		lineTax.Tax.Amount = amounts[i]
+		if c.inclusive {
+			lineTax.Net.Amount = line.Amount.Amount - amounts[i]
+		}
+
+		index, ok := charges[rule]
+		if !ok {
+			index = len(result.Charges)
+			charges[rule] = index
+			result.Charges = append(result.Charges, Charge{
+				Country: rule.Country,
+				Region:  rule.Region,
+				Class:   rule.Class,
+				Rate:    rule.Rate,
+				Taxable: money.New(0, req.Currency),
+				Amount:  money.New(0, req.Currency),
+			})
+		}
+		charge := &result.Charges[index]
+		charge.Taxable.Amount += lineTax.Net.Amount
+		charge.Amount.Amount += amounts[i]
+		result.Total.Amount += amounts[i]
+	}
+	return result, nil
+}
+
+// match returns the most specific rule for lines of class delivered to
+// address, or nil.
+func (c *RuleCalculator) match(address Address, class string) *Rule {
+	var best *Rule
+	for i := range c.rules {
+		rule := &c.rules[i]
+		if rule.matches(address, class) && (best == nil || rule.specificity() > best.specificity()) {
+			best = rule
+		}
+	}
+	return best
+}
+
+// exactTax returns the unrounded tax at rate on amount, which includes the
+// tax when inclusive is set.
+func exactTax(amount int64, rate *big.Rat, inclusive bool) *big.Rat {
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate)
+	if inclusive {
+		value.Quo(value, new(big.Rat).Add(big.NewRat(1, 1), rate))
+	}
+	return value
+}
+
+// roundPerOrder rounds the tax of the lines of each rule once, over their
+// sum, and divides it among the lines: each gets its tax rounded down, and
+// the units left go to the lines with the largest fractions.
+func roundPerOrder(matched []*Rule, exact []*big.Rat) []int64 {
+	amounts := make([]int64, len(exact))
+	groups := make(map[*Rule][]int)
+	var order []*Rule
+	for i, rule := range matched {
+		if rule == nil {
+			continue
+		}
+		if _, ok := groups[rule]; !ok {
+			order = append(order, rule)
+		}
+		groups[rule] = append(groups[rule], i)
+	}
+
+	for _, rule := range order {
+		lines := groups[rule]
+		sum := new(big.Rat)
+		fractions := make(map[int]*big.Rat, len(lines))
+		var floors int64
+		for _, i := range lines {
+			sum.Add(sum, exact[i])
+			floor := new(big.Int).Quo(exact[i].Num(), exact[i].Denom())
+			amounts[i] = floor.Int64()
+			floors += amounts[i]
+			fractions[i] = new(big.Rat).Sub(exact[i], new(big.Rat).SetInt(floor))
+		}
+
+		left := money.RoundHalfEven(sum) - floors
+		sort.SliceStable(lines, func(a, b int) bool { return fractions[lines[a]].Cmp(fractions[lines[b]]) > 0 })
+		for _, i := range lines[:left] {
+			amounts[i]++
+		}
+	}
+	return amounts
+}
+
+// formatPercent writes a percentage with up to four decimals and no
+// trailing zeros.
+func formatPercent(value *big.Rat) string {
+	s := value.FloatString(4)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func isCountry(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
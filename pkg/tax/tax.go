@@ -0,0 +1,134 @@
+// Package tax works out the tax on the lines of an order. Calculators are
+// registered by name: the built-in rule calculator charges configured
+// rates by country, region and tax class, and an external tax service can
+// be plugged in by registering a calculator that calls it.
+package tax
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"suitemedia/pkg/money"
+)
+
+// Tax classes products fall in unless they name another one, and the
+// class of shipping.
+const (
+	ClassStandard = "standard"
+	ClassShipping = "shipping"
+)
+
+// Rounding modes: tax is rounded on every line, or once per rate over the
+// whole order.
+const (
+	RoundPerLine  = "line"
+	RoundPerOrder = "order"
+)
+
+// Address is where an order is taxed. Country is an ISO 3166-1 alpha-2
+// code; Region, such as a state, is optional.
+type Address struct {
+	Country string
+	Region  string
+}
+
+// Line is an amount taxed under a tax class, after discounts.
+type Line struct {
+	ID     string
+	Class  string
+	Amount money.Money
+}
+
+// Request asks for the tax on Lines, all in Currency, delivered to
+// Address.
+type Request struct {
+	Currency string
+	Address  Address
+	Lines    []Line
+}
+
+// LineTax is the tax on a line. Net is the line without tax, which is less
+// than its amount when prices include tax.
+type LineTax struct {
+	ID   string
+	Rate string
+	Net  money.Money
+	Tax  money.Money
+}
+
+// Charge is the tax charged at one rate: Rate percent of Taxable, the net
+// amount of the lines taxed at it.
+type Charge struct {
+	Country string
+	Region  string
+	Class   string
+	Rate    string
+	Taxable money.Money
+	Amount  money.Money
+}
+
+// Result is the tax on a request. Inclusive tells whether the amounts of
+// the lines already included the tax. Lines has the tax of every line of
+// the request, in order, and Charges add it up by rate.
+type Result struct {
+	Inclusive bool
+	Lines     []LineTax
+	Charges   []Charge
+	Total     money.Money
+}
+
+// Calculator works out tax.
+type Calculator interface {
+	Name() string
+	Calculate(ctx context.Context, req Request) (*Result, error)
+}
+
+// Settings configure a calculator. PricesIncludeTax means the amounts of
+// lines include tax, and Rounding is RoundPerLine or RoundPerOrder.
+// Calculators backed by a service may ignore the rules.
+type Settings struct {
+	Rules            []Rule
+	PricesIncludeTax bool
+	Rounding         string
+}
+
+// Factory creates a calculator from settings.
+type Factory func(settings Settings) (Calculator, error)
+
+var calculators = map[string]Factory{
+	RuleBased: func(settings Settings) (Calculator, error) { return NewRuleCalculator(settings) },
+}
+
+// Register makes factory available under name, replacing any calculator
+// of that name. It is meant to be called during initialization and must
+// not race with Open.
+func Register(name string, factory Factory) {
+	calculators[strings.ToLower(name)] = factory
+}
+
+// Open creates the calculator registered under name.
+func Open(name string, settings Settings) (Calculator, error) {
+	factory, ok := calculators[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("tax: unknown calculator %q", name)
+	}
+	return factory(settings)
+}
+
+// Names returns the names of the registered calculators in order.
+func Names() []string {
+	names := make([]string, 0, len(calculators))
+	for name := range calculators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Registered reports whether a calculator is registered under name.
+func Registered(name string) bool {
+	_, ok := calculators[strings.ToLower(name)]
+	return ok
+}
@@ -0,0 +1,183 @@
+package tax
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"suitemedia/pkg/money"
+)
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		key, percent string
+		want         Rule
+		ok           bool
+	}{
+		{"ID", "11", Rule{Country: "ID", Rate: "11"}, true},
+		{"us-ca", "7.250", Rule{Country: "US", Region: "CA", Rate: "7.25"}, true},
+		{"US-CA/Food", "0", Rule{Country: "US", Region: "CA", Class: "food", Rate: "0"}, true},
+		{"DE/reduced", "7", Rule{Country: "DE", Class: "reduced", Rate: "7"}, true},
+		{"USA", "5", Rule{}, false},
+		{"US-", "5", Rule{}, false},
+		{"US/", "5", Rule{}, false},
+		{"US", "-1", Rule{}, false},
+		{"US", "101", Rule{}, false},
+		{"US", "1/3", Rule{}, false},
+	}
+	for _, tc := range cases {
+		rule, err := ParseRule(tc.key, tc.percent)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s=%s: Expected ok %v, got %v", tc.key, tc.percent, tc.ok, err)
+			continue
+		}
+		rule.rate = nil
+		if rule != tc.want {
+			t.Errorf("%s=%s: Expected %+v, got %+v", tc.key, tc.percent, tc.want, rule)
+		}
+	}
+
+	if _, err := ParseRules(map[string]string{"us-ca": "7", "US-CA": "8"}); err == nil {
+		t.Error("Expected rules differing only in case to be rejected")
+	}
+}
+
+func newCalculator(t *testing.T, rates map[string]string, inclusive bool, rounding string) *RuleCalculator {
+	t.Helper()
+	rules, err := ParseRules(rates)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	calculator, err := NewRuleCalculator(Settings{Rules: rules, PricesIncludeTax: inclusive, Rounding: rounding})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return calculator
+}
+
+func TestRuleCalculatorMatchesMostSpecificRule(t *testing.T) {
+	calculator := newCalculator(t, map[string]string{
+		"US":         "5",
+		"US-CA":      "7.25",
+		"US-CA/food": "0",
+		"US/food":    "1",
+	}, false, RoundPerLine)
+
+	req := Request{
+		Currency: "USD",
+		Address:  Address{Country: "us", Region: "ca"},
+		Lines: []Line{
+			{ID: "shirt", Class: ClassStandard, Amount: money.New(10000, "USD")},
+			{ID: "bread", Class: "food", Amount: money.New(500, "USD")},
+			{ID: "shipping", Class: ClassShipping, Amount: money.New(1000, "USD")},
+		},
+	}
+	result, err := calculator.Calculate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Lines[0].Rate != "7.25" || result.Lines[0].Tax != money.New(725, "USD") {
+		t.Errorf("Expected the state rate on the shirt, got %+v", result.Lines[0])
+	}
+	if result.Lines[1].Rate != "0" || !result.Lines[1].Tax.IsZero() {
+		t.Errorf("Expected food to be exempt in the state, got %+v", result.Lines[1])
+	}
+	if result.Total != money.New(797, "USD") || len(result.Charges) != 2 {
+		t.Errorf("Expected 7.97 USD in two charges, got %+v in %+v", result.Total, result.Charges)
+	}
+	if charge := result.Charges[0]; charge.Region != "CA" || charge.Taxable != money.New(11000, "USD") {
+		t.Errorf("Expected the shirt and shipping taxed at the state rate, got %+v", charge)
+	}
+
+	req.Address = Address{Country: "FR"}
+	result, err = calculator.Calculate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Total.IsZero() || len(result.Charges) != 0 || result.Lines[0].Net != money.New(10000, "USD") {
+		t.Errorf("Expected no tax without a matching rule, got %+v", result)
+	}
+}
+
+func TestRuleCalculatorRounding(t *testing.T) {
+	// Three lines of 0.10 at 5% owe 0.005 each: rounded per line that is
+	// nothing, rounded over the order it is 0.015, so 0.02.
+	req := Request{Currency: "USD", Address: Address{Country: "NL"}}
+	for _, id := range []string{"a", "b", "c"} {
+		req.Lines = append(req.Lines, Line{ID: id, Class: ClassStandard, Amount: money.New(10, "USD")})
+	}
+
+	perLine, err := newCalculator(t, map[string]string{"NL": "5"}, false, RoundPerLine).Calculate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !perLine.Total.IsZero() {
+		t.Errorf("Expected no tax when rounding per line, got %+v", perLine.Total)
+	}
+
+	perOrder, err := newCalculator(t, map[string]string{"NL": "5"}, false, RoundPerOrder).Calculate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var sum int64
+	for _, line := range perOrder.Lines {
+		sum += line.Tax.Amount
+	}
+	if perOrder.Total != money.New(2, "USD") || sum != 2 {
+		t.Errorf("Expected 2 cents spread over the lines when rounding per order, got %+v over %+v", perOrder.Total, perOrder.Lines)
+	}
+}
+
+func TestRuleCalculatorInclusivePrices(t *testing.T) {
+	calculator := newCalculator(t, map[string]string{"DE": "19"}, true, RoundPerLine)
+	result, err := calculator.Calculate(context.Background(), Request{
+		Currency: "EUR",
+		Address:  Address{Country: "DE"},
+		Lines:    []Line{{ID: "book", Class: ClassStandard, Amount: money.New(11900, "EUR")}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Inclusive || result.Lines[0].Tax != money.New(1900, "EUR") || result.Lines[0].Net != money.New(10000, "EUR") {
+		t.Errorf("Expected 19.00 of tax included in 119.00, got %+v", result.Lines[0])
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open("rules", Settings{Rounding: "sometimes"}); err == nil {
+		t.Error("Expected an unknown rounding to be rejected")
+	}
+	if _, err := Open("unknown", Settings{}); err == nil {
+		t.Error("Expected an unknown calculator to be rejected")
+	}
+
+	fake := NewFake(10)
+	Register("external", func(Settings) (Calculator, error) { return fake, nil })
+	defer delete(calculators, "external")
+	calculator, err := Open("External", Settings{})
+	if err != nil || calculator != fake {
+		t.Errorf("Expected the registered calculator, got %v, %v", calculator, err)
+	}
+}
+
+func TestFakeCalculator(t *testing.T) {
+	fake := NewFake(10)
+	req := Request{Currency: "USD", Lines: []Line{{ID: "a", Amount: money.New(1234, "USD")}}}
+
+	result, err := fake.Calculate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Total != money.New(123, "USD") || len(fake.Requests()) != 1 {
+		t.Errorf("Expected 10%% tax and the request to be kept, got %+v", result)
+	}
+
+	outage := errors.New("tax service unavailable")
+	fake.FailNext(outage)
+	if _, err := fake.Calculate(context.Background(), req); err != outage {
+		t.Errorf("Expected the failure, got %v", err)
+	}
+	if _, err := fake.Calculate(context.Background(), req); err != nil {
+		t.Errorf("Expected the failure to happen once, got %v", err)
+	}
+}